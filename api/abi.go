@@ -0,0 +1,97 @@
+package webapi
+
+import (
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/contracts"
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// abiSourceHeader tells where the ABI returned by HandleABI came from
+	abiSourceHeader = "X-ABI-Source"
+
+	// maxABIUploadSize caps the body of an ABI upload
+	maxABIUploadSize = 1 << 20
+)
+
+var abiUploadToken string
+
+// SetABIUploadToken sets the token that authorizes ABI uploads, which are
+// disabled when it is empty
+func SetABIUploadToken(token string) {
+	abiUploadToken = token
+}
+
+// authorizedABIUpload checks the bearer token of an upload request
+func authorizedABIUpload(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(abiUploadToken)) == 1
+}
+
+// HandleUploadABI stores the ABI of a contract, for contracts the node has
+// none for. The body is the ABI as a JSON array.
+func HandleUploadABI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		requestLogger(r).Error("DBClient is not initialized")
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	if abiUploadToken == "" {
+		http.Error(w, "error", http.StatusForbidden)
+		return
+	}
+	if !authorizedABIUpload(r) {
+		http.Error(w, "error", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	address, ok := mux.Vars(r)["address"]
+	if !ok || !common.IsHexAddress(address) {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxABIUploadSize))
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	abi, err := contracts.ParseABIJSON(body)
+	if err != nil {
+		requestLogger(r).Debug("Failed to parse ABI", "err", err)
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	contractAddress := common.HexToAddress(address)
+
+	requestLogger(r).Info("Uploading ABI", "address", contractAddress)
+
+	if err := dbc.InsertContractABI(contractAddress, string(abi), uint64(time.Now().Unix())); err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(abi)
+}
@@ -0,0 +1,409 @@
+package webapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// cacheHeader tells whether a response was served from the cache
+	cacheHeader = "X-Cache"
+
+	CACHE_HIT   = "HIT"
+	CACHE_STALE = "STALE"
+	CACHE_MISS  = "MISS"
+
+	// maxCachedBodySize caps the responses that are stored in the cache
+	maxCachedBodySize = 1 << 20
+
+	// immutableCacheTTL is how long the responses for blocks with enough
+	// confirmations are cached, as they won't change anymore
+	immutableCacheTTL = 24 * time.Hour
+)
+
+// the keys of the routes that aren't keyed by their path, which the
+// invalidation has to know
+const (
+	addressCachePrefix   = "address"
+	statsCachePrefix     = "stats"
+	delegatesCachePrefix = "delegates"
+	chainInfoCacheKey    = "chainInfo"
+)
+
+// cacheConfirmations is how many blocks a block needs on top of it for its
+// responses to be cached for immutableCacheTTL, none disables it
+var cacheConfirmations = uint64(30)
+
+// cacheRule is how the responses of a route are cached. Responses are
+// fresh for TTL, and are served for Stale more while they are refreshed in
+// the background.
+type cacheRule struct {
+	TTL   time.Duration
+	Stale time.Duration
+
+	// Key returns the redis key of a request, its path and query when unset
+	Key func(r *http.Request) string
+
+	// BlockNumber returns the block a response is about, if any, so that
+	// it is cached for longer once the block is confirmed
+	BlockNumber func(r *http.Request, body []byte) (uint64, bool)
+}
+
+// cacheRules maps the path templates of the routes to how they are cached.
+// Routes without a rule aren't cached.
+var cacheRules = map[string]*cacheRule{
+	"/block/{param}":                {TTL: time.Second, Stale: 10 * time.Second, BlockNumber: blockResponseNumber},
+	"/transaction/{ref:(?:latest)}": {TTL: time.Second, Stale: 10 * time.Second},
+	"/transaction/{hash}":           {TTL: time.Second, Stale: 10 * time.Second, BlockNumber: transactionResponseNumber},
+	"/transaction/{ref}/{address}":  {TTL: time.Second, Stale: 10 * time.Second},
+	"/address/{address}":            {TTL: time.Second, Stale: 10 * time.Second, Key: addressCacheKey(addressCachePrefix, "address")},
+	"/stats":                        {TTL: time.Second, Stale: 10 * time.Second, Key: addressCacheKey(statsCachePrefix, "address")},
+	"/stats/{address}":              {TTL: time.Second, Stale: 10 * time.Second, Key: addressCacheKey(statsCachePrefix, "address")},
+	"/rich-list":                    {TTL: time.Second, Stale: 10 * time.Second},
+	"/delegates":                    {TTL: time.Second, Stale: 10 * time.Second, Key: varCacheKey(delegatesCachePrefix, "number")},
+	"/delegates/{number}":           {TTL: time.Second, Stale: 10 * time.Second, Key: varCacheKey(delegatesCachePrefix, "number"), BlockNumber: delegatesNumber},
+	"/chain-info":                   {TTL: time.Second, Stale: 10 * time.Second, Key: staticCacheKey(chainInfoCacheKey)},
+	"/conversion-rate":              {TTL: time.Hour, Stale: time.Hour, Key: staticCacheKey("rates:EBK")},
+	"/charts/{metric}":              {TTL: time.Minute, Stale: 5 * time.Minute},
+}
+
+// SetCacheConfirmations sets how many confirmations a block needs for its
+// responses to be cached for longer
+func SetCacheConfirmations(confirmations uint64) {
+	cacheConfirmations = confirmations
+}
+
+// SetCacheTTLs overrides the TTLs of routes, given as path=ttl, e.g.
+// /stats=5s. A TTL of zero disables the cache for the route.
+func SetCacheTTLs(overrides []string) error {
+	for _, override := range overrides {
+		i := strings.LastIndex(override, "=")
+		if i < 0 {
+			return fmt.Errorf("invalid cache TTL %q, expected path=ttl", override)
+		}
+
+		path := override[:i]
+		ttl, err := time.ParseDuration(override[i+1:])
+		if err != nil {
+			return fmt.Errorf("invalid cache TTL %q: %v", override, err)
+		}
+
+		rule, ok := cacheRules[path]
+		if !ok {
+			return fmt.Errorf("invalid cache TTL %q, %s is not a cached route", override, path)
+		}
+
+		if ttl <= 0 {
+			delete(cacheRules, path)
+			continue
+		}
+		r := *rule
+		r.TTL = ttl
+		cacheRules[path] = &r
+	}
+	return nil
+}
+
+// staticCacheKey keys all the requests of a route the same
+func staticCacheKey(key string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return key
+	}
+}
+
+// addressCacheKey keys requests by the checksummed address of a route variable
+func addressCacheKey(prefix string, name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if address := mux.Vars(r)[name]; common.IsHexAddress(address) {
+			return addressKey(prefix, common.HexToAddress(address))
+		}
+		return prefix
+	}
+}
+
+func addressKey(prefix string, address common.Address) string {
+	return prefix + ":" + address.Hex()
+}
+
+// varCacheKey keys requests by a route variable, if set
+func varCacheKey(prefix string, name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if v := mux.Vars(r)[name]; v != "" {
+			return prefix + ":" + v
+		}
+		return prefix
+	}
+}
+
+// requestCacheKey keys requests by their path and query
+func requestCacheKey(r *http.Request) string {
+	return pathCacheKey(r.URL.Path, r.URL.Query())
+}
+
+// pathCacheKey is the key of a path and query, where hex values are
+// lowercased as they are case insensitive
+func pathCacheKey(path string, query url.Values) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "0x") {
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return "http:" + strings.Join(parts, "/") + "?" + query.Encode()
+}
+
+func blockResponseNumber(r *http.Request, body []byte) (uint64, bool) {
+	var block struct {
+		Number *uint64     `json:"number"`
+		Hash   common.Hash `json:"hash"`
+	}
+	if err := json.Unmarshal(body, &block); err != nil || block.Number == nil || block.Hash == (common.Hash{}) {
+		return 0, false
+	}
+	return *block.Number, true
+}
+
+func transactionResponseNumber(r *http.Request, body []byte) (uint64, bool) {
+	var tx struct {
+		BlockNumber *uint64     `json:"blockNumber"`
+		Hash        common.Hash `json:"hash"`
+	}
+	if err := json.Unmarshal(body, &tx); err != nil || tx.BlockNumber == nil || tx.Hash == (common.Hash{}) {
+		return 0, false
+	}
+	return *tx.BlockNumber, true
+}
+
+func delegatesNumber(r *http.Request, body []byte) (uint64, bool) {
+	number, err := strconv.ParseUint(mux.Vars(r)["number"], 10, 64)
+	return number, err == nil
+}
+
+// key returns the redis key of a request
+func (rule *cacheRule) key(r *http.Request) string {
+	if rule.Key != nil {
+		return rule.Key(r)
+	}
+	return requestCacheKey(r)
+}
+
+// ttl returns how long a response is fresh for, longer for confirmed blocks
+func (rule *cacheRule) ttl(r *http.Request, body []byte) time.Duration {
+	if rule.BlockNumber == nil || cacheConfirmations == 0 {
+		return rule.TTL
+	}
+
+	number, ok := rule.BlockNumber(r, body)
+	if !ok {
+		return rule.TTL
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		return rule.TTL
+	}
+
+	latest, err := dbc.GetLatestBlockNumber()
+	if err != nil || latest < number+cacheConfirmations {
+		return rule.TTL
+	}
+	return immutableCacheTTL
+}
+
+// cachedResponse is a response as stored in the cache
+type cachedResponse struct {
+	Status   int           `json:"status"`
+	Header   http.Header   `json:"header"`
+	Body     []byte        `json:"body"`
+	StoredAt int64         `json:"storedAt"`
+	TTL      time.Duration `json:"ttl"`
+}
+
+func (res *cachedResponse) fresh(now time.Time) bool {
+	return now.Before(time.Unix(0, res.StoredAt).Add(res.TTL))
+}
+
+func (res *cachedResponse) writeTo(w http.ResponseWriter, state string) {
+	for name, values := range res.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(cacheHeader, state)
+	w.WriteHeader(res.Status)
+	w.Write(res.Body)
+}
+
+func loadCachedResponse(key string) *cachedResponse {
+	data, ok := redis.GetCache().Get(key)
+	if !ok {
+		return nil
+	}
+
+	// entries of an older format are treated as missing
+	var res cachedResponse
+	if err := json.Unmarshal(data, &res); err != nil || res.Status == 0 {
+		return nil
+	}
+	return &res
+}
+
+func storeCachedResponse(key string, res *cachedResponse, stale time.Duration) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		logger.Error("Failed to encode cached response", "err", err)
+		return
+	}
+
+	redis.GetCache().Set(key, data, res.TTL+stale)
+}
+
+// responseRecorder captures a response to store it in the cache
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(b)
+}
+
+// fetch runs the handler of a request and caches its response, if it
+// succeeded
+func (rule *cacheRule) fetch(next http.Handler, r *http.Request, key string) *cachedResponse {
+	rec := &responseRecorder{header: make(http.Header)}
+	next.ServeHTTP(rec, r)
+	rec.WriteHeader(http.StatusOK)
+
+	res := &cachedResponse{
+		Status:   rec.status,
+		Header:   rec.header,
+		Body:     rec.body.Bytes(),
+		StoredAt: time.Now().UnixNano(),
+	}
+
+	if res.Status == http.StatusOK && len(res.Body) <= maxCachedBodySize {
+		res.TTL = rule.ttl(r, res.Body)
+		storeCachedResponse(key, res, rule.Stale)
+	}
+	return res
+}
+
+// flightGroup runs a function once per key at a time, and shares its
+// result with the callers asking for the same key meanwhile
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg  sync.WaitGroup
+	res *cachedResponse
+}
+
+func (g *flightGroup) Do(key string, fn func() *cachedResponse) *cachedResponse {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.res
+	}
+
+	c := &flightCall{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// release the callers waiting even if fn panics
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.res = fn()
+	return c.res
+}
+
+var cacheFlight = &flightGroup{calls: make(map[string]*flightCall)}
+
+// detachedContext keeps the values of a request's context, like the route
+// variables, without its cancellation, for refreshes that outlive it
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// CacheMiddleware caches the responses of the routes in cacheRules. Only
+// one request per key reaches the handler at a time, the rest wait for its
+// response, and stale responses are served while they are refreshed in
+// the background. Exports are never cached.
+func CacheMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule := cacheRules[routeTemplate(r)]
+
+		if rule == nil || r.Method != "GET" || r.URL.Query().Get("format") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := rule.key(r)
+
+		if cached := loadCachedResponse(key); cached != nil {
+			if cached.fresh(time.Now()) {
+				cached.writeTo(w, CACHE_HIT)
+				return
+			}
+
+			refresh := r.WithContext(detachedContext{r.Context()})
+			go cacheFlight.Do(key, func() *cachedResponse {
+				return rule.fetch(next, refresh, key)
+			})
+
+			cached.writeTo(w, CACHE_STALE)
+			return
+		}
+
+		// the response is shared with the requests waiting for it, so it is
+		// fetched without the cancellation of the request that got first
+		fetch := r.WithContext(detachedContext{r.Context()})
+		res := cacheFlight.Do(key, func() *cachedResponse {
+			return rule.fetch(next, fetch, key)
+		})
+		if res == nil {
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+
+		res.writeTo(w, CACHE_MISS)
+	})
+}
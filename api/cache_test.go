@@ -0,0 +1,62 @@
+package webapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCacheMissOutlivesFirstRequest(t *testing.T) {
+	redis.SetCache(redis.NewMemoryCache(redis.DefaultCacheSize))
+
+	started, release := make(chan struct{}), make(chan struct{})
+	router := mux.NewRouter()
+	router.Use(CacheMiddleware)
+	router.HandleFunc("/chain-info", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+
+		// handlers fail the requests whose context is done
+		if err := r.Context().Err(); err != nil {
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("{}"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := httptest.NewRecorder()
+	firstDone := make(chan struct{})
+	go func() {
+		router.ServeHTTP(first, httptest.NewRequest("GET", "/chain-info", nil).WithContext(ctx))
+		close(firstDone)
+	}()
+	<-started
+
+	second := httptest.NewRecorder()
+	secondDone := make(chan struct{})
+	go func() {
+		router.ServeHTTP(second, httptest.NewRequest("GET", "/chain-info", nil))
+		close(secondDone)
+	}()
+
+	// the first client goes away while the second waits for its response
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	close(release)
+	<-firstDone
+	<-secondDone
+
+	if second.Code != http.StatusOK || second.Header().Get(cacheHeader) != CACHE_MISS {
+		t.Errorf("waiting request = %d %s, want 200 %s", second.Code, second.Header().Get(cacheHeader), CACHE_MISS)
+	}
+	if cached := loadCachedResponse(staticCacheKey(chainInfoCacheKey)(nil)); cached == nil || cached.Status != http.StatusOK {
+		t.Errorf("cached response = %+v, want the response of the handler", cached)
+	}
+}
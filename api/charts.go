@@ -0,0 +1,135 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxChartPoints caps the buckets of a single chart request
+	maxChartPoints = 1000
+)
+
+// chartMetrics maps the metrics of the charts route to their rollup value
+var chartMetrics = map[string]func(models.Rollup) uint64{
+	"tx_count":             func(r models.Rollup) uint64 { return r.TxCount },
+	"active_addresses":     func(r models.Rollup) uint64 { return r.ActiveAddresses },
+	"new_addresses":        func(r models.Rollup) uint64 { return r.NewAddresses },
+	"gas_used":             func(r models.Rollup) uint64 { return r.GasUsed },
+	"avg_block_size":       func(r models.Rollup) uint64 { return r.AvgBlockSize() },
+	"contract_deployments": func(r models.Rollup) uint64 { return r.ContractDeployments },
+	"failed_txs":           func(r models.Rollup) uint64 { return r.FailedTxs },
+}
+
+// chartDefaultPoints is the range returned when from is not set
+var chartDefaultPoints = map[string]uint64{
+	models.ROLLUP_INTERVAL_HOUR: 48,
+	models.ROLLUP_INTERVAL_DAY:  30,
+}
+
+type chartPoint struct {
+	Timestamp uint64 `json:"timestamp"`
+	Value     uint64 `json:"value"`
+}
+
+type chartResult struct {
+	Metric   string       `json:"metric"`
+	Interval string       `json:"interval"`
+	Data     []chartPoint `json:"data"`
+}
+
+// HandleCharts returns the time series of a metric, one point per hour or
+// day, with zeros for the periods without activity
+func HandleCharts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		requestLogger(r).Error("DBClient is not initialized")
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	metric := mux.Vars(r)["metric"]
+	value, ok := chartMetrics[metric]
+	if !ok {
+		http.Error(w, "error", http.StatusNotFound)
+		return
+	}
+
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = models.ROLLUP_INTERVAL_DAY
+	}
+	length, ok := models.RollupIntervals[interval]
+	if !ok {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	// to is inclusive, parseExportTime returns the exclusive bound
+	to, err := parseExportTime(r.URL.Query().Get("to"), true)
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+	if to == 0 {
+		to = uint64(time.Now().Unix())
+	} else {
+		to--
+	}
+
+	from, err := parseExportTime(r.URL.Query().Get("from"), false)
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+	if span := (chartDefaultPoints[interval] - 1) * length; from == 0 && to-to%length > span {
+		from = to - to%length - span
+	}
+
+	buckets := models.RollupBuckets(interval, from, to)
+	if len(buckets) == 0 || len(buckets) > maxChartPoints {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	rollups, err := dbc.GetRollups(interval, from, to)
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	values := make(map[uint64]uint64, len(rollups))
+	for _, rollup := range rollups {
+		values[rollup.Bucket] = value(rollup)
+	}
+
+	result := chartResult{Metric: metric, Interval: interval, Data: make([]chartPoint, len(buckets))}
+	for i, bucket := range buckets {
+		result.Data[i] = chartPoint{Timestamp: bucket, Value: values[bucket]}
+	}
+
+	res, err := json.Marshal(result)
+
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+	} else {
+		w.Write(res)
+	}
+}
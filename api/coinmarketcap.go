@@ -5,11 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+
 	"github.com/urfave/cli"
 )
 
@@ -50,7 +51,7 @@ func GetLatestUSDConversionRate() ([]byte, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
 	req, err := http.NewRequest("GET", coinmarketcapEndpoint, nil)
 	if err != nil {
-		log.Printf("! Failed to create HTTP request. Error: %s", err.Error())
+		logger.Error("Failed to create the conversion rate request", "err", err)
 		return nil, errors.New("Failed to fetch conversion rate")
 	}
 
@@ -65,7 +66,7 @@ func GetLatestUSDConversionRate() ([]byte, error) {
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Printf("! Error sending HTTP request to server: %s", err.Error())
+		logger.Error("Failed to request the conversion rate", "err", err)
 		return nil, errors.New("Failed to fetch conversion rate")
 	}
 
@@ -75,7 +76,7 @@ func GetLatestUSDConversionRate() ([]byte, error) {
 	json.Unmarshal(respBody, &rate)
 
 	if rate.Data.Symbol != "EBK" {
-		log.Printf("! API returned data for wrong coin. (%s)", rate.Data.Symbol)
+		logger.Error("Conversion rate returned for the wrong coin", "symbol", rate.Data.Symbol)
 		return nil, errors.New("Failed to fetch conversion rate")
 	}
 
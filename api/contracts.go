@@ -0,0 +1,209 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/contracts"
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/rpc"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxContractCallSize caps the body of the contract call and storage requests
+	maxContractCallSize = 64 << 10
+)
+
+var (
+	contractCallTimeout = 5 * time.Second
+	contractCallGas     = uint64(10000000)
+)
+
+// SetContractCallLimits sets the timeout and the gas given to the calls
+// made by HandleContractCall
+func SetContractCallLimits(timeout time.Duration, gas uint64) {
+	contractCallTimeout = timeout
+	contractCallGas = gas
+}
+
+// contractCallRequest names the function to call, either by name or by
+// signature, with its arguments as JSON values. Block is a block number,
+// or "latest" when it is empty.
+type contractCallRequest struct {
+	Function string            `json:"function"`
+	Args     []json.RawMessage `json:"args"`
+	Block    json.RawMessage   `json:"block"`
+}
+
+// parseBlockNumber parses the block of a request, either a number or a
+// string, returning nil for the latest
+func parseBlockNumber(block json.RawMessage) (*uint64, bool) {
+	if len(block) == 0 || string(block) == "null" {
+		return nil, true
+	}
+
+	var s string
+	if err := json.Unmarshal(block, &s); err != nil {
+		s = string(block)
+	}
+	if s == "latest" {
+		return nil, true
+	}
+
+	number, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &number, true
+}
+
+// HandleContractCall runs a function of a contract with eth_call and
+// returns its decoded outputs, so that view functions can be queried
+// without a wallet
+func HandleContractCall(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	if ipc.GetIPC() == nil {
+		requestLogger(r).Error("IPC connection is not initialized")
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	address, ok := mux.Vars(r)["address"]
+	if !ok || !common.IsHexAddress(address) {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	var req contractCallRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxContractCallSize)).Decode(&req); err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	blockNumber, ok := parseBlockNumber(req.Block)
+	if !ok || req.Function == "" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), contractCallTimeout)
+	defer cancel()
+
+	requestLogger(r).Debug("Request contract call", "address", address, "function", req.Function)
+
+	result, err := contracts.Call(ctx, common.HexToAddress(address), req.Function, req.Args, contractCallGas, blockNumber)
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+
+		if argErr, ok := err.(*contracts.ArgumentError); ok {
+			http.Error(w, argErr.Error(), http.StatusBadRequest)
+		} else if ctx.Err() == context.DeadlineExceeded {
+			http.Error(w, "timeout", http.StatusGatewayTimeout)
+		} else if rpcErr, ok := err.(rpc.Error); ok {
+			// the call failed on the node, e.g. it reverted
+			http.Error(w, rpcErr.Error(), http.StatusUnprocessableEntity)
+		} else {
+			http.Error(w, "error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	res, err := json.Marshal(result)
+
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+	} else {
+		w.Write(res)
+	}
+}
+
+// contractStorageRequest is the layout of the variable to read, and the
+// block to read it at
+type contractStorageRequest struct {
+	contracts.StorageLayout
+	Block json.RawMessage `json:"block"`
+}
+
+// HandleContractStorage reads a storage slot of a contract. GET requests
+// read the raw slot given by the slot, type and block query parameters,
+// while POST requests take a layout to compute the slot of mapping values
+// and array elements.
+func HandleContractStorage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	if ipc.GetIPC() == nil {
+		requestLogger(r).Error("IPC connection is not initialized")
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	address, ok := mux.Vars(r)["address"]
+	if !ok || !common.IsHexAddress(address) {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	var req contractStorageRequest
+	if r.Method == "POST" {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxContractCallSize)).Decode(&req); err != nil {
+			requestLogger(r).Debug("Bad request", "err", err)
+			http.Error(w, "error", http.StatusBadRequest)
+			return
+		}
+	} else {
+		query := r.URL.Query()
+		req.Slot = json.RawMessage(strconv.Quote(query.Get("slot")))
+		req.Type = query.Get("type")
+		if block := query.Get("block"); block != "" {
+			req.Block = json.RawMessage(strconv.Quote(block))
+		}
+	}
+
+	blockNumber, ok := parseBlockNumber(req.Block)
+	if !ok {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	requestLogger(r).Debug("Request contract storage", "address", address, "slot", string(req.Slot))
+
+	result, err := contracts.ReadStorage(r.Context(), common.HexToAddress(address), req.StorageLayout, blockNumber)
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+
+		if argErr, ok := err.(*contracts.ArgumentError); ok {
+			http.Error(w, argErr.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	res, err := json.Marshal(result)
+
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+	} else {
+		w.Write(res)
+	}
+}
@@ -26,28 +26,17 @@ var (
 
 func getSignerAtSlot(delegates []common.Address, slot float64) common.Address {
 	dposConfig := params.MainnetDPOSConfig
-	if chainID, err := GetChainId(); chainID != 10 && err == nil {
-		dposConfig = params.TestnetDPOSConfig
+	if chainID, err := GetChainId(); err == nil {
+		dposConfig = ipc.DPOSConfig(chainID)
 	}
 
-	if dposConfig.DelegateCount == 0 || dposConfig.TurnBlockCount == 0 {
-		return common.Address{}
-	}
-
-	slot = slot / float64(dposConfig.TurnBlockCount)
-	s := int(slot) % int(dposConfig.DelegateCount)
-
-	if s < len(delegates) {
-		return delegates[s]
-	}
-
-	return common.Address{}
+	return ipc.SignerAtSlot(dposConfig, delegates, slot)
 }
 
 func getDelegatesStats(address string) (map[string]interface{}, error) {
 	dposConfig := params.MainnetDPOSConfig
-	if chainID, err := GetChainId(); chainID != 10 && err == nil {
-		dposConfig = params.TestnetDPOSConfig
+	if chainID, err := GetChainId(); err == nil {
+		dposConfig = ipc.DPOSConfig(chainID)
 	}
 
 	isAddressLookup := common.IsHexAddress(address)
@@ -0,0 +1,147 @@
+package webapi
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+// statsDay is divisible by the 18 seconds of a testnet round, 3 delegates
+// producing 6 blocks each, so the producer of the block at statsDay+offset
+// is statsDelegates[offset/6%3]
+const statsDay = 1583020800
+
+var statsDelegates = []common.Address{
+	common.HexToAddress("0x1111111111111111111111111111111111111111"),
+	common.HexToAddress("0x2222222222222222222222222222222222222222"),
+	common.HexToAddress("0x3333333333333333333333333333333333333333"),
+}
+
+// setupStatsChain indexes a block every second for 400 seconds, except at
+// offsets 100 and 101, where the slots were missed. The blocks at offsets
+// 50 and 390, slots of the third delegate, were produced by other ones.
+func setupStatsChain(t *testing.T) {
+	t.Helper()
+
+	node, err := ipc.NewFakeNode(&ipc.FakeFixture{
+		ChainID: 5,
+		Delegates: map[string][]models.DelegateVoteInfo{
+			"0": {{Address: statsDelegates[2], Stake: 30000, Elected: true}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipc.SetIPC(node)
+
+	// the chain id is cached
+	redis.SetCache(redis.NewMemoryCache(redis.DefaultCacheSize))
+
+	var blocks []*models.Block
+	for offset := uint64(0); offset < 400; offset++ {
+		if offset == 100 || offset == 101 {
+			continue
+		}
+
+		producer := statsDelegates[offset/6%3]
+		switch offset {
+		case 50:
+			producer = statsDelegates[1]
+		case 390:
+			producer = statsDelegates[0]
+		}
+
+		blocks = append(blocks, &models.Block{
+			Number:    hexutil.Uint64(len(blocks)),
+			TimeStamp: hexutil.Uint64(statsDay + offset),
+			Hash:      common.BigToHash(new(big.Int).SetUint64(offset + 1)),
+			Delegates: statsDelegates,
+			Producer:  producer,
+		})
+	}
+
+	store := db.NewMemoryStore()
+	if err := store.InsertBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+	db.SetClient(store)
+}
+
+func TestDelegatesStats(t *testing.T) {
+	setupStatsChain(t)
+
+	result, err := getDelegatesStats("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the two missed slots and the two blocks of other producers
+	if missed := result["total_missed_blocks"]; missed != 4 {
+		t.Errorf("total_missed_blocks = %v, want 4", missed)
+	}
+	if seconds := result["total_seconds_examined"]; seconds != 60*60 {
+		t.Errorf("total_seconds_examined = %v, want 3600", seconds)
+	}
+
+	// the 5 minutes period holds the 301 blocks before the latest one, from
+	// offset 96 to 398
+	expected := map[common.Address]models.DelegateInfo{
+		statsDelegates[0]: {TotalBlocks: 99, MissedBlocks: 0},
+		statsDelegates[1]: {TotalBlocks: 100, MissedBlocks: 0},
+		statsDelegates[2]: {TotalBlocks: 102, MissedBlocks: 1},
+	}
+
+	delegates := result["delegates"].([][]models.DelegateInfo)
+	if len(delegates) != len(expected) {
+		t.Fatalf("got %d delegates, want %d", len(delegates), len(expected))
+	}
+	for _, periods := range delegates {
+		if len(periods) != 1 {
+			t.Fatalf("got %d periods, want the 5 minutes one only", len(periods))
+		}
+		info := periods[0]
+
+		want, ok := expected[info.Address]
+		if !ok {
+			t.Errorf("unexpected delegate %x", info.Address)
+			continue
+		}
+		density := 1 - float64(want.MissedBlocks)/float64(want.TotalBlocks)
+		if info.SecondsExamined != 300 || info.TotalBlocks != want.TotalBlocks || info.MissedBlocks != want.MissedBlocks || math.Abs(info.Density-density) > 1e-9 {
+			t.Errorf("delegate %x = %+v, want %d blocks, %d missed", info.Address, info, want.TotalBlocks, want.MissedBlocks)
+		}
+	}
+}
+
+func TestDelegateStats(t *testing.T) {
+	setupStatsChain(t)
+
+	address := statsDelegates[2].Hex()
+	result, err := getDelegatesStats(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result["address"] != address {
+		t.Errorf("address = %v, want %s", result["address"], address)
+	}
+	if missed := result["total_missed_blocks"]; missed != 4 {
+		t.Errorf("total_missed_blocks = %v, want 4", missed)
+	}
+
+	delegates := result["delegates"].([][]models.DelegateInfo)
+	if len(delegates) != 1 || len(delegates[0]) != 1 {
+		t.Fatalf("delegates = %+v, want one period of one delegate", delegates)
+	}
+	if info := delegates[0][0]; info.Address != statsDelegates[2] || info.TotalBlocks != 102 || info.MissedBlocks != 1 || info.Stake != 30000 {
+		t.Errorf("delegate = %+v", info)
+	}
+}
@@ -0,0 +1,380 @@
+package webapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/contracts"
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+const (
+	ETHERSCAN_STATUS_OK    = "1"
+	ETHERSCAN_STATUS_NOTOK = "0"
+
+	ETHERSCAN_MESSAGE_OK    = "OK"
+	ETHERSCAN_MESSAGE_NOTOK = "NOTOK"
+
+	// etherscanMaxResults caps page x offset of the list actions, like Etherscan does
+	etherscanMaxResults = 10000
+)
+
+var (
+	// errEtherscanPageFull stops an export once a page has been read
+	errEtherscanPageFull = errors.New("page full")
+)
+
+// etherscanResponse is the envelope of every Etherscan API response.
+// Errors are returned with status 0 and the description as the result.
+type etherscanResponse struct {
+	Status  string      `json:"status"`
+	Message string      `json:"message"`
+	Result  interface{} `json:"result"`
+}
+
+type etherscanAction func(r *http.Request) etherscanResponse
+
+// etherscanActions maps the supported module and action params to their handlers
+var etherscanActions = map[string]map[string]etherscanAction{
+	"account": {
+		"balance": etherscanBalance,
+		"txlist":  etherscanTxList,
+		"tokentx": etherscanTokenTx,
+	},
+	"block": {
+		"getblocknobytime": etherscanBlockNumberByTime,
+	},
+	"transaction": {
+		"getstatus": etherscanTxStatus,
+	},
+	"contract": {
+		"getabi": etherscanABI,
+	},
+	"stats": {
+		"ethsupply": etherscanSupply,
+	},
+}
+
+// etherscanTransaction is a transaction of account.txlist, with every
+// field formatted as a string like Etherscan does
+type etherscanTransaction struct {
+	BlockNumber       string `json:"blockNumber"`
+	TimeStamp         string `json:"timeStamp"`
+	Hash              string `json:"hash"`
+	Nonce             string `json:"nonce"`
+	BlockHash         string `json:"blockHash"`
+	TransactionIndex  string `json:"transactionIndex"`
+	From              string `json:"from"`
+	To                string `json:"to"`
+	Value             string `json:"value"`
+	Gas               string `json:"gas"`
+	GasPrice          string `json:"gasPrice"`
+	IsError           string `json:"isError"`
+	TxReceiptStatus   string `json:"txreceipt_status"`
+	Input             string `json:"input"`
+	ContractAddress   string `json:"contractAddress"`
+	CumulativeGasUsed string `json:"cumulativeGasUsed"`
+	GasUsed           string `json:"gasUsed"`
+	Confirmations     string `json:"confirmations"`
+}
+
+// etherscanTxStatusResult is the result of transaction.getstatus
+type etherscanTxStatusResult struct {
+	IsError        string `json:"isError"`
+	ErrDescription string `json:"errDescription"`
+}
+
+func etherscanOK(result interface{}) etherscanResponse {
+	return etherscanResponse{Status: ETHERSCAN_STATUS_OK, Message: ETHERSCAN_MESSAGE_OK, Result: result}
+}
+
+func etherscanError(description string) etherscanResponse {
+	return etherscanResponse{Status: ETHERSCAN_STATUS_NOTOK, Message: ETHERSCAN_MESSAGE_NOTOK, Result: "Error! " + description}
+}
+
+// etherscanInternalError logs err and hides it from the client
+func etherscanInternalError(err error) etherscanResponse {
+	logger.Error("Etherscan request failed", "err", err)
+	return etherscanError("Internal error")
+}
+
+// etherscanAddress formats an address lowercase, the empty string for the zero address
+func etherscanAddress(address *common.Address) string {
+	if address == nil || *address == (common.Address{}) {
+		return ""
+	}
+	return strings.ToLower(address.Hex())
+}
+
+// etherscanUint parses an unsigned param, returning def when it is missing
+func etherscanUint(r *http.Request, name string, def uint64) (uint64, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// HandleEtherscan serves the Etherscan compatible `/api?module=...&action=...` interface.
+// Like Etherscan, errors are reported in the envelope with a 200 status.
+func HandleEtherscan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var response etherscanResponse
+
+	actions, ok := etherscanActions[r.FormValue("module")]
+	if !ok {
+		response = etherscanError("Missing Or invalid Module name")
+	} else if action, ok := actions[r.FormValue("action")]; !ok {
+		response = etherscanError("Missing Or invalid Action name")
+	} else {
+		response = action(r)
+	}
+
+	res, err := json.Marshal(response)
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(res)
+}
+
+func etherscanBalance(r *http.Request) etherscanResponse {
+	address := r.FormValue("address")
+	if !common.IsHexAddress(address) {
+		return etherscanError("Invalid address format")
+	}
+
+	// the node is only asked for the latest state
+	if tag := r.FormValue("tag"); tag != "" && tag != "latest" {
+		return etherscanError("Only the latest tag is supported")
+	}
+
+	ipc := ipc.GetIPCContext(r.Context())
+	if ipc == nil {
+		return etherscanInternalError(errors.New("IPC connection is not initialized"))
+	}
+
+	balance, err := ipc.GetAddressBalance(common.HexToAddress(address))
+	if err != nil {
+		return etherscanInternalError(err)
+	}
+
+	return etherscanOK(balance.String())
+}
+
+func etherscanTxList(r *http.Request) etherscanResponse {
+	address := r.FormValue("address")
+	if !common.IsHexAddress(address) {
+		return etherscanError("Invalid address format")
+	}
+
+	var filter models.ExportFilter
+	var err error
+
+	if filter.FromBlock, err = etherscanUint(r, "startblock", 0); err != nil {
+		return etherscanError("Invalid startblock")
+	}
+	if filter.ToBlock, err = etherscanUint(r, "endblock", 0); err != nil {
+		return etherscanError("Invalid endblock")
+	}
+
+	switch r.FormValue("sort") {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return etherscanError("Invalid sort order, use asc or desc")
+	}
+
+	page, err := etherscanUint(r, "page", 1)
+	if err != nil || page == 0 {
+		return etherscanError("Invalid page")
+	}
+	offset, err := etherscanUint(r, "offset", etherscanMaxResults)
+	if err != nil || offset == 0 {
+		return etherscanError("Invalid offset")
+	}
+	if page > etherscanMaxResults || page*offset > etherscanMaxResults {
+		return etherscanError("Result window is too large, PageNo x Offset size must be less than or equal to 10000")
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		return etherscanInternalError(errors.New("DBClient is not initialized"))
+	}
+
+	latestBlockNumber, err := dbc.GetLatestBlockNumber()
+	if err != nil {
+		return etherscanInternalError(err)
+	}
+
+	skip := (page - 1) * offset
+	txs := make([]etherscanTransaction, 0)
+
+	err = dbc.ExportTransactionsByAddress(address, models.ADDRESS_ALL, filter, func(tf models.TransactionFull) error {
+		if skip > 0 {
+			skip--
+			return nil
+		}
+
+		tx, txr := tf.Tx, tf.Txr
+
+		isError := "0"
+		if txr.Status != 1 {
+			isError = "1"
+		}
+
+		var confirmations uint64
+		if latestBlockNumber >= uint64(tx.BlockNumber) {
+			confirmations = latestBlockNumber - uint64(tx.BlockNumber) + 1
+		}
+
+		txs = append(txs, etherscanTransaction{
+			BlockNumber:       strconv.FormatUint(uint64(tx.BlockNumber), 10),
+			TimeStamp:         strconv.FormatUint(uint64(tx.Timestamp), 10),
+			Hash:              tx.Hash.Hex(),
+			Nonce:             strconv.FormatUint(uint64(tx.Nonce), 10),
+			BlockHash:         tx.BlockHash.Hex(),
+			TransactionIndex:  strconv.FormatUint(uint64(tx.TransactionIndex), 10),
+			From:              etherscanAddress(&tx.From),
+			To:                etherscanAddress(tx.To),
+			Value:             tx.Value.ToInt().String(),
+			Gas:               strconv.FormatUint(uint64(tx.GasLimit), 10),
+			GasPrice:          strconv.FormatUint(uint64(tx.GasPrice), 10),
+			IsError:           isError,
+			TxReceiptStatus:   strconv.FormatUint(uint64(txr.Status), 10),
+			Input:             hexutil.Encode(tx.Input),
+			ContractAddress:   etherscanAddress(txr.ContractAddress),
+			CumulativeGasUsed: strconv.FormatUint(uint64(txr.CumulativeGasUsed), 10),
+			GasUsed:           strconv.FormatUint(uint64(txr.GasUsed), 10),
+			Confirmations:     strconv.FormatUint(confirmations, 10),
+		})
+
+		if uint64(len(txs)) >= offset {
+			return errEtherscanPageFull
+		}
+		return nil
+	})
+	if err != nil && err != errEtherscanPageFull {
+		return etherscanInternalError(err)
+	}
+
+	if len(txs) == 0 {
+		return etherscanResponse{Status: ETHERSCAN_STATUS_NOTOK, Message: "No transactions found", Result: txs}
+	}
+
+	return etherscanOK(txs)
+}
+
+// etherscanTokenTx is answered for compatibility only, as the crawler
+// doesn't index token transfers. It replies like Etherscan does for an
+// address without any, so that clients don't treat it as a failure.
+func etherscanTokenTx(r *http.Request) etherscanResponse {
+	return etherscanResponse{Status: ETHERSCAN_STATUS_NOTOK, Message: "No transactions found", Result: []etherscanTransaction{}}
+}
+
+func etherscanBlockNumberByTime(r *http.Request) etherscanResponse {
+	timestamp, err := strconv.ParseUint(r.FormValue("timestamp"), 10, 64)
+	if err != nil {
+		return etherscanError("Invalid timestamp")
+	}
+
+	var condition models.TimestampCondition
+	switch r.FormValue("closest") {
+	case "", "before":
+		condition = models.TIMESTAMP_SMALLER_EQUAL_THAN
+	case "after":
+		condition = models.TIMESTAMP_GREATER_EQUAL_THAN
+	default:
+		return etherscanError("Invalid closest, use before or after")
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		return etherscanInternalError(errors.New("DBClient is not initialized"))
+	}
+
+	number, err := dbc.GetBlockNumberByTimestamp(timestamp, condition)
+	if err == sql.ErrNoRows {
+		return etherscanError("No closest block found")
+	} else if err != nil {
+		return etherscanInternalError(err)
+	}
+
+	return etherscanOK(strconv.FormatUint(number, 10))
+}
+
+func etherscanTxStatus(r *http.Request) etherscanResponse {
+	hash := r.FormValue("txhash")
+	if bytes, err := hexutil.Decode(hash); err != nil || len(bytes) != common.HashLength {
+		return etherscanError("Invalid transaction hash")
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		return etherscanInternalError(errors.New("DBClient is not initialized"))
+	}
+
+	txf, err := dbc.GetTransactionByHash(common.HexToHash(hash).Hex())
+	if err == sql.ErrNoRows || (err == nil && (txf == nil || txf.Tx == nil)) {
+		return etherscanError("Transaction not found")
+	} else if err != nil {
+		return etherscanInternalError(err)
+	}
+
+	// receipts don't keep the revert reason, so failures get a generic description
+	result := etherscanTxStatusResult{IsError: "0"}
+	if txf.Txr.Status != 1 {
+		result = etherscanTxStatusResult{IsError: "1", ErrDescription: "Reverted"}
+	}
+
+	return etherscanOK(result)
+}
+
+func etherscanABI(r *http.Request) etherscanResponse {
+	address := r.FormValue("address")
+	if !common.IsHexAddress(address) {
+		return etherscanError("Invalid address format")
+	}
+
+	abi, _, err := contracts.GetABIJSON(r.Context(), common.HexToAddress(address))
+	if err == contracts.ErrABINotFound {
+		return etherscanError("Contract source code not verified")
+	} else if err != nil {
+		return etherscanInternalError(err)
+	}
+
+	return etherscanOK(string(abi))
+}
+
+func etherscanSupply(r *http.Request) etherscanResponse {
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		return etherscanInternalError(errors.New("DBClient is not initialized"))
+	}
+
+	latestBlockNumber, err := dbc.GetLatestBlockNumber()
+	if err != nil {
+		return etherscanInternalError(err)
+	}
+
+	return etherscanOK(getTotalSupply(latestBlockNumber).String())
+}
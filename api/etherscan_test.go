@@ -0,0 +1,23 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestEtherscanTokenTx(t *testing.T) {
+	setupTestChain(t)
+
+	var res struct {
+		Status  string
+		Message string
+		Result  json.RawMessage
+	}
+	serveJSON(t, "GET", "/api?module=account&action=tokentx&address="+testAlice.Hex(), "", http.StatusOK, &res)
+
+	// like Etherscan for an address without token transfers
+	if res.Status != ETHERSCAN_STATUS_NOTOK || res.Message != "No transactions found" || string(res.Result) != "[]" {
+		t.Errorf("tokentx = %s %q %s, want no transactions and an empty result", res.Status, res.Message, res.Result)
+	}
+}
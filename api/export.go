@@ -0,0 +1,417 @@
+package webapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+const (
+	EXPORT_FORMAT_CSV    = "csv"
+	EXPORT_FORMAT_NDJSON = "ndjson"
+
+	// maxExportBlocks caps the range of a block export
+	maxExportBlocks = 100000
+
+	// maxExportTransactions caps the rows of a transaction export, narrower
+	// date ranges export the rest
+	maxExportTransactions = 100000
+
+	// exportFlushRows is how often the rows are flushed to the client
+	exportFlushRows = 500
+)
+
+var (
+	// exportAddressTypes maps the `ref` of the transaction routes to the
+	// address types that can be exported
+	exportAddressTypes = map[string]models.AddressType{
+		"from":  models.ADDRESS_FROM,
+		"to":    models.ADDRESS_TO,
+		"all":   models.ADDRESS_ALL,
+		"block": models.ADDRESS_BLOCKHASH,
+	}
+)
+
+var (
+	ErrUnknownExportFormat = errors.New("Unknown export format, use csv or ndjson")
+	ErrInvalidExportTime   = errors.New("Invalid date, use YYYY-MM-DD, RFC3339 or a unix timestamp")
+)
+
+// exportFormat returns the export format requested, or "" for the default JSON page
+func exportFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		return "", nil
+	case EXPORT_FORMAT_CSV, EXPORT_FORMAT_NDJSON:
+		return format, nil
+	default:
+		return "", ErrUnknownExportFormat
+	}
+}
+
+// parseExportTime parses a date, an RFC3339 time or a unix timestamp.
+// Plain dates of the upper bound include the whole day.
+func parseExportTime(s string, upper bool) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	if timestamp, err := strconv.ParseUint(s, 10, 64); err == nil {
+		if upper {
+			timestamp++
+		}
+		return timestamp, nil
+	}
+
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		if upper {
+			t = t.AddDate(0, 0, 1)
+		}
+		return uint64(t.Unix()), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		timestamp := uint64(t.Unix())
+		if upper {
+			timestamp++
+		}
+		return timestamp, nil
+	}
+
+	return 0, ErrInvalidExportTime
+}
+
+// parseExportFilter reads the inclusive `from` and `to` dates and the `order`
+func parseExportFilter(r *http.Request, defaultOrder string) (models.ExportFilter, error) {
+	var filter models.ExportFilter
+	var err error
+
+	if filter.FromTime, err = parseExportTime(r.URL.Query().Get("from"), false); err != nil {
+		return filter, err
+	}
+	if filter.ToTime, err = parseExportTime(r.URL.Query().Get("to"), true); err != nil {
+		return filter, err
+	}
+
+	order := r.URL.Query().Get("order")
+	if order != "asc" && order != "desc" {
+		order = defaultOrder
+	}
+	filter.Descending = order == "desc"
+
+	return filter, nil
+}
+
+// formatEbk formats a wei amount in EBK, without trailing zeros
+func formatEbk(wei *big.Int) string {
+	integer, fraction := new(big.Int).QuoRem(wei, ether, new(big.Int))
+	if fraction.Sign() == 0 {
+		return integer.String()
+	}
+	if fraction.Sign() < 0 {
+		fraction.Neg(fraction)
+	}
+	return integer.String() + "." + strings.TrimRight(fmt.Sprintf("%018s", fraction.String()), "0")
+}
+
+func formatExportTime(timestamp uint64) string {
+	return time.Unix(int64(timestamp), 0).UTC().Format(time.RFC3339)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// csvCell prefixes cells that spreadsheets would evaluate as formulas with
+// a quote, so that exported names can't run anything
+func csvCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// exportRow is a row of an export, encoded as is in NDJSON
+type exportRow interface {
+	csvRecord() []string
+}
+
+type txExportRow struct {
+	Hash             string `json:"hash"`
+	BlockNumber      uint64 `json:"blockNumber"`
+	TransactionIndex uint64 `json:"transactionIndex"`
+	Timestamp        uint64 `json:"timestamp"`
+	Date             string `json:"date"`
+	From             string `json:"from"`
+	FromEns          string `json:"fromEns"`
+	To               string `json:"to"`
+	ToEns            string `json:"toEns"`
+	ValueWei         string `json:"valueWei"`
+	ValueEbk         string `json:"valueEbk"`
+	GasUsed          uint64 `json:"gasUsed"`
+	GasPrice         uint64 `json:"gasPrice"`
+	Status           uint64 `json:"status"`
+	ContractAddress  string `json:"contractAddress"`
+}
+
+var txExportColumns = []string{"hash", "blockNumber", "transactionIndex", "timestamp", "date", "from", "fromEns", "to", "toEns",
+	"valueWei", "valueEbk", "gasUsed", "gasPrice", "status", "contractAddress"}
+
+func newTxExportRow(tf models.TransactionFull) txExportRow {
+	value := tf.Tx.Value.ToInt()
+
+	row := txExportRow{
+		Hash:             tf.Tx.Hash.Hex(),
+		BlockNumber:      uint64(tf.Tx.BlockNumber),
+		TransactionIndex: uint64(tf.Tx.TransactionIndex),
+		Timestamp:        uint64(tf.Tx.Timestamp),
+		Date:             formatExportTime(uint64(tf.Tx.Timestamp)),
+		From:             tf.Tx.From.Hex(),
+		FromEns:          stringValue(tf.Tx.FromEns),
+		ToEns:            stringValue(tf.Tx.ToEns),
+		ValueWei:         value.String(),
+		ValueEbk:         formatEbk(value),
+		GasUsed:          uint64(tf.Txr.GasUsed),
+		GasPrice:         uint64(tf.Tx.GasPrice),
+		Status:           uint64(tf.Txr.Status),
+	}
+	if tf.Tx.To != nil {
+		row.To = tf.Tx.To.Hex()
+	}
+	if tf.Txr.ContractAddress != nil && (*tf.Txr.ContractAddress != common.Address{}) {
+		row.ContractAddress = tf.Txr.ContractAddress.Hex()
+	}
+
+	return row
+}
+
+func (row txExportRow) csvRecord() []string {
+	return []string{row.Hash, strconv.FormatUint(row.BlockNumber, 10), strconv.FormatUint(row.TransactionIndex, 10),
+		strconv.FormatUint(row.Timestamp, 10), row.Date, row.From, row.FromEns, row.To, row.ToEns, row.ValueWei, row.ValueEbk,
+		strconv.FormatUint(row.GasUsed, 10), strconv.FormatUint(row.GasPrice, 10), strconv.FormatUint(row.Status, 10), row.ContractAddress}
+}
+
+type blockExportRow struct {
+	Number           uint64 `json:"number"`
+	Timestamp        uint64 `json:"timestamp"`
+	Date             string `json:"date"`
+	Hash             string `json:"hash"`
+	Producer         string `json:"producer"`
+	ProducerEns      string `json:"producerEns"`
+	TransactionCount uint64 `json:"transactionCount"`
+	GasUsed          uint64 `json:"gasUsed"`
+	GasLimit         uint64 `json:"gasLimit"`
+	Size             uint64 `json:"size"`
+}
+
+var blockExportColumns = []string{"number", "timestamp", "date", "hash", "producer", "producerEns", "transactionCount", "gasUsed", "gasLimit", "size"}
+
+func newBlockExportRow(block models.Block) blockExportRow {
+	return blockExportRow{
+		Number:           uint64(block.Number),
+		Timestamp:        uint64(block.TimeStamp),
+		Date:             formatExportTime(uint64(block.TimeStamp)),
+		Hash:             block.Hash.Hex(),
+		Producer:         block.Producer.Hex(),
+		ProducerEns:      stringValue(block.ProducerEns),
+		TransactionCount: uint64(block.TransactionCount),
+		GasUsed:          uint64(block.GasUsed),
+		GasLimit:         uint64(block.GasLimit),
+		Size:             uint64(block.Size),
+	}
+}
+
+func (row blockExportRow) csvRecord() []string {
+	return []string{strconv.FormatUint(row.Number, 10), strconv.FormatUint(row.Timestamp, 10), row.Date, row.Hash, row.Producer,
+		row.ProducerEns, strconv.FormatUint(row.TransactionCount, 10), strconv.FormatUint(row.GasUsed, 10),
+		strconv.FormatUint(row.GasLimit, 10), strconv.FormatUint(row.Size, 10)}
+}
+
+type balanceExportRow struct {
+	Rank        uint64 `json:"rank"`
+	Address     string `json:"address"`
+	AddressEns  string `json:"addressEns"`
+	BalanceWei  string `json:"balanceWei"`
+	BalanceEbk  string `json:"balanceEbk"`
+	BlockNumber uint64 `json:"blockNumber"`
+}
+
+var balanceExportColumns = []string{"rank", "address", "addressEns", "balanceWei", "balanceEbk", "blockNumber"}
+
+func newBalanceExportRow(rank uint64, balance models.Balance) balanceExportRow {
+	// balances are stored in 1/10000 of EBK
+	wei := new(big.Int).Mul(new(big.Int).SetUint64(balance.Amount), precisionFactor)
+
+	return balanceExportRow{
+		Rank:        rank,
+		Address:     balance.Address.Hex(),
+		AddressEns:  balance.AddressEns,
+		BalanceWei:  wei.String(),
+		BalanceEbk:  formatEbk(wei),
+		BlockNumber: balance.BlockNumber,
+	}
+}
+
+func (row balanceExportRow) csvRecord() []string {
+	return []string{strconv.FormatUint(row.Rank, 10), row.Address, row.AddressEns, row.BalanceWei, row.BalanceEbk,
+		strconv.FormatUint(row.BlockNumber, 10)}
+}
+
+// exporter streams rows to the client in CSV or NDJSON
+type exporter struct {
+	w       http.ResponseWriter
+	csv     *csv.Writer
+	json    *json.Encoder
+	flusher http.Flusher
+	rows    int
+}
+
+// newExporter sends the headers (and the CSV header row) of a download
+func newExporter(w http.ResponseWriter, format, filename string, columns []string) (*exporter, error) {
+	e := &exporter{w: w}
+	e.flusher, _ = w.(http.Flusher)
+
+	if format == EXPORT_FORMAT_CSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+"."+format))
+
+	if format == EXPORT_FORMAT_CSV {
+		e.csv = csv.NewWriter(w)
+		return e, e.csv.Write(columns)
+	}
+
+	e.json = json.NewEncoder(w)
+	return e, nil
+}
+
+func (e *exporter) write(row exportRow) error {
+	var err error
+	if e.csv != nil {
+		record := row.csvRecord()
+		for i := range record {
+			record[i] = csvCell(record[i])
+		}
+		err = e.csv.Write(record)
+	} else {
+		err = e.json.Encode(row)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *exporter) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+	return nil
+}
+
+// exportTransactions streams the transactions of an address or block
+func exportTransactions(w http.ResponseWriter, r *http.Request, dbc db.Store, address string, addrtype models.AddressType, format string) {
+	filter, err := parseExportFilter(r, "asc")
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter.Limit = maxExportTransactions
+
+	requestLogger(r).Debug("Export transactions by address", "address", address, "type", addrtype, "format", format)
+
+	liftTimeouts(r)
+	e, err := newExporter(w, format, "transactions-"+address, txExportColumns)
+	if err == nil {
+		err = dbc.ExportTransactionsByAddress(address, addrtype, filter, func(tf models.TransactionFull) error {
+			return e.write(newTxExportRow(tf))
+		})
+	}
+	if err == nil {
+		err = e.flush()
+	}
+
+	// the response has started already, the client only sees a truncated file
+	if err != nil {
+		requestLogger(r).Error("Export failed", "err", err)
+	}
+}
+
+// exportBlocks streams the blocks numbered from fromNumber to toNumber
+func exportBlocks(w http.ResponseWriter, r *http.Request, dbc db.Store, fromNumber, toNumber uint64, format string) {
+	filter, err := parseExportFilter(r, "desc")
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requestLogger(r).Debug("Export blocks", "from", fromNumber, "to", toNumber, "format", format)
+
+	liftTimeouts(r)
+	e, err := newExporter(w, format, fmt.Sprintf("blocks-%d-%d", fromNumber, toNumber), blockExportColumns)
+	if err == nil {
+		err = dbc.ExportBlocks(fromNumber, toNumber, filter, func(block models.Block) error {
+			return e.write(newBlockExportRow(block))
+		})
+	}
+	if err == nil {
+		err = e.flush()
+	}
+
+	if err != nil {
+		requestLogger(r).Error("Export failed", "err", err)
+	}
+}
+
+// exportRichList streams the whole rich list
+func exportRichList(w http.ResponseWriter, r *http.Request, dbc db.Store, format string) {
+	requestLogger(r).Debug("Export rich list", "format", format)
+
+	rank := uint64(0)
+
+	liftTimeouts(r)
+	e, err := newExporter(w, format, "rich-list", balanceExportColumns)
+	if err == nil {
+		err = dbc.ExportBalances(func(balance models.Balance) error {
+			rank++
+			return e.write(newBalanceExportRow(rank, balance))
+		})
+	}
+	if err == nil {
+		err = e.flush()
+	}
+
+	if err != nil {
+		requestLogger(r).Error("Export failed", "err", err)
+	}
+}
@@ -0,0 +1,70 @@
+package webapi
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+func TestCSVCell(t *testing.T) {
+	cases := map[string]string{
+		"":                  "",
+		"alice.ebk":         "alice.ebk",
+		"=1+2":              "'=1+2",
+		"+1":                "'+1",
+		"-1":                "'-1",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"\t=1":              "'\t=1",
+		"0x1234=":           "0x1234=",
+		"2020-01-01T00:00Z": "2020-01-01T00:00Z",
+	}
+
+	for cell, expected := range cases {
+		if escaped := csvCell(cell); escaped != expected {
+			t.Errorf("csvCell(%q) = %q, want %q", cell, escaped, expected)
+		}
+	}
+}
+
+func TestExportTransactionsCSV(t *testing.T) {
+	_, store := setupTestChain(t)
+
+	// ENS names are chosen by anyone
+	name := `=HYPERLINK("https://example.com")`
+	if err := store.InsertEns(models.ENS{Address: testAlice, Hash: common.HexToHash("0xa11ce"), Name: name}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve("GET", "/transaction/from/"+testAlice.Hex()+"?format=csv", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) < 2 {
+		t.Fatalf("got %d records, want the header and transactions", len(records))
+	}
+
+	column := -1
+	for i, name := range records[0] {
+		if name == "fromEns" {
+			column = i
+		}
+	}
+	for _, record := range records[1:] {
+		if ens := record[column]; ens != "'"+name {
+			t.Errorf("fromEns = %q, want it quoted", ens)
+		}
+		if strings.HasPrefix(record[0], "'") {
+			t.Errorf("hash = %q, want it as is", record[0])
+		}
+	}
+}
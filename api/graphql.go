@@ -0,0 +1,69 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/graphql"
+)
+
+// graphqlRequest is the body of a GraphQL POST request
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// HandleGraphQL runs a GraphQL query, passed either as a JSON body with POST
+// or as query, operationName and variables params with GET
+func HandleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphqlRequest
+
+	switch r.Method {
+	case "GET":
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				requestLogger(r).Debug("Failed to parse GraphQL variables", "err", err)
+				http.Error(w, "error", http.StatusBadRequest)
+				return
+			}
+		}
+	case "POST":
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			requestLogger(r).Debug("Failed to parse GraphQL request", "err", err)
+			http.Error(w, "error", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	if req.Query == "" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		requestLogger(r).Error("DBClient is not initialized")
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := graphql.Execute(r.Context(), dbc, req.Query, req.OperationName, req.Variables)
+
+	res, err := json.Marshal(response)
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(res)
+}
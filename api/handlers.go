@@ -4,13 +4,12 @@ import (
 	"database/sql"
 	"encoding/binary"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"log"
 	"math/big"
 	"net/http"
 	"strconv"
 
+	"github.com/ebakus/ebakus-block-explorer-backend/contracts"
 	"github.com/ebakus/ebakus-block-explorer-backend/db"
 	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
 	"github.com/ebakus/ebakus-block-explorer-backend/models"
@@ -34,9 +33,9 @@ func HandleBlock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbc := db.GetClient()
+	dbc := db.GetClientContext(r.Context())
 	if dbc == nil {
-		log.Printf("! Error: DBClient is not initialized!")
+		requestLogger(r).Error("DBClient is not initialized")
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
@@ -52,17 +51,17 @@ func HandleBlock(w http.ResponseWriter, r *http.Request) {
 		hash, ok := vars["param"]
 
 		if !ok {
-			log.Printf("! Error: %s", errors.New("Parameter is n"))
+			requestLogger(r).Debug("Missing parameter", "name", "n")
 			http.Error(w, "error", http.StatusBadRequest)
 			return
 		}
 
-		log.Println("Request Block by Hash:", hash)
+		requestLogger(r).Debug("Request block by hash", "hash", hash)
 		var err error
 		block, err = dbc.GetBlockByHash(hash)
 
 		if err != nil {
-			log.Printf("! Error: %s", err.Error())
+			requestLogger(r).Error("Request failed", "err", err)
 			http.Error(w, "error", http.StatusInternalServerError)
 			return
 		}
@@ -76,7 +75,7 @@ func HandleBlock(w http.ResponseWriter, r *http.Request) {
 		rawId, err := strconv.ParseInt(vars["param"], 10, 64)
 
 		if err != nil {
-			log.Printf("! Error: %s", err.Error())
+			requestLogger(r).Debug("Bad request", "err", err)
 			http.Error(w, "error", http.StatusBadRequest)
 			return
 		}
@@ -86,13 +85,13 @@ func HandleBlock(w http.ResponseWriter, r *http.Request) {
 		if rngParam != "" {
 			rng, err := strconv.ParseUint(rngParam, 10, 32)
 			if err != nil {
-				log.Printf("! Error parsing range: %s", err.Error())
+				requestLogger(r).Debug("Failed to parse range", "err", err)
 				http.Error(w, "error", http.StatusBadRequest)
 				return
 			}
 
 			if rawId < 0 && rawId != -1 {
-				log.Printf("! Error: Bad negative id")
+				requestLogger(r).Debug("Bad negative id")
 				http.Error(w, "error", http.StatusBadRequest)
 				return
 			}
@@ -104,27 +103,97 @@ func HandleBlock(w http.ResponseWriter, r *http.Request) {
 				id = uint32(rawId)
 			}
 
+			format, err := exportFormat(r)
+			if err != nil {
+				requestLogger(r).Debug("Bad request", "err", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if format != "" {
+				toNumber := uint64(rawId)
+				if rawId == -1 {
+					toNumber, err = dbc.GetLatestBlockNumber()
+					if err != nil {
+						requestLogger(r).Error("Request failed", "err", err)
+						http.Error(w, "error", http.StatusInternalServerError)
+						return
+					}
+				}
+
+				if rng > maxExportBlocks {
+					rng = maxExportBlocks
+				}
+
+				fromNumber := uint64(0)
+				if toNumber+1 > rng {
+					fromNumber = toNumber + 1 - rng
+				}
+
+				exportBlocks(w, r, dbc, fromNumber, toNumber, format)
+				return
+			}
+
 			if rng > 100 {
 				rng = 100
 			}
 
-			blocks, err := dbc.GetBlockRange(id, uint32(rng))
+			cursor, err := parseCursor(r)
+			if err != nil {
+				requestLogger(r).Debug("Bad request", "err", err)
+				http.Error(w, "error", http.StatusBadRequest)
+				return
+			}
+
+			// fetch one more block to know if there are more pages
+			var blocks []models.Block
+			if cursor == nil {
+				blocks, err = dbc.GetBlockRange(id, uint32(rng)+1, false)
+			} else if cursor.Prev {
+				blocks, err = dbc.GetBlockRange(uint32(cursor.Number)+1, uint32(rng)+1, true)
+				for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+					blocks[i], blocks[j] = blocks[j], blocks[i]
+				}
+			} else if cursor.Number > 0 {
+				blocks, err = dbc.GetBlockRange(uint32(cursor.Number)-1, uint32(rng)+1, false)
+			}
 
 			if err != nil {
-				log.Printf("! Error: %s", err.Error())
+				requestLogger(r).Error("Request failed", "err", err)
 				http.Error(w, "error", http.StatusInternalServerError)
 				return
 			}
 
-			if blocks == nil {
+			if blocks == nil && cursor == nil {
 				http.Error(w, "error", http.StatusNotFound)
 				return
 			}
 
-			res, err := json.Marshal(blocks)
+			hasMore := uint64(len(blocks)) > rng
+			if hasMore {
+				if cursor != nil && cursor.Prev {
+					blocks = blocks[1:]
+				} else {
+					blocks = blocks[:rng]
+				}
+			}
+
+			if blocks == nil {
+				blocks = make([]models.Block, 0)
+			}
+
+			var first, last *models.Cursor
+			if len(blocks) > 0 {
+				first = &models.Cursor{Number: uint64(blocks[0].Number)}
+				last = &models.Cursor{Number: uint64(blocks[len(blocks)-1].Number)}
+			}
+
+			page := models.NewPage(blocks, cursor, hasMore, first, last)
+
+			res, err := json.Marshal(page)
 
 			if err != nil {
-				log.Printf("! Error: %s", err.Error())
+				requestLogger(r).Error("Request failed", "err", err)
 				http.Error(w, "error", http.StatusInternalServerError)
 			} else {
 				w.Write(res)
@@ -133,11 +202,11 @@ func HandleBlock(w http.ResponseWriter, r *http.Request) {
 			return
 		} else {
 			id := uint64(rawId)
-			log.Println("Request Block by ID:", id)
+			requestLogger(r).Debug("Request block by ID", "id", id)
 			block, err = dbc.GetBlockByID(id)
 
 			if err != nil {
-				log.Printf("! Error: %s", err.Error())
+				requestLogger(r).Error("Request failed", "err", err)
 				http.Error(w, "error", http.StatusInternalServerError)
 				return
 			}
@@ -152,13 +221,20 @@ func HandleBlock(w http.ResponseWriter, r *http.Request) {
 	res, err := block.MarshalJSON()
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
 		w.Write(res)
 	}
 }
 
+// decodeRequested tells whether the transactions should be returned with
+// their input (and logs) decoded, with `decode=true`
+func decodeRequested(r *http.Request) bool {
+	decode, _ := strconv.ParseBool(r.URL.Query().Get("decode"))
+	return decode
+}
+
 // HandleTxByHash finds and returns a transaction by hash
 func HandleTxByHash(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
@@ -166,9 +242,9 @@ func HandleTxByHash(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbc := db.GetClient()
+	dbc := db.GetClientContext(r.Context())
 	if dbc == nil {
-		log.Printf("! Error: DBClient is not initialized!")
+		requestLogger(r).Error("DBClient is not initialized")
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
@@ -182,17 +258,17 @@ func HandleTxByHash(w http.ResponseWriter, r *http.Request) {
 	hash, ok := vars["hash"]
 
 	if !ok {
-		log.Printf("! Error: %s", errors.New("Parameter is n"))
+		requestLogger(r).Debug("Missing parameter", "name", "n")
 		http.Error(w, "error", http.StatusBadRequest)
 		return
 	}
 
-	log.Println("Request Transaction by Hash:", hash)
+	requestLogger(r).Debug("Request transaction by hash", "hash", hash)
 	var err error
 	txf, err = dbc.GetTransactionByHash(hash)
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
@@ -204,10 +280,14 @@ func HandleTxByHash(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if decodeRequested(r) {
+		contracts.NewDecoder(r.Context()).Decode(txf, true)
+	}
+
 	res, err := txf.MarshalJSON()
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
 		w.Write(res)
@@ -220,16 +300,16 @@ func HandleAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbc := db.GetClient()
+	dbc := db.GetClientContext(r.Context())
 	if dbc == nil {
-		log.Printf("! Error: DBClient is not initialized!")
+		requestLogger(r).Error("DBClient is not initialized")
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
 
-	ipc := ipc.GetIPC()
+	ipc := ipc.GetIPCContext(r.Context())
 	if ipc == nil {
-		log.Printf("! Error: IPCInterface is not initialized!")
+		requestLogger(r).Error("IPCInterface is not initialized")
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
@@ -240,24 +320,15 @@ func HandleAddress(w http.ResponseWriter, r *http.Request) {
 
 	addressHex, ok := vars["address"]
 	if !ok || !common.IsHexAddress(addressHex) {
-		log.Printf("! Error: %s", errors.New("Parameter is n"))
+		requestLogger(r).Debug("Missing parameter", "name", "n")
 		http.Error(w, "error", http.StatusBadRequest)
 		return
 	}
 
-	// correct case sensivity for redis
 	address := common.HexToAddress(addressHex)
 	addressHex = address.Hex()
-	redisKey := "address:" + addressHex
 
-	log.Println("Request Address info for:", addressHex)
-
-	if ok, _ := redis.Exists(redisKey); ok {
-		if res, err := redis.Get(redisKey); err == nil {
-			w.Write(res)
-			return
-		}
-	}
+	requestLogger(r).Debug("Request address", "address", addressHex)
 
 	blockRewards, txCount, err := dbc.GetAddressTotals(addressHex)
 	isContract, err := dbc.GetIsContractAddress(addressHex)
@@ -280,11 +351,9 @@ func HandleAddress(w http.ResponseWriter, r *http.Request) {
 	res, err := json.Marshal(result)
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
-		redis.Set(redisKey, res)
-		redis.Expire(redisKey, 1) // 1 second
 		w.Write(res)
 	}
 }
@@ -296,9 +365,9 @@ func HandleTxByAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbc := db.GetClient()
+	dbc := db.GetClientContext(r.Context())
 	if dbc == nil {
-		log.Printf("! Error: DBClient is not initialized!")
+		requestLogger(r).Error("DBClient is not initialized")
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
@@ -313,32 +382,37 @@ func HandleTxByAddress(w http.ResponseWriter, r *http.Request) {
 	reference, ok := vars["ref"]
 
 	if !ok {
-		log.Printf("! Error: %s", errors.New("Parameter is n"))
+		requestLogger(r).Debug("Missing parameter", "name", "n")
 		http.Error(w, "error", http.StatusBadRequest)
 		return
 	}
 
-	offsetString := r.URL.Query().Get("offset")
-	limitString := r.URL.Query().Get("limit")
-	orderString := r.URL.Query().Get("order")
+	format, err := exportFormat(r)
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	var offset, limit uint64
-	var err error
-	if offsetString != "" {
-		offset, err = strconv.ParseUint(offsetString, 10, 32)
-		if err != nil {
-			log.Printf("! Error parsing range: %s", err.Error())
+	if format != "" {
+		addrtype, ok := exportAddressTypes[reference]
+		if !ok {
 			http.Error(w, "error", http.StatusBadRequest)
 			return
 		}
-	} else {
-		offset = 0
+
+		exportTransactions(w, r, dbc, address, addrtype, format)
+		return
 	}
 
+	limitString := r.URL.Query().Get("limit")
+	orderString := r.URL.Query().Get("order")
+
+	var limit uint64
 	if limitString != "" {
 		limit, err = strconv.ParseUint(limitString, 10, 32)
 		if err != nil {
-			log.Printf("! Error parsing range: %s", err.Error())
+			requestLogger(r).Debug("Failed to parse range", "err", err)
 			http.Error(w, "error", http.StatusBadRequest)
 			return
 		}
@@ -346,6 +420,13 @@ func HandleTxByAddress(w http.ResponseWriter, r *http.Request) {
 		limit = 20
 	}
 
+	cursor, err := parseCursor(r)
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
 	if orderString == "" {
 		orderString = "asc"
 	}
@@ -354,39 +435,66 @@ func HandleTxByAddress(w http.ResponseWriter, r *http.Request) {
 		orderString = "asc"
 	}
 
-	log.Println("Request Transaction by Address:", address, "-", reference, offset, limit, orderString)
+	requestLogger(r).Debug("Request transactions by address", "address", address, "ref", reference, "limit", limit, "order", orderString)
 
+	// fetch one more transaction to know if there are more pages
 	switch reference {
 	case "from":
-		txs, err = dbc.GetTransactionsByAddress(address, models.ADDRESS_FROM, offset, limit, orderString)
+		txs, err = dbc.GetTransactionsByAddress(address, models.ADDRESS_FROM, cursor, limit+1, orderString)
 	case "to":
-		txs, err = dbc.GetTransactionsByAddress(address, models.ADDRESS_TO, offset, limit, orderString)
+		txs, err = dbc.GetTransactionsByAddress(address, models.ADDRESS_TO, cursor, limit+1, orderString)
 	case "all":
-		txs, err = dbc.GetTransactionsByAddress(address, models.ADDRESS_ALL, offset, limit, orderString)
+		txs, err = dbc.GetTransactionsByAddress(address, models.ADDRESS_ALL, cursor, limit+1, orderString)
 	case "block":
-		txs, err = dbc.GetTransactionsByAddress(address, models.ADDRESS_BLOCKHASH, offset, limit, orderString)
+		txs, err = dbc.GetTransactionsByAddress(address, models.ADDRESS_BLOCKHASH, cursor, limit+1, orderString)
 	case "latest":
-		txs, err = dbc.GetTransactionsByAddress(address, models.LATEST, offset, limit, orderString)
+		txs, err = dbc.GetTransactionsByAddress(address, models.LATEST, cursor, limit+1, orderString)
 	default:
 		http.Error(w, "error", http.StatusBadRequest)
 		return
 	}
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
 
-	if txs == nil {
+	if txs == nil && cursor == nil {
 		http.Error(w, "error", http.StatusNotFound)
 		return
 	}
 
-	res, err := json.Marshal(txs)
+	hasMore := uint64(len(txs)) > limit
+	if hasMore {
+		if cursor != nil && cursor.Prev {
+			txs = txs[1:]
+		} else {
+			txs = txs[:limit]
+		}
+	}
+
+	if txs == nil {
+		txs = make([]models.TransactionFull, 0)
+	}
+
+	var first, last *models.Cursor
+	if len(txs) > 0 {
+		first = &models.Cursor{Number: uint64(txs[0].Tx.BlockNumber), Index: uint64(txs[0].Tx.TransactionIndex)}
+		last = &models.Cursor{Number: uint64(txs[len(txs)-1].Tx.BlockNumber), Index: uint64(txs[len(txs)-1].Tx.TransactionIndex)}
+	}
+
+	if decodeRequested(r) {
+		decoder := contracts.NewDecoder(r.Context())
+		for i := range txs {
+			decoder.Decode(&txs[i], false)
+		}
+	}
+
+	res, err := json.Marshal(models.NewPage(txs, cursor, hasMore, first, last))
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
 		w.Write(res)
@@ -405,25 +513,12 @@ func HandleStats(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	address, ok := vars["address"]
 	if ok {
-		log.Println("Request Stats for:", address)
-	}
-
-	// correct case sensivity for redis
-	redisKey := "stats"
-	if common.IsHexAddress(address) {
-		redisKey += ":" + common.HexToAddress(address).Hex()
-	}
-
-	if ok, _ := redis.Exists(redisKey); ok {
-		if res, err := redis.Get(redisKey); err == nil {
-			w.Write(res)
-			return
-		}
+		requestLogger(r).Debug("Request stats", "address", address)
 	}
 
 	result, err := getDelegatesStats(address)
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 
 		if err == ErrAddressNotFoundInDelegates {
 			http.Error(w, "error", http.StatusNotFound)
@@ -436,11 +531,9 @@ func HandleStats(w http.ResponseWriter, r *http.Request) {
 	res, err := json.Marshal(result)
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
-		redis.Set(redisKey, res)
-		redis.Expire(redisKey, 1)
 		w.Write(res)
 	}
 }
@@ -452,16 +545,16 @@ func HandleDelegates(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbc := db.GetClient()
+	dbc := db.GetClientContext(r.Context())
 	if dbc == nil {
-		log.Printf("! Error: DBClient is not initialized!")
+		requestLogger(r).Error("DBClient is not initialized")
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
 
-	ipc := ipc.GetIPC()
+	ipc := ipc.GetIPCContext(r.Context())
 	if ipc == nil {
-		log.Printf("! Error: IPCInterface is not initialized!")
+		requestLogger(r).Error("IPCInterface is not initialized")
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
@@ -470,48 +563,35 @@ func HandleDelegates(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 
-	redisKey := "delegates"
-	redisExpiryTime := uint64(1)
-
 	var blockNumber uint64
 	rawId, err := strconv.ParseInt(vars["number"], 10, 64)
 	if err == nil {
 		// Block number requested
 		blockNumber = uint64(rawId)
 
-		log.Println("Request Delegates for block number:", blockNumber)
+		requestLogger(r).Debug("Request delegates", "block", blockNumber)
 
 		if blockNumber < 0 {
-			log.Printf("! Error: Bad negative id")
+			requestLogger(r).Debug("Bad negative id")
 			http.Error(w, "error", http.StatusBadRequest)
 			return
 		}
-
-		redisKey = fmt.Sprintf("%s:%d", redisKey, blockNumber)
-		redisExpiryTime = 60 * 60 * 24 // 1 day
 	} else {
 		// Latest block requested
-		log.Println("Request Delegates for latest block")
+		requestLogger(r).Debug("Request delegates of the latest block")
 
 		var err error
 		blockNumber, err = dbc.GetLatestBlockNumber()
 		if err != nil {
-			log.Printf("! Error: %s", err.Error())
+			requestLogger(r).Error("Request failed", "err", err)
 			http.Error(w, "error", http.StatusInternalServerError)
 			return
 		}
 	}
 
-	if ok, _ := redis.Exists(redisKey); ok {
-		if res, err := redis.Get(redisKey); err == nil {
-			w.Write(res)
-			return
-		}
-	}
-
 	delegates, err := ipc.GetDelegates(blockNumber)
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
@@ -519,75 +599,61 @@ func HandleDelegates(w http.ResponseWriter, r *http.Request) {
 	res, err := json.Marshal(delegates)
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
-		redis.Set(redisKey, res)
-		redis.Expire(redisKey, redisExpiryTime)
 		w.Write(res)
 	}
 }
 
-// HandleABI returns the ABI for a contract
+// HandleABI returns the ABI for a contract, from the node, the uploaded
+// ABIs or else derived from the signatures of the methods called. Where it
+// came from is set in the X-ABI-Source header.
 func HandleABI(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "error", http.StatusBadRequest)
 		return
 	}
 
-	ipc := ipc.GetIPC()
-	if ipc == nil {
-		log.Printf("! Error: IPC connection is not initialized!")
-		http.Error(w, "error", http.StatusInternalServerError)
-		return
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
 	address, ok := vars["address"]
 	if !ok || !common.IsHexAddress(address) {
-		log.Println("Request ABI for:", address)
+		requestLogger(r).Debug("Request ABI", "address", address)
 		http.Error(w, "error", http.StatusBadRequest)
 		return
 	}
 
 	contractAddress := common.HexToAddress(address)
-	redisKey := "abi:" + contractAddress.Hex()
-
-	if ok, _ := redis.Exists(redisKey); ok {
-		if res, err := redis.Get(redisKey); err == nil {
-			w.Write(res)
-			return
-		}
-	}
-
-	abi, err := ipc.GetABIForContract(contractAddress)
-	if err != nil {
-		log.Printf("! Error: %s", err.Error())
-		http.Error(w, "error", http.StatusInternalServerError)
-		return
-	}
 
-	var res []map[string]interface{}
-	if err := json.Unmarshal([]byte(abi), &res); err != nil {
-		log.Printf("! Error: %s", err.Error())
-		http.Error(w, "error", http.StatusInternalServerError)
-		return
+	out, source, err := contracts.GetABIJSON(r.Context(), contractAddress)
+	if err == contracts.ErrABINotFound {
+		out, err = contracts.GetPartialABIJSON(r.Context(), contractAddress)
+		source = models.ABI_SOURCE_SIGNATURES
 	}
 
-	out, err := json.Marshal(res)
-
-	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+	if err == contracts.ErrABINotFound {
+		http.Error(w, "error", http.StatusNotFound)
+	} else if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
-		redis.Set(redisKey, out)
-		redis.Expire(redisKey, 60*60*24) // 1 day
+		w.Header().Set(abiSourceHeader, source)
 		w.Write(out)
 	}
 }
 
+// getTotalSupply returns the supply in wei at a block number, the initial
+// distribution plus the block rewards paid since
+func getTotalSupply(blockNumber uint64) *big.Int {
+	dposConfig := params.MainnetDPOSConfig
+	initialDistributionWei := new(big.Int).Mul(new(big.Int).SetUint64(dposConfig.InitialDistribution), ether)
+	blockRewards := 3171 * blockNumber
+	blockRewardsWei := new(big.Int).Mul(new(big.Int).SetUint64(blockRewards), precisionFactor)
+	return new(big.Int).Add(initialDistributionWei, blockRewardsWei)
+}
+
 // HandleChainInfo returns useful info for this chain
 func HandleChainInfo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
@@ -595,37 +661,28 @@ func HandleChainInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbc := db.GetClient()
+	dbc := db.GetClientContext(r.Context())
 	if dbc == nil {
-		log.Printf("! Error: DBClient is not initialized!")
+		requestLogger(r).Error("DBClient is not initialized")
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 
-	redisKey := "chainInfo"
-
-	if ok, _ := redis.Exists(redisKey); ok {
-		if res, err := redis.Get(redisKey); err == nil {
-			w.Write(res)
-			return
-		}
-	}
-
 	res := make(map[string]interface{})
 
 	var err error
 	latestBlockNumber, err := dbc.GetLatestBlockNumber()
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
 
 	latestBlock, err := dbc.GetBlockByID(latestBlockNumber)
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
@@ -634,11 +691,7 @@ func HandleChainInfo(w http.ResponseWriter, r *http.Request) {
 	res["block_timestamp"] = uint64(latestBlock.TimeStamp)
 	res["block_hash"] = latestBlock.Hash.Hex()
 
-	dposConfig := params.MainnetDPOSConfig
-	initialDistributionWei := new(big.Int).Mul(new(big.Int).SetUint64(dposConfig.InitialDistribution), ether)
-	blockRewards := 3171 * latestBlockNumber
-	blockRewardsWei := new(big.Int).Mul(new(big.Int).SetUint64(blockRewards), precisionFactor)
-	totalSupplyWei := new(big.Int).Add(initialDistributionWei, blockRewardsWei)
+	totalSupplyWei := getTotalSupply(latestBlockNumber)
 
 	res["total_supply_wei"] = totalSupplyWei
 	res["circulating_supply_wei"] = totalSupplyWei
@@ -646,11 +699,9 @@ func HandleChainInfo(w http.ResponseWriter, r *http.Request) {
 	out, err := json.Marshal(res)
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
-		redis.Set(redisKey, out)
-		redis.Expire(redisKey, 1) // 1 second
 		w.Write(out)
 	}
 }
@@ -662,35 +713,23 @@ func HandleRichList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbc := db.GetClient()
+	dbc := db.GetClientContext(r.Context())
 	if dbc == nil {
-		log.Printf("! Error: DBClient is not initialized!")
+		requestLogger(r).Error("DBClient is not initialized")
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 
-	offsetString := r.URL.Query().Get("offset")
 	limitString := r.URL.Query().Get("limit")
 
-	var offset, limit uint64
+	var limit uint64
 	var err error
-	if offsetString != "" {
-		offset, err = strconv.ParseUint(offsetString, 10, 32)
-		if err != nil {
-			log.Printf("! Error parsing range: %s", err.Error())
-			http.Error(w, "error", http.StatusBadRequest)
-			return
-		}
-	} else {
-		offset = 0
-	}
-
 	if limitString != "" {
 		limit, err = strconv.ParseUint(limitString, 10, 32)
 		if err != nil {
-			log.Printf("! Error parsing range: %s", err.Error())
+			requestLogger(r).Debug("Failed to parse range", "err", err)
 			http.Error(w, "error", http.StatusBadRequest)
 			return
 		}
@@ -698,19 +737,54 @@ func HandleRichList(w http.ResponseWriter, r *http.Request) {
 		limit = 100
 	}
 
-	log.Println("Request richlist limit/offset: ", limit, offset)
+	format, err := exportFormat(r)
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if format != "" {
+		exportRichList(w, r, dbc, format)
+		return
+	}
+
+	cursor, err := parseCursor(r)
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	requestLogger(r).Debug("Request rich list", "limit", limit)
 
-	richlist, err := dbc.GetTopBalances(limit, offset)
+	// fetch one more entry to know if there are more pages
+	richlist, err := dbc.GetTopBalances(cursor, limit+1)
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
 
-	res, err := json.Marshal(richlist)
+	hasMore := uint64(len(richlist)) > limit
+	if hasMore {
+		if cursor != nil && cursor.Prev {
+			richlist = richlist[1:]
+		} else {
+			richlist = richlist[:limit]
+		}
+	}
+
+	var first, last *models.Cursor
+	if len(richlist) > 0 {
+		first = &models.Cursor{Number: richlist[0].Amount, Address: &richlist[0].Address}
+		last = &models.Cursor{Number: richlist[len(richlist)-1].Amount, Address: &richlist[len(richlist)-1].Address}
+	}
+
+	res, err := json.Marshal(models.NewPage(richlist, cursor, hasMore, first, last))
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
 		w.Write(res)
@@ -724,9 +798,9 @@ func HandleAddReverseRegistrar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbc := db.GetClient()
+	dbc := db.GetClientContext(r.Context())
 	if dbc == nil {
-		log.Printf("! Error: DBClient is not initialized!")
+		requestLogger(r).Error("DBClient is not initialized")
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
@@ -735,14 +809,14 @@ func HandleAddReverseRegistrar(w http.ResponseWriter, r *http.Request) {
 	var ens models.ENS
 	err := decoder.Decode(&ens)
 	if err != nil {
-		log.Println("Error InsertEns failed", err.Error())
+		requestLogger(r).Error("Failed to insert ENS name", "err", err)
 		http.Error(w, "error", http.StatusBadRequest)
 		return
 	}
 
 	err = dbc.InsertEns(ens)
 	if err != nil {
-		log.Println("Error InsertEns failed", err.Error())
+		requestLogger(r).Error("Failed to insert ENS name", "err", err)
 		http.Error(w, "error", http.StatusBadRequest)
 		return
 	}
@@ -750,9 +824,10 @@ func HandleAddReverseRegistrar(w http.ResponseWriter, r *http.Request) {
 	res, err := json.Marshal(ens)
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
+		w.Header().Set("Content-Type", "application/json")
 		w.Write(res)
 	}
 }
@@ -764,9 +839,9 @@ func HandleGetReverseRegistrar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbc := db.GetClient()
+	dbc := db.GetClientContext(r.Context())
 	if dbc == nil {
-		log.Printf("! Error: DBClient is not initialized!")
+		requestLogger(r).Error("DBClient is not initialized")
 		http.Error(w, "error", http.StatusInternalServerError)
 		return
 	}
@@ -776,14 +851,14 @@ func HandleGetReverseRegistrar(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	address, ok := vars["address"]
 	if !ok {
-		log.Printf("! Error: %s", errors.New("Parameter is address"))
+		requestLogger(r).Debug("Missing parameter", "name", "address")
 		http.Error(w, "error", http.StatusBadRequest)
 		return
 	}
 
 	name, err := dbc.GetEnsName(address)
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		if err == sql.ErrNoRows {
 			http.Error(w, http.StatusText(404), http.StatusNotFound)
 		} else {
@@ -798,7 +873,7 @@ func HandleGetReverseRegistrar(w http.ResponseWriter, r *http.Request) {
 	out, err := json.Marshal(res)
 
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
 		w.Write(out)
@@ -814,21 +889,11 @@ func HandleGetConversionRate(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	redisKey := "rates:EBK"
-	if ok, _ := redis.Exists(redisKey); ok {
-		if res, err := redis.Get(redisKey); err == nil {
-			w.Write(res)
-			return
-		}
-	}
-
 	out, err := GetLatestUSDConversionRate()
 	if err != nil {
-		log.Printf("! Error: %s", err.Error())
+		requestLogger(r).Error("Request failed", "err", err)
 		http.Error(w, "error", http.StatusInternalServerError)
 	} else {
-		redis.Set(redisKey, out)
-		redis.Expire(redisKey, 60*60) // 1 hour
 		w.Write(out)
 	}
 }
@@ -841,11 +906,9 @@ func GetChainId() (uint64, error) {
 	}
 
 	redisKey := "chainId"
-	if ok, _ := redis.Exists(redisKey); ok {
-		if bChainID, err := redis.Get(redisKey); err == nil {
-			chainID := uint64(binary.LittleEndian.Uint64(bChainID))
-			return chainID, nil
-		}
+	if bChainID, ok := redis.GetCache().Get(redisKey); ok && len(bChainID) == 8 {
+		chainID := uint64(binary.LittleEndian.Uint64(bChainID))
+		return chainID, nil
 	}
 
 	chainID, err := ipc.GetChainId()
@@ -856,7 +919,17 @@ func GetChainId() (uint64, error) {
 	bChainID := make([]byte, 8)
 	binary.LittleEndian.PutUint64(bChainID, chainID)
 
-	redis.Set(redisKey, bChainID)
+	redis.GetCache().Set(redisKey, bChainID, 0)
 
 	return chainID, nil
 }
+
+// parseCursor returns the pagination cursor of the request, if any
+func parseCursor(r *http.Request) (*models.Cursor, error) {
+	cursorString := r.URL.Query().Get("cursor")
+	if cursorString == "" {
+		return nil, nil
+	}
+
+	return models.DecodeCursor(cursorString)
+}
@@ -0,0 +1,353 @@
+package webapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/gorilla/mux"
+)
+
+const testFixture = "../ipc/testdata/chain.json"
+
+var (
+	// testAlice sends the first transaction of the fixture and has 4 of them
+	testAlice = common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	testBob   = common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+)
+
+func TestMain(m *testing.M) {
+	if err := logger.Init("crit", logger.LOG_FORMAT_LOGFMT); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// indexChain copies the blocks and transactions of node to store, the way
+// the crawler does
+func indexChain(node ipc.Node, store db.Store) error {
+	head, err := node.GetBlockNumber()
+	if err != nil {
+		return err
+	}
+
+	var blocks []*models.Block
+	var transactions []models.TransactionFull
+	for number := uint64(0); number <= head; number++ {
+		bl, err := node.GetBlock(number)
+		if err != nil {
+			return fmt.Errorf("block %d: %v", number, err)
+		}
+		blocks = append(blocks, bl)
+
+		for _, hash := range bl.Transactions {
+			hash := hash
+			tx, txr, err := node.GetTransactionByHash(&hash)
+			if err != nil {
+				return fmt.Errorf("transaction %s: %v", hash.Hex(), err)
+			}
+			tx.Timestamp = bl.TimeStamp
+			transactions = append(transactions, models.TransactionFull{Tx: tx, Txr: txr})
+		}
+	}
+
+	if err := store.InsertBlocks(blocks); err != nil {
+		return err
+	}
+	if err := store.InsertTransactions(transactions); err != nil {
+		return err
+	}
+
+	return store.RefreshRollups(uint64(blocks[0].TimeStamp), uint64(blocks[len(blocks)-1].TimeStamp))
+}
+
+// setupTestChain serves the fixture from a fake node, indexed in a memory
+// store with the balances of the fixture and a name for alice
+func setupTestChain(t *testing.T) (*ipc.FakeNode, *db.MemoryStore) {
+	t.Helper()
+
+	node, err := ipc.NewFakeNodeFromFile(testFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipc.SetIPC(node)
+	redis.SetCache(redis.NewMemoryCache(redis.DefaultCacheSize))
+
+	store := db.NewMemoryStore()
+	db.SetClient(store)
+
+	if err := indexChain(node, store); err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := store.GetLatestBlockNumber()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// balances are stored in units of 1e-4 EBK, as the crawler does
+	for _, address := range []common.Address{testAlice, testBob} {
+		balance, err := node.GetAddressBalance(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		amount := new(big.Int).Div(balance, precisionFactor)
+		if err := store.InsertBalance(address, amount.Uint64(), head); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.SetGlobalInt(models.GLOBAL_RICH_LIST_LAST_BLOCK, head); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.InsertEns(models.ENS{Address: testAlice, Hash: common.HexToHash("0xa11ce"), Name: "alice.ebk"}); err != nil {
+		t.Fatal(err)
+	}
+
+	return node, store
+}
+
+func testRouter() *mux.Router {
+	router := mux.NewRouter().StrictSlash(true)
+	RegisterRoutes(router)
+	return router
+}
+
+// serve makes a request to the routes of the explorer
+func serve(method, uri, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, uri, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	w := httptest.NewRecorder()
+	testRouter().ServeHTTP(w, req)
+	return w
+}
+
+// serveJSON makes a request that has to be answered with status, and
+// decodes the response to result
+func serveJSON(t *testing.T, method, uri, body string, status int, result interface{}) {
+	t.Helper()
+
+	w := serve(method, uri, body)
+	if w.Code != status {
+		t.Fatalf("%s %s: status %d, want %d: %s", method, uri, w.Code, status, strings.TrimSpace(w.Body.String()))
+	}
+	if result == nil {
+		return
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("%s %s: content type %q", method, uri, ct)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), result); err != nil {
+		t.Fatalf("%s %s: %v", method, uri, err)
+	}
+}
+
+type testBlock struct {
+	Number           uint64         `json:"number"`
+	Hash             common.Hash    `json:"hash"`
+	TransactionCount uint64         `json:"transactionCount"`
+	Producer         common.Address `json:"producer"`
+}
+
+type testTransaction struct {
+	Hash             common.Hash    `json:"hash"`
+	BlockNumber      uint64         `json:"blockNumber"`
+	TransactionIndex uint64         `json:"transactionIndex"`
+	From             common.Address `json:"from"`
+	FromEns          *string        `json:"fromEns"`
+	Value            *big.Int       `json:"value"`
+}
+
+type testPage struct {
+	Data json.RawMessage `json:"data"`
+	Next *string         `json:"next"`
+	Prev *string         `json:"prev"`
+}
+
+func TestHandleBlock(t *testing.T) {
+	node, _ := setupTestChain(t)
+
+	expected, err := node.GetBlock(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uri := range []string{"/block/1", "/block/" + expected.Hash.Hex()} {
+		var bl testBlock
+		serveJSON(t, "GET", uri, "", http.StatusOK, &bl)
+		if bl.Number != 1 || bl.Hash != expected.Hash || bl.TransactionCount != 1 || bl.Producer != expected.Producer {
+			t.Errorf("GET %s = %+v", uri, bl)
+		}
+	}
+
+	serveJSON(t, "GET", "/block/abc", "", http.StatusBadRequest, nil)
+	serveJSON(t, "GET", "/block/-2?range=2", "", http.StatusBadRequest, nil)
+
+	// the latest blocks, newest first, and the pages before them
+	var page testPage
+	serveJSON(t, "GET", "/block/-1?range=2", "", http.StatusOK, &page)
+	var blocks []testBlock
+	if err := json.Unmarshal(page.Data, &blocks); err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 || blocks[0].Number != 5 || blocks[1].Number != 4 || page.Next == nil || page.Prev != nil {
+		t.Fatalf("GET /block/-1?range=2 = %+v, next %v", blocks, page.Next)
+	}
+
+	serveJSON(t, "GET", "/block/-1?range=2&cursor="+*page.Next, "", http.StatusOK, &page)
+	blocks = nil
+	if err := json.Unmarshal(page.Data, &blocks); err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 || blocks[0].Number != 3 || blocks[1].Number != 2 || page.Next == nil || page.Prev == nil {
+		t.Errorf("second page = %+v", blocks)
+	}
+}
+
+func TestHandleTxByHash(t *testing.T) {
+	node, _ := setupTestChain(t)
+
+	hash := common.HexToHash("0x12c678774b02dd775629a82d21ea7bcf0301c1477975feb821202d8665b7a307")
+	expected, _, err := node.GetTransactionByHash(&hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tx testTransaction
+	serveJSON(t, "GET", "/transaction/"+hash.Hex(), "", http.StatusOK, &tx)
+	if tx.Hash != hash || tx.BlockNumber != 1 || tx.From != testAlice || tx.Value.Cmp(expected.Value.ToInt()) != 0 {
+		t.Errorf("GET /transaction/%s = %+v", hash.Hex(), tx)
+	}
+	if tx.FromEns == nil || *tx.FromEns != "alice.ebk" {
+		t.Errorf("fromEns = %v, want alice.ebk", tx.FromEns)
+	}
+
+	serveJSON(t, "GET", "/transaction/"+common.HexToHash("0xdeadbeef").Hex(), "", http.StatusNotFound, nil)
+}
+
+func TestHandleTxByAddress(t *testing.T) {
+	setupTestChain(t)
+
+	txPage := func(uri string) ([]testTransaction, testPage) {
+		var page testPage
+		serveJSON(t, "GET", uri, "", http.StatusOK, &page)
+		var txs []testTransaction
+		if err := json.Unmarshal(page.Data, &txs); err != nil {
+			t.Fatal(err)
+		}
+		return txs, page
+	}
+
+	uri := "/transaction/all/" + testAlice.Hex() + "?limit=3"
+	txs, page := txPage(uri)
+	if len(txs) != 3 || txs[0].BlockNumber != 1 || txs[1].BlockNumber != 3 || txs[2].TransactionIndex != 1 || page.Next == nil || page.Prev != nil {
+		t.Fatalf("GET %s = %+v", uri, txs)
+	}
+
+	txs, page = txPage(uri + "&cursor=" + *page.Next)
+	if len(txs) != 1 || txs[0].BlockNumber != 5 || page.Next != nil || page.Prev == nil {
+		t.Fatalf("second page = %+v", txs)
+	}
+
+	txs, _ = txPage(uri + "&cursor=" + *page.Prev)
+	if len(txs) != 3 || txs[0].BlockNumber != 1 {
+		t.Errorf("page before the second = %+v", txs)
+	}
+
+	txs, _ = txPage("/transaction/from/" + testBob.Hex() + "?order=desc")
+	if len(txs) != 1 || txs[0].From != testBob {
+		t.Errorf("transactions from bob = %+v", txs)
+	}
+
+	// a cursor past the last page is an empty page, not a missing address
+	txs, page = txPage(uri + "&cursor=" + models.Cursor{Number: 100}.Encode())
+	if len(txs) != 0 || page.Next != nil || page.Prev != nil {
+		t.Errorf("page past the last = %+v, next %v, prev %v", txs, page.Next, page.Prev)
+	}
+
+	serveJSON(t, "GET", "/transaction/all/"+testAlice.Hex()+"?limit=x", "", http.StatusBadRequest, nil)
+	serveJSON(t, "GET", "/transaction/all/"+testAlice.Hex()+"?cursor=%21", "", http.StatusBadRequest, nil)
+
+	// cursors whose JSON was tampered with are rejected
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(`{"n":"1"}`))
+	serveJSON(t, "GET", "/transaction/all/"+testAlice.Hex()+"?cursor="+tampered, "", http.StatusBadRequest, nil)
+}
+
+func TestHandleAddress(t *testing.T) {
+	node, _ := setupTestChain(t)
+
+	balance, err := node.GetAddressBalance(testAlice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result models.AddressResult
+	serveJSON(t, "GET", "/address/"+testAlice.Hex(), "", http.StatusOK, &result)
+	if result.Address != testAlice || result.TxCount != 4 || result.IsContract || result.Balance.Cmp(balance) != 0 {
+		t.Errorf("GET /address/%s = %+v", testAlice.Hex(), result)
+	}
+	if result.AddressEns == nil || *result.AddressEns != "alice.ebk" {
+		t.Errorf("addressEns = %v, want alice.ebk", result.AddressEns)
+	}
+
+	serveJSON(t, "GET", "/address/0x1234", "", http.StatusBadRequest, nil)
+}
+
+func TestHandleRichList(t *testing.T) {
+	setupTestChain(t)
+
+	var page testPage
+	serveJSON(t, "GET", "/rich-list?limit=1", "", http.StatusOK, &page)
+	var balances []struct {
+		Address common.Address `json:"address"`
+	}
+	if err := json.Unmarshal(page.Data, &balances); err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 1 || balances[0].Address != testAlice || page.Next == nil {
+		t.Fatalf("GET /rich-list?limit=1 = %+v", balances)
+	}
+
+	balances = nil
+	serveJSON(t, "GET", "/rich-list?limit=1&cursor="+*page.Next, "", http.StatusOK, &page)
+	if err := json.Unmarshal(page.Data, &balances); err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 1 || balances[0].Address != testBob || page.Next != nil {
+		t.Errorf("second page = %+v", balances)
+	}
+}
+
+func TestHandleReverseRegistrar(t *testing.T) {
+	setupTestChain(t)
+
+	body := `{"address": "` + testBob.Hex() + `", "hash": "0x00000000000000000000000000000000000000000000000000000000000000b0", "name": "bob.ebk"}`
+	serveJSON(t, "POST", "/ens", body, http.StatusOK, &models.ENS{})
+
+	var result struct {
+		Name string `json:"name"`
+	}
+	serveJSON(t, "GET", "/ens/"+testBob.Hex(), "", http.StatusOK, &result)
+	if result.Name != "bob.ebk" {
+		t.Errorf("GET /ens/%s = %q, want bob.ebk", testBob.Hex(), result.Name)
+	}
+
+	serveJSON(t, "GET", "/ens/0x2222222222222222222222222222222222222222", "", http.StatusNotFound, nil)
+	serveJSON(t, "POST", "/ens", "{", http.StatusBadRequest, nil)
+}
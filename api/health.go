@@ -0,0 +1,294 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+)
+
+// readyCheckTimeout is how long each readiness check may take before the
+// dependency counts as unreachable
+const readyCheckTimeout = 5 * time.Second
+
+var (
+	// the index is out of sync once it is behind the node by more than
+	// readyMaxLagBlocks blocks or readyMaxLag, once the rich list was computed
+	// more than readyMaxRichListLag blocks ago, or the ENS names were synced
+	// more than readyMaxENSAge ago. Zero disables a threshold.
+	readyMaxLagBlocks   = uint64(300)
+	readyMaxLag         = 5 * time.Minute
+	readyMaxRichListLag = uint64(0)
+	readyMaxENSAge      = time.Duration(0)
+
+	// readyRequiresRedis makes the explorer unready while Redis is down,
+	// rather than only when it serves from the in-memory cache
+	readyRequiresRedis = false
+)
+
+// SetReadyThresholds sets how far behind the index may fall before the
+// explorer is no longer ready. Zero disables a threshold.
+func SetReadyThresholds(maxLagBlocks uint64, maxLag time.Duration, maxRichListLag uint64, maxENSAge time.Duration) {
+	readyMaxLagBlocks, readyMaxLag = maxLagBlocks, maxLag
+	readyMaxRichListLag, readyMaxENSAge = maxRichListLag, maxENSAge
+}
+
+// SetRedisRequired sets whether the explorer is unready while Redis is down
+func SetRedisRequired(required bool) {
+	readyRequiresRedis = required
+}
+
+// health tells how the explorer is running. Cache is the backend the
+// responses are currently cached in, which is memory while Redis is down.
+type health struct {
+	Status string `json:"status"`
+	Cache  string `json:"cache"`
+}
+
+// readiness tells whether the explorer can serve requests. Checks maps each
+// dependency to "ok" or the reason it failed.
+type readiness struct {
+	Ready  bool               `json:"ready"`
+	Checks map[string]string  `json:"checks"`
+	Sync   *models.SyncStatus `json:"sync,omitempty"`
+}
+
+// withTimeout runs fn, giving up on it after readyCheckTimeout
+func withTimeout(fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(readyCheckTimeout):
+		return errors.New("timed out")
+	}
+}
+
+// nodeHead returns the head block of the node
+func nodeHead(node ipc.Node) (*models.Block, error) {
+	if node == nil {
+		return nil, errors.New("IPC is not initialized")
+	}
+
+	var head *models.Block
+	err := withTimeout(func() error {
+		number, err := node.GetBlockNumber()
+		if err != nil {
+			return err
+		}
+		head, err = node.GetBlock(number)
+		return err
+	})
+	return head, err
+}
+
+// syncStatus compares the index with the head of the node, which is nil
+// when the node can't be reached
+func syncStatus(dbc db.Store, head *models.Block) (*models.SyncStatus, error) {
+	status := &models.SyncStatus{}
+
+	var indexed *models.Block
+	err := withTimeout(func() error {
+		var err error
+		if status.IndexedHead, err = dbc.GetLatestBlockNumber(); err != nil {
+			return err
+		}
+		if status.RichListBlock, err = dbc.GetGlobalInt(models.GLOBAL_RICH_LIST_LAST_BLOCK); err != nil {
+			return err
+		}
+		if status.EnsLastSync, err = dbc.GetGlobalInt(models.GLOBAL_ENS_LAST_SYNC); err != nil {
+			return err
+		}
+		if status.IndexedHead > 0 {
+			indexed, err = dbc.GetBlockByID(status.IndexedHead)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// without the node the lag is measured from now, which also catches a
+	// node that stopped with the crawler
+	now := uint64(time.Now().Unix())
+	if head != nil {
+		number := uint64(head.Number)
+		status.NodeHead = &number
+		if number > status.IndexedHead {
+			status.LagBlocks = number - status.IndexedHead
+		}
+		now = uint64(head.TimeStamp)
+	} else {
+		status.Problems = append(status.Problems, "the node is unreachable")
+	}
+
+	if indexed == nil {
+		status.Problems = append(status.Problems, "no blocks are indexed")
+	} else if now > uint64(indexed.TimeStamp) {
+		status.LagSeconds = now - uint64(indexed.TimeStamp)
+	}
+
+	if readyMaxLagBlocks > 0 && status.LagBlocks > readyMaxLagBlocks {
+		status.Problems = append(status.Problems,
+			fmt.Sprintf("the index is %d blocks behind the node, more than %d", status.LagBlocks, readyMaxLagBlocks))
+	}
+	if readyMaxLag > 0 && time.Duration(status.LagSeconds)*time.Second > readyMaxLag {
+		status.Problems = append(status.Problems,
+			fmt.Sprintf("the index is %ds behind the node, more than %s", status.LagSeconds, readyMaxLag))
+	}
+	if readyMaxRichListLag > 0 && status.IndexedHead > status.RichListBlock+readyMaxRichListLag {
+		status.Problems = append(status.Problems,
+			fmt.Sprintf("the rich list was computed at block %d, more than %d blocks ago", status.RichListBlock, readyMaxRichListLag))
+	}
+	if readyMaxENSAge > 0 {
+		synced := time.Unix(int64(status.EnsLastSync), 0)
+		if status.EnsLastSync == 0 {
+			status.Problems = append(status.Problems, "the ENS names were never synced")
+		} else if time.Since(synced) > readyMaxENSAge {
+			status.Problems = append(status.Problems,
+				fmt.Sprintf("the ENS names were last synced at %d, more than %s ago", status.EnsLastSync, readyMaxENSAge))
+		}
+	}
+
+	status.Synced = len(status.Problems) == 0
+	return status, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, res interface{}) {
+	out, err := json.Marshal(res)
+	if err != nil {
+		logger.Error("Failed to encode response", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(out)
+}
+
+// HandleHealth reports the status of the explorer
+func HandleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	res := health{
+		Status: "ok",
+		Cache:  redis.GetCache().Backend(),
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// HandleReady reports whether the database, the node and Redis can be
+// reached and the index is in sync, replying 503 when they aren't. Redis
+// only has to be up when it is required, as the cache falls back to memory.
+func HandleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	res := readiness{Ready: true, Checks: make(map[string]string)}
+	report := func(check string, err error, fatal bool) {
+		if err == nil {
+			res.Checks[check] = "ok"
+			return
+		}
+		res.Checks[check] = err.Error()
+		if fatal {
+			res.Ready = false
+		}
+	}
+
+	var wg sync.WaitGroup
+	var head *models.Block
+	var nodeErr, redisErr error
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		head, nodeErr = nodeHead(ipc.GetIPCContext(r.Context()))
+	}()
+	go func() {
+		defer wg.Done()
+		redisErr = withTimeout(redis.Ping)
+	}()
+
+	dbc := db.GetClientContext(r.Context())
+	var dbErr error
+	if dbc == nil {
+		dbErr = errors.New("DBClient is not initialized")
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+		dbErr = dbc.Ping(ctx)
+		cancel()
+	}
+	wg.Wait()
+
+	report("db", dbErr, true)
+	report("node", nodeErr, true)
+	report("redis", redisErr, readyRequiresRedis)
+
+	if dbErr == nil {
+		status, err := syncStatus(dbc, head)
+		report("sync", err, true)
+		if err == nil {
+			res.Sync = status
+			if !status.Synced {
+				res.Checks["sync"] = "out of sync"
+				res.Ready = false
+			}
+		}
+	}
+
+	code := http.StatusOK
+	if !res.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, res)
+}
+
+// HandleSyncStatus reports how far the index is behind the node
+func HandleSyncStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		requestLogger(r).Error("DBClient is not initialized")
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	head, err := nodeHead(ipc.GetIPCContext(r.Context()))
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+	}
+
+	status, err := syncStatus(dbc, head)
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
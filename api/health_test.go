@@ -0,0 +1,37 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+)
+
+// unreachableStore is a store whose database went away
+type unreachableStore struct {
+	*db.MemoryStore
+}
+
+func (unreachableStore) Ping(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestHandleReadyPing(t *testing.T) {
+	_, store := setupTestChain(t)
+
+	var res readiness
+	serveJSON(t, "GET", "/ready", "", http.StatusOK, &res)
+	if !res.Ready || res.Checks["db"] != "ok" {
+		t.Errorf("ready = %+v, want ready with the database reachable", res)
+	}
+
+	// the globals of the memory store still answer, the ping doesn't
+	db.SetClient(unreachableStore{store})
+	res = readiness{}
+	serveJSON(t, "GET", "/ready", "", http.StatusServiceUnavailable, &res)
+	if res.Ready || res.Checks["db"] != "connection refused" {
+		t.Errorf("ready = %+v, want unready with the database unreachable", res)
+	}
+}
@@ -0,0 +1,76 @@
+package webapi
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/events"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+)
+
+// decodedQuery is the query of the decoded variant of a response, which is
+// purged along with the plain one
+var decodedQuery = url.Values{"decode": []string{"true"}}
+
+// pathCacheKeys returns the keys of a path, plain and decoded. Other
+// variants, like pages, are only cached shortly and left to expire.
+func pathCacheKeys(path string) []string {
+	return []string{pathCacheKey(path, nil), pathCacheKey(path, decodedQuery)}
+}
+
+// changeCacheKeys maps a change of the index to the keys of the cached
+// responses it affects
+func changeCacheKeys(change events.Change) []string {
+	switch change.Type {
+	case events.CHANGE_BLOCK_ADDED, events.CHANGE_BLOCK_REMOVED:
+		keys := []string{
+			chainInfoCacheKey,
+			statsCachePrefix,
+			delegatesCachePrefix,
+			fmt.Sprintf("%s:%d", delegatesCachePrefix, change.Number),
+		}
+		keys = append(keys, pathCacheKeys("/transaction/latest")...)
+		keys = append(keys, pathCacheKeys(fmt.Sprintf("/block/%d", change.Number))...)
+		if change.Hash != nil {
+			keys = append(keys, pathCacheKeys("/block/"+change.Hash.Hex())...)
+		}
+		for _, hash := range change.Transactions {
+			keys = append(keys, pathCacheKeys("/transaction/"+hash.Hex())...)
+		}
+		return keys
+
+	case events.CHANGE_ADDRESS_TOUCHED:
+		if change.Address == nil {
+			return nil
+		}
+		address := *change.Address
+
+		keys := []string{addressKey(addressCachePrefix, address), addressKey(statsCachePrefix, address)}
+		for _, ref := range []string{"from", "to", "all"} {
+			keys = append(keys, pathCacheKeys("/transaction/"+ref+"/"+address.Hex())...)
+		}
+		return keys
+	}
+
+	return nil
+}
+
+// RunCacheInvalidation purges the cached responses affected by the changes
+// the crawler publishes, until changes is closed
+func RunCacheInvalidation(changes <-chan []events.Change) {
+	for batch := range changes {
+		keys := make(map[string]struct{})
+		for _, change := range batch {
+			for _, key := range changeCacheKeys(change) {
+				keys[key] = struct{}{}
+			}
+		}
+
+		for key := range keys {
+			if err := redis.GetCache().Delete(key); err != nil {
+				logger.Error("Failed to clear cache", "key", key, "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package webapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs taken from the clients
+const maxRequestIDLength = 64
+
+// validRequestID tells whether a request ID set by the client, or the proxy
+// in front of the explorer, is safe to log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// requestLogger returns the logger of a request, which logs its ID
+func requestLogger(r *http.Request) logger.Logger {
+	return logger.FromContext(r.Context())
+}
+
+// RequestIDMiddleware gives each request an ID, the one in its X-Request-ID
+// header when valid, and returns it in the same header. The ID is logged
+// with every line logged for the request, including its queries and calls
+// to the node.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := logger.WithContext(r.Context(), logger.New("reqid", id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AccessLogMiddleware logs each request with its status, latency and client.
+// The routes polled by load balancers and scrapers are logged at debug.
+func AccessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := routeTemplate(r)
+
+		sr := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(sr, r)
+
+		if sr.status == 0 {
+			sr.status = http.StatusOK
+		}
+
+		// the query is left out, as it may hold an API key
+		ctx := []interface{}{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", route,
+			"status", sr.status,
+			"bytes", sr.size,
+			"elapsed", time.Since(start),
+			"client", clientIP(r),
+		}
+
+		log := requestLogger(r)
+		if rateLimitExempt[route] {
+			log.Debug("Request", ctx...)
+		} else {
+			log.Info("Request", ctx...)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package webapi
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/metrics"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ebakus_explorer_http_requests_total",
+		Help: "Requests served, by route, method and status",
+	}, []string{"route", "method", "status"})
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ebakus_explorer_http_request_duration_seconds",
+		Help:    "Latency of the requests, by route and method",
+		Buckets: metrics.DefaultBuckets,
+	}, []string{"route", "method"})
+	cacheResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ebakus_explorer_cache_responses_total",
+		Help: "Responses of cached routes, by route and whether they were a hit, stale or a miss",
+	}, []string{"route", "result"})
+	cacheRedis = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "ebakus_explorer_cache_redis",
+		Help: "Whether the cache is on redis, rather than in memory",
+	}, func() float64 {
+		if redis.GetCache().Backend() == redis.CACHE_BACKEND_REDIS {
+			return 1
+		}
+		return 0
+	})
+)
+
+// metricsToken is the bearer token /metrics requires, none when empty
+var metricsToken string
+
+// SetMetricsToken sets the bearer token that authorizes reading the metrics
+func SetMetricsToken(token string) {
+	metricsToken = token
+}
+
+// statusRecorder captures the status and the size of a response, passing
+// on flushes and hijacks for the streaming routes
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	n, err := sr.ResponseWriter.Write(b)
+	sr.size += n
+	return n, err
+}
+
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response can't be hijacked")
+	}
+	// websocket upgrades reply on the connection itself
+	sr.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// MetricsMiddleware counts and times the requests by route
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := routeTemplate(r)
+
+		sr := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(sr, r)
+
+		if sr.status == 0 {
+			sr.status = http.StatusOK
+		}
+		requestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(sr.status)).Inc()
+		requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+
+		if result := w.Header().Get(cacheHeader); result != "" {
+			cacheResponses.WithLabelValues(route, strings.ToLower(result)).Inc()
+		}
+	})
+}
+
+// HandleMetrics serves the metrics in the Prometheus text format
+func HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	if metricsToken != "" {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(metricsToken)) != 1 {
+			http.Error(w, "error", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	metrics.Handler().ServeHTTP(w, r)
+}
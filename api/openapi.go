@@ -0,0 +1,29 @@
+package webapi
+
+import (
+	"net/http"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/openapi"
+)
+
+// HandleOpenAPI returns the OpenAPI document of the API
+func HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(openapi.Spec))
+}
+
+// HandleDocs returns a page that renders the OpenAPI document
+func HandleDocs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(openapi.DocsPage))
+}
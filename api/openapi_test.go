@@ -0,0 +1,473 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/apikeys"
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/openapi"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// specWebhookSecret is the secret of the webhook the store is seeded with
+	specWebhookSecret = "openapi"
+
+	// specABIUploadToken authorizes the ABI uploads of the checks
+	specABIUploadToken = "openapi"
+)
+
+var (
+	// specContract is a token contract added on top of the fixture, so that
+	// decoded inputs and logs, contract calls and storage are covered
+	specContract = common.HexToAddress("0xcccccccccccccccccccccccccccccccccccccccc")
+
+	specContractABI = `[
+		{"type": "function", "name": "totalSupply", "stateMutability": "view", "inputs": [], "outputs": [{"name": "", "type": "uint256"}]},
+		{"type": "function", "name": "transfer", "stateMutability": "nonpayable", "inputs": [{"name": "to", "type": "address"}, {"name": "value", "type": "uint256"}], "outputs": [{"name": "", "type": "bool"}]},
+		{"type": "event", "name": "Transfer", "anonymous": false, "inputs": [{"name": "from", "type": "address", "indexed": true}, {"name": "to", "type": "address", "indexed": true}, {"name": "value", "type": "uint256", "indexed": false}]}
+	]`
+
+	// specSkipped are the operations that can't be checked offline
+	specSkipped = map[string]string{
+		"GET /conversion-rate": "queries CoinMarketCap",
+	}
+
+	// literalVariable matches route variables that only take one value,
+	// e.g. {ref:(?:latest)}, which are literal paths in the document
+	literalVariable = regexp.MustCompile(`\{[^:}]+:\(\?:([\w-]+)\)\}`)
+
+	// patternVariable matches route variables with a pattern, e.g. {id:[0-9]+}
+	patternVariable = regexp.MustCompile(`\{([^:}]+):[^}]+\}`)
+)
+
+// specChain is what the checks need to know about the indexed chain
+type specChain struct {
+	head        *models.Block
+	block       *models.Block
+	transaction *models.Transaction
+	contractTx  *models.Transaction
+	apiKey      string
+}
+
+// specCheck is a request made to the explorer, and the status it has to be
+// answered with
+type specCheck struct {
+	method      string
+	uri         string
+	contentType string
+	body        string
+	header      map[string]string
+	status      int
+}
+
+func specGet(uri string, status int) specCheck {
+	return specCheck{method: "GET", uri: uri, status: status}
+}
+
+func specPost(uri, body string, status int) specCheck {
+	return specCheck{method: "POST", uri: uri, contentType: "application/json", body: body, status: status}
+}
+
+func withHeader(c specCheck, header map[string]string) specCheck {
+	c.header = header
+	return c
+}
+
+// specPath converts a route template to the path of the document
+func specPath(template string) string {
+	template = literalVariable.ReplaceAllString(template, "$1")
+	return patternVariable.ReplaceAllString(template, "{$1}")
+}
+
+// contractBlock is a block after head with a token transfer, sent by from
+func contractBlock(head *models.Block, from common.Address) ipc.FakeChain {
+	number := head.Number + 1
+	to := specContract
+	recipient := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	amount := common.LeftPadBytes(big.NewInt(1000).Bytes(), 32)
+	transfer := append(append(common.FromHex("a9059cbb"), common.LeftPadBytes(recipient.Bytes(), 32)...), amount...)
+
+	tx := &models.Transaction{
+		Hash:        common.HexToHash("0x5bec0c0ffee5bec0c0ffee5bec0c0ffee5bec0c0ffee5bec0c0ffee5bec0c0ff"),
+		Nonce:       9,
+		BlockHash:   common.HexToHash("0x5bec0b10c45bec0b10c45bec0b10c45bec0b10c45bec0b10c45bec0b10c45bec"),
+		BlockNumber: number,
+		From:        from,
+		To:          &to,
+		GasLimit:    100000,
+		WorkNonce:   1,
+		Input:       models.InputData(transfer),
+	}
+
+	block := &models.Block{
+		Number:           number,
+		TimeStamp:        head.TimeStamp + 1,
+		Hash:             tx.BlockHash,
+		ParentHash:       head.Hash,
+		TransactionsRoot: common.HexToHash("0x01"),
+		ReceiptsRoot:     common.HexToHash("0x02"),
+		Size:             0x200,
+		GasUsed:          0xc350,
+		GasLimit:         head.GasLimit,
+		Transactions:     []common.Hash{tx.Hash},
+		Delegates:        head.Delegates,
+		Producer:         head.Delegates[int(number)%len(head.Delegates)],
+	}
+
+	transferTopic := common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	return ipc.FakeChain{
+		Blocks: []*models.Block{block},
+		Transactions: []ipc.FakeTransaction{{
+			Transaction: tx,
+			Receipt:     &models.TransactionReceipt{Status: 1, GasUsed: 0xc350, CumulativeGasUsed: 0xc350},
+			Logs: []models.Log{{
+				Address: specContract,
+				Topics:  []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(recipient.Bytes())},
+				Data:    amount,
+			}},
+		}},
+	}
+}
+
+// setupSpecChain serves the fixture, with the token contract, from a fake
+// node and indexes it in a memory store, seeded with balances, an ENS name,
+// an API key and a webhook
+func setupSpecChain(t *testing.T) *specChain {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(testFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture ipc.FakeFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture.ABIs = map[common.Address]json.RawMessage{specContract: json.RawMessage(specContractABI)}
+	fixture.Calls = map[common.Address]map[string]hexutil.Bytes{specContract: {
+		"0x18160ddd": common.LeftPadBytes(big.NewInt(1000000).Bytes(), 32), // totalSupply()
+	}}
+	fixture.Storage = map[common.Address]map[common.Hash]common.Hash{specContract: {
+		common.BigToHash(big.NewInt(0)): common.BigToHash(big.NewInt(1000000)),
+	}}
+
+	node, err := ipc.NewFakeNode(&fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head := fixture.Blocks[len(fixture.Blocks)-1]
+	first := fixture.Transactions[0].Transaction
+	contract := contractBlock(head, first.From)
+	if err := node.AddBlocks(contract); err != nil {
+		t.Fatal(err)
+	}
+	ipc.SetIPC(node)
+	redis.SetCache(redis.NewMemoryCache(redis.DefaultCacheSize))
+
+	store := db.NewMemoryStore()
+	db.SetClient(store)
+
+	if err := indexChain(node, store); err != nil {
+		t.Fatal(err)
+	}
+
+	number, err := store.GetLatestBlockNumber()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// balances are stored in units of 1e-4 EBK, as the crawler does
+	for address, balance := range fixture.Balances {
+		amount := new(big.Int).Div(balance.ToInt(), precisionFactor)
+		if err := store.InsertBalance(address, amount.Uint64(), number); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.SetGlobalInt(models.GLOBAL_RICH_LIST_LAST_BLOCK, number); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertEns(models.ENS{Address: first.From, Hash: common.HexToHash("0xa11ce"), Name: "alice.ebk"}); err != nil {
+		t.Fatal(err)
+	}
+
+	key, apiKey, err := apikeys.Issue(store, "openapi", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	webhook := &models.Webhook{
+		APIKeyID:  apiKey.ID,
+		URL:       "https://example.com/hook",
+		Secret:    specWebhookSecret,
+		EventType: models.WEBHOOK_EVENT_TRANSFER,
+		Address:   first.From,
+		Direction: models.WEBHOOK_DIRECTION_ANY,
+		MinValue:  big.NewInt(0),
+		CreatedAt: uint64(head.TimeStamp),
+	}
+	if err := store.InsertWebhook(webhook); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := json.Marshal(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery := models.WebhookDelivery{
+		WebhookID:  webhook.ID,
+		EventType:  models.WEBHOOK_EVENT_TRANSFER,
+		Payload:    payload,
+		Status:     models.WEBHOOK_DELIVERY_DELIVERED,
+		Attempts:   1,
+		StatusCode: 200,
+		CreatedAt:  uint64(head.TimeStamp),
+		UpdatedAt:  uint64(head.TimeStamp),
+	}
+	if err := store.InsertWebhookDeliveries([]models.WebhookDelivery{delivery}); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := store.GetBlockByID(number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bl, err := store.GetBlockByID(uint64(first.BlockNumber))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &specChain{
+		head:        latest,
+		block:       bl,
+		transaction: first,
+		contractTx:  contract.Transactions[0].Transaction,
+		apiKey:      key,
+	}
+}
+
+// specChecks lists the requests made to the explorer, in order. The webhook
+// is deleted last.
+func specChecks(ch *specChain) []specCheck {
+	block := ch.block.Hash.Hex()
+	tx := ch.transaction.Hash.Hex()
+	address := ch.transaction.From.Hex()
+	producer := ch.head.Producer.Hex()
+	contract := specContract.Hex()
+	secret := map[string]string{"X-Webhook-Secret": specWebhookSecret}
+	apiKey := map[string]string{"X-API-Key": ch.apiKey}
+	graphql := `{ blocks(first: 2) { number hash transactionCount } }`
+
+	return []specCheck{
+		specGet("/health", http.StatusOK),
+		specGet("/ready", http.StatusOK),
+		specGet("/sync-status", http.StatusOK),
+		specGet("/metrics", http.StatusOK),
+		specGet("/openapi.json", http.StatusOK),
+		specGet("/docs", http.StatusOK),
+
+		specGet(fmt.Sprintf("/block/%d", ch.block.Number), http.StatusOK),
+		specGet("/block/"+block, http.StatusOK),
+		specGet("/block/-1?range=2", http.StatusOK),
+		specGet(fmt.Sprintf("/block/%d?range=3&format=ndjson", ch.head.Number), http.StatusOK),
+		specGet(fmt.Sprintf("/block/%d?range=3&format=csv", ch.head.Number), http.StatusOK),
+		specGet("/block/abc", http.StatusBadRequest),
+
+		specGet("/transaction/"+tx, http.StatusOK),
+		specGet("/transaction/"+ch.contractTx.Hash.Hex()+"?decode=true", http.StatusOK),
+		specGet("/transaction/"+common.HexToHash("0xdeadbeef").Hex(), http.StatusNotFound),
+		specGet("/transaction/latest?limit=2", http.StatusOK),
+		specGet("/transaction/all/"+address+"?limit=2", http.StatusOK),
+		specGet("/transaction/from/"+address+"?order=asc", http.StatusOK),
+		specGet("/transaction/block/"+block, http.StatusOK),
+		specGet("/transaction/all/"+address+"?format=ndjson", http.StatusOK),
+		specGet("/transaction/all/"+address+"?format=csv", http.StatusOK),
+
+		specGet("/address/"+address, http.StatusOK),
+		specGet("/address/"+contract, http.StatusOK),
+		specGet("/stats", http.StatusOK),
+		specGet("/stats/"+producer, http.StatusOK),
+
+		specGet("/rich-list", http.StatusOK),
+		specGet("/rich-list?format=ndjson", http.StatusOK),
+
+		specPost("/ens", `{"address": "`+contract+`", "hash": "0x00000000000000000000000000000000000000000000000000000000000c0de0", "name": "token.ebk"}`, http.StatusOK),
+		specGet("/ens/"+address, http.StatusOK),
+
+		specGet("/delegates", http.StatusOK),
+		specGet("/delegates/0", http.StatusOK),
+
+		specGet("/abi/"+contract, http.StatusOK),
+		specGet("/abi/"+address, http.StatusNotFound),
+		withHeader(specPost("/abi/0xdddddddddddddddddddddddddddddddddddddddd", specContractABI, http.StatusCreated),
+			map[string]string{"Authorization": "Bearer " + specABIUploadToken}),
+		specPost("/contract/"+contract+"/call", `{"function": "totalSupply"}`, http.StatusOK),
+		specPost("/contract/"+contract+"/call", `{"function": "burn"}`, http.StatusBadRequest),
+		specGet("/contract/"+contract+"/storage?slot=0&type=uint", http.StatusOK),
+		specPost("/contract/"+contract+"/storage", `{"slot": 0, "type": "uint"}`, http.StatusOK),
+
+		specGet("/chain-info", http.StatusOK),
+
+		specGet("/search?q=1", http.StatusOK),
+		specGet("/search?q="+block, http.StatusOK),
+		specGet("/search?q="+tx, http.StatusOK),
+		specGet("/search?q="+address, http.StatusOK),
+		specGet("/search?q="+address[:6], http.StatusOK),
+		specGet("/search?q=alice.ebk", http.StatusOK),
+		specGet("/search", http.StatusBadRequest),
+
+		specGet(fmt.Sprintf("/charts/tx_count?interval=hour&from=%d&to=%d", ch.block.TimeStamp, ch.head.TimeStamp+3600), http.StatusOK),
+		specGet("/charts/bogus", http.StatusNotFound),
+
+		specGet("/api?module=account&action=txlist&address="+address, http.StatusOK),
+		specGet("/api?module=account&action=balance&address="+address, http.StatusOK),
+		specGet("/api?module=account&action=tokentx&address="+address, http.StatusOK),
+		specGet("/api?module=transaction&action=getstatus&txhash="+tx, http.StatusOK),
+		specGet("/api?module=stats&action=ethsupply", http.StatusOK),
+		specGet("/api?module=bogus", http.StatusOK),
+		{
+			method:      "POST",
+			uri:         "/api",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "module=account&action=balance&address=" + address,
+			status:      http.StatusOK,
+		},
+
+		specPost("/rpc", `{"jsonrpc": "2.0", "id": 1, "method": "eth_blockNumber", "params": []}`, http.StatusOK),
+		specPost("/rpc", `[{"jsonrpc": "2.0", "id": 1, "method": "eth_chainId"}, {"jsonrpc": "2.0", "id": 2, "method": "eth_bogus"}]`, http.StatusOK),
+
+		specGet("/graphql?query="+url.QueryEscape(graphql), http.StatusOK),
+		specPost("/graphql", `{"query": "`+strings.Replace(graphql, `"`, `\"`, -1)+`"}`, http.StatusOK),
+
+		// there is no broker to stream events from
+		specGet("/stream/ws", http.StatusServiceUnavailable),
+		specGet("/stream/sse", http.StatusServiceUnavailable),
+
+		withHeader(specPost("/webhooks", `{"url": "https://example.com/hook", "eventType": "transfer", "address": "`+address+`", "minValue": "1000"}`, http.StatusCreated), apiKey),
+		withHeader(specPost("/webhooks", `{"url": "https://example.com/hook", "eventType": "bogus", "address": "`+address+`"}`, http.StatusBadRequest), apiKey),
+		withHeader(specPost("/webhooks", `{"url": "http://127.0.0.1/hook", "eventType": "transfer", "address": "`+address+`"}`, http.StatusBadRequest), apiKey),
+		specPost("/webhooks", `{"url": "https://example.com/hook", "eventType": "transfer", "address": "`+address+`"}`, http.StatusUnauthorized),
+		withHeader(specGet("/webhooks/1", http.StatusOK), secret),
+		withHeader(specGet("/webhooks/1/deliveries", http.StatusOK), secret),
+		withHeader(specGet("/webhooks/99", http.StatusNotFound), secret),
+		withHeader(specCheck{method: "DELETE", uri: "/webhooks/1", status: http.StatusNoContent}, secret),
+	}
+}
+
+// runSpecCheck makes the request of c and checks the response against doc,
+// returning the operation it exercised
+func runSpecCheck(router *mux.Router, doc *openapi.Document, c specCheck) (string, error) {
+	req := httptest.NewRequest(c.method, c.uri, strings.NewReader(c.body))
+	if c.contentType != "" {
+		req.Header.Set("Content-Type", c.contentType)
+	}
+	for key, value := range c.header {
+		req.Header.Set(key, value)
+	}
+
+	var match mux.RouteMatch
+	if !router.Match(req, &match) || match.Route == nil {
+		return "", fmt.Errorf("no route")
+	}
+	template, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return "", err
+	}
+	path := specPath(template)
+	op := c.method + " " + path
+
+	// bad requests are made on purpose
+	if c.status < http.StatusBadRequest {
+		if err := doc.ValidateRequest(c.method, path, c.contentType, []byte(c.body)); err != nil {
+			return op, err
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != c.status {
+		return op, fmt.Errorf("status %d, want %d: %s", w.Code, c.status, strings.TrimSpace(w.Body.String()))
+	}
+
+	return op, doc.ValidateResponse(c.method, path, w.Code, w.Header().Get("Content-Type"), w.Body.Bytes())
+}
+
+// TestOpenAPI checks that every route is documented, and that the responses
+// of the explorer match the document. Update the document along with the
+// handlers, or a model's MarshalJSON, when it fails.
+func TestOpenAPI(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := setupSpecChain(t)
+	SetABIUploadToken(specABIUploadToken)
+	defer SetABIUploadToken("")
+
+	router := testRouter()
+
+	documented := make(map[string]bool)
+	for _, op := range doc.Operations() {
+		documented[op.Method+" "+op.Path] = true
+	}
+	routed := make(map[string]bool)
+	err = router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return fmt.Errorf("route %s has no methods", template)
+		}
+		for _, method := range methods {
+			op := method + " " + specPath(template)
+			routed[op] = true
+			if !documented[op] {
+				t.Errorf("%s is not documented", op)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	covered := make(map[string]bool)
+	for _, c := range specChecks(ch) {
+		op, err := runSpecCheck(router, doc, c)
+		if op != "" {
+			covered[op] = true
+		}
+		if err != nil {
+			t.Errorf("%s %s: %v", c.method, c.uri, err)
+		}
+	}
+
+	for op := range documented {
+		if !routed[op] {
+			t.Errorf("%s is documented but not routed", op)
+		} else if _, ok := specSkipped[op]; !ok && !covered[op] {
+			t.Errorf("%s is not checked", op)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package webapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/apikeys"
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// API keys are sent in the header. The query parameter, which Etherscan
+	// clients use, is deprecated and only read when the header is missing.
+	apiKeyHeader = "X-API-Key"
+	apiKeyParam  = "apikey"
+
+	rateLimitHeader     = "X-RateLimit-Limit"
+	rateRemainingHeader = "X-RateLimit-Remaining"
+)
+
+var (
+	// ipRateLimit is the requests per minute of each IP without a key, and
+	// keyRateLimit of each key without a limit of its own. None disables it.
+	ipRateLimit  = uint64(300)
+	keyRateLimit = uint64(1200)
+
+	// trustProxy takes the client IP from X-Forwarded-For, as set by the
+	// proxy in front of the explorer
+	trustProxy = false
+)
+
+// maxLimits maps the path templates of the routes to the largest limit
+// they are queried with. Larger limits are lowered to it.
+var maxLimits = map[string]uint64{
+	"/transaction/{ref:(?:latest)}": 100,
+	"/transaction/{ref}/{address}":  100,
+	"/rich-list":                    1000,
+}
+
+// rateLimitExempt are the routes that are neither limited nor need a key
+var rateLimitExempt = map[string]bool{
+	"/health":       true,
+	"/ready":        true,
+	"/sync-status":  true,
+	"/metrics":      true,
+	"/openapi.json": true,
+	"/docs":         true,
+}
+
+// apiKeyContextKey keeps the API key a request was authenticated with in
+// its context
+type apiKeyContextKey struct{}
+
+// SetRateLimits sets the requests per minute of each IP without a key and
+// of each key without a limit of its own
+func SetRateLimits(ip, key uint64) {
+	ipRateLimit, keyRateLimit = ip, key
+}
+
+// SetTrustProxy sets whether the client IP is taken from X-Forwarded-For
+func SetTrustProxy(trust bool) {
+	trustProxy = trust
+}
+
+// SetMaxLimits overrides the maximum limits of routes, given as path=limit,
+// e.g. /rich-list=500. A limit of zero lifts the maximum.
+func SetMaxLimits(overrides []string) error {
+	for _, override := range overrides {
+		i := strings.LastIndex(override, "=")
+		if i < 0 || !strings.HasPrefix(override, "/") {
+			return fmt.Errorf("invalid max limit %q, expected path=limit", override)
+		}
+
+		path := override[:i]
+		limit, err := strconv.ParseUint(override[i+1:], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid max limit %q: %v", override, err)
+		}
+
+		if limit == 0 {
+			delete(maxLimits, path)
+			continue
+		}
+		maxLimits[path] = limit
+	}
+	return nil
+}
+
+// routeTemplate returns the path template of the route of a request
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return ""
+}
+
+// clientIP returns the IP requests without a key are limited by
+func clientIP(r *http.Request) string {
+	if trustProxy {
+		// the proxy appends the address it was connected from
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			parts := strings.Split(forwarded, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestAPIKey returns the key of a request, removing it from the query so
+// that it doesn't end up in cache keys or logs
+func requestAPIKey(r *http.Request) string {
+	query := r.URL.Query()
+	key := query.Get(apiKeyParam)
+	if _, ok := query[apiKeyParam]; ok {
+		query.Del(apiKeyParam)
+		r.URL.RawQuery = query.Encode()
+	}
+
+	// the header takes precedence over the query
+	if header := r.Header.Get(apiKeyHeader); header != "" {
+		return header
+	}
+	return key
+}
+
+// authenticatedAPIKey returns the API key of a request, looking it up when
+// the request didn't go through RateLimitMiddleware. It returns
+// apikeys.ErrUnknownKey for requests without a valid key.
+func authenticatedAPIKey(r *http.Request, dbc db.Store) (*models.APIKey, error) {
+	if apiKey, ok := r.Context().Value(apiKeyContextKey{}).(*models.APIKey); ok {
+		return apiKey, nil
+	}
+
+	key := requestAPIKey(r)
+	if key == "" {
+		return nil, apikeys.ErrUnknownKey
+	}
+	return apikeys.Lookup(dbc, key)
+}
+
+// clampLimit lowers the limit of a request to the maximum of its route
+func clampLimit(r *http.Request, template string) {
+	max, ok := maxLimits[template]
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	limit, err := strconv.ParseUint(query.Get("limit"), 10, 64)
+	if err != nil || limit <= max {
+		return
+	}
+
+	query.Set("limit", strconv.FormatUint(max, 10))
+	r.URL.RawQuery = query.Encode()
+}
+
+// retryAfter sets the seconds to wait before retrying, rounded up
+func retryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int64((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
+// RateLimitMiddleware authenticates the API keys of requests and limits the
+// requests of each key, or of each IP for requests without one, with token
+// buckets that hold a minute of requests. Keys with a daily quota are
+// refused once they used it. The limits fail open when the cache fails.
+func RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template := routeTemplate(r)
+		if rateLimitExempt[template] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bucket := "ratelimit:ip:" + clientIP(r)
+		limit := ipRateLimit
+
+		var quota, keyID uint64
+		if key := requestAPIKey(r); key != "" {
+			dbc := db.GetClientContext(r.Context())
+			if dbc == nil {
+				requestLogger(r).Error("DBClient is not initialized")
+				http.Error(w, "error", http.StatusInternalServerError)
+				return
+			}
+
+			apiKey, err := apikeys.Lookup(dbc, key)
+			if err == apikeys.ErrUnknownKey {
+				http.Error(w, "invalid API key", http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				requestLogger(r).Error("Failed to look up API key", "err", err)
+				http.Error(w, "error", http.StatusInternalServerError)
+				return
+			}
+
+			bucket = fmt.Sprintf("ratelimit:key:%d", apiKey.ID)
+			limit = keyRateLimit
+			if apiKey.RateLimit > 0 {
+				limit = apiKey.RateLimit
+			}
+			quota, keyID = apiKey.DailyQuota, apiKey.ID
+
+			r = r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, apiKey))
+		}
+
+		if limit > 0 {
+			left, wait, err := redis.GetCache().TakeToken(bucket, limit, time.Minute)
+			if err != nil {
+				requestLogger(r).Error("Failed to take a rate limit token", "err", err)
+			} else {
+				w.Header().Set(rateLimitHeader, strconv.FormatUint(limit, 10))
+				w.Header().Set(rateRemainingHeader, strconv.FormatUint(left, 10))
+				if wait > 0 {
+					retryAfter(w, wait)
+					http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+					return
+				}
+			}
+		}
+
+		if keyID != 0 {
+			used, err := apikeys.RecordUsage(keyID)
+			if err != nil {
+				requestLogger(r).Error("Failed to record API key usage", "err", err)
+			} else if quota > 0 && used > quota {
+				retryAfter(w, apikeys.UntilTomorrow())
+				http.Error(w, "daily quota exceeded", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		clampLimit(r, template)
+
+		next.ServeHTTP(w, r)
+	})
+}
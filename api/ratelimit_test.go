@@ -0,0 +1,32 @@
+package webapi
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestAPIKey(t *testing.T) {
+	for _, c := range []struct {
+		uri, header string
+		key, query  string
+	}{
+		{"/blocks?limit=10", "", "", "limit=10"},
+		{"/blocks?apikey=query&limit=10", "", "query", "limit=10"},
+		{"/blocks?limit=10", "header", "header", "limit=10"},
+		// the key of the query is removed even when the header is used
+		{"/blocks?apikey=query&limit=10", "header", "header", "limit=10"},
+		{"/blocks?apikey=&limit=10", "", "", "limit=10"},
+	} {
+		r := httptest.NewRequest("GET", c.uri, nil)
+		if c.header != "" {
+			r.Header.Set(apiKeyHeader, c.header)
+		}
+
+		if key := requestAPIKey(r); key != c.key {
+			t.Errorf("%s with header %q: key = %q, want %q", c.uri, c.header, key, c.key)
+		}
+		if r.URL.RawQuery != c.query {
+			t.Errorf("%s with header %q: query = %q, want %q", c.uri, c.header, r.URL.RawQuery, c.query)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package webapi
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes sets up the route handlers of the explorer on router.
+// Every route has to be described in the OpenAPI document served at
+// /openapi.json, TestOpenAPI fails otherwise.
+func RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/health", HandleHealth).Methods("GET")
+	router.HandleFunc("/ready", HandleReady).Methods("GET")
+	router.HandleFunc("/sync-status", HandleSyncStatus).Methods("GET")
+	router.HandleFunc("/metrics", HandleMetrics).Methods("GET")
+
+	router.HandleFunc("/openapi.json", HandleOpenAPI).Methods("GET")
+	router.HandleFunc("/docs", HandleDocs).Methods("GET")
+
+	router.HandleFunc("/block/{param}", HandleBlock).Methods("GET")
+	router.HandleFunc("/transaction/{ref:(?:latest)}", HandleTxByAddress).Methods("GET")
+	router.HandleFunc("/transaction/{hash}", HandleTxByHash).Methods("GET")
+	router.HandleFunc("/transaction/{ref}/{address}", HandleTxByAddress).Methods("GET")
+
+	router.HandleFunc("/address/{address}", HandleAddress).Methods("GET")
+	router.HandleFunc("/stats", HandleStats).Methods("GET")
+	router.HandleFunc("/stats/{address}", HandleStats).Methods("GET")
+
+	router.HandleFunc("/rich-list", HandleRichList).Methods("GET")
+
+	router.HandleFunc("/ens", HandleAddReverseRegistrar).Methods("POST")
+	router.HandleFunc("/ens/{address}", HandleGetReverseRegistrar).Methods("GET")
+
+	router.HandleFunc("/delegates", HandleDelegates).Methods("GET")
+	router.HandleFunc("/delegates/{number}", HandleDelegates).Methods("GET")
+
+	router.HandleFunc("/abi/{address}", HandleABI).Methods("GET")
+	router.HandleFunc("/abi/{address}", HandleUploadABI).Methods("POST")
+	router.HandleFunc("/contract/{address}/call", HandleContractCall).Methods("POST")
+	router.HandleFunc("/contract/{address}/storage", HandleContractStorage).Methods("GET", "POST")
+
+	router.HandleFunc("/chain-info", HandleChainInfo).Methods("GET")
+
+	router.HandleFunc("/conversion-rate", HandleGetConversionRate).Methods("GET")
+
+	router.HandleFunc("/search", HandleSearch).Methods("GET")
+
+	router.HandleFunc("/charts/{metric}", HandleCharts).Methods("GET")
+
+	router.HandleFunc("/api", HandleEtherscan).Methods("GET", "POST")
+
+	router.HandleFunc("/rpc", HandleRPC).Methods("POST")
+
+	router.HandleFunc("/graphql", HandleGraphQL).Methods("GET", "POST")
+
+	router.HandleFunc("/stream/ws", HandleStreamWS).Methods("GET")
+	router.HandleFunc("/stream/sse", HandleStreamSSE).Methods("GET")
+
+	router.HandleFunc("/webhooks", HandleAddWebhook).Methods("POST")
+	router.HandleFunc("/webhooks/{id:[0-9]+}", HandleWebhook).Methods("GET", "DELETE")
+	router.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", HandleWebhookDeliveries).Methods("GET")
+}
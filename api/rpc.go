@@ -0,0 +1,84 @@
+package webapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/jsonrpc"
+)
+
+const (
+	// rpcMaxBodySize caps the size of a JSON-RPC request body
+	rpcMaxBodySize = 1 << 20
+
+	// rpcMaxBatchSize caps the number of requests in a batch
+	rpcMaxBatchSize = 100
+)
+
+var rpcGateway = jsonrpc.NewGateway(false)
+
+// SetRPCGateway sets the gateway that answers the JSON-RPC endpoint
+func SetRPCGateway(gateway *jsonrpc.Gateway) {
+	rpcGateway = gateway
+}
+
+// HandleRPC answers a JSON-RPC request or a batch of them. Like with
+// a node, errors are reported in the response body with a 200 status.
+func HandleRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, rpcMaxBodySize))
+	if err != nil {
+		requestLogger(r).Debug("Failed to read JSON-RPC request", "err", err)
+		http.Error(w, "error", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var response interface{}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			response = jsonrpc.ErrorResponse(nil, jsonrpc.CODE_PARSE_ERROR, "parse error")
+		} else if len(batch) == 0 {
+			response = jsonrpc.ErrorResponse(nil, jsonrpc.CODE_INVALID_REQUEST, "empty batch")
+		} else if len(batch) > rpcMaxBatchSize {
+			response = jsonrpc.ErrorResponse(nil, jsonrpc.CODE_INVALID_REQUEST, "batch too large")
+		} else {
+			responses := make([]jsonrpc.Response, len(batch))
+			for i, raw := range batch {
+				var req jsonrpc.Request
+				if err := json.Unmarshal(raw, &req); err != nil {
+					responses[i] = jsonrpc.ErrorResponse(nil, jsonrpc.CODE_INVALID_REQUEST, "invalid request")
+					continue
+				}
+				responses[i] = rpcGateway.Handle(r.Context(), req)
+			}
+			response = responses
+		}
+	} else {
+		var req jsonrpc.Request
+		if err := json.Unmarshal(body, &req); err != nil {
+			response = jsonrpc.ErrorResponse(nil, jsonrpc.CODE_PARSE_ERROR, "parse error")
+		} else {
+			response = rpcGateway.Handle(r.Context(), req)
+		}
+	}
+
+	res, err := json.Marshal(response)
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(res)
+}
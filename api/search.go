@@ -0,0 +1,175 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+const (
+	searchDefaultLimit = 10
+	searchMaxLimit     = 50
+)
+
+var (
+	hexRegexp = regexp.MustCompile(`^(0x)?[0-9a-f]+$`)
+)
+
+// classifySearchQuery finds out what the user typed, the query is expected lowercase
+func classifySearchQuery(q string) string {
+	if _, err := strconv.ParseUint(q, 10, 64); err == nil {
+		return models.SEARCH_QUERY_BLOCK_NUMBER
+	}
+
+	if hexRegexp.MatchString(q) {
+		digits := strings.TrimPrefix(q, "0x")
+		switch {
+		case len(digits) == 2*common.HashLength:
+			return models.SEARCH_QUERY_HASH
+		case len(digits) == 2*common.AddressLength:
+			return models.SEARCH_QUERY_ADDRESS
+		case len(digits) < 2*common.AddressLength && strings.HasPrefix(q, "0x"):
+			return models.SEARCH_QUERY_ADDRESS_PREFIX
+		}
+	}
+
+	return models.SEARCH_QUERY_NAME
+}
+
+// HandleSearch classifies the query and looks it up across blocks,
+// transactions, addresses and ENS names
+func HandleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		requestLogger(r).Error("DBClient is not initialized")
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	if q == "" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	limit := uint64(searchDefaultLimit)
+	if limitString := r.URL.Query().Get("limit"); limitString != "" {
+		var err error
+		limit, err = strconv.ParseUint(limitString, 10, 32)
+		if err != nil {
+			requestLogger(r).Debug("Failed to parse limit", "err", err)
+			http.Error(w, "error", http.StatusBadRequest)
+			return
+		}
+	}
+	if limit == 0 || limit > searchMaxLimit {
+		limit = searchMaxLimit
+	}
+
+	kind := classifySearchQuery(q)
+
+	requestLogger(r).Debug("Request search", "q", q, "kind", kind)
+
+	results := make([]models.SearchResult, 0)
+
+	switch kind {
+	case models.SEARCH_QUERY_BLOCK_NUMBER:
+		number, _ := strconv.ParseUint(q, 10, 64)
+		block, err := dbc.GetBlockByID(number)
+		if err != nil {
+			requestLogger(r).Error("Request failed", "err", err)
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+		if block.Hash != (common.Hash{}) {
+			results = append(results, models.SearchResult{Type: models.SEARCH_RESULT_BLOCK, Match: models.SEARCH_MATCH_EXACT, Data: block})
+		}
+
+	case models.SEARCH_QUERY_HASH:
+		hash := common.HexToHash(q).Hex()
+
+		// the hash can be either a block or a transaction
+		if block, err := dbc.GetBlockByHash(hash); err == nil && block != nil {
+			results = append(results, models.SearchResult{Type: models.SEARCH_RESULT_BLOCK, Match: models.SEARCH_MATCH_EXACT, Data: block})
+		}
+
+		txf, err := dbc.GetTransactionByHash(hash)
+		if err != nil {
+			requestLogger(r).Error("Request failed", "err", err)
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+		if txf.Tx != nil {
+			results = append(results, models.SearchResult{Type: models.SEARCH_RESULT_TRANSACTION, Match: models.SEARCH_MATCH_EXACT, Data: txf})
+		}
+
+	case models.SEARCH_QUERY_ADDRESS:
+		address := common.HexToAddress(q)
+		results = append(results, addressSearchResult(dbc, address, models.SEARCH_MATCH_EXACT))
+
+	case models.SEARCH_QUERY_ADDRESS_PREFIX:
+		addresses, err := dbc.SearchAddresses(strings.TrimPrefix(q, "0x"), limit)
+		if err != nil {
+			requestLogger(r).Error("Request failed", "err", err)
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+		for _, address := range addresses {
+			results = append(results, addressSearchResult(dbc, address, models.SEARCH_MATCH_PREFIX))
+		}
+
+	case models.SEARCH_QUERY_NAME:
+		entries, err := dbc.SearchEnsNames(q, limit)
+		if err != nil {
+			requestLogger(r).Error("Request failed", "err", err)
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+		for _, ens := range entries {
+			match := models.SEARCH_MATCH_PREFIX
+			if ens.Name == q {
+				match = models.SEARCH_MATCH_EXACT
+			}
+			results = append(results, models.SearchResult{Type: models.SEARCH_RESULT_ENS, Match: match, Data: ens})
+		}
+
+		// exact matches first
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].Match == models.SEARCH_MATCH_EXACT && results[j].Match != models.SEARCH_MATCH_EXACT
+		})
+
+		// Tokens are not indexed yet, when they are they should be searched here too
+	}
+
+	res, err := json.Marshal(models.SearchResponse{Query: q, Kind: kind, Results: results})
+
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+	} else {
+		w.Write(res)
+	}
+}
+
+func addressSearchResult(dbc db.Store, address common.Address, match string) models.SearchResult {
+	result := models.AddressSearchResult{Address: address}
+	if addressEns, err := dbc.GetEnsName(address.Hex()); err == nil {
+		result.AddressEns = &addressEns
+	}
+	return models.SearchResult{Type: models.SEARCH_RESULT_ADDRESS, Match: match, Data: result}
+}
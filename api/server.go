@@ -0,0 +1,45 @@
+package webapi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// connContextKey keeps the connection a request came on in its context
+type connContextKey struct{}
+
+var (
+	// streamsClosing is closed when the server shuts down, ending the
+	// streams, which would otherwise hold the shutdown until it times out
+	streamsClosing   = make(chan struct{})
+	closeStreamsOnce sync.Once
+)
+
+// ConnContext keeps the connection of the requests in their context, for
+// the long running routes to lift the timeouts. It is meant to be the
+// ConnContext of the server.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, c)
+}
+
+// liftTimeouts lets the response of a long running route, a stream or an
+// export, take longer than the read and write timeouts of the server. The
+// server keeps reading the connection while the handler runs, to notice
+// clients going away, and would cancel the request once the read timeout
+// is reached.
+func liftTimeouts(r *http.Request) {
+	if c, ok := r.Context().Value(connContextKey{}).(net.Conn); ok {
+		c.SetDeadline(time.Time{})
+	}
+}
+
+// CloseStreams ends the streams of the WebSocket and SSE routes, for the
+// server to shut down
+func CloseStreams() {
+	closeStreamsOnce.Do(func() {
+		close(streamsClosing)
+	})
+}
@@ -0,0 +1,51 @@
+package webapi
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/events"
+)
+
+func TestStreamOutlivesReadTimeout(t *testing.T) {
+	const readTimeout = 100 * time.Millisecond
+
+	src := make(chan []byte)
+	defer close(src)
+	broker := events.NewBroker()
+	go broker.Run(src)
+	SetStreamBroker(broker)
+	defer SetStreamBroker(nil)
+
+	server := httptest.NewUnstartedServer(testRouter())
+	server.Config.ReadTimeout = readTimeout
+	server.Config.ConnContext = ConnContext
+	server.Start()
+	defer server.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	res, err := client.Get(server.URL + "/stream/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	// the event is published once the read timeout has passed
+	time.Sleep(3 * readTimeout)
+	src <- []byte(`{"type":"` + events.TYPE_BLOCK + `"}`)
+
+	body := bufio.NewReader(res.Body)
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			t.Fatalf("the stream ended before the event: %v", err)
+		}
+		if strings.TrimSpace(line) == "event: "+events.TYPE_BLOCK {
+			return
+		}
+	}
+}
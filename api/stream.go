@@ -0,0 +1,199 @@
+package webapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/events"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	streamPingInterval = 30 * time.Second
+	streamWriteTimeout = 10 * time.Second
+)
+
+var (
+	streamBroker *events.Broker
+
+	upgrader = websocket.Upgrader{
+		// the API is public and served with permissive CORS already
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+)
+
+// SetStreamBroker sets the broker the streaming endpoints subscribe to
+func SetStreamBroker(broker *events.Broker) {
+	streamBroker = broker
+}
+
+// parseStreamFilter reads the `types`, `producer` and `address` query params
+func parseStreamFilter(r *http.Request) (events.Filter, error) {
+	var filter events.Filter
+
+	if typesString := r.URL.Query().Get("types"); typesString != "" {
+		filter.Types = make(map[string]bool)
+		for _, t := range strings.Split(typesString, ",") {
+			switch t {
+			case events.TYPE_BLOCK, events.TYPE_TRANSACTION, events.TYPE_REORG:
+				filter.Types[t] = true
+			default:
+				return filter, fmt.Errorf("unknown event type %q", t)
+			}
+		}
+	}
+
+	if producer := r.URL.Query().Get("producer"); producer != "" {
+		if !common.IsHexAddress(producer) {
+			return filter, fmt.Errorf("invalid producer address %q", producer)
+		}
+		address := common.HexToAddress(producer)
+		filter.Producer = &address
+	}
+
+	if addressString := r.URL.Query().Get("address"); addressString != "" {
+		if !common.IsHexAddress(addressString) {
+			return filter, fmt.Errorf("invalid address %q", addressString)
+		}
+		address := common.HexToAddress(addressString)
+		filter.Address = &address
+	}
+
+	return filter, nil
+}
+
+// HandleStreamWS pushes new blocks, transactions and reorg notices over a WebSocket
+func HandleStreamWS(w http.ResponseWriter, r *http.Request) {
+	if streamBroker == nil {
+		requestLogger(r).Error("Stream broker is not initialized")
+		http.Error(w, "error", http.StatusServiceUnavailable)
+		return
+	}
+
+	filter, err := parseStreamFilter(r)
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already replied to the client
+		requestLogger(r).Error("Request failed", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := streamBroker.Subscribe(filter)
+	defer streamBroker.Unsubscribe(sub)
+
+	// clients are not expected to send anything, but reading is needed
+	// to process control frames and to notice when they go away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(streamWriteTimeout))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, msg.Data); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+
+		case <-closed:
+			return
+
+		case <-streamsClosing:
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down"), time.Now().Add(streamWriteTimeout))
+			return
+		}
+	}
+}
+
+// HandleStreamSSE pushes new blocks, transactions and reorg notices as Server-Sent Events
+func HandleStreamSSE(w http.ResponseWriter, r *http.Request) {
+	if streamBroker == nil {
+		requestLogger(r).Error("Stream broker is not initialized")
+		http.Error(w, "error", http.StatusServiceUnavailable)
+		return
+	}
+
+	filter, err := parseStreamFilter(r)
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		requestLogger(r).Error("Streaming is not supported by the connection")
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// stop buffering proxies like nginx from holding the events back
+	w.Header().Set("X-Accel-Buffering", "no")
+	liftTimeouts(r)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sub := streamBroker.Subscribe(filter)
+	defer streamBroker.Unsubscribe(sub)
+
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, msg.Data); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-ticker.C:
+			// comment lines keep idle connections open through proxies
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+
+		case <-streamsClosing:
+			return
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package webapi
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/apikeys"
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/webhooks"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// webhookSecretHeader authenticates the management of a webhook with
+	// the secret returned when it was created
+	webhookSecretHeader = "X-Webhook-Secret"
+
+	webhookDeliveriesDefaultLimit = 50
+	webhookDeliveriesMaxLimit     = 500
+)
+
+// maxWebhooks is the webhooks each API key can create, 0 for unlimited
+var maxWebhooks = uint64(20)
+
+// SetMaxWebhooks sets the webhooks each API key can create, 0 for unlimited
+func SetMaxWebhooks(max uint64) {
+	maxWebhooks = max
+}
+
+// webhookRequest is the body of a webhook creation, MinValue is in wei
+type webhookRequest struct {
+	URL       string `json:"url"`
+	EventType string `json:"eventType"`
+	Address   string `json:"address"`
+	Direction string `json:"direction"`
+	MinValue  string `json:"minValue"`
+}
+
+func (req webhookRequest) toWebhook() (*models.Webhook, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("url must be an absolute http(s) URL")
+	}
+
+	// names are checked once resolved, when delivering
+	host := u.Hostname()
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && !webhooks.PublicIP(ip)) {
+		return nil, errors.New("url must point to a public address")
+	}
+
+	switch req.EventType {
+	case models.WEBHOOK_EVENT_TRANSFER, models.WEBHOOK_EVENT_MISSED_SLOT:
+	default:
+		return nil, errors.New("unknown eventType")
+	}
+
+	if !common.IsHexAddress(req.Address) {
+		return nil, errors.New("invalid address")
+	}
+
+	direction := req.Direction
+	switch direction {
+	case "":
+		direction = models.WEBHOOK_DIRECTION_ANY
+	case models.WEBHOOK_DIRECTION_IN, models.WEBHOOK_DIRECTION_OUT, models.WEBHOOK_DIRECTION_ANY:
+	default:
+		return nil, errors.New("direction must be in, out or any")
+	}
+
+	minValue := new(big.Int)
+	if req.MinValue != "" {
+		if _, ok := minValue.SetString(req.MinValue, 10); !ok || minValue.Sign() < 0 {
+			return nil, errors.New("minValue must be a non negative amount in wei")
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return &models.Webhook{
+		URL:       u.String(),
+		Secret:    hex.EncodeToString(secret),
+		EventType: req.EventType,
+		Address:   common.HexToAddress(req.Address),
+		Direction: direction,
+		MinValue:  minValue,
+		CreatedAt: uint64(time.Now().Unix()),
+	}, nil
+}
+
+// HandleAddWebhook creates a webhook subscription for the API key of the
+// request. The response is the only time the secret, used for signing the
+// deliveries and for managing the webhook, is returned.
+func HandleAddWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		requestLogger(r).Error("DBClient is not initialized")
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	apiKey, err := authenticatedAPIKey(r, dbc)
+	if err == apikeys.ErrUnknownKey {
+		http.Error(w, "an API key is required", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		requestLogger(r).Error("Failed to look up API key", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := req.toWebhook()
+	if err != nil {
+		requestLogger(r).Debug("Bad request", "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	webhook.APIKeyID = apiKey.ID
+
+	if maxWebhooks > 0 {
+		count, err := dbc.CountWebhooks(apiKey.ID)
+		if err != nil {
+			requestLogger(r).Error("Request failed", "err", err)
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+		if count >= maxWebhooks {
+			http.Error(w, "too many webhooks", http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := dbc.InsertWebhook(webhook); err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	res, err := json.Marshal(struct {
+		Webhook *models.Webhook `json:"webhook"`
+		Secret  string          `json:"secret"`
+	}{webhook, webhook.Secret})
+
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(res)
+}
+
+// authorizedWebhook returns the webhook of the request if the request
+// carries its secret, otherwise it replies with an error and returns nil
+func authorizedWebhook(w http.ResponseWriter, r *http.Request, dbc db.Store) *models.Webhook {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "error", http.StatusBadRequest)
+		return nil
+	}
+
+	webhook, err := dbc.GetWebhook(id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "not found", http.StatusNotFound)
+		return nil
+	} else if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return nil
+	}
+
+	secret := r.Header.Get(webhookSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(webhook.Secret)) != 1 {
+		// same reply as a missing webhook, so ids can't be probed
+		http.Error(w, "not found", http.StatusNotFound)
+		return nil
+	}
+
+	return webhook
+}
+
+// HandleWebhook returns or deletes a webhook
+func HandleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "DELETE" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		requestLogger(r).Error("DBClient is not initialized")
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	webhook := authorizedWebhook(w, r, dbc)
+	if webhook == nil {
+		return
+	}
+
+	if r.Method == "DELETE" {
+		if err := dbc.DeleteWebhook(webhook.ID); err != nil {
+			requestLogger(r).Error("Request failed", "err", err)
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	res, err := json.Marshal(webhook)
+
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	}
+}
+
+// HandleWebhookDeliveries returns the delivery log of a webhook, newest first
+func HandleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "error", http.StatusBadRequest)
+		return
+	}
+
+	dbc := db.GetClientContext(r.Context())
+	if dbc == nil {
+		requestLogger(r).Error("DBClient is not initialized")
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	webhook := authorizedWebhook(w, r, dbc)
+	if webhook == nil {
+		return
+	}
+
+	limit := uint64(webhookDeliveriesDefaultLimit)
+	if limitString := r.URL.Query().Get("limit"); limitString != "" {
+		var err error
+		limit, err = strconv.ParseUint(limitString, 10, 32)
+		if err != nil {
+			requestLogger(r).Debug("Failed to parse limit", "err", err)
+			http.Error(w, "error", http.StatusBadRequest)
+			return
+		}
+	}
+	if limit == 0 || limit > webhookDeliveriesMaxLimit {
+		limit = webhookDeliveriesMaxLimit
+	}
+
+	deliveries, err := dbc.GetWebhookDeliveries(webhook.ID, limit)
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+
+	res, err := json.Marshal(deliveries)
+
+	if err != nil {
+		requestLogger(r).Error("Request failed", "err", err)
+		http.Error(w, "error", http.StatusInternalServerError)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	}
+}
@@ -0,0 +1,62 @@
+package webapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/apikeys"
+)
+
+// addWebhook creates a webhook for url with key, which may be empty
+func addWebhook(key, url string) *httptest.ResponseRecorder {
+	body := `{"url": "` + url + `", "eventType": "transfer", "address": "` + testAlice.Hex() + `"}`
+	req := httptest.NewRequest("POST", "/webhooks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(apiKeyHeader, key)
+	}
+
+	w := httptest.NewRecorder()
+	testRouter().ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleAddWebhook(t *testing.T) {
+	_, store := setupTestChain(t)
+
+	key, apiKey, err := apikeys.Issue(store, "test", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer SetMaxWebhooks(maxWebhooks)
+	SetMaxWebhooks(2)
+
+	if w := addWebhook("", "https://example.com/hook"); w.Code != http.StatusUnauthorized {
+		t.Errorf("without an API key: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := addWebhook("bogus", "https://example.com/hook"); w.Code != http.StatusUnauthorized {
+		t.Errorf("with an unknown API key: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	for _, url := range []string{"http://127.0.0.1/hook", "http://localhost:8080/hook", "http://[::1]/hook", "http://169.254.169.254/latest", "http://10.0.0.1/hook"} {
+		if w := addWebhook(key, url); w.Code != http.StatusBadRequest {
+			t.Errorf("webhook to %s: status %d, want %d", url, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := addWebhook(key, "https://example.com/hook"); w.Code != http.StatusCreated {
+			t.Fatalf("webhook %d: status %d, want %d: %s", i, w.Code, http.StatusCreated, w.Body.String())
+		}
+	}
+	if w := addWebhook(key, "https://example.com/hook"); w.Code != http.StatusForbidden {
+		t.Errorf("webhook over the maximum: status %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	if count, err := store.CountWebhooks(apiKey.ID); err != nil || count != 2 {
+		t.Errorf("CountWebhooks() = %d, %v, want 2", count, err)
+	}
+}
@@ -0,0 +1,155 @@
+// Package apikeys issues the keys clients identify with to the API, and
+// counts the requests made with them.
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+)
+
+const (
+	// keyPrefix starts every key, so that leaked keys are easy to spot
+	keyPrefix = "ebk_"
+
+	// keyDisplayLength is how much of a key is kept to tell keys apart
+	keyDisplayLength = len(keyPrefix) + 8
+
+	// lookupCacheTTL is how long lookups are cached, and so how long a
+	// revoked key may still be accepted by the explorers
+	lookupCacheTTL = time.Minute
+
+	// UsageDays is how many days of usage are kept
+	UsageDays = 7
+
+	dayFormat = "2006-01-02"
+)
+
+// ErrUnknownKey is returned for keys that were never issued or are revoked
+var ErrUnknownKey = errors.New("unknown API key")
+
+// Hash returns the hash a key is stored as
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue creates a key, returning it along with the stored APIKey. The key
+// can't be recovered later.
+func Issue(dbc db.Store, name string, rateLimit, dailyQuota uint64) (string, *models.APIKey, error) {
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", nil, err
+	}
+	key := keyPrefix + hex.EncodeToString(random)
+
+	apiKey := &models.APIKey{
+		Name:       name,
+		Hash:       Hash(key),
+		Prefix:     key[:keyDisplayLength],
+		RateLimit:  rateLimit,
+		DailyQuota: dailyQuota,
+		CreatedAt:  uint64(time.Now().Unix()),
+	}
+
+	if err := dbc.InsertAPIKey(apiKey); err != nil {
+		return "", nil, err
+	}
+	return key, apiKey, nil
+}
+
+func lookupCacheKey(hash string) string {
+	return "apikey:" + hash
+}
+
+// Lookup returns the APIKey of a key, or ErrUnknownKey. Lookups, unknown
+// keys included, are cached for lookupCacheTTL.
+func Lookup(dbc db.Store, key string) (*models.APIKey, error) {
+	hash := Hash(key)
+	cacheKey := lookupCacheKey(hash)
+
+	if data, ok := redis.GetCache().Get(cacheKey); ok {
+		var apiKey *models.APIKey
+		if err := json.Unmarshal(data, &apiKey); err == nil {
+			if apiKey == nil {
+				return nil, ErrUnknownKey
+			}
+			apiKey.Hash = hash
+			return apiKey, nil
+		}
+	}
+
+	apiKey, err := dbc.GetAPIKeyByHash(hash)
+	if err == sql.ErrNoRows || (err == nil && apiKey.Revoked()) {
+		apiKey = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(apiKey); err == nil {
+		redis.GetCache().Set(cacheKey, data, lookupCacheTTL)
+	}
+
+	if apiKey == nil {
+		return nil, ErrUnknownKey
+	}
+	return apiKey, nil
+}
+
+// Revoke revokes a key and drops its cached lookup, although explorers
+// caching in memory only notice once their lookup expires
+func Revoke(dbc db.Store, id uint64) error {
+	apiKey, err := dbc.GetAPIKey(id)
+	if err != nil {
+		return err
+	}
+
+	if err := dbc.RevokeAPIKey(id, uint64(time.Now().Unix())); err != nil {
+		return err
+	}
+
+	return redis.GetCache().Delete(lookupCacheKey(apiKey.Hash))
+}
+
+func usageCacheKey(id uint64, day time.Time) string {
+	return fmt.Sprintf("apikey:usage:%d:%s", id, day.UTC().Format(dayFormat))
+}
+
+// RecordUsage counts a request made with a key and returns the requests
+// made with it today, in UTC
+func RecordUsage(id uint64) (uint64, error) {
+	return redis.GetCache().Incr(usageCacheKey(id, time.Now()), UsageDays*24*time.Hour)
+}
+
+// Usage returns the requests made with a key in the last days, today first
+func Usage(id uint64, days int) []models.APIKeyUsage {
+	usage := make([]models.APIKeyUsage, days)
+
+	day := time.Now().UTC()
+	for i := range usage {
+		usage[i].Day = day.Format(dayFormat)
+		if data, ok := redis.GetCache().Get(usageCacheKey(id, day)); ok {
+			usage[i].Requests, _ = strconv.ParseUint(string(data), 10, 64)
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return usage
+}
+
+// UntilTomorrow returns the time left until the daily quotas reset
+func UntilTomorrow() time.Duration {
+	now := time.Now().UTC()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return tomorrow.Sub(now)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/apikeys"
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/urfave/cli"
+)
+
+// initAPIKeys connects to the db, and to redis where the explorers keep the
+// usage of the keys and their cached lookups
+func initAPIKeys(c *cli.Context) db.Store {
+	if err := db.InitFromCli(c); err != nil {
+		logger.Crit("Failed to load db client", "err", err)
+	}
+
+	if err := redis.InitFromCli(c); err != nil {
+		logger.Warn("Failed to connect to redis, usage is not available", "err", err)
+	} else {
+		redis.SetCache(redis.NewRedisCache())
+	}
+
+	return db.GetClient()
+}
+
+func apiKeyID(c *cli.Context) (uint64, error) {
+	id, err := strconv.ParseUint(c.Args().First(), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected the id of an API key")
+	}
+	return id, nil
+}
+
+func formatTimestamp(timestamp uint64) string {
+	if timestamp == 0 {
+		return "-"
+	}
+	return time.Unix(int64(timestamp), 0).UTC().Format("2006-01-02 15:04:05 MST")
+}
+
+func formatRate(key models.APIKey) string {
+	if key.RateLimit == 0 {
+		return "default"
+	}
+	return fmt.Sprintf("%d/min", key.RateLimit)
+}
+
+func formatQuota(key models.APIKey) string {
+	if key.DailyQuota == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d/day", key.DailyQuota)
+}
+
+func doIssueAPIKey(c *cli.Context) error {
+	dbc := initAPIKeys(c)
+
+	name := c.String("name")
+	if name == "" {
+		return fmt.Errorf("the name of the key is required")
+	}
+
+	key, apiKey, err := apikeys.Issue(dbc, name, c.Uint64("ratelimit"), c.Uint64("quota"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Issued API key %d for %s, rate %s, quota %s\n", apiKey.ID, apiKey.Name, formatRate(*apiKey), formatQuota(*apiKey))
+	fmt.Printf("\n%s\n\nThe key is not stored and can't be shown again.\n", key)
+	return nil
+}
+
+func doRevokeAPIKey(c *cli.Context) error {
+	id, err := apiKeyID(c)
+	if err != nil {
+		return err
+	}
+
+	dbc := initAPIKeys(c)
+	if err := apikeys.Revoke(dbc, id); err == sql.ErrNoRows {
+		return fmt.Errorf("API key %d not found", id)
+	} else if err != nil {
+		return err
+	}
+
+	fmt.Printf("Revoked API key %d\n", id)
+	return nil
+}
+
+func doListAPIKeys(c *cli.Context) error {
+	dbc := initAPIKeys(c)
+
+	keys, err := dbc.GetAPIKeys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		status := "active"
+		if key.Revoked() {
+			status = "revoked"
+		}
+		today := apikeys.Usage(key.ID, 1)[0].Requests
+		fmt.Printf("%4d  %-12s  %-20s %-8s %-10s %-12s %8d today\n", key.ID, key.Prefix, key.Name, status,
+			formatRate(key), formatQuota(key), today)
+	}
+
+	fmt.Printf("\n%d keys\n", len(keys))
+	return nil
+}
+
+func doShowAPIKey(c *cli.Context) error {
+	id, err := apiKeyID(c)
+	if err != nil {
+		return err
+	}
+
+	dbc := initAPIKeys(c)
+	key, err := dbc.GetAPIKey(id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("API key %d not found", id)
+	} else if err != nil {
+		return err
+	}
+
+	fmt.Printf("ID:       %d\n", key.ID)
+	fmt.Printf("Name:     %s\n", key.Name)
+	fmt.Printf("Key:      %s...\n", key.Prefix)
+	fmt.Printf("Rate:     %s\n", formatRate(*key))
+	fmt.Printf("Quota:    %s\n", formatQuota(*key))
+	fmt.Printf("Created:  %s\n", formatTimestamp(key.CreatedAt))
+	fmt.Printf("Revoked:  %s\n", formatTimestamp(key.RevokedAt))
+
+	fmt.Printf("\nRequests per day (UTC):\n")
+	for _, usage := range apikeys.Usage(key.ID, apikeys.UsageDays) {
+		fmt.Printf("  %s  %8d\n", usage.Day, usage.Requests)
+	}
+	return nil
+}
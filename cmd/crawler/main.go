@@ -3,7 +3,6 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"math/big"
 	"os"
 	"os/user"
@@ -13,10 +12,13 @@ import (
 	"time"
 
 	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/events"
 	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
 	ipcModule "github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
 	"github.com/ebakus/ebakus-block-explorer-backend/models"
 	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+	"github.com/ebakus/ebakus-block-explorer-backend/webhooks"
 
 	"github.com/ebakus/go-ebakus/common"
 
@@ -29,7 +31,8 @@ import (
 const maxRichList = 1000
 const maxAccountsPerRun = 1000000
 const maxBlocksPerRun = 500000
-const rich_list_last_block = "rich_list_last_block"
+const rich_list_last_block = models.GLOBAL_RICH_LIST_LAST_BLOCK
+const ens_last_sync = models.GLOBAL_ENS_LAST_SYNC
 
 var (
 	valueDecimalPoints = int64(4)
@@ -49,40 +52,43 @@ func doRichlist(c *cli.Context) error {
 	}
 	defer lock.Unlock()
 
+	defer startMetrics(c)()
+	stime := time.Now()
+
 	ipcFile := expandHome(c.String("ipc"))
-	ipc, err := ipcModule.NewIPCInterface(ipcFile)
+	ipc, err := ipcModule.Dial(ipcFile)
 	if err != nil {
-		log.Fatal("Failed to connect to ebakus", err)
+		logger.Crit("Failed to connect to ebakus", "err", err)
 	}
 
 	err = db.InitFromCli(c)
 	if err != nil {
-		log.Fatal("Failed to load db client")
+		logger.Crit("Failed to load db client", "err", err)
 	}
 	db := db.GetClient()
 
 	lastBlock, err := ipc.GetBlockNumber()
 	if err != nil {
-		log.Fatal("Failed to get last block number")
+		logger.Crit("Failed to get last block number", "err", err)
 	}
 
 	firstBlock, err := db.GetGlobalInt(rich_list_last_block)
 	if err != nil {
-		log.Fatal("Failed to get first block number")
+		logger.Crit("Failed to get first block number", "err", err)
 	}
 
 	if lastBlock-firstBlock > maxBlocksPerRun {
 		lastBlock = firstBlock + maxBlocksPerRun
 	}
 
-	log.Printf("Going to process blocks from %d to %d", firstBlock, lastBlock)
+	logger.Info("Going to process blocks", "from", firstBlock, "to", lastBlock)
 
 	accounts := make(map[common.Address]uint64)
 
 	i := firstBlock
 	for ; i < lastBlock; i++ {
 		if i%50000 == 0 {
-			log.Println("Fetching block: ", i)
+			logger.Info("Fetching block", "number", i)
 		}
 
 		block, err := db.GetBlockByID(i)
@@ -92,7 +98,7 @@ func doRichlist(c *cli.Context) error {
 
 		blockNumber := uint64(block.Number)
 
-		txs, err := db.GetTransactionsByAddress(block.Hash.Hex(), models.ADDRESS_BLOCKHASH, 0, 0xffff, "")
+		txs, err := db.GetTransactionsByAddress(block.Hash.Hex(), models.ADDRESS_BLOCKHASH, nil, 0xffff, "asc")
 		if err != nil {
 			break
 		}
@@ -108,7 +114,7 @@ func doRichlist(c *cli.Context) error {
 
 		// log.Println("Max accounts reached", len(accounts))
 		if len(accounts) > maxAccountsPerRun {
-			log.Println("Max accounts reached")
+			logger.Warn("Max accounts reached", "accounts", len(accounts))
 			break
 		}
 	}
@@ -120,7 +126,7 @@ func doRichlist(c *cli.Context) error {
 	// 	log.Println(err)
 	// }
 
-	log.Println("Total accounts touched: ", len(accounts))
+	logger.Info("Total accounts touched", "accounts", len(accounts))
 
 	// addressToBalance := make(map[common.Address]*models.Balance)
 
@@ -135,18 +141,18 @@ func doRichlist(c *cli.Context) error {
 	for address, bn := range accounts {
 		i++
 		if i%50000 == 0 {
-			log.Println("Updating balance: ", i, " of ", len(accounts))
+			logger.Info("Updating balances", "done", i, "accounts", len(accounts))
 		}
 		// balObj := addressToBalance[address]
 
 		bigBalance, err := ipc.GetAddressBalance(address)
 		if err != nil {
-			log.Println("Retrieve balance failed:", address, err)
+			logger.Error("Failed to get balance", "address", address, "err", err)
 			continue
 		}
 		staked, err := ipc.GetAddressStaked(address)
 		if err != nil {
-			log.Println("Retrieve stake failed:", address, err)
+			logger.Error("Failed to get stake", "address", address, "err", err)
 			continue
 		}
 
@@ -167,9 +173,9 @@ func doRichlist(c *cli.Context) error {
 		db.InsertBalance(address, totalBalance, bn)
 	}
 
-	balances, err := db.GetTopBalances(maxRichList, 0)
+	balances, err := db.GetTopBalances(nil, maxRichList)
 	if err != nil {
-		log.Println(err)
+		logger.Error("Failed to get the top balances", "err", err)
 	}
 
 	if len(balances) > 0 {
@@ -178,9 +184,11 @@ func doRichlist(c *cli.Context) error {
 
 	err = db.SetGlobalInt(rich_list_last_block, lastBlock)
 	if err != nil {
-		log.Fatal("Failed to set last processed block number")
+		logger.Crit("Failed to set last processed block number", "err", err)
 	}
 
+	jobDone("richlist", stime)
+
 	return nil
 }
 
@@ -218,23 +226,30 @@ func expandHome(path string) string {
 	return path
 }
 
-func streamInsertBlocks(db *db.DBClient, ch chan *models.Block) (int, error) {
+func streamInsertBlocks(db db.Store, ch chan *models.Block, publish bool, watcher *webhooks.Watcher, rollups *rollupRange) (int, error) {
 	const bufSize = 400
 	count := 0
 	blocks := make([]*models.Block, 0, bufSize)
 
 	for block := range ch {
 		if len(ch) >= 512 {
-			log.Println("Chocking ", block.Number, len(ch))
+			logger.Warn("Choking on blocks", "number", block.Number, "backlog", len(ch))
 		}
 
 		blocks = append(blocks, block)
+		rollups.add(uint64(block.TimeStamp))
 
 		if len(blocks) >= bufSize {
 			err := db.InsertBlocks(blocks[:])
 			if err != nil {
 				return 0, err
 			}
+			blocksIngested.Add(float64(len(blocks)))
+			events.PublishChanges(events.BlocksAdded(blocks))
+			if publish {
+				events.PublishBlocks(blocks)
+			}
+			watcher.Blocks(blocks)
 			count = count + len(blocks)
 			blocks = make([]*models.Block, 0, bufSize)
 		}
@@ -244,13 +259,20 @@ func streamInsertBlocks(db *db.DBClient, ch chan *models.Block) (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	blocksIngested.Add(float64(len(blocks)))
+	events.PublishChanges(events.BlocksAdded(blocks))
+	if publish {
+		events.PublishBlocks(blocks)
+	}
+	watcher.Blocks(blocks)
+	watcher.Flush()
 
 	count = count + len(blocks)
 
 	return count, nil
 }
 
-func streamInsertTransactions(wg *sync.WaitGroup, db *db.DBClient, txsCh <-chan models.TransactionFull) {
+func streamInsertTransactions(wg *sync.WaitGroup, db db.Store, txsCh <-chan models.TransactionFull, publish bool, watcher *webhooks.Watcher) {
 	defer wg.Done()
 	const bufSize = 20
 	count := 0
@@ -258,7 +280,7 @@ func streamInsertTransactions(wg *sync.WaitGroup, db *db.DBClient, txsCh <-chan
 
 	for t := range txsCh {
 		if len(txsCh) >= 512 {
-			log.Println("Chocking on transactions", t.Tx.Hash, len(txsCh))
+			logger.Warn("Choking on transactions", "hash", t.Tx.Hash, "backlog", len(txsCh))
 		}
 
 		txs = append(txs, t)
@@ -266,7 +288,14 @@ func streamInsertTransactions(wg *sync.WaitGroup, db *db.DBClient, txsCh <-chan
 		if len(txs) >= bufSize {
 			err := db.InsertTransactions(txs[:])
 			if err != nil {
-				log.Println("Error streamInsertTransactions", err.Error())
+				logger.Error("Failed to insert transactions", "err", err)
+			} else {
+				transactionsIngested.Add(float64(len(txs)))
+				events.PublishChanges(events.TransactionsAdded(txs))
+				if publish {
+					events.PublishTransactions(txs)
+				}
+				watcher.Transactions(txs)
 			}
 			count = count + len(txs)
 			txs = make([]models.TransactionFull, 0, bufSize)
@@ -275,25 +304,53 @@ func streamInsertTransactions(wg *sync.WaitGroup, db *db.DBClient, txsCh <-chan
 
 	err := db.InsertTransactions(txs[:])
 	if err != nil {
-		log.Println("Error streamInsertTransactions", err.Error())
+		logger.Error("Failed to insert transactions", "err", err)
+	} else {
+		transactionsIngested.Add(float64(len(txs)))
+		events.PublishChanges(events.TransactionsAdded(txs))
+		if publish {
+			events.PublishTransactions(txs)
+		}
+		watcher.Transactions(txs)
 	}
 	count = count + len(txs)
-	fmt.Println("Finished inserting", count, "transactions")
+	logger.Info("Finished inserting transactions", "count", count)
 }
 
-func streamDeleteBlockWithTransactions(wg *sync.WaitGroup, db *db.DBClient, dCh <-chan *models.Block, bCh chan<- *models.Block, tCh chan<- ipc.TransactionWithTimestamp, pCh chan<- common.Address) {
+func streamDeleteBlockWithTransactions(wg *sync.WaitGroup, db db.Store, dCh <-chan *models.Block, bCh chan<- *models.Block, tCh chan<- ipc.TransactionWithTimestamp, pCh chan<- common.Address, publish bool, rollups *rollupRange) {
 	defer wg.Done()
 
 	for bl := range dCh {
+		var oldHash common.Hash
+		var removed []events.Change
+		if oldBl, err := db.GetBlockByID(uint64(bl.Number)); err == nil && oldBl.Hash != (common.Hash{}) {
+			oldHash = oldBl.Hash
+			// the replaced block may have been in another bucket
+			rollups.add(uint64(oldBl.TimeStamp))
+
+			// the addresses of the replaced transactions are affected too
+			oldTxs, err := db.GetTransactionsByBlockNumbers([]uint64{uint64(bl.Number)})
+			if err != nil {
+				logger.Error("Failed to get the transactions of a replaced block", "number", bl.Number, "err", err)
+			}
+			removed = events.BlockRemoved(oldBl, oldTxs)
+		}
+
 		err := db.DeleteBlockWithTransactionsByID(uint64(bl.Number), bl.Producer)
 
 		if err != nil {
-			log.Println("Error streamDeleteBlockWithTransactions", err.Error())
+			logger.Error("Failed to delete a replaced block", "number", bl.Number, "err", err)
 			continue
 			// TODO: exit here?
 
 		}
 
+		reorgedBlocks.Inc()
+		events.PublishChanges(removed)
+		if publish {
+			events.PublishReorg(uint64(bl.Number), oldHash, bl.Hash)
+		}
+
 		bCh <- bl
 		pCh <- bl.Producer
 
@@ -307,7 +364,7 @@ func streamDeleteBlockWithTransactions(wg *sync.WaitGroup, db *db.DBClient, dCh
 	close(pCh)
 }
 
-func streamInsertProducers(wg *sync.WaitGroup, db *db.DBClient, pCh chan common.Address) (int, error) {
+func streamInsertProducers(wg *sync.WaitGroup, db db.Store, pCh chan common.Address) (int, error) {
 	defer wg.Done()
 
 	const bufSize = 50
@@ -352,6 +409,50 @@ func streamInsertProducers(wg *sync.WaitGroup, db *db.DBClient, pCh chan common.
 	return count, nil
 }
 
+// crawl indexes the chain of the node backwards from block last, down to
+// the first block that is already indexed with the same hash. The blocks
+// indexed with another hash are replaced, along with their transactions.
+// It returns how many blocks were inserted.
+func crawl(ipc ipcModule.Node, db db.Store, last uint64, publish bool, watcher *webhooks.Watcher) int {
+	deleteCh := make(chan *models.Block, 512)
+	blockCh := make(chan *models.Block, 512)
+	txsHashCh := make(chan ipcModule.TransactionWithTimestamp, 512)
+	txsCh := make(chan models.TransactionFull, 512)
+	producerCh := make(chan common.Address, 512)
+
+	channelBacklog.set("delete", func() int { return len(deleteCh) })
+	channelBacklog.set("blocks", func() int { return len(blockCh) })
+	channelBacklog.set("transaction_hashes", func() int { return len(txsHashCh) })
+	channelBacklog.set("transactions", func() int { return len(txsCh) })
+	channelBacklog.set("producers", func() int { return len(producerCh) })
+
+	rollups := &rollupRange{}
+
+	var wg sync.WaitGroup
+
+	wg.Add(6)
+	go ipcModule.StreamBlocks(ipc, &wg, db, blockCh, txsHashCh, producerCh, deleteCh, last)
+	go streamInsertProducers(&wg, db, producerCh)
+	go streamDeleteBlockWithTransactions(&wg, db, deleteCh, blockCh, txsHashCh, producerCh, publish, rollups)
+
+	go ipcModule.StreamTransactions(ipc, &wg, db, txsCh, txsHashCh)
+	go streamInsertTransactions(&wg, db, txsCh, publish, watcher)
+
+	var count int
+	go func() {
+		defer wg.Done()
+		count, _ = streamInsertBlocks(db, blockCh, publish, watcher, rollups)
+	}()
+
+	wg.Wait()
+
+	if err := rollups.refresh(db); err != nil {
+		logger.Error("Failed to refresh rollups", "err", err)
+	}
+
+	return count
+}
+
 func pullNewBlocks(c *cli.Context) error {
 	lock, err := lockfile.New(filepath.Join(os.TempDir(), "ebakus-crawler-"+c.String("dbname")+".lock"))
 	if err != nil {
@@ -365,58 +466,56 @@ func pullNewBlocks(c *cli.Context) error {
 	}
 	defer lock.Unlock()
 
+	defer startMetrics(c)()
+
 	ipcFile := expandHome(c.String("ipc"))
-	ipc, err := ipcModule.NewIPCInterface(ipcFile)
+	ipc, err := ipcModule.Dial(ipcFile)
 	if err != nil {
-		log.Fatal("Failed to connect to ebakus", err)
+		logger.Crit("Failed to connect to ebakus", "err", err)
 	}
 
 	err = db.InitFromCli(c)
 	if err != nil {
-		log.Fatal("Failed to load db client")
+		logger.Crit("Failed to load db client", "err", err)
 	}
 	db := db.GetClient()
 
 	if err := redis.InitFromCli(c); err != nil {
-		log.Fatal("Failed to connect to redis", err)
+		logger.Crit("Failed to connect to redis", "err", err)
 	}
 	defer redis.Pool.Close()
 
 	last, err := ipc.GetBlockNumber()
 	if err != nil {
-		log.Fatal("Failed to get last block number")
+		logger.Crit("Failed to get last block number", "err", err)
 	}
 
-	log.Printf("Going to insert blocks backwards from %d", last)
+	logger.Info("Going to insert blocks backwards", "from", last)
+	observeHeadLag(db, last)
 
 	stime := time.Now()
 
-	deleteCh := make(chan *models.Block, 512)
-	blockCh := make(chan *models.Block, 512)
-	txsHashCh := make(chan ipcModule.TransactionWithTimestamp, 512)
-	txsCh := make(chan models.TransactionFull, 512)
-	producerCh := make(chan common.Address, 512)
-
-	var wg sync.WaitGroup
+	publish := !c.Bool("noevents")
 
-	wg.Add(6)
-	go ipc.StreamBlocks(&wg, db, blockCh, txsHashCh, producerCh, deleteCh, last)
-	go streamInsertProducers(&wg, db, producerCh)
-	go streamDeleteBlockWithTransactions(&wg, db, deleteCh, blockCh, txsHashCh, producerCh)
+	chainID, err := ipc.GetChainId()
+	if err != nil {
+		logger.Crit("Failed to get the chain id", "err", err)
+	}
 
-	go ipc.StreamTransactions(&wg, db, txsCh, txsHashCh)
-	go streamInsertTransactions(&wg, db, txsCh)
+	watcher, err := webhooks.NewWatcher(db, chainID)
+	if err != nil {
+		logger.Crit("Failed to load the webhooks", "err", err)
+	}
 
-	var count int
-	go func() {
-		defer wg.Done()
-		count, _ = streamInsertBlocks(db, blockCh)
-	}()
+	count := crawl(ipc, db, last, publish, watcher)
 
-	wg.Wait()
+	if head, err := ipc.GetBlockNumber(); err == nil {
+		observeHeadLag(db, head)
+	}
+	jobDone("fetchblocks", stime)
 
 	elapsed := time.Now().Sub(stime)
-	log.Printf("Processed %d blocks in %.3f (%.0f bps)", count, elapsed.Seconds(), float64(count)/elapsed.Seconds())
+	logger.Info("Processed blocks", "count", count, "elapsed", elapsed, "bps", int(float64(count)/elapsed.Seconds()))
 
 	return err
 }
@@ -434,34 +533,40 @@ func doEnsSync(c *cli.Context) error {
 	}
 	defer lock.Unlock()
 
+	defer startMetrics(c)()
+
 	ipcFile := expandHome(c.String("ipc"))
-	ipc, err := ipcModule.NewIPCInterface(ipcFile)
+	ipc, err := ipcModule.Dial(ipcFile)
 	if err != nil {
-		log.Fatal("Failed to connect to ebakus", err)
+		logger.Crit("Failed to connect to ebakus", "err", err)
 	}
 
 	err = db.InitFromCli(c)
 	if err != nil {
-		log.Fatal("Failed to load db client")
+		logger.Crit("Failed to load db client", "err", err)
 	}
 	db := db.GetClient()
 
 	ensContractAddress := common.HexToAddress(c.String("enscontractaddress"))
 	zeroAddress := common.Address{}
 	if ensContractAddress == zeroAddress {
-		log.Fatal("No contract address defined for the ENS contract")
+		logger.Crit("No contract address defined for the ENS contract")
 	}
 
-	log.Printf("Going to sync up ENS names with its addresses")
+	logger.Info("Going to sync up ENS names with their addresses")
 
 	stime := time.Now()
 
 	numberOfEntries, err := db.GetEnsCount()
 	if err != nil {
-		log.Fatal("Failed to get number of ENS entries in DB", err.Error())
+		logger.Crit("Failed to get number of ENS entries in DB", "err", err)
 	}
 	if numberOfEntries == 0 {
-		log.Println("No ENS entries to process")
+		logger.Info("No ENS entries to process")
+		if err := db.SetGlobalInt(ens_last_sync, uint64(time.Now().Unix())); err != nil {
+			logger.Error("Failed to set the last ENS sync time", "err", err)
+		}
+		jobDone("enssync", stime)
 		return nil
 	}
 
@@ -471,14 +576,14 @@ func doEnsSync(c *cli.Context) error {
 	for i := uint64(0); i < numberOfEntries; i += chunkSize {
 		entries, err := db.GetEnsEntriesRange(chunkSize, i)
 		if err != nil {
-			log.Fatal("Failed to get ENS entries from DB", err.Error())
+			logger.Crit("Failed to get ENS entries from DB", "err", err)
 			return err
 		}
 
 		for _, ens := range entries {
 			addr, err := ipc.GetENSAddress(ensContractAddress, ens.Hash)
 			if err != nil {
-				log.Println("Failed to get ENS address from node state:", err.Error())
+				logger.Error("Failed to get ENS address from node state", "err", err)
 				continue
 			}
 
@@ -491,17 +596,33 @@ func doEnsSync(c *cli.Context) error {
 
 			err = db.InsertEns(ens)
 			if err != nil {
-				log.Fatal("Error InsertEns failed", err.Error())
+				logger.Crit("Failed to insert ENS name", "err", err)
 			}
 		}
 	}
 
+	if err := db.SetGlobalInt(ens_last_sync, uint64(time.Now().Unix())); err != nil {
+		logger.Error("Failed to set the last ENS sync time", "err", err)
+	}
+	jobDone("enssync", stime)
+
 	elapsed := time.Now().Sub(stime)
-	log.Printf("Updated %d of %d names in %.3f (%.0f bps)", updatedEntries, numberOfEntries, elapsed.Seconds(), float64(numberOfEntries)/elapsed.Seconds())
+	logger.Info("Updated ENS names", "updated", updatedEntries, "count", numberOfEntries, "elapsed", elapsed, "nps", int(float64(numberOfEntries)/elapsed.Seconds()))
 
 	return nil
 }
 
+// initCommand loads the config file into the flags and sets up the logger
+func initCommand(flags []cli.Flag) cli.BeforeFunc {
+	loadConfig := altsrc.InitInputSourceWithContext(flags, altsrc.NewYamlSourceFromFlagFunc("config"))
+	return func(c *cli.Context) error {
+		if err := loadConfig(c); err != nil {
+			return err
+		}
+		return logger.InitFromCli(c)
+	}
+}
+
 func main() {
 	app := cli.NewApp()
 	app.Name = "Ebakus Blockchain Explorer"
@@ -525,6 +646,10 @@ func main() {
 			Usage: "The ebakus node to connect to e.g. ./ebakus/ebakus.ipc",
 			Value: "~/ebakus/ebakus.ipc",
 		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "dbdriver",
+			Value: "postgres",
+		}),
 		altsrc.NewStringFlag(cli.StringFlag{
 			Name:  "dbhost",
 			Value: "localhost",
@@ -569,6 +694,24 @@ func main() {
 			Name:  "enscontractaddress",
 			Value: "",
 		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "metricsaddr",
+			Usage: "Address to serve the metrics on while running, e.g. :9101",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "metricsfile",
+			Usage: "File to write the metrics to on exit, e.g. for the textfile collector of the node exporter",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "loglevel",
+			Usage: "Level of the logged lines, trace, debug, info, warn, error or crit",
+			Value: "info",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "logformat",
+			Usage: "Format of the logged lines, logfmt or json",
+			Value: logger.LOG_FORMAT_LOGFMT,
+		}),
 		cli.StringFlag{
 			Name:  "config",
 			Value: "config.yaml",
@@ -580,15 +723,20 @@ func main() {
 			Name:    "fetchblocks",
 			Aliases: []string{"f"},
 			Usage:   "Fetch new blocks from ebakus node",
-			Before:  altsrc.InitInputSourceWithContext(genericFlags, altsrc.NewYamlSourceFromFlagFunc("config")),
-			Flags:   genericFlags,
-			Action:  pullNewBlocks,
+			Before:  initCommand(genericFlags),
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "noevents",
+					Usage: "Don't publish the new blocks and transactions for streaming, e.g. during the initial sync",
+				},
+			}, genericFlags...),
+			Action: pullNewBlocks,
 		},
 		{
 			Name:    "getblock",
 			Aliases: []string{"gb"},
 			Usage:   "Retrieve block from database",
-			Before:  altsrc.InitInputSourceWithContext(genericFlags, altsrc.NewYamlSourceFromFlagFunc("config")),
+			Before:  initCommand(genericFlags),
 			Flags:   genericFlags,
 			Action:  getBlock,
 		},
@@ -596,7 +744,7 @@ func main() {
 			Name:    "computerich",
 			Aliases: []string{"cr"},
 			Usage:   "Compute richlist",
-			Before:  altsrc.InitInputSourceWithContext(genericFlags, altsrc.NewYamlSourceFromFlagFunc("config")),
+			Before:  initCommand(genericFlags),
 			Flags:   genericFlags,
 			Action:  doRichlist,
 		},
@@ -604,10 +752,131 @@ func main() {
 			Name:    "enssync",
 			Aliases: []string{"ens"},
 			Usage:   "ENS names will sync up its address",
-			Before:  altsrc.InitInputSourceWithContext(genericFlags, altsrc.NewYamlSourceFromFlagFunc("config")),
+			Before:  initCommand(genericFlags),
 			Flags:   genericFlags,
 			Action:  doEnsSync,
 		},
+		{
+			Name:   "webhooks",
+			Usage:  "Deliver the queued webhook events, retrying failures",
+			Before: initCommand(genericFlags),
+			Flags: append([]cli.Flag{
+				cli.DurationFlag{
+					Name:  "interval",
+					Usage: "How often to look for due deliveries",
+					Value: 5 * time.Second,
+				},
+				cli.DurationFlag{
+					Name:  "timeout",
+					Usage: "Timeout of each delivery request",
+					Value: 10 * time.Second,
+				},
+			}, genericFlags...),
+			Action: doDeliverWebhooks,
+		},
+		{
+			Name:   "rollups",
+			Usage:  "Recompute the hourly and daily analytics rollups, e.g. to backfill them",
+			Before: initCommand(genericFlags),
+			Flags: append([]cli.Flag{
+				cli.Uint64Flag{
+					Name:  "from",
+					Usage: "Unix timestamp to start from, defaults to the first block",
+				},
+				cli.Uint64Flag{
+					Name:  "to",
+					Usage: "Unix timestamp to stop at, defaults to the latest block",
+				},
+			}, genericFlags...),
+			Action: doRollups,
+		},
+		{
+			Name:  "apikeys",
+			Usage: "Manage the API keys of the explorer",
+			Subcommands: []cli.Command{
+				{
+					Name:   "issue",
+					Usage:  "Issue a new API key",
+					Before: initCommand(genericFlags),
+					Flags: append([]cli.Flag{
+						cli.StringFlag{
+							Name:  "name",
+							Usage: "Who the key is issued to",
+						},
+						cli.Uint64Flag{
+							Name:  "ratelimit",
+							Usage: "Requests per minute, defaults to the keyratelimit of the explorer",
+						},
+						cli.Uint64Flag{
+							Name:  "quota",
+							Usage: "Requests per day, unlimited by default",
+						},
+					}, genericFlags...),
+					Action: doIssueAPIKey,
+				},
+				{
+					Name:      "revoke",
+					Usage:     "Revoke an API key",
+					ArgsUsage: "<id>",
+					Before:    initCommand(genericFlags),
+					Flags:     genericFlags,
+					Action:    doRevokeAPIKey,
+				},
+				{
+					Name:   "list",
+					Usage:  "List the API keys with their usage today",
+					Before: initCommand(genericFlags),
+					Flags:  genericFlags,
+					Action: doListAPIKeys,
+				},
+				{
+					Name:      "show",
+					Usage:     "Show an API key with its usage of the last days",
+					ArgsUsage: "<id>",
+					Before:    initCommand(genericFlags),
+					Flags:     genericFlags,
+					Action:    doShowAPIKey,
+				},
+			},
+		},
+		{
+			Name:  "migrate",
+			Usage: "Manage the database schema",
+			Subcommands: []cli.Command{
+				{
+					Name:   "up",
+					Usage:  "Apply pending migrations",
+					Before: initCommand(genericFlags),
+					Flags: append([]cli.Flag{
+						cli.IntFlag{
+							Name:  "to",
+							Usage: "Migrate up to this version, defaults to the latest",
+						},
+					}, genericFlags...),
+					Action: doMigrateUp,
+				},
+				{
+					Name:   "down",
+					Usage:  "Roll back applied migrations",
+					Before: initCommand(genericFlags),
+					Flags: append([]cli.Flag{
+						cli.IntFlag{
+							Name:  "steps",
+							Usage: "Number of migrations to roll back",
+							Value: 1,
+						},
+					}, genericFlags...),
+					Action: doMigrateDown,
+				},
+				{
+					Name:   "status",
+					Usage:  "List migrations and whether they are applied",
+					Before: initCommand(genericFlags),
+					Flags:  genericFlags,
+					Action: doMigrateStatus,
+				},
+			},
+		},
 	}
 
 	app.Run(os.Args)
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/webhooks"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+const testFixture = "../../ipc/testdata/chain.json"
+
+func TestMain(m *testing.M) {
+	if err := logger.Init("crit", logger.LOG_FORMAT_LOGFMT); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// expectIndexed checks that the index holds the chain of the node
+func expectIndexed(t *testing.T, node ipc.Node, store db.Store) {
+	t.Helper()
+
+	head, err := node.GetBlockNumber()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest, err := store.GetLatestBlockNumber(); err != nil || latest != head {
+		t.Fatalf("GetLatestBlockNumber() = %d, %v, want %d", latest, err, head)
+	}
+
+	for number := uint64(0); number <= head; number++ {
+		expected, err := node.GetBlock(number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bl, err := store.GetBlockByID(number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bl.Hash != expected.Hash || bl.Producer != expected.Producer || int(bl.TransactionCount) != len(expected.Transactions) {
+			t.Errorf("block %d = %x by %x, want %x by %x", number, bl.Hash, bl.Producer, expected.Hash, expected.Producer)
+		}
+
+		for _, hash := range expected.Transactions {
+			if tf, err := store.GetTransactionByHash(hash.Hex()); err != nil || tf.Tx == nil || tf.Tx.BlockHash != expected.Hash {
+				t.Errorf("transaction %x of block %d is not indexed: %v", hash, number, err)
+			}
+		}
+	}
+}
+
+// expectProducer checks the blocks counted for a producer
+func expectProducer(t *testing.T, store db.Store, address string, blocks uint64) {
+	t.Helper()
+
+	producer, err := store.GetProducer(address)
+	if err != nil {
+		t.Fatalf("GetProducer(%s) = %v", address, err)
+	}
+
+	rewards := new(big.Int).Mul(new(big.Int).SetUint64(3171*blocks), precisionFactor)
+	if producer.ProducedBlocksCount != blocks || producer.BlockRewards.Cmp(rewards) != 0 {
+		t.Errorf("producer %s = %d blocks, %s rewards, want %d, %s", address, producer.ProducedBlocksCount, producer.BlockRewards, blocks, rewards)
+	}
+}
+
+func TestCrawlReorg(t *testing.T) {
+	node, err := ipc.NewFakeNodeFromFile(testFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := db.NewMemoryStore()
+
+	watcher, err := webhooks.NewWatcher(store, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := crawl(node, store, 5, false, watcher); count != 6 {
+		t.Errorf("crawl() inserted %d blocks, want 6", count)
+	}
+	expectIndexed(t, node, store)
+
+	// the reorg replaces blocks 4 and 5 and adds block 6
+	replaced := common.HexToHash("0x7a535830021099b3a76cd4ef3d481b5cc8892479a54a9230100b70b4042e86ce")
+	if err := node.ApplyReorg(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := crawl(node, store, 6, false, watcher); count != 3 {
+		t.Errorf("crawl() after the reorg inserted %d blocks, want 3", count)
+	}
+	expectIndexed(t, node, store)
+
+	if tf, err := store.GetTransactionByHash(replaced.Hex()); err != nil || tf.Tx != nil {
+		t.Errorf("transaction of a replaced block = %+v, %v, want it removed", tf, err)
+	}
+
+	// the replaced blocks were produced by the same delegates
+	expectProducer(t, store, "0x1111111111111111111111111111111111111111", 3)
+	expectProducer(t, store, "0x2222222222222222222222222222222222222222", 2)
+	expectProducer(t, store, "0x3333333333333333333333333333333333333333", 2)
+
+	// the rollups are refreshed for the replaced blocks too
+	first, err := node.GetBlock(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := uint64(first.TimeStamp)
+	rollups, err := store.GetRollups(models.ROLLUP_INTERVAL_DAY, day, day)
+	if err != nil || len(rollups) != 1 || rollups[0].BlockCount != 7 || rollups[0].TxCount != 5 {
+		t.Errorf("GetRollups() = %+v, %v, want 7 blocks and 5 transactions", rollups, err)
+	}
+
+	if count := crawl(node, store, 6, false, watcher); count != 0 {
+		t.Errorf("crawl() of an indexed chain inserted %d blocks", count)
+	}
+	expectIndexed(t, node, store)
+}
+
+func TestCrawlMissingReceipt(t *testing.T) {
+	node, err := ipc.NewFakeNodeFromFile(testFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := db.NewMemoryStore()
+
+	watcher, err := webhooks.NewWatcher(store, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// transactions without receipts are skipped, not the blocks
+	missing := common.HexToHash("0x3942f770cee2207f43d4ce3e4e761fea7428346f6f6ff7f2e317c4a3e12cd101")
+	node.DropReceipt(missing)
+
+	if count := crawl(node, store, 5, false, watcher); count != 6 {
+		t.Errorf("crawl() inserted %d blocks, want 6", count)
+	}
+	if tf, err := store.GetTransactionByHash(missing.Hex()); err != nil || tf.Tx != nil {
+		t.Errorf("transaction without a receipt = %+v, %v", tf, err)
+	}
+	if _, count, _ := store.GetAddressTotals("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"); count != 3 {
+		t.Errorf("transactions of alice = %d, want 3", count)
+	}
+}
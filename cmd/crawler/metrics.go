@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/urfave/cli"
+)
+
+var (
+	blocksIngested = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ebakus_crawler_blocks_ingested_total",
+		Help: "Blocks inserted in the index",
+	})
+	transactionsIngested = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ebakus_crawler_transactions_ingested_total",
+		Help: "Transactions inserted in the index",
+	})
+	reorgedBlocks = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ebakus_crawler_reorged_blocks_total",
+		Help: "Blocks of the index replaced by a reorg",
+	})
+	headLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ebakus_crawler_head_lag_blocks",
+		Help: "Blocks the index is behind the node, at the start and the end of a run",
+	})
+	jobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ebakus_crawler_job_duration_seconds",
+		Help:    "Duration of the runs of the crawler jobs",
+		Buckets: metrics.JobBuckets,
+	}, []string{"job"})
+	jobLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ebakus_crawler_job_last_success_timestamp_seconds",
+		Help: "When the crawler jobs last finished successfully",
+	}, []string{"job"})
+
+	channelBacklog = newBacklogCollector()
+)
+
+// backlogCollector reads the lengths of the channels of the fetchblocks
+// pipeline when the metrics are collected, as each crawl has its channels
+type backlogCollector struct {
+	desc *prometheus.Desc
+
+	mu       sync.Mutex
+	channels map[string]func() int
+}
+
+func newBacklogCollector() *backlogCollector {
+	c := &backlogCollector{
+		desc: prometheus.NewDesc("ebakus_crawler_channel_backlog",
+			"Items waiting in the channels of the fetchblocks pipeline", []string{"channel"}, nil),
+		channels: make(map[string]func() int),
+	}
+	prometheus.MustRegister(c)
+	return c
+}
+
+// set makes the backlog of a channel read from length
+func (c *backlogCollector) set(channel string, length func() int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.channels[channel] = length
+}
+
+func (c *backlogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *backlogCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for channel, length := range c.channels {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(length()), channel)
+	}
+}
+
+// startMetrics serves the metrics on metricsaddr, if set, while the command
+// runs. The returned func writes them to metricsfile, if set, for commands
+// that exit before they are scraped.
+func startMetrics(c *cli.Context) func() {
+	if addr := c.String("metricsaddr"); addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		go func() {
+			if err := http.ListenAndServe(addr, mux); err != nil {
+				logger.Error("Failed to serve metrics", "err", err)
+			}
+		}()
+	}
+
+	return func() {
+		if path := c.String("metricsfile"); path != "" {
+			if err := metrics.WriteFile(path); err != nil {
+				logger.Error("Failed to write metrics", "err", err)
+			}
+		}
+	}
+}
+
+// jobDone records a successful run of a job
+func jobDone(job string, start time.Time) {
+	jobDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+	jobLastSuccess.WithLabelValues(job).SetToCurrentTime()
+}
+
+// observeHeadLag records how far the index is behind the head of the node
+func observeHeadLag(db db.Store, head uint64) {
+	latest, err := db.GetLatestBlockNumber()
+	if err != nil {
+		logger.Error("Failed to get the latest block number", "err", err)
+		return
+	}
+
+	lag := float64(0)
+	if head > latest {
+		lag = float64(head - latest)
+	}
+	headLag.Set(lag)
+}
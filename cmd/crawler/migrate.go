@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/schema"
+
+	"github.com/urfave/cli"
+)
+
+func doMigrateUp(c *cli.Context) error {
+	tdb, err := db.OpenFromCli(c)
+	if err != nil {
+		logger.Crit("Failed to connect to db", "err", err)
+	}
+	defer tdb.Close()
+
+	count, err := db.MigrateUp(tdb, c.Int("to"))
+	if err != nil {
+		logger.Crit("Migration failed", "err", err)
+	}
+
+	version, err := db.SchemaVersion(tdb)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Applied migrations", "count", count, "version", version)
+	return nil
+}
+
+func doMigrateDown(c *cli.Context) error {
+	tdb, err := db.OpenFromCli(c)
+	if err != nil {
+		logger.Crit("Failed to connect to db", "err", err)
+	}
+	defer tdb.Close()
+
+	count, err := db.MigrateDown(tdb, c.Int("steps"))
+	if err != nil {
+		logger.Crit("Rollback failed", "err", err)
+	}
+
+	version, err := db.SchemaVersion(tdb)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Rolled back migrations", "count", count, "version", version)
+	return nil
+}
+
+func doMigrateStatus(c *cli.Context) error {
+	tdb, err := db.OpenFromCli(c)
+	if err != nil {
+		logger.Crit("Failed to connect to db", "err", err)
+	}
+	defer tdb.Close()
+
+	migrations, err := db.GetMigrationStatus(tdb)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, m := range migrations {
+		applied := "pending"
+		if m.AppliedAt != nil {
+			applied = m.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		} else {
+			pending++
+		}
+		fmt.Printf("%4d  %-30s %s\n", m.Version, m.Name, applied)
+	}
+
+	fmt.Printf("\n%d pending, latest version is %d\n", pending, schema.Latest())
+	return nil
+}
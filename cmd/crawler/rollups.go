@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/nightlyone/lockfile"
+	"github.com/urfave/cli"
+)
+
+// rollupRange collects the time range touched by the inserted and the
+// reorged blocks of a crawl, for the rollups to be refreshed afterwards
+type rollupRange struct {
+	mu       sync.Mutex
+	from, to uint64
+	touched  bool
+}
+
+func (r *rollupRange) add(timestamp uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.touched || timestamp < r.from {
+		r.from = timestamp
+	}
+	if !r.touched || timestamp > r.to {
+		r.to = timestamp
+	}
+	r.touched = true
+}
+
+// refresh recomputes the rollups of the collected range
+func (r *rollupRange) refresh(store db.Store) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.touched {
+		return nil
+	}
+
+	logger.Info("Refreshing rollups", "from", r.from, "to", r.to)
+	return refreshRollups(store, r.from, r.to)
+}
+
+// refreshRollups recomputes the rollups of [from, to] a day at a time, so
+// that each refresh is a short transaction
+func refreshRollups(store db.Store, from, to uint64) error {
+	day := models.RollupIntervals[models.ROLLUP_INTERVAL_DAY]
+	for _, bucket := range models.RollupBuckets(models.ROLLUP_INTERVAL_DAY, from, to) {
+		start, end := bucket, bucket+day-1
+		if start < from {
+			start = from
+		}
+		if end > to {
+			end = to
+		}
+
+		if err := store.RefreshRollups(start, end); err != nil {
+			return err
+		}
+		logger.Debug("Refreshed rollups", "bucket", bucket)
+	}
+	return nil
+}
+
+func doRollups(c *cli.Context) error {
+	// shares the lock of fetchblocks, which refreshes rollups as well
+	lock, err := lockfile.New(filepath.Join(os.TempDir(), "ebakus-crawler-"+c.String("dbname")+".lock"))
+	if err != nil {
+		fmt.Printf("Cannot init lock. reason: %v", err)
+		return err
+	}
+	err = lock.TryLock()
+	if err != nil {
+		fmt.Printf("Cannot lock %q, reason: %v", lock, err)
+		return err
+	}
+	defer lock.Unlock()
+
+	err = db.InitFromCli(c)
+	if err != nil {
+		logger.Crit("Failed to load db client", "err", err)
+	}
+	store := db.GetClient()
+
+	from, to := c.Uint64("from"), c.Uint64("to")
+
+	if !c.IsSet("from") {
+		first, err := store.GetBlockByID(0)
+		if err != nil {
+			logger.Crit("Failed to get the first block", "err", err)
+		}
+		from = uint64(first.TimeStamp)
+	}
+
+	if !c.IsSet("to") {
+		last, err := store.GetLatestBlockNumber()
+		if err != nil {
+			logger.Crit("Failed to get the latest block number", "err", err)
+		}
+		block, err := store.GetBlockByID(last)
+		if err != nil {
+			logger.Crit("Failed to get the latest block", "err", err)
+		}
+		to = uint64(block.TimeStamp)
+	}
+
+	logger.Info("Refreshing rollups", "from", from, "to", to)
+	if err := refreshRollups(store, from, to); err != nil {
+		logger.Crit("Failed to refresh rollups", "err", err)
+	}
+	logger.Info("Refreshed rollups", "from", from, "to", to)
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+)
+
+// rollupStore records the ranges of the rollup refreshes
+type rollupStore struct {
+	*db.MemoryStore
+	refreshes [][2]uint64
+}
+
+func (s *rollupStore) RefreshRollups(from, to uint64) error {
+	s.refreshes = append(s.refreshes, [2]uint64{from, to})
+	return s.MemoryStore.RefreshRollups(from, to)
+}
+
+func TestRefreshRollupsPerDay(t *testing.T) {
+	const day = 24 * 60 * 60
+	const start = 1583020800 // a midnight
+
+	store := &rollupStore{MemoryStore: db.NewMemoryStore()}
+
+	r := &rollupRange{}
+	r.add(start + 10*3600)
+	r.add(start + 2*day + 3600)
+	if err := r.refresh(store); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][2]uint64{
+		{start + 10*3600, start + day - 1},
+		{start + day, start + 2*day - 1},
+		{start + 2*day, start + 2*day + 3600},
+	}
+	if len(store.refreshes) != len(expected) {
+		t.Fatalf("got refreshes %v, want %v", store.refreshes, expected)
+	}
+	for i := range expected {
+		if store.refreshes[i] != expected[i] {
+			t.Errorf("refresh %d = %v, want %v", i, store.refreshes[i], expected[i])
+		}
+	}
+}
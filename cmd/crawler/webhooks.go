@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/webhooks"
+
+	"github.com/nightlyone/lockfile"
+	"github.com/urfave/cli"
+)
+
+func doDeliverWebhooks(c *cli.Context) error {
+	// deliveries are picked without row locking, so only one deliverer may run
+	lock, err := lockfile.New(filepath.Join(os.TempDir(), "ebakus-webhooks-"+c.String("dbname")+".lock"))
+	if err != nil {
+		fmt.Printf("Cannot init lock. reason: %v", err)
+		return err
+	}
+	err = lock.TryLock()
+	if err != nil {
+		fmt.Printf("Cannot lock %q, reason: %v", lock, err)
+		return err
+	}
+	defer lock.Unlock()
+
+	defer startMetrics(c)()
+
+	err = db.InitFromCli(c)
+	if err != nil {
+		logger.Crit("Failed to load db client", "err", err)
+	}
+
+	logger.Info("Delivering webhooks", "interval", c.Duration("interval"))
+
+	deliverer := webhooks.NewDeliverer(db.GetClient(), c.Duration("timeout"))
+	deliverer.Run(c.Duration("interval"))
+
+	return nil
+}
@@ -3,18 +3,19 @@ package main
 import (
 	"bytes"
 	"html/template"
-	"log"
 	"os"
 	"os/user"
 	"path/filepath"
+	"time"
 
 	api "github.com/ebakus/ebakus-block-explorer-backend/api"
 	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/events"
 	ipcModule "github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/jsonrpc"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
 	"github.com/ebakus/ebakus-block-explorer-backend/redis"
 
-	"net/http"
-
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 
@@ -23,7 +24,7 @@ import (
 )
 
 type explorerContext struct {
-	db     *db.DBClient
+	db     db.Store
 	router *mux.Router
 }
 
@@ -43,6 +44,10 @@ func (ec explorerContext) initExplorer() cli.BeforeFunc {
 			return err
 		}
 
+		if err := logger.InitFromCli(c); err != nil {
+			return err
+		}
+
 		var err error
 		err = db.InitFromCli(c)
 		if err != nil {
@@ -51,17 +56,59 @@ func (ec explorerContext) initExplorer() cli.BeforeFunc {
 		ec.db = db.GetClient()
 
 		ipcFile := expandHome(c.String("ipc"))
-		if _, err := ipcModule.NewIPCInterface(ipcFile); err != nil {
-			log.Fatal("Failed to connect to ebakus", err)
+		if _, err := ipcModule.Dial(ipcFile); err != nil {
+			logger.Crit("Failed to connect to ebakus", "err", err)
+		}
+
+		if err := redis.InitCacheFromCli(c); err != nil {
+			logger.Crit("Failed to connect to redis", "err", err)
+		}
+
+		// events and changes are only published on redis
+		if redis.Pool != nil {
+			broker, err := events.Listen()
+			if err != nil {
+				logger.Crit("Failed to subscribe to events", "err", err)
+			}
+			api.SetStreamBroker(broker)
+
+			changes, err := events.ListenChanges()
+			if err != nil {
+				logger.Crit("Failed to subscribe to changes", "err", err)
+			}
+			go api.RunCacheInvalidation(changes)
+		} else {
+			logger.Warn("Streaming and cache invalidation are disabled without redis")
+		}
+
+		rpcGateway := jsonrpc.NewGateway(c.Bool("rpcallowwrite"))
+		rpcGateway.SetCallLimits(c.Duration("contractcalltimeout"), uint64(c.Int("contractcallgas")))
+		api.SetRPCGateway(rpcGateway)
+
+		api.SetABIUploadToken(c.String("abiuploadtoken"))
+
+		api.SetMetricsToken(c.String("metricstoken"))
+
+		api.SetReadyThresholds(uint64(c.Int("readymaxlagblocks")), c.Duration("readymaxlag"),
+			uint64(c.Int("readymaxrichlistlag")), c.Duration("readymaxensage"))
+		api.SetRedisRequired(c.String("cachebackend") == redis.CACHE_BACKEND_REDIS)
+
+		api.SetContractCallLimits(c.Duration("contractcalltimeout"), uint64(c.Int("contractcallgas")))
+
+		if err := api.SetCacheTTLs(c.StringSlice("cachettl")); err != nil {
+			return err
 		}
+		api.SetCacheConfirmations(uint64(c.Int("cacheconfirmations")))
 
-		if err := redis.InitFromCli(c); err != nil {
-			log.Fatal("Failed to connect to redis", err)
+		api.SetRateLimits(uint64(c.Int("ratelimit")), uint64(c.Int("keyratelimit")))
+		api.SetTrustProxy(c.Bool("trustproxy"))
+		api.SetMaxWebhooks(uint64(c.Int("maxwebhooks")))
+		if err := api.SetMaxLimits(c.StringSlice("maxlimit")); err != nil {
+			return err
 		}
-		redis.CleanupHook()
 
 		if err := api.InitCoinmarketcapDefaultsFromCli(c); err != nil {
-			log.Println(err)
+			logger.Warn("Failed to init the conversion rates", "err", err)
 		}
 
 		return nil
@@ -72,7 +119,7 @@ func (ec explorerContext) startServer() cli.ActionFunc {
 	templ, err := template.New("webapi_bindaddr").Parse("{{.Address}}:{{.Port}}")
 
 	if err != nil {
-		log.Println(err.Error())
+		logger.Error("Failed to parse the bind address template", "err", err)
 	}
 
 	// Part of the init that depends on cmd arguments
@@ -89,46 +136,26 @@ func (ec explorerContext) startServer() cli.ActionFunc {
 		err = templ.Execute(buff, data)
 
 		if err != nil {
-			log.Println(err.Error())
+			logger.Error("Failed to format the bind address", "err", err)
 		}
 
-		log.Printf("* Ebakus explorer started on http://%s", buff.String())
-
 		ec.router = mux.NewRouter().StrictSlash(true)
 
-		// Setup route handlers...
-		ec.router.HandleFunc("/block/{param}", api.HandleBlock).Methods("GET")
-		ec.router.HandleFunc("/transaction/{ref:(?:latest)}", api.HandleTxByAddress).Methods("GET")
-		ec.router.HandleFunc("/transaction/{hash}", api.HandleTxByHash).Methods("GET")
-		ec.router.HandleFunc("/transaction/{ref}/{address}", api.HandleTxByAddress).Methods("GET")
+		api.RegisterRoutes(ec.router)
 
-		ec.router.HandleFunc("/address/{address}", api.HandleAddress).Methods("GET")
-		ec.router.HandleFunc("/stats", api.HandleStats).Methods("GET")
-		ec.router.HandleFunc("/stats/{address}", api.HandleStats).Methods("GET")
+		ec.router.Use(api.RequestIDMiddleware)
+		ec.router.Use(api.AccessLogMiddleware)
+		ec.router.Use(api.MetricsMiddleware)
+		ec.router.Use(api.RateLimitMiddleware)
+		ec.router.Use(api.CacheMiddleware)
 
-		ec.router.HandleFunc("/rich-list", api.HandleRichList).Methods("GET")
+		handler := cors.New(cors.Options{
+			AllowedMethods: []string{"GET", "POST", "DELETE", "HEAD"},
+			AllowedHeaders: []string{"Origin", "Accept", "Content-Type", "X-Requested-With", "X-Webhook-Secret", "X-API-Key", "X-Request-ID"},
+			ExposedHeaders: []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "Retry-After", "X-Request-ID"},
+		}).Handler(ec.router)
 
-		ec.router.HandleFunc("/ens", api.HandleAddReverseRegistrar).Methods("POST")
-		ec.router.HandleFunc("/ens/{address}", api.HandleGetReverseRegistrar).Methods("GET")
-
-		ec.router.HandleFunc("/delegates", api.HandleDelegates).Methods("GET")
-		ec.router.HandleFunc("/delegates/{number}", api.HandleDelegates).Methods("GET")
-
-		ec.router.HandleFunc("/abi/{address}", api.HandleABI).Methods("GET")
-
-		ec.router.HandleFunc("/chain-info", api.HandleChainInfo).Methods("GET")
-
-		ec.router.HandleFunc("/conversion-rate", api.HandleGetConversionRate).Methods("GET")
-
-		handler := cors.Default().Handler(ec.router)
-		err = http.ListenAndServe(buff.String(), handler)
-
-		if err != nil {
-			log.Println(err.Error())
-			return err
-		}
-
-		return nil
+		return serve(c, buff.String(), handler)
 	}
 }
 
@@ -168,6 +195,11 @@ func main() {
 			Usage: "Port where the API is served",
 			Value: 8080,
 		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "dbdriver",
+			Usage: "Storage backend, postgres or memory",
+			Value: "postgres",
+		}),
 		altsrc.NewStringFlag(cli.StringFlag{
 			Name:  "dbhost",
 			Usage: "PostgreSQL database hostname",
@@ -218,6 +250,126 @@ func main() {
 			Name:  "coinmarketcapapikey",
 			Value: "",
 		}),
+		altsrc.NewBoolFlag(cli.BoolFlag{
+			Name:  "rpcallowwrite",
+			Usage: "Forward transactions sent to the JSON-RPC gateway to the node",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "abiuploadtoken",
+			Usage: "Bearer token that authorizes ABI uploads, which are disabled without one",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "metricstoken",
+			Usage: "Bearer token that authorizes reading /metrics, which is public without one",
+		}),
+		altsrc.NewIntFlag(cli.IntFlag{
+			Name:  "readymaxlagblocks",
+			Usage: "Blocks the index may be behind the node before /ready fails, 0 disables the check",
+			Value: 300,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "readymaxlag",
+			Usage: "Time the index may be behind the node before /ready fails, 0 disables the check",
+			Value: 5 * time.Minute,
+		}),
+		altsrc.NewIntFlag(cli.IntFlag{
+			Name:  "readymaxrichlistlag",
+			Usage: "Blocks since the rich list was computed before /ready fails, 0 disables the check",
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "readymaxensage",
+			Usage: "Time since the ENS names were synced before /ready fails, 0 disables the check",
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "contractcalltimeout",
+			Usage: "Timeout of the contract calls made for /contract/{address}/call and of eth_call and eth_estimateGas on /rpc",
+			Value: 5 * time.Second,
+		}),
+		altsrc.NewIntFlag(cli.IntFlag{
+			Name:  "contractcallgas",
+			Usage: "Gas given to the contract calls made for /contract/{address}/call, and cap of the gas of eth_call and eth_estimateGas on /rpc",
+			Value: 10000000,
+		}),
+		altsrc.NewStringSliceFlag(cli.StringSliceFlag{
+			Name:  "cachettl",
+			Usage: "Overrides the cache TTL of a route as path=ttl, e.g. /stats=5s, where 0 disables caching",
+		}),
+		altsrc.NewIntFlag(cli.IntFlag{
+			Name:  "cacheconfirmations",
+			Usage: "Confirmations after which the responses for a block are cached for a day, 0 disables it",
+			Value: 30,
+		}),
+		altsrc.NewIntFlag(cli.IntFlag{
+			Name:  "ratelimit",
+			Usage: "Requests per minute of each IP without an API key, 0 disables the limit",
+			Value: 300,
+		}),
+		altsrc.NewIntFlag(cli.IntFlag{
+			Name:  "keyratelimit",
+			Usage: "Requests per minute of each API key without a limit of its own, 0 disables the limit",
+			Value: 1200,
+		}),
+		altsrc.NewIntFlag(cli.IntFlag{
+			Name:  "maxwebhooks",
+			Usage: "Webhooks each API key can create, 0 for unlimited",
+			Value: 20,
+		}),
+		altsrc.NewBoolFlag(cli.BoolFlag{
+			Name:  "trustproxy",
+			Usage: "Take the client IP from X-Forwarded-For, when running behind a proxy",
+		}),
+		altsrc.NewStringSliceFlag(cli.StringSliceFlag{
+			Name:  "maxlimit",
+			Usage: "Overrides the maximum limit of a route as path=limit, e.g. /rich-list=500, where 0 lifts it",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "cachebackend",
+			Usage: "Cache backend, auto, redis or memory. Auto falls back to memory while redis is unavailable",
+			Value: "auto",
+		}),
+		altsrc.NewIntFlag(cli.IntFlag{
+			Name:  "cachesize",
+			Usage: "Bytes the in-memory cache holds",
+			Value: redis.DefaultCacheSize,
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "loglevel",
+			Usage: "Level of the logged lines, trace, debug, info, warn, error or crit",
+			Value: "info",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "logformat",
+			Usage: "Format of the logged lines, logfmt or json",
+			Value: logger.LOG_FORMAT_LOGFMT,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "readtimeout",
+			Usage: "Time to read a request, including its body, except for streams and exports, 0 disables the timeout",
+			Value: 15 * time.Second,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "writetimeout",
+			Usage: "Time to write a response, except for streams and exports, 0 disables the timeout",
+			Value: 60 * time.Second,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "idletimeout",
+			Usage: "Time to keep idle connections open for the next request",
+			Value: 120 * time.Second,
+		}),
+		altsrc.NewDurationFlag(cli.DurationFlag{
+			Name:  "shutdowntimeout",
+			Usage: "Time to wait for the requests in flight on SIGINT or SIGTERM",
+			Value: 30 * time.Second,
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "tlscert",
+			Usage: "Certificate file to serve over TLS with, along with tlskey",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:  "tlskey",
+			Usage: "Private key file of the TLS certificate",
+		}),
 		cli.StringFlag{
 			Name:  "config",
 			Value: "config.yaml",
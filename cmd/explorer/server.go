@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	api "github.com/ebakus/ebakus-block-explorer-backend/api"
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	ipcModule "github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/urfave/cli"
+)
+
+// serve serves handler on addr, over TLS when a certificate is configured,
+// until SIGINT or SIGTERM. It then stops taking requests, waits for the ones
+// in flight up to the shutdown timeout and closes the connections to Redis,
+// the database and the node, in that order.
+func serve(c *cli.Context, addr string, handler http.Handler) error {
+	certFile, keyFile := c.String("tlscert"), c.String("tlskey")
+	if (certFile == "") != (keyFile == "") {
+		err := errors.New("tlscert and tlskey must be set together")
+		logger.Error("Failed to serve", "err", err)
+		return err
+	}
+
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  c.Duration("readtimeout"),
+		WriteTimeout: c.Duration("writetimeout"),
+		IdleTimeout:  c.Duration("idletimeout"),
+		ConnContext:  api.ConnContext,
+	}
+	server.RegisterOnShutdown(api.CloseStreams)
+
+	scheme := "http"
+	if certFile != "" {
+		scheme = "https"
+		// streams and exports lift the timeouts of their connection,
+		// which HTTP/2 shares between requests
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if certFile != "" {
+			errCh <- server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			errCh <- server.ListenAndServe()
+		}
+	}()
+
+	logger.Info("Ebakus explorer started", "url", scheme+"://"+addr)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-errCh:
+		logger.Error("Failed to serve", "err", err)
+		closeClients()
+		return err
+
+	case sig := <-signals:
+		logger.Info("Shutting down", "signal", sig, "timeout", c.Duration("shutdowntimeout"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Duration("shutdowntimeout"))
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Warn("Requests in flight were cut off", "err", err)
+	}
+	closeClients()
+
+	logger.Info("Ebakus explorer stopped")
+	return nil
+}
+
+// closeClients closes the connections to Redis, the database and the node
+func closeClients() {
+	if err := redis.Close(); err != nil {
+		logger.Error("Failed to close the redis pool", "err", err)
+	}
+	if err := db.Close(); err != nil {
+		logger.Error("Failed to close the database", "err", err)
+	}
+	ipcModule.Close()
+}
@@ -0,0 +1,171 @@
+// Package contracts resolves the ABIs of contracts and decodes the calls
+// and the events of their transactions.
+package contracts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/ebakus/go-ebakus/accounts/abi"
+	"github.com/ebakus/go-ebakus/common"
+)
+
+const (
+	// abiCacheSeconds is how long the ABIs fetched from the node are cached
+	abiCacheSeconds = 60 * 60 * 24
+
+	// partialABITransactions is how many of the latest transactions to a
+	// contract are looked at to derive its partial ABI
+	partialABITransactions = 200
+)
+
+var (
+	// ErrMissingIPC is returned when there is no node to fetch ABIs from
+	ErrMissingIPC = errors.New("IPC connection is not initialized")
+
+	// ErrMissingStore is returned when there is no DB client to read uploaded ABIs from
+	ErrMissingStore = errors.New("DBClient is not initialized")
+
+	// ErrABINotFound is returned when neither the node nor the registry has an ABI
+	ErrABINotFound = errors.New("No ABI found for the contract")
+
+	// ErrEmptyABI is returned for ABIs without any entries
+	ErrEmptyABI = errors.New("The ABI has no entries")
+)
+
+// ParseABIJSON validates an ABI and returns it compacted
+func ParseABIJSON(data []byte) ([]byte, error) {
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrEmptyABI
+	}
+
+	var parsed abi.ABI
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(entries)
+}
+
+// GetNodeABIJSON returns the ABI the node has for a contract, cached
+func GetNodeABIJSON(ctx context.Context, address common.Address) ([]byte, error) {
+	redisKey := "abi:" + address.Hex()
+
+	if res, ok := redis.GetCache().Get(redisKey); ok {
+		return res, nil
+	}
+
+	node := ipc.GetIPCContext(ctx)
+	if node == nil {
+		return nil, ErrMissingIPC
+	}
+
+	// the node fails, or returns an empty string, for addresses without an ABI
+	raw, err := node.GetABIForContract(address)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := ParseABIJSON([]byte(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	redis.GetCache().Set(redisKey, out, abiCacheSeconds*time.Second)
+
+	return out, nil
+}
+
+// GetRegistryABIJSON returns the ABI uploaded for a contract
+func GetRegistryABIJSON(ctx context.Context, address common.Address) ([]byte, error) {
+	dbc := db.GetClientContext(ctx)
+	if dbc == nil {
+		return nil, ErrMissingStore
+	}
+
+	data, err := dbc.GetContractABI(address)
+	if err == sql.ErrNoRows {
+		return nil, ErrABINotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return []byte(data), nil
+}
+
+// GetABIJSON returns the ABI of a contract from the node, or else from the
+// registry of uploaded ABIs, along with its source
+func GetABIJSON(ctx context.Context, address common.Address) ([]byte, string, error) {
+	if data, err := GetNodeABIJSON(ctx, address); err == nil {
+		return data, models.ABI_SOURCE_NODE, nil
+	}
+
+	data, err := GetRegistryABIJSON(ctx, address)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, models.ABI_SOURCE_REGISTRY, nil
+}
+
+// GetABI returns the parsed ABI of a contract along with its source
+func GetABI(ctx context.Context, address common.Address) (*abi.ABI, string, error) {
+	data, source, err := GetABIJSON(ctx, address)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var contractABI abi.ABI
+	if err := json.Unmarshal(data, &contractABI); err != nil {
+		return nil, "", err
+	}
+
+	return &contractABI, source, nil
+}
+
+// GetPartialABIJSON derives an ABI from the well known signatures of the
+// methods called in the latest transactions to a contract, or returns
+// ErrABINotFound when none of them is known
+func GetPartialABIJSON(ctx context.Context, address common.Address) ([]byte, error) {
+	dbc := db.GetClientContext(ctx)
+	if dbc == nil {
+		return nil, ErrMissingStore
+	}
+
+	txs, err := dbc.GetTransactionsByAddress(address.Hex(), models.ADDRESS_TO, nil, partialABITransactions, "desc")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	entries := make([]json.RawMessage, 0)
+
+	for _, txf := range txs {
+		if len(txf.Tx.Input) < 4 {
+			continue
+		}
+
+		selector := string(txf.Tx.Input[:4])
+		if entry, ok := signatureEntries[selector]; ok && !seen[selector] {
+			seen[selector] = true
+			entries = append(entries, entry)
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrABINotFound
+	}
+
+	return json.Marshal(entries)
+}
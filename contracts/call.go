@@ -0,0 +1,244 @@
+package contracts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/accounts/abi"
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+// ArgumentError is returned for calls the ABI can't encode, e.g. an
+// unknown function or arguments of the wrong type
+type ArgumentError struct {
+	Message string
+}
+
+func (e *ArgumentError) Error() string {
+	return e.Message
+}
+
+func argumentError(format string, args ...interface{}) error {
+	return &ArgumentError{Message: fmt.Sprintf(format, args...)}
+}
+
+// findMethod looks a method up by its name, or by its signature
+// (e.g. "balanceOf(address)") for overloaded methods
+func findMethod(contractABI *abi.ABI, function string) (*abi.Method, error) {
+	for _, method := range contractABI.Methods {
+		if method.Sig() == function {
+			return &method, nil
+		}
+	}
+
+	if method, ok := contractABI.Methods[function]; ok {
+		return &method, nil
+	}
+
+	return nil, argumentError("function %s not found in the ABI", function)
+}
+
+// Call runs a method of a contract with eth_call at blockNumber, or the
+// latest block when it is nil. The arguments are JSON values encoded with
+// the contract's ABI, and the outputs are decoded the same way transaction
+// inputs are.
+func Call(ctx context.Context, address common.Address, function string, args []json.RawMessage, gas uint64, blockNumber *uint64) (*models.CallResult, error) {
+	node := ipc.GetIPCContext(ctx)
+	if node == nil {
+		return nil, ErrMissingIPC
+	}
+
+	contractABI, source, err := GetABI(ctx, address)
+	if err == ErrABINotFound {
+		return nil, argumentError("no ABI found for %s", address.Hex())
+	} else if err != nil {
+		return nil, err
+	}
+
+	method, err := findMethod(contractABI, function)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) != len(method.Inputs) {
+		return nil, argumentError("%s expects %d arguments, got %d", method.Sig(), len(method.Inputs), len(args))
+	}
+
+	values := make([]interface{}, len(args))
+	for i, input := range method.Inputs {
+		if values[i], err = convertArgument(input.Type, args[i]); err != nil {
+			return nil, argumentError("argument %d (%s %s): %s", i, input.Type.String(), input.Name, err.Error())
+		}
+	}
+
+	packed, err := method.Inputs.Pack(values...)
+	if err != nil {
+		return nil, argumentError("%s", err.Error())
+	}
+
+	output, err := node.CallContract(ctx, address, append(method.ID(), packed...), gas, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CallResult{
+		Method:    method.RawName,
+		Signature: method.Sig(),
+		Source:    source,
+		Output:    hexutil.Encode(output),
+		Outputs:   make([]models.DecodedParam, 0, len(method.Outputs)),
+	}
+
+	// calls to accounts without code, or that ran out of gas, return nothing
+	if len(method.Outputs) > 0 && len(output) == 0 {
+		return result, nil
+	}
+
+	decoded, err := method.Outputs.UnpackValues(output)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, out := range method.Outputs {
+		result.Outputs = append(result.Outputs, models.DecodedParam{Name: out.Name, Type: out.Type.String(), Value: formatValue(decoded[i])})
+	}
+
+	return result, nil
+}
+
+// convertArgument converts a JSON value to the Go type the ABI packs for t.
+// Numbers may be given as JSON numbers or as decimal or 0x prefixed hex
+// strings, and bytes as hex strings.
+func convertArgument(t abi.Type, raw json.RawMessage) (interface{}, error) {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		return convertNumber(t, raw)
+
+	case abi.BoolTy:
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return nil, fmt.Errorf("expected a boolean")
+		}
+		return b, nil
+
+	case abi.StringTy:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, fmt.Errorf("expected a string")
+		}
+		return s, nil
+
+	case abi.AddressTy:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil || !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("expected an address")
+		}
+		return common.HexToAddress(s), nil
+
+	case abi.BytesTy:
+		return convertBytes(raw)
+
+	case abi.FixedBytesTy:
+		b, err := convertBytes(raw)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > t.Size {
+			return nil, fmt.Errorf("expected at most %d bytes", t.Size)
+		}
+		array := reflect.New(t.Type).Elem()
+		reflect.Copy(array, reflect.ValueOf(b))
+		return array.Interface(), nil
+
+	case abi.SliceTy, abi.ArrayTy:
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("expected an array")
+		}
+
+		var list reflect.Value
+		if t.T == abi.SliceTy {
+			list = reflect.MakeSlice(t.Type, len(items), len(items))
+		} else if len(items) != t.Size {
+			return nil, fmt.Errorf("expected %d items", t.Size)
+		} else {
+			list = reflect.New(t.Type).Elem()
+		}
+
+		for i, item := range items {
+			value, err := convertArgument(*t.Elem, item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %s", i, err.Error())
+			}
+			list.Index(i).Set(reflect.ValueOf(value))
+		}
+		return list.Interface(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported type")
+}
+
+func convertNumber(t abi.Type, raw json.RawMessage) (interface{}, error) {
+	s := string(raw)
+	if err := json.Unmarshal(raw, &s); err != nil {
+		// not a string, use the JSON number as is
+		s = string(raw)
+	}
+
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok && strings.HasPrefix(s, "0x") {
+		n, ok = new(big.Int).SetString(s[2:], 16)
+	}
+	if !ok {
+		return nil, fmt.Errorf("expected an integer")
+	}
+
+	if t.T == abi.UintTy && n.Sign() < 0 {
+		return nil, fmt.Errorf("expected an unsigned integer")
+	}
+
+	bits := n.BitLen()
+	if t.T == abi.IntTy {
+		// the sign takes a bit, and -2^(n-1) still fits in n bits
+		if n.Sign() < 0 {
+			bits = new(big.Int).Not(n).BitLen()
+		}
+		bits++
+	}
+	if bits > t.Size {
+		return nil, fmt.Errorf("overflows %s", t.String())
+	}
+
+	if t.Type == reflect.TypeOf((*big.Int)(nil)) {
+		return n, nil
+	}
+
+	value := reflect.New(t.Type).Elem()
+	if t.T == abi.IntTy {
+		value.SetInt(n.Int64())
+	} else {
+		value.SetUint(n.Uint64())
+	}
+	return value.Interface(), nil
+}
+
+func convertBytes(raw json.RawMessage) ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("expected a hex string")
+	}
+
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("expected a hex string")
+	}
+	return b, nil
+}
@@ -0,0 +1,98 @@
+package contracts
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ebakus/go-ebakus/accounts/abi"
+)
+
+// words concatenates 32 byte words, given in hex and left padded with zeros
+func words(values ...string) string {
+	var b strings.Builder
+	for _, v := range values {
+		b.WriteString(strings.Repeat("0", 64-len(v)))
+		b.WriteString(v)
+	}
+	return b.String()
+}
+
+func TestConvertArgument(t *testing.T) {
+	ff := strings.Repeat("f", 64)
+
+	for _, c := range []struct {
+		typ, arg string
+		// packed is the ABI encoding of the argument, empty when the
+		// conversion fails
+		packed string
+	}{
+		// signed integers take a bit for the sign
+		{"int8", `127`, words("7f")},
+		{"int8", `-128`, ff[:62] + "80"},
+		{"int8", `128`, ""},
+		{"int8", `-129`, ""},
+		{"int64", `"-1"`, ff},
+		{"int256", `"-57896044618658097711785492504343953926634992332820282019728792003956564819968"`, "8" + strings.Repeat("0", 63)},
+		{"int256", `"57896044618658097711785492504343953926634992332820282019728792003956564819968"`, ""},
+
+		{"uint8", `255`, words("ff")},
+		{"uint8", `256`, ""},
+		{"uint8", `-1`, ""},
+		{"uint64", `"0x10"`, words("10")},
+		{"uint64", `"18446744073709551615"`, words("ffffffffffffffff")},
+		{"uint64", `"18446744073709551616"`, ""},
+		{"uint256", `"0x` + ff + `"`, ff},
+		{"uint256", `"115792089237316195423570985008687907853269984665640564039457584007913129639936"`, ""},
+		{"uint256", `1.5`, ""},
+		{"uint256", `"0xzz"`, ""},
+
+		{"bool", `true`, words("1")},
+		{"bool", `"true"`, ""},
+		{"address", `"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"`, words(strings.Repeat("a", 40))},
+		{"address", `"0xaaaa"`, ""},
+		{"string", `"abc"`, words("20", "3") + "616263" + strings.Repeat("0", 58)},
+
+		// fixed bytes are right padded, and may be shorter than their size
+		{"bytes4", `"0x01020304"`, "01020304" + strings.Repeat("0", 56)},
+		{"bytes4", `"0x01"`, "01" + strings.Repeat("0", 62)},
+		{"bytes4", `"0x0102030405"`, ""},
+		{"bytes32", `"0x` + ff + `"`, ff},
+		{"bytes32", `"0x` + ff + `00"`, ""},
+		{"bytes4", `"01020304"`, ""},
+		{"bytes", `"0x0102"`, words("20", "2") + "0102" + strings.Repeat("0", 60)},
+
+		{"uint8[2]", `[1, "2"]`, words("1", "2")},
+		{"uint8[2]", `[1]`, ""},
+		{"uint8[2]", `[1, 256]`, ""},
+		{"uint256[]", `["1", "0x2"]`, words("20", "2", "1", "2")},
+		{"uint256[]", `[]`, words("20", "0")},
+		{"uint256[]", `"1"`, ""},
+		{"int8[][2]", `[[-1], []]`, words("20", "40", "80", "1", ff, "0")},
+	} {
+		typ, err := abi.NewType(c.typ, "", nil)
+		if err != nil {
+			t.Fatalf("%s: %v", c.typ, err)
+		}
+
+		value, err := convertArgument(typ, json.RawMessage(c.arg))
+		if c.packed == "" {
+			if err == nil {
+				t.Errorf("convertArgument(%s, %s) = %v, want an error", c.typ, c.arg, value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("convertArgument(%s, %s) failed: %v", c.typ, c.arg, err)
+			continue
+		}
+
+		packed, err := abi.Arguments{{Type: typ}}.Pack(value)
+		if err != nil {
+			t.Errorf("packing %s %s failed: %v", c.typ, c.arg, err)
+		} else if got := hex.EncodeToString(packed); got != c.packed {
+			t.Errorf("%s %s packed as\n%s\nwant\n%s", c.typ, c.arg, got, c.packed)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/accounts/abi"
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+// errTopicsMismatch is returned when a log doesn't have a topic for each
+// indexed argument of the event, e.g. an ERC721 Transfer matched against
+// the ERC20 one
+var errTopicsMismatch = errors.New("topics don't match the indexed arguments")
+
+// abiSource is an ABI to decode with, and where it came from
+type abiSource struct {
+	abi    *abi.ABI
+	source string
+}
+
+// Decoder decodes transaction inputs and logs. It remembers the ABIs it
+// looked up, so a list of transactions to the same contract fetches it
+// once, and is meant to be used for a single request.
+type Decoder struct {
+	ctx  context.Context
+	abis map[common.Address]*abiSource
+}
+
+// NewDecoder creates a Decoder for a request, whose context carries the
+// logger of the lookups
+func NewDecoder(ctx context.Context) *Decoder {
+	return &Decoder{ctx: ctx, abis: make(map[common.Address]*abiSource)}
+}
+
+// sources returns the ABIs to try for a contract, most specific first.
+// Addresses without an ABI, like plain accounts, only get the table of
+// well known signatures.
+func (d *Decoder) sources(address common.Address) []abiSource {
+	contractABI, ok := d.abis[address]
+	if !ok {
+		if parsed, source, err := GetABI(d.ctx, address); err == nil {
+			contractABI = &abiSource{parsed, source}
+		}
+		d.abis[address] = contractABI
+	}
+
+	sources := make([]abiSource, 0, 2)
+	if contractABI != nil {
+		sources = append(sources, *contractABI)
+	}
+	return append(sources, abiSource{signaturesABI, models.ABI_SOURCE_SIGNATURES})
+}
+
+// Decode sets the decoded input of a transaction, and its decoded logs
+// when withLogs is set, which costs a call to the node
+func (d *Decoder) Decode(txf *models.TransactionFull, withLogs bool) {
+	if txf == nil || txf.Tx == nil {
+		return
+	}
+
+	txf.DecodedInput = d.DecodeInput(txf.Tx)
+
+	if withLogs {
+		node := ipc.GetIPCContext(d.ctx)
+		if node == nil {
+			return
+		}
+		if logs, err := node.GetTransactionLogs(txf.Tx.Hash); err == nil {
+			txf.DecodedLogs = d.DecodeLogs(logs)
+		}
+	}
+}
+
+// DecodeInput decodes the method call of a transaction, or returns nil
+// when it is a plain transfer, a contract creation or an unknown method
+func (d *Decoder) DecodeInput(tx *models.Transaction) *models.DecodedInput {
+	if tx.To == nil || len(tx.Input) < 4 {
+		return nil
+	}
+
+	for _, s := range d.sources(*tx.To) {
+		method, err := s.abi.MethodById(tx.Input)
+		if err != nil {
+			continue
+		}
+
+		values, err := method.Inputs.UnpackValues(tx.Input[4:])
+		if err != nil {
+			continue
+		}
+
+		params := make([]models.DecodedParam, len(method.Inputs))
+		for i, input := range method.Inputs {
+			params[i] = models.DecodedParam{Name: input.Name, Type: input.Type.String(), Value: formatValue(values[i])}
+		}
+
+		return &models.DecodedInput{
+			Method:    method.RawName,
+			Signature: method.Sig(),
+			Selector:  hexutil.Encode(method.ID()),
+			Source:    s.source,
+			Params:    params,
+		}
+	}
+
+	return nil
+}
+
+// DecodeLogs decodes the logs of known events, skipping the rest
+func (d *Decoder) DecodeLogs(logs []models.Log) []models.DecodedLog {
+	result := make([]models.DecodedLog, 0, len(logs))
+
+	for _, log := range logs {
+		if len(log.Topics) == 0 {
+			continue
+		}
+
+		for _, s := range d.sources(log.Address) {
+			event, err := s.abi.EventByID(log.Topics[0])
+			if err != nil {
+				continue
+			}
+
+			params, err := decodeEvent(event, log)
+			if err != nil {
+				continue
+			}
+
+			result = append(result, models.DecodedLog{
+				LogIndex:  uint64(log.LogIndex),
+				Address:   log.Address,
+				Event:     event.RawName,
+				Signature: event.Sig(),
+				Source:    s.source,
+				Params:    params,
+			})
+			break
+		}
+	}
+
+	return result
+}
+
+// decodeEvent decodes the indexed arguments of an event from the topics
+// of a log and the rest from its data
+func decodeEvent(event *abi.Event, log models.Log) ([]models.DecodedParam, error) {
+	topics := log.Topics[1:]
+	if len(topics) != len(event.Inputs)-event.Inputs.LengthNonIndexed() {
+		return nil, errTopicsMismatch
+	}
+
+	values, err := event.Inputs.UnpackValues(log.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]models.DecodedParam, len(event.Inputs))
+	for i, input := range event.Inputs {
+		param := models.DecodedParam{Name: input.Name, Type: input.Type.String(), Indexed: input.Indexed}
+
+		if !input.Indexed {
+			param.Value, values = formatValue(values[0]), values[1:]
+		} else {
+			topic := topics[0]
+			topics = topics[1:]
+
+			param.Value = topic.Hex()
+			// reference types are indexed by their hash, value types
+			// are encoded in the topic as in data
+			if !isReferenceType(input.Type) {
+				if value, err := (abi.Arguments{{Type: input.Type}}).UnpackValues(topic.Bytes()); err == nil {
+					param.Value = formatValue(value[0])
+				}
+			}
+		}
+
+		params[i] = param
+	}
+
+	return params, nil
+}
+
+func isReferenceType(t abi.Type) bool {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return true
+	}
+	return false
+}
+
+// formatValue converts a decoded value to its JSON representation:
+// numbers as decimal strings, bytes and addresses as hex
+func formatValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case common.Address:
+		return hexutil.Encode(v.Bytes())
+	case common.Hash:
+		return v.Hex()
+	case []byte:
+		return hexutil.Encode(v)
+	case bool, string:
+		return v
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprint(value)
+
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return hexutil.Encode(b)
+		}
+		fallthrough
+
+	case reflect.Slice:
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = formatValue(rv.Index(i).Interface())
+		}
+		return items
+
+	case reflect.Struct:
+		fields := make(map[string]interface{}, rv.NumField())
+		for i := 0; i < rv.NumField(); i++ {
+			fields[rv.Type().Field(i).Name] = formatValue(rv.Field(i).Interface())
+		}
+		return fields
+	}
+
+	return value
+}
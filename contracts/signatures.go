@@ -0,0 +1,71 @@
+package contracts
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/ebakus/go-ebakus/accounts/abi"
+)
+
+// signaturesJSON lists well known methods and events, used to decode the
+// transactions of contracts whose ABI is not available
+const signaturesJSON = `[
+  {"type": "function", "name": "transfer", "inputs": [{"name": "to", "type": "address"}, {"name": "value", "type": "uint256"}]},
+  {"type": "function", "name": "transferFrom", "inputs": [{"name": "from", "type": "address"}, {"name": "to", "type": "address"}, {"name": "value", "type": "uint256"}]},
+  {"type": "function", "name": "approve", "inputs": [{"name": "spender", "type": "address"}, {"name": "value", "type": "uint256"}]},
+  {"type": "function", "name": "increaseAllowance", "inputs": [{"name": "spender", "type": "address"}, {"name": "addedValue", "type": "uint256"}]},
+  {"type": "function", "name": "decreaseAllowance", "inputs": [{"name": "spender", "type": "address"}, {"name": "subtractedValue", "type": "uint256"}]},
+  {"type": "function", "name": "mint", "inputs": [{"name": "to", "type": "address"}, {"name": "value", "type": "uint256"}]},
+  {"type": "function", "name": "burn", "inputs": [{"name": "value", "type": "uint256"}]},
+  {"type": "function", "name": "deposit", "inputs": []},
+  {"type": "function", "name": "withdraw", "inputs": [{"name": "value", "type": "uint256"}]},
+  {"type": "function", "name": "safeTransferFrom", "inputs": [{"name": "from", "type": "address"}, {"name": "to", "type": "address"}, {"name": "tokenId", "type": "uint256"}]},
+  {"type": "function", "name": "setApprovalForAll", "inputs": [{"name": "operator", "type": "address"}, {"name": "approved", "type": "bool"}]},
+  {"type": "function", "name": "transferOwnership", "inputs": [{"name": "newOwner", "type": "address"}]},
+  {"type": "function", "name": "renounceOwnership", "inputs": []},
+  {"type": "function", "name": "setName", "inputs": [{"name": "name", "type": "string"}]},
+  {"type": "event", "name": "Transfer", "inputs": [{"name": "from", "type": "address", "indexed": true}, {"name": "to", "type": "address", "indexed": true}, {"name": "value", "type": "uint256"}]},
+  {"type": "event", "name": "Approval", "inputs": [{"name": "owner", "type": "address", "indexed": true}, {"name": "spender", "type": "address", "indexed": true}, {"name": "value", "type": "uint256"}]},
+  {"type": "event", "name": "ApprovalForAll", "inputs": [{"name": "owner", "type": "address", "indexed": true}, {"name": "operator", "type": "address", "indexed": true}, {"name": "approved", "type": "bool"}]},
+  {"type": "event", "name": "OwnershipTransferred", "inputs": [{"name": "previousOwner", "type": "address", "indexed": true}, {"name": "newOwner", "type": "address", "indexed": true}]},
+  {"type": "event", "name": "Deposit", "inputs": [{"name": "dst", "type": "address", "indexed": true}, {"name": "value", "type": "uint256"}]},
+  {"type": "event", "name": "Withdrawal", "inputs": [{"name": "src", "type": "address", "indexed": true}, {"name": "value", "type": "uint256"}]}
+]`
+
+var (
+	// signaturesABI looks methods up by their 4-byte selector and events
+	// by their 32-byte topic
+	signaturesABI = mustParseABI(signaturesJSON)
+
+	// signatureEntries maps the 4-byte selectors of the methods to their
+	// ABI entry, to build partial ABIs from
+	signatureEntries = mustIndexMethods(signaturesJSON)
+)
+
+func mustParseABI(data string) *abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(data))
+	if err != nil {
+		panic(err)
+	}
+	return &parsed
+}
+
+func mustIndexMethods(data string) map[string]json.RawMessage {
+	var entries []json.RawMessage
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		panic(err)
+	}
+
+	index := make(map[string]json.RawMessage)
+	for _, entry := range entries {
+		parsed := mustParseABI("[" + string(entry) + "]")
+		for _, method := range parsed.Methods {
+			compacted, err := json.Marshal(entry)
+			if err != nil {
+				panic(err)
+			}
+			index[string(method.ID())] = compacted
+		}
+	}
+	return index
+}
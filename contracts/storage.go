@@ -0,0 +1,154 @@
+package contracts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/accounts/abi"
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+// uint256Type is the type slots are parsed as
+var uint256Type, _ = abi.NewType("uint256", "", nil)
+
+// StorageStep is a step from a slot to the one of a mapping value or an
+// array element. Mapping steps have a Key of KeyType, which is an ABI type
+// like address, uint256 or string. Array steps have an Index in a dynamic
+// array whose elements take ElementSlots slots, one when unset.
+type StorageStep struct {
+	Key          json.RawMessage `json:"key"`
+	KeyType      string          `json:"keyType"`
+	Index        *uint64         `json:"index"`
+	ElementSlots uint64          `json:"elementSlots"`
+}
+
+// StorageLayout describes where a variable is stored, as solidity lays it
+// out: the slot of the state variable, the path through mappings and arrays
+// and the offset of the member in a struct. Type is the type to decode the
+// value as, one of address, uint, bool and bytes.
+type StorageLayout struct {
+	Slot   json.RawMessage `json:"slot"`
+	Path   []StorageStep   `json:"path"`
+	Offset uint64          `json:"offset"`
+	Type   string          `json:"type"`
+}
+
+// ResolveSlot computes the slot a layout points to
+func ResolveSlot(layout StorageLayout) (common.Hash, error) {
+	base, err := convertNumber(uint256Type, layout.Slot)
+	if err != nil {
+		return common.Hash{}, argumentError("slot: %s", err.Error())
+	}
+	slot := common.BigToHash(base.(*big.Int))
+
+	for i, step := range layout.Path {
+		if step.Index != nil {
+			elementSlots := step.ElementSlots
+			if elementSlots == 0 {
+				elementSlots = 1
+			}
+			slot = ipc.ArraySlot(slot, *step.Index, elementSlots)
+			continue
+		}
+
+		key, err := encodeMappingKey(step.KeyType, step.Key)
+		if err != nil {
+			return common.Hash{}, argumentError("path %d: %s", i, err.Error())
+		}
+		slot = ipc.MappingSlot(slot, key)
+	}
+
+	return ipc.OffsetSlot(slot, new(big.Int).SetUint64(layout.Offset)), nil
+}
+
+// encodeMappingKey encodes a key the way solidity hashes it, padded to 32
+// bytes for value types and as is for strings and bytes
+func encodeMappingKey(keyType string, raw json.RawMessage) ([]byte, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("missing key")
+	}
+
+	t, err := abi.NewType(keyType, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key type %q", keyType)
+	}
+
+	value, err := convertArgument(t, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.T {
+	case abi.StringTy:
+		return []byte(value.(string)), nil
+	case abi.BytesTy:
+		return value.([]byte), nil
+	case abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return nil, fmt.Errorf("invalid key type %q", keyType)
+	}
+
+	return abi.Arguments{{Type: t}}.Pack(value)
+}
+
+// ReadStorage reads the slot a layout points to at blockNumber, or at the
+// latest block when it is nil, and decodes its value
+func ReadStorage(ctx context.Context, address common.Address, layout StorageLayout, blockNumber *uint64) (*models.StorageValue, error) {
+	node := ipc.GetIPCContext(ctx)
+	if node == nil {
+		return nil, ErrMissingIPC
+	}
+
+	if layout.Type == "" {
+		layout.Type = "bytes"
+	}
+
+	slot, err := ResolveSlot(layout)
+	if err != nil {
+		return nil, err
+	}
+
+	// fail before calling the node for unknown types
+	if _, err := decodeStorageValue(common.Hash{}, layout.Type); err != nil {
+		return nil, err
+	}
+
+	raw, err := node.GetStorageAt(address, slot, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := decodeStorageValue(raw, layout.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StorageValue{
+		Slot:  slot.Hex(),
+		Raw:   raw.Hex(),
+		Type:  layout.Type,
+		Value: value,
+	}, nil
+}
+
+// decodeStorageValue decodes a slot that holds a single value, which
+// solidity keeps in the lower order bytes
+func decodeStorageValue(raw common.Hash, valueType string) (interface{}, error) {
+	switch valueType {
+	case "address":
+		return formatValue(common.BytesToAddress(raw.Bytes())), nil
+	case "uint":
+		return formatValue(raw.Big()), nil
+	case "bool":
+		return raw.Big().Sign() != 0, nil
+	case "bytes":
+		return hexutil.Encode(raw.Bytes()), nil
+	}
+
+	return nil, argumentError("invalid type %q, expected address, uint, bool or bytes", valueType)
+}
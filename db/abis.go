@@ -0,0 +1,21 @@
+package db
+
+import (
+	"github.com/ebakus/go-ebakus/common"
+)
+
+// InsertContractABI stores the ABI of a contract, replacing the one
+// uploaded before
+func (cli *DBClient) InsertContractABI(address common.Address, abi string, uploadedAt uint64) error {
+	query := `INSERT INTO contract_abis(address, abi, uploaded_at) VALUES($1, $2, $3)
+		ON CONFLICT (address) DO UPDATE SET abi = EXCLUDED.abi, uploaded_at = EXCLUDED.uploaded_at`
+	_, err := cli.db.Exec("InsertContractABI", query, address.Bytes(), abi, uploadedAt)
+	return err
+}
+
+// GetContractABI returns the uploaded ABI of a contract, or sql.ErrNoRows
+func (cli *DBClient) GetContractABI(address common.Address) (string, error) {
+	var abi string
+	err := cli.db.QueryRow("GetContractABI", "SELECT abi FROM contract_abis WHERE address = $1", address.Bytes()).Scan(&abi)
+	return abi, err
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+)
+
+const apiKeyColumns = "id, name, key_hash, prefix, rate_limit, daily_quota, created_at, revoked_at"
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := row.Scan(&key.ID, &key.Name, &key.Hash, &key.Prefix, &key.RateLimit, &key.DailyQuota, &key.CreatedAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// InsertAPIKey stores a new API key and sets its ID
+func (cli *DBClient) InsertAPIKey(key *models.APIKey) error {
+	query := "INSERT INTO api_keys(name, key_hash, prefix, rate_limit, daily_quota, created_at) VALUES($1, $2, $3, $4, $5, $6) RETURNING id"
+	return cli.db.QueryRow("InsertAPIKey", query, key.Name, key.Hash, key.Prefix, key.RateLimit, key.DailyQuota, key.CreatedAt).Scan(&key.ID)
+}
+
+// GetAPIKey returns the API key with the id, or sql.ErrNoRows
+func (cli *DBClient) GetAPIKey(id uint64) (*models.APIKey, error) {
+	row := cli.db.QueryRow("GetAPIKey", "SELECT "+apiKeyColumns+" FROM api_keys WHERE id = $1", id)
+	return scanAPIKey(row)
+}
+
+// GetAPIKeyByHash returns the API key with the hash, or sql.ErrNoRows
+func (cli *DBClient) GetAPIKeyByHash(hash string) (*models.APIKey, error) {
+	row := cli.db.QueryRow("GetAPIKeyByHash", "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = $1", hash)
+	return scanAPIKey(row)
+}
+
+// GetAPIKeys returns all the API keys, revoked ones included
+func (cli *DBClient) GetAPIKeys() ([]models.APIKey, error) {
+	rows, err := cli.db.Query("GetAPIKeys", "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.APIKey, 0)
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *key)
+	}
+
+	return result, rows.Err()
+}
+
+// RevokeAPIKey marks the API key as revoked, or returns sql.ErrNoRows
+func (cli *DBClient) RevokeAPIKey(id uint64, revokedAt uint64) error {
+	res, err := cli.db.Exec("RevokeAPIKey", "UPDATE api_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at = 0", id, revokedAt)
+	if err != nil {
+		return err
+	}
+
+	if count, err := res.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		// already revoked keys are left as they are
+		if _, err := cli.GetAPIKey(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,341 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/lib/pq"
+)
+
+// transactionColumns are the columns scanned by scanTransaction, for a
+// query on transactions aliased as t
+const transactionColumns = "t.hash, t.nonce, t.block_hash, t.block_number, t.tx_index, t.addr_from, t.addr_to, t.value," +
+	" t.gas_limit, t.gas_used, t.cumulative_gas_used, t.gas_price, t.contract_address, t.input, t.status," +
+	" t.work_nonce, t.timestamp," +
+	" COALESCE((SELECT name FROM ens WHERE ens.address = t.addr_from ORDER BY name LIMIT 1), '') from_ens," +
+	" COALESCE((SELECT name FROM ens WHERE ens.address = t.addr_to ORDER BY name LIMIT 1), '') to_ens," +
+	" COALESCE((SELECT name FROM ens WHERE ens.address = t.contract_address ORDER BY name LIMIT 1), '') contract_ens"
+
+// blockColumns are the columns scanned by scanBlock, for a query on blocks aliased as b
+const blockColumns = "b.number, b.timestamp, b.hash, b.parent_hash, b.transactions_root, b.receipts_root, b.size," +
+	" b.transaction_count, b.gas_used, b.gas_limit, b.delegates, b.producer, b.signature," +
+	" COALESCE((SELECT name FROM ens WHERE ens.address = b.producer ORDER BY name LIMIT 1), '') producer_ens"
+
+func scanTransaction(row rowScanner) (models.TransactionFull, error) {
+	var tx models.Transaction
+	var txr models.TransactionReceipt
+
+	var originalHash, blockHash, addrfrom, addrto, addrContract, input []byte
+	var value string
+
+	if err := row.Scan(&originalHash,
+		&tx.Nonce,
+		&blockHash,
+		&tx.BlockNumber,
+		&tx.TransactionIndex,
+		&addrfrom,
+		&addrto,
+		&value,
+		&tx.GasLimit,
+		&txr.GasUsed,
+		&txr.CumulativeGasUsed,
+		&tx.GasPrice,
+		&addrContract,
+		&input,
+		&txr.Status,
+		&tx.WorkNonce,
+		&tx.Timestamp,
+		&tx.FromEns,
+		&tx.ToEns,
+		&txr.ContractAddressEns); err != nil {
+		return models.TransactionFull{}, err
+	}
+
+	tx.Hash = common.BytesToHash(originalHash)
+	tx.BlockHash.SetBytes(blockHash)
+	tx.From.SetBytes(addrfrom)
+	addressTo := common.BytesToAddress(addrto)
+	tx.To = &addressTo
+	tx.Value = parseValue(value)
+
+	contractAddress := common.BytesToAddress(addrContract)
+	txr.ContractAddress = &contractAddress
+	tx.Input = input
+
+	return models.TransactionFull{Tx: &tx, Txr: &txr}, nil
+}
+
+func scanBlock(row rowScanner) (models.Block, error) {
+	var block models.Block
+	var hash, parentHash, transactionsRoot, receiptsRoot, delegatesRaw, producer []byte
+
+	if err := row.Scan(&block.Number,
+		&block.TimeStamp,
+		&hash,
+		&parentHash,
+		&transactionsRoot,
+		&receiptsRoot,
+		&block.Size,
+		&block.TransactionCount,
+		&block.GasUsed,
+		&block.GasLimit,
+		&delegatesRaw,
+		&producer,
+		&block.Signature,
+		&block.ProducerEns); err != nil {
+		return block, err
+	}
+
+	block.Hash.SetBytes(hash)
+	block.ParentHash.SetBytes(parentHash)
+	block.TransactionsRoot.SetBytes(transactionsRoot)
+	block.ReceiptsRoot.SetBytes(receiptsRoot)
+
+	delegates := make([]common.Address, 0)
+	delegateCount := len(delegatesRaw) / 20
+	for i := 0; i < delegateCount; i++ {
+		var d common.Address
+		copy(d[:], delegatesRaw[20*i:20*i+20])
+		delegates = append(delegates, d)
+	}
+
+	block.Delegates = delegates
+	block.Producer.SetBytes(producer)
+
+	return block, nil
+}
+
+func addressesArray(addresses []common.Address) interface{} {
+	array := make(pq.ByteaArray, len(addresses))
+	for i := range addresses {
+		array[i] = addresses[i].Bytes()
+	}
+	return array
+}
+
+func numbersArray(numbers []uint64) interface{} {
+	array := make(pq.Int64Array, len(numbers))
+	for i, number := range numbers {
+		array[i] = int64(number)
+	}
+	return array
+}
+
+// GetBlocksByNumbers returns the blocks with the numbers, in no particular
+// order. Missing blocks are left out.
+func (cli *DBClient) GetBlocksByNumbers(numbers []uint64) ([]models.Block, error) {
+	query := "SELECT " + blockColumns + " FROM blocks AS b WHERE b.number = ANY($1)"
+	rows, err := cli.db.Query("GetBlocksByNumbers", query, numbersArray(numbers))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.Block, 0, len(numbers))
+
+	for rows.Next() {
+		block, err := scanBlock(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, block)
+	}
+
+	return result, rows.Err()
+}
+
+// GetTransactionsByBlockNumbers returns the transactions of the blocks,
+// ordered by (block_number, tx_index)
+func (cli *DBClient) GetTransactionsByBlockNumbers(numbers []uint64) ([]models.TransactionFull, error) {
+	query := strings.Join([]string{
+		"SELECT ", transactionColumns,
+		" FROM transactions AS t",
+		" WHERE t.block_number = ANY($1)",
+		" ORDER BY t.block_number, t.tx_index"}, "")
+	rows, err := cli.db.Query("GetTransactionsByBlockNumbers", query, numbersArray(numbers))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.TransactionFull, 0)
+
+	for rows.Next() {
+		tf, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, tf)
+	}
+
+	return result, rows.Err()
+}
+
+// GetEnsNames returns the ENS name of each address that has one, picking
+// the first in lexical order like the other queries do
+func (cli *DBClient) GetEnsNames(addresses []common.Address) (map[common.Address]string, error) {
+	rows, err := cli.db.Query("GetEnsNames", "SELECT address, MIN(name) FROM ens WHERE address = ANY($1) GROUP BY address", addressesArray(addresses))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[common.Address]string)
+
+	for rows.Next() {
+		var address []byte
+		var name string
+		if err := rows.Scan(&address, &name); err != nil {
+			return nil, err
+		}
+		result[common.BytesToAddress(address)] = name
+	}
+
+	return result, rows.Err()
+}
+
+// GetProducers returns the producers among the addresses
+func (cli *DBClient) GetProducers(addresses []common.Address) ([]models.Producer, error) {
+	rows, err := cli.db.Query("GetProducers", "SELECT address, produced_blocks_count, block_rewards FROM producers WHERE address = ANY($1)", addressesArray(addresses))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.Producer, 0, len(addresses))
+
+	for rows.Next() {
+		var producer models.Producer
+		var address []byte
+		var value uint64
+
+		if err := rows.Scan(&address, &producer.ProducedBlocksCount, &value); err != nil {
+			return nil, err
+		}
+
+		producer.Address.SetBytes(address)
+		producer.BlockRewards = new(big.Int).Mul(new(big.Int).SetUint64(value), precisionFactor)
+
+		result = append(result, producer)
+	}
+
+	return result, rows.Err()
+}
+
+// GetIsContractAddresses returns which of the addresses are contracts,
+// leaving out the ones that are not
+func (cli *DBClient) GetIsContractAddresses(addresses []common.Address) (map[common.Address]bool, error) {
+	result := make(map[common.Address]bool)
+
+	// system contracts
+	for _, address := range addresses {
+		if bytes.Compare(address.Bytes(), []byte{1, 2}) <= 0 {
+			result[address] = true
+		}
+	}
+
+	rows, err := cli.db.Query("GetIsContractAddresses", "SELECT DISTINCT contract_address FROM transactions WHERE contract_address = ANY($1)", addressesArray(addresses))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address []byte
+		if err := rows.Scan(&address); err != nil {
+			return nil, err
+		}
+		result[common.BytesToAddress(address)] = true
+	}
+
+	return result, rows.Err()
+}
+
+// GetTransactionCounts returns the transactions count of each address
+func (cli *DBClient) GetTransactionCounts(addresses []common.Address) (map[common.Address]uint64, error) {
+	query := "SELECT a.address," +
+		" (SELECT count(*) FROM transactions WHERE addr_from = a.address OR addr_to = a.address)" +
+		" FROM unnest($1::bytea[]) AS a(address)"
+	rows, err := cli.db.Query("GetTransactionCounts", query, addressesArray(addresses))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[common.Address]uint64, len(addresses))
+
+	for rows.Next() {
+		var address []byte
+		var count uint64
+		if err := rows.Scan(&address, &count); err != nil {
+			return nil, err
+		}
+		result[common.BytesToAddress(address)] = count
+	}
+
+	return result, rows.Err()
+}
+
+// addressScanner scans the address a row belongs to before the columns
+// scanned by scanTransaction
+type addressScanner struct {
+	rows    rowScanner
+	address []byte
+}
+
+func (s *addressScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append([]interface{}{&s.address}, dest...)...)
+}
+
+// GetTransactionsByAddresses returns a page of the transactions of each
+// address, like GetTransactionsByAddress with ADDRESS_ALL does. Pages only
+// continue after the cursor, cursor.Prev is not supported.
+func (cli *DBClient) GetTransactionsByAddresses(addresses []common.Address, cursor *models.Cursor, limit uint64, order string) (map[common.Address][]models.TransactionFull, error) {
+	direction, comparison := "ASC", ">"
+	if order == "desc" {
+		direction, comparison = "DESC", "<"
+	}
+
+	args := []interface{}{addressesArray(addresses)}
+	conditions := []string{"(addr_to = a.address OR addr_from = a.address)"}
+
+	if cursor != nil {
+		args = append(args, cursor.Number, cursor.Index)
+		conditions = append(conditions, fmt.Sprintf("(block_number, tx_index) %s ($%d, $%d)", comparison, len(args)-1, len(args)))
+	}
+
+	args = append(args, limit)
+	query := strings.Join([]string{
+		"SELECT a.address, ", transactionColumns,
+		" FROM unnest($1::bytea[]) AS a(address)",
+		" CROSS JOIN LATERAL (SELECT * FROM transactions",
+		"   WHERE ", strings.Join(conditions, " AND "),
+		"   ORDER BY block_number ", direction, ", tx_index ", direction,
+		fmt.Sprintf("   LIMIT $%d) AS t", len(args)),
+		" ORDER BY a.address, t.block_number ", direction, ", t.tx_index ", direction}, "")
+
+	rows, err := cli.db.Query("GetTransactionsByAddresses", query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[common.Address][]models.TransactionFull, len(addresses))
+	scanner := &addressScanner{rows: rows}
+
+	for rows.Next() {
+		tf, err := scanTransaction(scanner)
+		if err != nil {
+			return nil, err
+		}
+		address := common.BytesToAddress(scanner.address)
+		result[address] = append(result[address], tf)
+	}
+
+	return result, rows.Err()
+}
@@ -2,16 +2,17 @@ package db
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 	"math/big"
 	"strings"
 	"text/template"
 
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
 	"github.com/ebakus/ebakus-block-explorer-backend/models"
-	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+	"github.com/ebakus/ebakus-block-explorer-backend/schema"
 
 	"github.com/ebakus/go-ebakus/common"
 	"github.com/ebakus/go-ebakus/common/hexutil"
@@ -20,10 +21,10 @@ import (
 )
 
 type DBClient struct {
-	db *sql.DB
+	db timedDB
 }
 
-var client *DBClient
+var client Store
 
 var (
 	valueDecimalPoints = int64(4)
@@ -31,11 +32,19 @@ var (
 	bigIntZero         = new(big.Int).SetUint64(0)
 )
 
+// parseValue parses a value of the transactions, which are stored in wei
+func parseValue(value string) hexutil.Big {
+	v, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return hexutil.Big{}
+	}
+	return hexutil.Big(*v)
+}
+
 func makeConnString(name, host string, port int, user string, pass string) (string, error) {
 	templ, err := template.New("psql_connection_string").Parse("postgres://{{.User}}:{{.Pass}}@{{.Host}}:{{.Port}}/{{.Name}}?sslmode=disable")
 
 	if err != nil {
-		log.Println(err.Error())
 		return string(""), err
 	}
 
@@ -62,76 +71,111 @@ func makeConnString(name, host string, port int, user string, pass string) (stri
 // InitFromCli is the same as Init but receives it's parameters
 // from a Context struct of the cli package (aka from program arguments)
 func InitFromCli(c *cli.Context) error {
-	dbname := c.String("dbname")
-	dbhost := c.String("dbhost")
-	dbport := c.Int("dbport")
-	dbuser := c.String("dbuser")
-	dbpass := c.String("dbpass")
+	if c.String("dbdriver") == "memory" {
+		logger.Warn("Using in-memory storage, data will be lost on exit")
+		SetClient(NewMemoryStore())
+		return nil
+	}
 
-	return Init(dbname, dbhost, dbport, dbuser, dbpass)
+	return Init(connParamsFromCli(c))
 }
 
-// Init creates a connection to the database and runs any
-// checks necessary to ensure the module is ready to execute
-// queries.
-func Init(name, host string, port int, user string, pass string) error {
+func connParamsFromCli(c *cli.Context) (name, host string, port int, user string, pass string) {
+	return c.String("dbname"), c.String("dbhost"), c.Int("dbport"), c.String("dbuser"), c.String("dbpass")
+}
+
+// Open connects to the database without any further checks
+func Open(name, host string, port int, user string, pass string) (*sql.DB, error) {
 	conn, err := makeConnString(name, host, port, user, pass)
 
 	if err != nil {
-		log.Println(err.Error())
-		return err
+		return nil, err
 	}
 
 	tdb, err := sql.Open("postgres", conn)
 
 	if err != nil {
-		log.Println(err.Error())
-		return err
+		logger.Error("Failed to open the database", "err", err)
+		return nil, err
 	}
 
 	err = tdb.Ping()
 	if err != nil {
-		log.Println(err.Error())
-		return err
+		logger.Error("Failed to connect to the database", "host", host, "port", port, "err", err)
+		return nil, err
 	}
 
-	// Check if all required tables exist
-	rows, err := tdb.Query("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = 'public' AND table_name = 'blocks');")
+	return tdb, nil
+}
 
+// Init creates a connection to the database and runs any
+// checks necessary to ensure the module is ready to execute
+// queries.
+func Init(name, host string, port int, user string, pass string) error {
+	tdb, err := Open(name, host, port, user, pass)
 	if err != nil {
-		log.Println(err.Error())
 		return err
 	}
-	defer rows.Close()
 
-	var tableExists bool
-	rows.Next()
-	if err := rows.Scan(&tableExists); err != nil {
-		log.Println(err.Error())
+	// Check that the schema is up to date
+	version, err := SchemaVersion(tdb)
+	if err != nil {
+		logger.Error("Failed to read the schema version", "err", err)
+		tdb.Close()
 		return err
 	}
 
-	if !tableExists {
-		log.Println("Missing table: blocks. Make sure all required tables are created.")
-		return errors.New("Missing table: blocks")
+	if version != schema.Latest() {
+		logger.Error("Database schema is outdated, run the migrate command first", "version", version, "expected", schema.Latest())
+		tdb.Close()
+		return ErrSchemaOutdated
 	}
 
-	client = &DBClient{tdb}
+	client = &DBClient{timedDB{tdb, logger.New()}}
 
 	return nil
 }
 
-// GetClient returns the current DBClient instance.
+// GetClient returns the current Store instance.
 // Dev Commentary: I'm sorry for this but I needed a way to have
 // the DBClient available throughout the project. If you know
 // a better way to do this I'd like to know it too.
-func GetClient() *DBClient {
+func GetClient() Store {
+	return client
+}
+
+// GetClientContext returns the current Store instance, logging its queries
+// with the logger carried by ctx, e.g. with the ID of the request
+func GetClientContext(ctx context.Context) Store {
+	if cli, ok := client.(*DBClient); ok {
+		return &DBClient{timedDB{cli.db.DB, logger.FromContext(ctx)}}
+	}
 	return client
 }
 
+// SetClient replaces the current Store instance, e.g. with a
+// MemoryStore for tests and local demos
+func SetClient(store Store) {
+	client = store
+}
+
+// Close closes the connections of the current Store instance to the
+// database, if any
+func Close() error {
+	if cli, ok := client.(*DBClient); ok {
+		return cli.db.Close()
+	}
+	return nil
+}
+
+// Ping checks that the database can be reached, giving up when ctx is done
+func (cli *DBClient) Ping(ctx context.Context) error {
+	return cli.db.PingContext(ctx)
+}
+
 // GetLatestBlockNumber returns the most recent block id (aka number)
 func (cli *DBClient) GetLatestBlockNumber() (uint64, error) {
-	rows, err := cli.db.Query("SELECT max(number) FROM blocks")
+	rows, err := cli.db.Query("GetLatestBlockNumber", "SELECT max(number) FROM blocks")
 	if err != nil {
 		return 0, err
 	}
@@ -156,7 +200,7 @@ func (cli *DBClient) GetBlockByID(number uint64) (*models.Block, error) {
 		"   LEFT JOIN ens ON ens.address = b.producer",
 		" WHERE b.number = $1",
 		" GROUP BY b.number"}, "")
-	rows, err := cli.db.Query(query, number)
+	rows, err := cli.db.Query("GetBlockByID", query, number)
 	if err != nil {
 		return nil, err
 	}
@@ -220,7 +264,7 @@ func (cli *DBClient) GetBlockByHash(hash string) (*models.Block, error) {
 		"   LEFT JOIN ens ON ens.address = b.producer",
 		" WHERE b.hash = E'\\\\", hash[1:], "'",
 		" GROUP BY b.number"}, "")
-	rows, err := cli.db.Query(query)
+	rows, err := cli.db.Query("GetBlockByHash", query)
 
 	if err != nil {
 		return nil, err
@@ -275,10 +319,16 @@ func (cli *DBClient) GetBlockByHash(hash string) (*models.Block, error) {
 	return &block, nil
 }
 
-// GetBlockRange returns range of blocks
-func (cli *DBClient) GetBlockRange(fromNumber, rng uint32) ([]models.Block, error) {
+// GetBlockRange returns range of blocks starting from fromNumber (inclusive),
+// going downwards or, when ascending is set, upwards
+func (cli *DBClient) GetBlockRange(fromNumber, rng uint32, ascending bool) ([]models.Block, error) {
 
 	withQuery := "SELECT * FROM blocks WHERE number <= $1 ORDER BY number DESC LIMIT $2"
+	order := "DESC"
+	if ascending {
+		withQuery = "SELECT * FROM blocks WHERE number >= $1 ORDER BY number ASC LIMIT $2"
+		order = "ASC"
+	}
 
 	query := strings.Join([]string{
 		"WITH b AS (", withQuery, ")",
@@ -288,9 +338,9 @@ func (cli *DBClient) GetBlockRange(fromNumber, rng uint32) ([]models.Block, erro
 		"   LEFT JOIN ens ON ens.address = b.producer",
 		" GROUP BY b.number, b.timestamp, b.hash, b.parent_hash, b.transactions_root, b.receipts_root,",
 		"   b.size, b.transaction_count, b.gas_used, b.gas_limit, b.delegates, b.producer, b.signature",
-		" ORDER BY b.number DESC LIMIT $2"}, "")
+		" ORDER BY b.number ", order, " LIMIT $2"}, "")
 
-	rows, err := cli.db.Query(query, fromNumber, rng)
+	rows, err := cli.db.Query("GetBlockRange", query, fromNumber, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -374,7 +424,7 @@ func (cli *DBClient) GetBlocksByTimestamp(timestamp hexutil.Uint64, timestampCon
 		"   b.size, b.transaction_count, b.gas_used, b.gas_limit, b.delegates, b.producer, b.signature",
 		" ORDER BY b.timestamp DESC"}, "")
 
-	rows, err := cli.db.Query(query, timestamp)
+	rows, err := cli.db.Query("GetBlocksByTimestamp", query, timestamp)
 	if err != nil {
 		return nil, err
 	}
@@ -426,6 +476,28 @@ func (cli *DBClient) GetBlocksByTimestamp(timestamp hexutil.Uint64, timestampCon
 	return result, nil
 }
 
+// GetBlockNumberByTimestamp returns the number of the block closest to
+// timestamp that matches the condition, or sql.ErrNoRows when there is none
+func (cli *DBClient) GetBlockNumberByTimestamp(timestamp uint64, timestampCondition models.TimestampCondition) (uint64, error) {
+	var query string
+
+	switch timestampCondition {
+	case models.TIMESTAMP_SMALLER_EQUAL_THAN:
+		query = "SELECT number FROM blocks WHERE timestamp <= $1 ORDER BY timestamp DESC, number DESC LIMIT 1"
+	case models.TIMESTAMP_GREATER_EQUAL_THAN:
+		query = "SELECT number FROM blocks WHERE timestamp >= $1 ORDER BY timestamp ASC, number ASC LIMIT 1"
+	default:
+		query = "SELECT number FROM blocks WHERE timestamp = $1 ORDER BY number DESC LIMIT 1"
+	}
+
+	var number uint64
+	if err := cli.db.QueryRow("GetBlockNumberByTimestamp", query, timestamp).Scan(&number); err != nil {
+		return 0, err
+	}
+
+	return number, nil
+}
+
 // GetTransactionByHash finds and returns the transaction with the provided Hash
 func (cli *DBClient) GetTransactionByHash(hash string) (*models.TransactionFull, error) {
 	// Query for bytea value with the hex method, pass from char [1,end) since
@@ -447,7 +519,7 @@ func (cli *DBClient) GetTransactionByHash(hash string) (*models.TransactionFull,
 		" GROUP BY t.hash, t.nonce, t.block_hash, t.block_number, t.tx_index, t.addr_from, t.addr_to, t.value,",
 		"   t.gas_limit, t.gas_used, t.cumulative_gas_used, t.gas_price, t.contract_address, t.input, t.status,",
 		"   t.work_nonce, t.timestamp"}, "")
-	rows, err := cli.db.Query(query)
+	rows, err := cli.db.Query("GetTransactionByHash", query)
 
 	if err != nil {
 		return nil, err
@@ -458,7 +530,7 @@ func (cli *DBClient) GetTransactionByHash(hash string) (*models.TransactionFull,
 	var txr models.TransactionReceipt
 
 	var originalHash, blockHash, addrfrom, addrto, addrContract, input []byte
-	var value uint64
+	var value string
 
 	if foundData := rows.Next(); !foundData {
 		return &models.TransactionFull{Tx: nil, Txr: nil}, nil
@@ -493,7 +565,7 @@ func (cli *DBClient) GetTransactionByHash(hash string) (*models.TransactionFull,
 	tx.From.SetBytes(addrfrom)
 	addressTo := common.BytesToAddress(addrto)
 	tx.To = &addressTo
-	tx.Value = (hexutil.Big)(*new(big.Int).Mul(new(big.Int).SetUint64(value), precisionFactor)) // value * ether (1e18) / 10000
+	tx.Value = parseValue(value)
 
 	contractAddress := common.BytesToAddress(addrContract)
 	txr.ContractAddress = &contractAddress
@@ -549,7 +621,7 @@ func (cli *DBClient) DeleteBlockWithTransactionsByID(number uint64, producer com
 func (cli *DBClient) GetAddressTotals(address string) (blockRewards *big.Int, txCount uint64, err error) {
 
 	query := strings.Join([]string{"SELECT count(*) FROM transactions WHERE addr_from = E'\\\\", address[1:], "' OR addr_to = E'\\\\", address[1:], "'"}, "")
-	rows, err := cli.db.Query(query)
+	rows, err := cli.db.Query("GetAddressTotals", query)
 
 	if err != nil {
 		return bigIntZero, 0, err
@@ -581,39 +653,59 @@ func (cli *DBClient) GetIsContractAddress(address string) (bool, error) {
 
 	query := strings.Join([]string{"SELECT count(*) FROM transactions WHERE contract_address = E'\\\\", address[1:], "'"}, "")
 	varInt := uint64(0)
-	cli.db.QueryRow(query).Scan(&varInt)
+	cli.db.QueryRow("GetIsContractAddress", query).Scan(&varInt)
 	return varInt > 0, nil
 }
 
 // GetTransactionByAddress finds and returns the transaction with the provided address
-// as source (FROM) or destination (TO), or the transactions of a block
-func (cli *DBClient) GetTransactionsByAddress(address string, addrtype models.AddressType, offset, limit uint64, order string) ([]models.TransactionFull, error) {
-	// Query for bytea value with the hex method, pass from char [1,end) since
-	// the required structure is E'\\xDEADBEEF'
-	// For more, check https://www.postgresql.org/docs/9.0/static/datatype-binary.html
-	withQuery := "SELECT * FROM transactions"
+// as source (FROM) or destination (TO), or the transactions of a block.
+// Results are ordered by (block_number, tx_index) and continue after the
+// cursor, or end before it when cursor.Prev is set.
+func (cli *DBClient) GetTransactionsByAddress(address string, addrtype models.AddressType, cursor *models.Cursor, limit uint64, order string) ([]models.TransactionFull, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
 
 	switch addrtype {
 	case models.ADDRESS_TO:
-		withQuery = strings.Join([]string{withQuery, " WHERE addr_to = E'\\\\", address[1:], "'"}, "")
+		args = append(args, common.HexToAddress(address).Bytes())
+		conditions = append(conditions, "addr_to = $1")
 	case models.ADDRESS_FROM:
-		withQuery = strings.Join([]string{withQuery, " WHERE addr_from = E'\\\\", address[1:], "'"}, "")
+		args = append(args, common.HexToAddress(address).Bytes())
+		conditions = append(conditions, "addr_from = $1")
 	case models.ADDRESS_ALL:
-		withQuery = strings.Join([]string{withQuery, " WHERE addr_to = E'\\\\", address[1:], "'", " or addr_from = E'\\\\", address[1:], "'"}, "")
+		args = append(args, common.HexToAddress(address).Bytes())
+		conditions = append(conditions, "(addr_to = $1 OR addr_from = $1)")
 	case models.ADDRESS_BLOCKHASH:
-		withQuery = strings.Join([]string{"SELECT transactions.* FROM transactions, blocks WHERE blocks.number = transactions.block_number AND blocks.hash = E'\\\\", address[1:], "'"}, "")
+		args = append(args, common.HexToHash(address).Bytes())
+		conditions = append(conditions, "block_number = (SELECT number FROM blocks WHERE hash = $1 LIMIT 1)")
 	}
 
-	if order != "asc" {
-		switch addrtype {
-		case models.LATEST:
-			withQuery = strings.Join([]string{withQuery, " ORDER BY block_number ", order}, "")
-		default:
-			withQuery = strings.Join([]string{withQuery, " ORDER BY timestamp ", order}, "")
-		}
+	// pages before the cursor are queried in reverse and flipped afterwards
+	descending := order == "desc"
+	reverse := cursor != nil && cursor.Prev
+	if reverse {
+		descending = !descending
+	}
+
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if cursor != nil {
+		args = append(args, cursor.Number, cursor.Index)
+		conditions = append(conditions, fmt.Sprintf("(block_number, tx_index) %s ($%d, $%d)", comparison, len(args)-1, len(args)))
+	}
+
+	withQuery := "SELECT * FROM transactions"
+	if len(conditions) > 0 {
+		withQuery = strings.Join([]string{withQuery, " WHERE ", strings.Join(conditions, " AND ")}, "")
 	}
 
-	withQuery = strings.Join([]string{withQuery, " OFFSET $1 LIMIT $2"}, "")
+	args = append(args, limit)
+	withQuery = strings.Join([]string{withQuery,
+		" ORDER BY block_number ", direction, ", tx_index ", direction,
+		fmt.Sprintf(" LIMIT $%d", len(args))}, "")
 
 	query := strings.Join([]string{
 		"WITH t AS (", withQuery, ")",
@@ -627,18 +719,10 @@ func (cli *DBClient) GetTransactionsByAddress(address string, addrtype models.Ad
 		"   LEFT JOIN ens AS ensc ON ensc.address = t.contract_address",
 		" GROUP BY t.hash, t.nonce, t.block_hash, t.block_number, t.tx_index, t.addr_from, t.addr_to,",
 		"   t.value, t.gas_limit, t.gas_used, t.cumulative_gas_used, t.gas_price, t.contract_address,",
-		"   t.input, t.status, t.work_nonce, t.timestamp"}, "")
-
-	if order != "asc" {
-		switch addrtype {
-		case models.LATEST:
-			query = strings.Join([]string{query, " ORDER BY t.block_number ", order}, "")
-		default:
-			query = strings.Join([]string{query, " ORDER BY t.timestamp ", order}, "")
-		}
-	}
+		"   t.input, t.status, t.work_nonce, t.timestamp",
+		" ORDER BY t.block_number ", direction, ", t.tx_index ", direction}, "")
 
-	rows, err := cli.db.Query(query, offset, limit)
+	rows, err := cli.db.Query("GetTransactionsByAddress", query, args...)
 
 	if err != nil {
 		return nil, err
@@ -654,7 +738,7 @@ func (cli *DBClient) GetTransactionsByAddress(address string, addrtype models.Ad
 		var txr models.TransactionReceipt
 
 		var originalHash, blockHash, addrfrom, addrto, addrContract, input []byte
-		var value uint64
+		var value string
 
 		rows.Scan(&originalHash,
 			&tx.Nonce,
@@ -677,7 +761,6 @@ func (cli *DBClient) GetTransactionsByAddress(address string, addrtype models.Ad
 			&tx.ToEns,
 			&txr.ContractAddressEns)
 		if err = rows.Err(); err != nil {
-			log.Println(err)
 			return nil, err
 		}
 
@@ -686,7 +769,7 @@ func (cli *DBClient) GetTransactionsByAddress(address string, addrtype models.Ad
 		tx.From.SetBytes(addrfrom)
 		addressTo := common.BytesToAddress(addrto)
 		tx.To = &addressTo
-		tx.Value = (hexutil.Big)(*new(big.Int).Mul(new(big.Int).SetUint64(value), precisionFactor)) // value * ether (1e18) / 10000
+		tx.Value = parseValue(value)
 
 		contractAddress := common.BytesToAddress(addrContract)
 		txr.ContractAddress = &contractAddress
@@ -695,6 +778,12 @@ func (cli *DBClient) GetTransactionsByAddress(address string, addrtype models.Ad
 		result = append(result, models.TransactionFull{Tx: &tx, Txr: &txr})
 	}
 
+	if reverse {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+
 	return result, nil
 }
 
@@ -735,10 +824,8 @@ func (cli *DBClient) InsertTransactions(transactions []models.TransactionFull) e
 	for _, txf := range transactions {
 		tx := txf.Tx
 		txr := txf.Txr
-		log.Println("Adding", tx.BlockNumber, tx.TransactionIndex, tx.Input)
+		cli.db.log.Trace("Adding transaction", "block", tx.BlockNumber, "index", tx.TransactionIndex, "hash", tx.Hash)
 
-		// value * 10000 / ether (1e18)
-		v := new(big.Int).Div(tx.Value.ToInt(), precisionFactor).Uint64() // stupid go postgres driver
 		var to, contractAddress []byte
 		if tx.To != nil {
 			to = tx.To.Bytes()
@@ -757,7 +844,7 @@ func (cli *DBClient) InsertTransactions(transactions []models.TransactionFull) e
 			tx.TransactionIndex,
 			tx.From.Bytes(),
 			to,
-			v,
+			tx.Value.ToInt().String(),
 			txr.GasUsed,
 			txr.CumulativeGasUsed,
 			tx.GasLimit,
@@ -768,33 +855,23 @@ func (cli *DBClient) InsertTransactions(transactions []models.TransactionFull) e
 		)
 
 		if err != nil {
-			log.Println("Error on Transaction", tx.BlockNumber, err.Error())
-		}
-
-		if tx.To != nil {
-			if err := redis.Delete("address:" + tx.To.Hex()); err != nil {
-				log.Println("Failed to clear redis cache for ", "address:"+tx.To.Hex(), err.Error())
-			}
-		}
-
-		if err := redis.Delete("address:" + tx.From.Hex()); err != nil {
-			log.Println("Failed to clear redis cache for ", "address:"+tx.From.Hex(), err.Error())
+			cli.db.log.Error("Failed to add transaction", "block", tx.BlockNumber, "hash", tx.Hash, "err", err)
 		}
 	}
 
 	_, err = stmt.Exec()
 	if err != nil {
-		log.Println("PQTX Exec", err.Error())
+		cli.db.log.Error("Failed to copy transactions", "err", err)
 	}
 
 	err = stmt.Close()
 	if err != nil {
-		log.Println("PQTX Close", err.Error())
+		cli.db.log.Error("Failed to close the transactions copy", "err", err)
 	}
 
 	err = txn.Commit()
 	if err != nil {
-		log.Println("PQTX Commit", err.Error())
+		cli.db.log.Error("Failed to commit transactions", "err", err)
 	}
 
 	return nil
@@ -852,27 +929,23 @@ func (cli *DBClient) InsertBlocks(blocks []*models.Block) error {
 		)
 
 		if err != nil {
-			log.Println(err.Error())
-		}
-
-		if err := redis.Delete("address:" + bl.Producer.Hex()); err != nil {
-			log.Println("Failed to clear redis cache for ", "address:"+bl.Producer.Hex(), err.Error())
+			cli.db.log.Error("Failed to add block", "number", bl.Number, "err", err)
 		}
 	}
 
 	_, err = stmt.Exec()
 	if err != nil {
-		log.Println(err.Error())
+		cli.db.log.Error("Failed to copy blocks", "err", err)
 	}
 
 	err = stmt.Close()
 	if err != nil {
-		log.Println(err.Error())
+		cli.db.log.Error("Failed to close the blocks copy", "err", err)
 	}
 
 	err = txn.Commit()
 	if err != nil {
-		log.Println(err.Error())
+		cli.db.log.Error("Failed to commit blocks", "err", err)
 	}
 
 	return nil
@@ -887,7 +960,7 @@ func (cli *DBClient) InsertBalance(address common.Address, balance uint64, block
 	`
 	adr := common.Bytes2Hex(address[:])[:]
 	//	log.Println(fmt.Sprintf(sql, adr, balance, blockNumber))
-	rows, err := cli.db.Query(fmt.Sprintf(sql, adr, balance, blockNumber))
+	rows, err := cli.db.Query("InsertBalance", fmt.Sprintf(sql, adr, balance, blockNumber))
 	rows.Close()
 
 	return err
@@ -897,7 +970,7 @@ func (cli *DBClient) InsertBalance(address common.Address, balance uint64, block
 func (cli *DBClient) GetBalanceStats() (uint64, uint64, uint64, error) {
 	query := `select count(*), max(amount), min(amount) from balances`
 	var count, max, min uint64
-	err := cli.db.QueryRow(query).Scan(&count, &max, &min)
+	err := cli.db.QueryRow("GetBalanceStats", query).Scan(&count, &max, &min)
 	if err != nil {
 		return 0, 0, 0, err
 	}
@@ -905,9 +978,30 @@ func (cli *DBClient) GetBalanceStats() (uint64, uint64, uint64, error) {
 	return count, max, min, nil
 }
 
-// GetTopBalances gets the rich list
-func (cli *DBClient) GetTopBalances(limit uint64, offset uint64) ([]models.Balance, error) {
-	withQuery := "SELECT address, amount, block_number FROM balances ORDER BY amount DESC LIMIT $1 OFFSET $2"
+// GetTopBalances gets the rich list, ordered by (amount, address) descending,
+// continuing after the cursor or ending before it when cursor.Prev is set
+func (cli *DBClient) GetTopBalances(cursor *models.Cursor, limit uint64) ([]models.Balance, error) {
+	direction := "DESC"
+	reverse := cursor != nil && cursor.Prev
+	if reverse {
+		direction = "ASC"
+	}
+
+	args := []interface{}{limit}
+	withQuery := "SELECT address, amount, block_number FROM balances"
+	if cursor != nil {
+		comparison := "<"
+		if reverse {
+			comparison = ">"
+		}
+		var address []byte
+		if cursor.Address != nil {
+			address = cursor.Address.Bytes()
+		}
+		args = append(args, cursor.Number, address)
+		withQuery = strings.Join([]string{withQuery, " WHERE (amount, address) ", comparison, " ($2, $3)"}, "")
+	}
+	withQuery = strings.Join([]string{withQuery, " ORDER BY amount ", direction, ", address ", direction, " LIMIT $1"}, "")
 
 	query := strings.Join([]string{
 		"WITH b AS (", withQuery, ")",
@@ -916,8 +1010,8 @@ func (cli *DBClient) GetTopBalances(limit uint64, offset uint64) ([]models.Balan
 		" FROM b",
 		"   LEFT JOIN ens ON ens.address = b.address",
 		" GROUP BY b.address, b.amount, b.block_number",
-		" ORDER BY b.amount DESC"}, "")
-	rows, err := cli.db.Query(query, limit, offset)
+		" ORDER BY b.amount ", direction, ", b.address ", direction}, "")
+	rows, err := cli.db.Query("GetTopBalances", query, args...)
 
 	if err != nil {
 		return nil, err
@@ -934,7 +1028,6 @@ func (cli *DBClient) GetTopBalances(limit uint64, offset uint64) ([]models.Balan
 
 		rows.Scan(&addressBytes, &amount, &blockNumber, &addressEns)
 		if err = rows.Err(); err != nil {
-			log.Println(err)
 			return nil, err
 		}
 
@@ -943,6 +1036,12 @@ func (cli *DBClient) GetTopBalances(limit uint64, offset uint64) ([]models.Balan
 		result = append(result, models.Balance{Address: address, AddressEns: addressEns, Amount: amount, BlockNumber: blockNumber})
 	}
 
+	if reverse {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+
 	return result, nil
 }
 
@@ -950,7 +1049,7 @@ func (cli *DBClient) GetTopBalances(limit uint64, offset uint64) ([]models.Balan
 func (cli *DBClient) PurgeBalanceObject(minAmount uint64) error {
 	query := `DELETE FROM balances WHERE amount < $1`
 
-	cli.db.QueryRow(query, minAmount)
+	cli.db.QueryRow("PurgeBalanceObject", query, minAmount)
 
 	return nil
 }
@@ -959,7 +1058,7 @@ func (cli *DBClient) PurgeBalanceObject(minAmount uint64) error {
 func (cli *DBClient) GetGlobalInt(varName string) (uint64, error) {
 	query := `SELECT value_int FROM globals WHERE var_name = $1`
 	varInt := uint64(0)
-	cli.db.QueryRow(query, varName).Scan(&varInt)
+	cli.db.QueryRow("GetGlobalInt", query, varName).Scan(&varInt)
 	return varInt, nil
 }
 
@@ -970,9 +1069,8 @@ func (cli *DBClient) SetGlobalInt(varName string, valInt uint64) error {
 		ON CONFLICT (var_name) DO UPDATE SET value_int = excluded.value_int
 	`
 
-	rows, err := cli.db.Query(sql, varName, valInt)
+	rows, err := cli.db.Query("SetGlobalInt", sql, varName, valInt)
 	if err != nil {
-		log.Println(err)
 		return err
 	}
 	rows.Close()
@@ -988,7 +1086,7 @@ func (cli *DBClient) InsertEns(ens models.ENS) error {
 	`
 	adr := common.Bytes2Hex(ens.Address[:])[:]
 	namehash := common.Bytes2Hex(ens.Hash[:])[:]
-	rows, err := cli.db.Query(fmt.Sprintf(sql, namehash, adr, ens.Name))
+	rows, err := cli.db.Query("InsertEns", fmt.Sprintf(sql, namehash, adr, ens.Name))
 	rows.Close()
 
 	return err
@@ -998,7 +1096,7 @@ func (cli *DBClient) InsertEns(ens models.ENS) error {
 func (cli *DBClient) GetEnsName(address string) (string, error) {
 	query := strings.Join([]string{"SELECT name FROM ens WHERE address = E'\\\\", address[1:], "'"}, "")
 	var name string
-	rows := cli.db.QueryRow(query)
+	rows := cli.db.QueryRow("GetEnsName", query)
 	err := rows.Scan(&name)
 	return name, err
 }
@@ -1007,7 +1105,7 @@ func (cli *DBClient) GetEnsName(address string) (string, error) {
 func (cli *DBClient) GetEnsCount() (uint64, error) {
 	query := `SELECT count(*) FROM ens`
 	var count uint64
-	err := cli.db.QueryRow(query).Scan(&count)
+	err := cli.db.QueryRow("GetEnsCount", query).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -1022,7 +1120,7 @@ func (cli *DBClient) GetEnsEntriesRange(limit uint64, offset uint64) ([]models.E
 	}
 
 	query := "SELECT * FROM ens LIMIT $1 OFFSET $2"
-	rows, err := cli.db.Query(query, limit, offset)
+	rows, err := cli.db.Query("GetEnsEntriesRange", query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -1058,7 +1156,7 @@ func (cli *DBClient) InsertProducer(producer models.Producer) error {
 	`
 	adr := common.Bytes2Hex(producer.Address[:])[:]
 	blockRewards := new(big.Int).Div(producer.BlockRewards, precisionFactor).Uint64()
-	rows, err := cli.db.Query(fmt.Sprintf(sql, adr, producer.ProducedBlocksCount, blockRewards))
+	rows, err := cli.db.Query("InsertProducer", fmt.Sprintf(sql, adr, producer.ProducedBlocksCount, blockRewards))
 	rows.Close()
 
 	return err
@@ -1071,7 +1169,7 @@ func (cli *DBClient) GetProducer(address string) (*models.Producer, error) {
 	var producerAddress []byte
 	var value uint64
 
-	rows := cli.db.QueryRow(query)
+	rows := cli.db.QueryRow("GetProducer", query)
 	if err := rows.Scan(&producerAddress, &producer.ProducedBlocksCount, &value); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+// exportFetchSize is the number of rows fetched from the cursor at once
+const exportFetchSize = 1000
+
+// streamQuery runs query through a server side cursor and calls scan for
+// every row, so that exports never hold more than a batch of rows in memory
+func (cli *DBClient) streamQuery(query string, args []interface{}, scan func(rows *sql.Rows) error) error {
+	txn, err := cli.db.Begin()
+	if err != nil {
+		return err
+	}
+	// the transaction is read only, rolling back just closes the cursor
+	defer txn.Rollback()
+
+	if _, err := txn.Exec("DECLARE export_cursor NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM export_cursor", exportFetchSize)
+
+	for {
+		rows, err := txn.Query(fetch)
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for rows.Next() {
+			count++
+			if err := scan(rows); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if count < exportFetchSize {
+			return nil
+		}
+	}
+}
+
+// exportTimeConditions appends the time range of the filter to conditions
+func exportTimeConditions(filter models.ExportFilter, column string, conditions []string, args []interface{}) ([]string, []interface{}) {
+	if filter.FromTime > 0 {
+		args = append(args, filter.FromTime)
+		conditions = append(conditions, fmt.Sprintf("%s >= $%d", column, len(args)))
+	}
+	if filter.ToTime > 0 {
+		args = append(args, filter.ToTime)
+		conditions = append(conditions, fmt.Sprintf("%s < $%d", column, len(args)))
+	}
+	return conditions, args
+}
+
+// exportBlockConditions appends the block range of the filter to conditions
+func exportBlockConditions(filter models.ExportFilter, column string, conditions []string, args []interface{}) ([]string, []interface{}) {
+	if filter.FromBlock > 0 {
+		args = append(args, filter.FromBlock)
+		conditions = append(conditions, fmt.Sprintf("%s >= $%d", column, len(args)))
+	}
+	if filter.ToBlock > 0 {
+		args = append(args, filter.ToBlock)
+		conditions = append(conditions, fmt.Sprintf("%s <= $%d", column, len(args)))
+	}
+	return conditions, args
+}
+
+// ExportTransactionsByAddress calls fn for every transaction of the address
+// (or the block, for ADDRESS_BLOCKHASH) in the filter's time and block range
+func (cli *DBClient) ExportTransactionsByAddress(address string, addrtype models.AddressType, filter models.ExportFilter, fn func(models.TransactionFull) error) error {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	switch addrtype {
+	case models.ADDRESS_TO:
+		args = append(args, common.HexToAddress(address).Bytes())
+		conditions = append(conditions, "t.addr_to = $1")
+	case models.ADDRESS_FROM:
+		args = append(args, common.HexToAddress(address).Bytes())
+		conditions = append(conditions, "t.addr_from = $1")
+	case models.ADDRESS_ALL:
+		args = append(args, common.HexToAddress(address).Bytes())
+		conditions = append(conditions, "(t.addr_to = $1 OR t.addr_from = $1)")
+	case models.ADDRESS_BLOCKHASH:
+		args = append(args, common.HexToHash(address).Bytes())
+		conditions = append(conditions, "t.block_number = (SELECT number FROM blocks WHERE hash = $1 LIMIT 1)")
+	default:
+		return fmt.Errorf("unsupported address type %d", addrtype)
+	}
+
+	conditions, args = exportTimeConditions(filter, "t.timestamp", conditions, args)
+	conditions, args = exportBlockConditions(filter, "t.block_number", conditions, args)
+
+	direction := "ASC"
+	if filter.Descending {
+		direction = "DESC"
+	}
+
+	// the names are looked up per row, as aggregating them would need the whole result
+	query := strings.Join([]string{
+		"SELECT ", transactionColumns,
+		" FROM transactions AS t",
+		" WHERE ", strings.Join(conditions, " AND "),
+		" ORDER BY t.block_number ", direction, ", t.tx_index ", direction}, "")
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	return cli.streamQuery(query, args, func(rows *sql.Rows) error {
+		tf, err := scanTransaction(rows)
+		if err != nil {
+			return err
+		}
+		return fn(tf)
+	})
+}
+
+// ExportBlocks calls fn for every block numbered from fromNumber to toNumber
+// (inclusive) in the filter's time range
+func (cli *DBClient) ExportBlocks(fromNumber, toNumber uint64, filter models.ExportFilter, fn func(models.Block) error) error {
+	conditions := []string{"b.number >= $1", "b.number <= $2"}
+	args := []interface{}{fromNumber, toNumber}
+
+	conditions, args = exportTimeConditions(filter, "b.timestamp", conditions, args)
+
+	direction := "ASC"
+	if filter.Descending {
+		direction = "DESC"
+	}
+
+	query := strings.Join([]string{
+		"SELECT ", blockColumns,
+		" FROM blocks AS b",
+		" WHERE ", strings.Join(conditions, " AND "),
+		" ORDER BY b.number ", direction}, "")
+
+	return cli.streamQuery(query, args, func(rows *sql.Rows) error {
+		block, err := scanBlock(rows)
+		if err != nil {
+			return err
+		}
+		return fn(block)
+	})
+}
+
+// ExportBalances calls fn for every entry of the rich list, richest first
+func (cli *DBClient) ExportBalances(fn func(models.Balance) error) error {
+	query := strings.Join([]string{
+		"SELECT b.address, b.amount, b.block_number,",
+		"   COALESCE((SELECT name FROM ens WHERE ens.address = b.address ORDER BY name LIMIT 1), '') address_ens",
+		" FROM balances AS b",
+		" ORDER BY b.amount DESC, b.address DESC"}, "")
+
+	return cli.streamQuery(query, nil, func(rows *sql.Rows) error {
+		var balance models.Balance
+		var address []byte
+
+		if err := rows.Scan(&address, &balance.Amount, &balance.BlockNumber, &balance.AddressEns); err != nil {
+			return err
+		}
+		balance.Address.SetBytes(address)
+
+		return fn(balance)
+	})
+}
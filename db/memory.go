@@ -0,0 +1,1323 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+// MemoryStore is a Store that keeps all data in process memory.
+// It mirrors the behaviour of the PostgreSQL backend (including the
+// precision loss of stored values) so it can stand in for it in
+// tests and local demos.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	blocks       map[uint64]*models.Block
+	transactions map[common.Hash]*models.TransactionFull
+	balances     map[common.Address]*models.Balance
+	ens          map[common.Hash]models.ENS
+	producers    map[common.Address]*models.Producer
+	globals      map[string]uint64
+
+	webhooks          map[uint64]*models.Webhook
+	webhookDeliveries []*models.WebhookDelivery
+	lastWebhookID     uint64
+	lastDeliveryID    uint64
+
+	rollups map[string]map[uint64]models.Rollup
+
+	contractABIs map[common.Address]string
+
+	apiKeys      map[uint64]*models.APIKey
+	lastAPIKeyID uint64
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		blocks:       make(map[uint64]*models.Block),
+		transactions: make(map[common.Hash]*models.TransactionFull),
+		balances:     make(map[common.Address]*models.Balance),
+		ens:          make(map[common.Hash]models.ENS),
+		producers:    make(map[common.Address]*models.Producer),
+		globals:      make(map[string]uint64),
+		webhooks:     make(map[uint64]*models.Webhook),
+		rollups:      make(map[string]map[uint64]models.Rollup),
+		contractABIs: make(map[common.Address]string),
+		apiKeys:      make(map[uint64]*models.APIKey),
+	}
+}
+
+// Ping always succeeds, as the store is in process
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// truncateValue drops the precision that is lost when block rewards are
+// stored in the database as 1/10000 of ether
+func truncateValue(value *big.Int) *big.Int {
+	v := new(big.Int).Div(value, precisionFactor)
+	return v.Mul(v, precisionFactor)
+}
+
+// ensNameLocked returns the first (in lexical order) ENS name for address,
+// the same way the SQL queries aggregate them
+func (m *MemoryStore) ensNameLocked(address common.Address) string {
+	names := make([]string, 0)
+	for _, e := range m.ens {
+		if e.Address == address {
+			names = append(names, e.Name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+func (m *MemoryStore) blockCopyLocked(bl *models.Block) models.Block {
+	block := *bl
+	block.Transactions = nil
+	block.Delegates = append(make([]common.Address, 0, len(bl.Delegates)), bl.Delegates...)
+	producerEns := m.ensNameLocked(bl.Producer)
+	block.ProducerEns = &producerEns
+	return block
+}
+
+func (m *MemoryStore) transactionCopyLocked(txf *models.TransactionFull) models.TransactionFull {
+	tx := *txf.Tx
+	txr := *txf.Txr
+
+	// NULL addresses are returned as zero addresses by the database
+	to := common.Address{}
+	if txf.Tx.To != nil {
+		to = *txf.Tx.To
+	}
+	tx.To = &to
+
+	contractAddress := common.Address{}
+	if txf.Txr.ContractAddress != nil {
+		contractAddress = *txf.Txr.ContractAddress
+	}
+	txr.ContractAddress = &contractAddress
+
+	fromEns := m.ensNameLocked(tx.From)
+	toEns := m.ensNameLocked(to)
+	contractEns := m.ensNameLocked(contractAddress)
+	tx.FromEns = &fromEns
+	tx.ToEns = &toEns
+	txr.ContractAddressEns = &contractEns
+
+	return models.TransactionFull{Tx: &tx, Txr: &txr}
+}
+
+// GetLatestBlockNumber returns the most recent block id (aka number)
+func (m *MemoryStore) GetLatestBlockNumber() (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var maxNumber uint64
+	for number := range m.blocks {
+		if number > maxNumber {
+			maxNumber = number
+		}
+	}
+	return maxNumber, nil
+}
+
+// GetBlockByID finds and returns the block with the provided ID
+func (m *MemoryStore) GetBlockByID(number uint64) (*models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bl, ok := m.blocks[number]
+	if !ok {
+		// same as the database, an empty block is returned when not found
+		return &models.Block{}, nil
+	}
+
+	block := m.blockCopyLocked(bl)
+	return &block, nil
+}
+
+// GetBlockByHash finds and returns the block with the provided Hash
+func (m *MemoryStore) GetBlockByHash(hash string) (*models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, bl := range m.blocks {
+		if strings.Compare(hash, strings.Join([]string{"0x", common.Bytes2Hex(bl.Hash[:])}, "")) == 0 {
+			block := m.blockCopyLocked(bl)
+			return &block, nil
+		}
+	}
+
+	return nil, errors.New("wrong block found")
+}
+
+// GetBlockRange returns range of blocks starting from fromNumber (inclusive),
+// going downwards or, when ascending is set, upwards
+func (m *MemoryStore) GetBlockRange(fromNumber, rng uint32, ascending bool) ([]models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	numbers := make([]uint64, 0)
+	for number := range m.blocks {
+		if (!ascending && number <= uint64(fromNumber)) || (ascending && number >= uint64(fromNumber)) {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return (numbers[i] > numbers[j]) != ascending })
+
+	var result []models.Block
+	for i, number := range numbers {
+		if uint32(i) >= rng {
+			break
+		}
+		result = append(result, m.blockCopyLocked(m.blocks[number]))
+	}
+
+	return result, nil
+}
+
+// GetBlocksByTimestamp finds and returns the block info ordered by timestamp
+func (m *MemoryStore) GetBlocksByTimestamp(timestamp hexutil.Uint64, timestampCondition models.TimestampCondition, producer string) ([]models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.Block
+	for _, bl := range m.blocks {
+		switch timestampCondition {
+		case models.TIMESTAMP_EQUAL:
+			if bl.TimeStamp != timestamp {
+				continue
+			}
+		case models.TIMESTAMP_GREATER_EQUAL_THAN:
+			if bl.TimeStamp < timestamp {
+				continue
+			}
+		case models.TIMESTAMP_SMALLER_EQUAL_THAN:
+			if bl.TimeStamp > timestamp {
+				continue
+			}
+		}
+
+		if common.IsHexAddress(producer) && bl.Producer != common.HexToAddress(producer) {
+			continue
+		}
+
+		result = append(result, m.blockCopyLocked(bl))
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].TimeStamp == result[j].TimeStamp {
+			return result[i].Number > result[j].Number
+		}
+		return result[i].TimeStamp > result[j].TimeStamp
+	})
+
+	return result, nil
+}
+
+// GetBlockNumberByTimestamp returns the number of the block closest to
+// timestamp that matches the condition, or sql.ErrNoRows when there is none
+func (m *MemoryStore) GetBlockNumberByTimestamp(timestamp uint64, timestampCondition models.TimestampCondition) (uint64, error) {
+	blocks, err := m.GetBlocksByTimestamp(hexutil.Uint64(timestamp), timestampCondition, "")
+	if err != nil {
+		return 0, err
+	}
+	if len(blocks) == 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	// the blocks are ordered by timestamp descending
+	if timestampCondition == models.TIMESTAMP_GREATER_EQUAL_THAN {
+		closest := blocks[len(blocks)-1]
+		for _, block := range blocks {
+			if block.TimeStamp == closest.TimeStamp && block.Number < closest.Number {
+				closest = block
+			}
+		}
+		return uint64(closest.Number), nil
+	}
+	return uint64(blocks[0].Number), nil
+}
+
+// InsertBlocks adds a number of Blocks in the store
+func (m *MemoryStore) InsertBlocks(blocks []*models.Block) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, bl := range blocks {
+		number := uint64(bl.Number)
+		if _, exists := m.blocks[number]; exists {
+			logger.Warn("Block already exists", "number", number)
+			continue
+		}
+
+		block := *bl
+		block.TransactionCount = hexutil.Uint64(len(bl.Transactions))
+		block.ProducerEns = nil
+		m.blocks[number] = &block
+	}
+
+	return nil
+}
+
+// DeleteBlockWithTransactionsByID deletes the block and its transactions by block number
+func (m *MemoryStore) DeleteBlockWithTransactionsByID(number uint64, producer common.Address) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.blocks, number)
+
+	for hash, txf := range m.transactions {
+		if uint64(txf.Tx.BlockNumber) == number {
+			delete(m.transactions, hash)
+		}
+	}
+
+	blockRewards := new(big.Int).Mul(big.NewInt(3171), precisionFactor)
+	prod, ok := m.producers[producer]
+	if !ok {
+		m.producers[producer] = &models.Producer{Address: producer, BlockRewards: new(big.Int)}
+	} else if prod.ProducedBlocksCount > 0 && prod.BlockRewards.Cmp(blockRewards) >= 0 {
+		prod.ProducedBlocksCount--
+		prod.BlockRewards = new(big.Int).Sub(prod.BlockRewards, blockRewards)
+	}
+
+	return nil
+}
+
+// GetTransactionByHash finds and returns the transaction with the provided Hash
+func (m *MemoryStore) GetTransactionByHash(hash string) (*models.TransactionFull, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	txf, ok := m.transactions[common.HexToHash(hash)]
+	if !ok {
+		return &models.TransactionFull{Tx: nil, Txr: nil}, nil
+	}
+
+	result := m.transactionCopyLocked(txf)
+	return &result, nil
+}
+
+// GetTransactionsByAddress finds and returns the transaction with the provided address
+// as source (FROM) or destination (TO), or the transactions of a block.
+// Results are ordered by (block_number, tx_index) and continue after the
+// cursor, or end before it when cursor.Prev is set.
+func (m *MemoryStore) GetTransactionsByAddress(address string, addrtype models.AddressType, cursor *models.Cursor, limit uint64, order string) ([]models.TransactionFull, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var blockNumber uint64
+	blockFound := false
+	if addrtype == models.ADDRESS_BLOCKHASH {
+		hash := common.HexToHash(address)
+		for number, bl := range m.blocks {
+			if bl.Hash == hash {
+				blockNumber = number
+				blockFound = true
+				break
+			}
+		}
+	}
+
+	// pages before the cursor are collected in reverse and flipped afterwards
+	descending := order == "desc"
+	reverse := cursor != nil && cursor.Prev
+	if reverse {
+		descending = !descending
+	}
+
+	less := func(a, b *models.Transaction) bool {
+		if a.BlockNumber != b.BlockNumber {
+			return a.BlockNumber < b.BlockNumber
+		}
+		return a.TransactionIndex < b.TransactionIndex
+	}
+
+	addr := common.HexToAddress(address)
+	matches := make([]*models.TransactionFull, 0)
+	for _, txf := range m.transactions {
+		isTo := txf.Tx.To != nil && *txf.Tx.To == addr
+		isFrom := txf.Tx.From == addr
+
+		switch addrtype {
+		case models.ADDRESS_TO:
+			if !isTo {
+				continue
+			}
+		case models.ADDRESS_FROM:
+			if !isFrom {
+				continue
+			}
+		case models.ADDRESS_ALL:
+			if !isTo && !isFrom {
+				continue
+			}
+		case models.ADDRESS_BLOCKHASH:
+			if !blockFound || uint64(txf.Tx.BlockNumber) != blockNumber {
+				continue
+			}
+		}
+
+		if cursor != nil {
+			pos := &models.Transaction{BlockNumber: hexutil.Uint64(cursor.Number), TransactionIndex: hexutil.Uint64(cursor.Index)}
+			if (descending && !less(txf.Tx, pos)) || (!descending && !less(pos, txf.Tx)) {
+				continue
+			}
+		}
+
+		matches = append(matches, txf)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return less(matches[i].Tx, matches[j].Tx) != descending
+	})
+
+	result := make([]models.TransactionFull, 0)
+	for i := 0; i < len(matches) && uint64(len(result)) < limit; i++ {
+		result = append(result, m.transactionCopyLocked(matches[i]))
+	}
+
+	if reverse {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+
+	return result, nil
+}
+
+// InsertTransactions adds a number of Transactions in the store
+func (m *MemoryStore) InsertTransactions(transactions []models.TransactionFull) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, txf := range transactions {
+		if _, exists := m.transactions[txf.Tx.Hash]; exists {
+			logger.Warn("Transaction already exists", "block", txf.Tx.BlockNumber, "hash", txf.Tx.Hash)
+			continue
+		}
+
+		tx := *txf.Tx
+		txr := *txf.Txr
+		tx.Value = hexutil.Big(*new(big.Int).Set(txf.Tx.Value.ToInt()))
+		tx.FromEns = nil
+		tx.ToEns = nil
+		txr.ContractAddressEns = nil
+
+		m.transactions[tx.Hash] = &models.TransactionFull{Tx: &tx, Txr: &txr}
+	}
+
+	return nil
+}
+
+// GetAddressTotals returns the block rewards and the transactions count of an address
+func (m *MemoryStore) GetAddressTotals(address string) (blockRewards *big.Int, txCount uint64, err error) {
+	addr := common.HexToAddress(address)
+
+	m.mu.RLock()
+	for _, txf := range m.transactions {
+		if txf.Tx.From == addr || (txf.Tx.To != nil && *txf.Tx.To == addr) {
+			txCount++
+		}
+	}
+	m.mu.RUnlock()
+
+	producer, err := m.GetProducer(address)
+	if err != nil {
+		return bigIntZero, txCount, err
+	}
+
+	return producer.BlockRewards, txCount, nil
+}
+
+// GetIsContractAddress checks if an address is a contract
+func (m *MemoryStore) GetIsContractAddress(address string) (bool, error) {
+	// return true for system contracts
+	if bytes.Compare(common.HexToAddress(address).Bytes(), []byte{1, 2}) <= 0 {
+		return true, nil
+	}
+
+	addr := common.HexToAddress(address)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, txf := range m.transactions {
+		if txf.Txr.ContractAddress != nil && *txf.Txr.ContractAddress == addr {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// InsertBalance inserts/updates the balance of an address
+func (m *MemoryStore) InsertBalance(address common.Address, balance uint64, blockNumber uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.balances[address] = &models.Balance{Address: address, Amount: balance, BlockNumber: blockNumber}
+	return nil
+}
+
+// GetBalanceStats gets the balances stats
+func (m *MemoryStore) GetBalanceStats() (uint64, uint64, uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.balances) == 0 {
+		return 0, 0, 0, errors.New("no balances found")
+	}
+
+	var count, max, min uint64
+	min = ^uint64(0)
+	for _, b := range m.balances {
+		count++
+		if b.Amount > max {
+			max = b.Amount
+		}
+		if b.Amount < min {
+			min = b.Amount
+		}
+	}
+
+	return count, max, min, nil
+}
+
+// GetTopBalances gets the rich list, ordered by (amount, address) descending,
+// continuing after the cursor or ending before it when cursor.Prev is set
+func (m *MemoryStore) GetTopBalances(cursor *models.Cursor, limit uint64) ([]models.Balance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reverse := cursor != nil && cursor.Prev
+
+	// greater reports whether a comes before b in the rich list
+	greater := func(a, b *models.Balance) bool {
+		if a.Amount == b.Amount {
+			return bytes.Compare(a.Address[:], b.Address[:]) > 0
+		}
+		return a.Amount > b.Amount
+	}
+
+	balances := make([]models.Balance, 0, len(m.balances))
+	for _, b := range m.balances {
+		if cursor != nil {
+			pos := &models.Balance{Amount: cursor.Number}
+			if cursor.Address != nil {
+				pos.Address = *cursor.Address
+			}
+			if (!reverse && !greater(pos, b)) || (reverse && !greater(b, pos)) {
+				continue
+			}
+		}
+		balances = append(balances, *b)
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return greater(&balances[i], &balances[j]) != reverse
+	})
+
+	result := make([]models.Balance, 0)
+	for i := 0; i < len(balances) && uint64(len(result)) < limit; i++ {
+		b := balances[i]
+		b.AddressEns = m.ensNameLocked(b.Address)
+		result = append(result, b)
+	}
+
+	if reverse {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+
+	return result, nil
+}
+
+// PurgeBalanceObject purges balances less than minAmount
+func (m *MemoryStore) PurgeBalanceObject(minAmount uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for address, b := range m.balances {
+		if b.Amount < minAmount {
+			delete(m.balances, address)
+		}
+	}
+	return nil
+}
+
+// InsertEns inserts/updates the address for a namehash
+func (m *MemoryStore) InsertEns(ens models.ENS) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ens[ens.Hash] = ens
+	return nil
+}
+
+// GetEnsName gets the ENS name for an address
+func (m *MemoryStore) GetEnsName(address string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	addr := common.HexToAddress(address)
+	for _, e := range m.ens {
+		if e.Address == addr {
+			return e.Name, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+// GetEnsCount gets the count of ENS entries
+func (m *MemoryStore) GetEnsCount() (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return uint64(len(m.ens)), nil
+}
+
+// GetEnsEntriesRange gets range of entries
+func (m *MemoryStore) GetEnsEntriesRange(limit uint64, offset uint64) ([]models.ENS, error) {
+	if limit == 0 {
+		limit = 20
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := make([]models.ENS, 0, len(m.ens))
+	for _, e := range m.ens {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Hash[:], entries[j].Hash[:]) < 0
+	})
+
+	var result []models.ENS
+	for i := offset; i < uint64(len(entries)) && uint64(len(result)) < limit; i++ {
+		result = append(result, entries[i])
+	}
+
+	return result, nil
+}
+
+// InsertProducer inserts/updates a producer and his stats
+func (m *MemoryStore) InsertProducer(producer models.Producer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	blockRewards := truncateValue(producer.BlockRewards)
+
+	prod, ok := m.producers[producer.Address]
+	if !ok {
+		m.producers[producer.Address] = &models.Producer{
+			Address:             producer.Address,
+			ProducedBlocksCount: producer.ProducedBlocksCount,
+			BlockRewards:        blockRewards,
+		}
+		return nil
+	}
+
+	prod.ProducedBlocksCount += producer.ProducedBlocksCount
+	prod.BlockRewards = new(big.Int).Add(prod.BlockRewards, blockRewards)
+	return nil
+}
+
+// GetProducer gets the producer
+func (m *MemoryStore) GetProducer(address string) (*models.Producer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prod, ok := m.producers[common.HexToAddress(address)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	producer := *prod
+	producer.BlockRewards = new(big.Int).Set(prod.BlockRewards)
+	return &producer, nil
+}
+
+// GetGlobalInt gets global int
+func (m *MemoryStore) GetGlobalInt(varName string) (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.globals[varName], nil
+}
+
+// SetGlobalInt sets global int
+func (m *MemoryStore) SetGlobalInt(varName string, valInt uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.globals[varName] = valInt
+	return nil
+}
+
+// SearchEnsNames returns the ENS entries whose name starts with prefix
+func (m *MemoryStore) SearchEnsNames(prefix string, limit uint64) ([]models.ENS, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.ENS, 0)
+	for _, e := range m.ens {
+		if strings.HasPrefix(e.Name, prefix) {
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	if uint64(len(result)) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// SearchAddresses returns known addresses (senders, recipients or producers)
+// starting with the hex prefix (without 0x)
+func (m *MemoryStore) SearchAddresses(prefix string, limit uint64) ([]common.Address, error) {
+	lo, hi := addressPrefixRange(prefix)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	found := make(map[common.Address]bool)
+	add := func(address common.Address) {
+		if bytes.Compare(address[:], lo[:]) >= 0 && bytes.Compare(address[:], hi[:]) <= 0 {
+			found[address] = true
+		}
+	}
+	for _, txf := range m.transactions {
+		add(txf.Tx.From)
+		if txf.Tx.To != nil {
+			add(*txf.Tx.To)
+		}
+	}
+	for _, bl := range m.blocks {
+		add(bl.Producer)
+	}
+
+	result := make([]common.Address, 0, len(found))
+	for address := range found {
+		result = append(result, address)
+	}
+	sort.Slice(result, func(i, j int) bool { return bytes.Compare(result[i][:], result[j][:]) < 0 })
+
+	if uint64(len(result)) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func webhookCopy(webhook *models.Webhook) models.Webhook {
+	w := *webhook
+	if webhook.MinValue != nil {
+		w.MinValue = new(big.Int).Set(webhook.MinValue)
+	} else {
+		w.MinValue = new(big.Int)
+	}
+	return w
+}
+
+// InsertWebhook stores a new webhook and sets its ID
+func (m *MemoryStore) InsertWebhook(webhook *models.Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.apiKeys[webhook.APIKeyID]; webhook.APIKeyID != 0 && !ok {
+		return errors.New("API key not found")
+	}
+
+	m.lastWebhookID++
+	webhook.ID = m.lastWebhookID
+
+	w := webhookCopy(webhook)
+	m.webhooks[w.ID] = &w
+	return nil
+}
+
+// GetWebhook returns the webhook with the id, or sql.ErrNoRows
+func (m *MemoryStore) GetWebhook(id uint64) (*models.Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	webhook, ok := m.webhooks[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	w := webhookCopy(webhook)
+	return &w, nil
+}
+
+// GetWebhooks returns all the webhooks
+func (m *MemoryStore) GetWebhooks() ([]models.Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.Webhook, 0, len(m.webhooks))
+	for _, webhook := range m.webhooks {
+		result = append(result, webhookCopy(webhook))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result, nil
+}
+
+// CountWebhooks returns how many webhooks were created with an API key
+func (m *MemoryStore) CountWebhooks(apiKeyID uint64) (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := uint64(0)
+	for _, webhook := range m.webhooks {
+		if apiKeyID != 0 && webhook.APIKeyID == apiKeyID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteWebhook removes the webhook and its delivery log, or returns sql.ErrNoRows
+func (m *MemoryStore) DeleteWebhook(id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.webhooks[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.webhooks, id)
+
+	deliveries := m.webhookDeliveries[:0]
+	for _, d := range m.webhookDeliveries {
+		if d.WebhookID != id {
+			deliveries = append(deliveries, d)
+		}
+	}
+	m.webhookDeliveries = deliveries
+
+	return nil
+}
+
+// InsertWebhookDeliveries adds deliveries to the log
+func (m *MemoryStore) InsertWebhookDeliveries(deliveries []models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range deliveries {
+		if _, ok := m.webhooks[d.WebhookID]; !ok {
+			return errors.New("webhook not found")
+		}
+	}
+
+	for i := range deliveries {
+		d := deliveries[i]
+		m.lastDeliveryID++
+		d.ID = m.lastDeliveryID
+		m.webhookDeliveries = append(m.webhookDeliveries, &d)
+	}
+
+	return nil
+}
+
+// GetPendingWebhookDeliveries returns up to limit pending deliveries due at now
+func (m *MemoryStore) GetPendingWebhookDeliveries(now uint64, limit uint64) ([]models.WebhookDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.WebhookDelivery, 0)
+	for _, d := range m.webhookDeliveries {
+		if d.Status == models.WEBHOOK_DELIVERY_PENDING && d.NextAttemptAt <= now {
+			result = append(result, *d)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].NextAttemptAt < result[j].NextAttemptAt })
+	if uint64(len(result)) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook, newest first
+func (m *MemoryStore) GetWebhookDeliveries(webhookID uint64, limit uint64) ([]models.WebhookDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.WebhookDelivery, 0)
+	for i := len(m.webhookDeliveries) - 1; i >= 0 && uint64(len(result)) < limit; i-- {
+		if d := m.webhookDeliveries[i]; d.WebhookID == webhookID {
+			result = append(result, *d)
+		}
+	}
+
+	return result, nil
+}
+
+// UpdateWebhookDelivery records the outcome of a delivery attempt
+func (m *MemoryStore) UpdateWebhookDelivery(delivery models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range m.webhookDeliveries {
+		if d.ID == delivery.ID {
+			d.Status = delivery.Status
+			d.Attempts = delivery.Attempts
+			d.StatusCode = delivery.StatusCode
+			d.Error = delivery.Error
+			d.NextAttemptAt = 0
+			if delivery.Status == models.WEBHOOK_DELIVERY_PENDING {
+				d.NextAttemptAt = delivery.NextAttemptAt
+			}
+			d.UpdatedAt = delivery.UpdatedAt
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// inExportRange reports whether timestamp is in the time range of the filter
+func inExportRange(filter models.ExportFilter, timestamp uint64) bool {
+	return (filter.FromTime == 0 || timestamp >= filter.FromTime) && (filter.ToTime == 0 || timestamp < filter.ToTime)
+}
+
+// inExportBlockRange reports whether number is in the block range of the filter
+func inExportBlockRange(filter models.ExportFilter, number uint64) bool {
+	return (filter.FromBlock == 0 || number >= filter.FromBlock) && (filter.ToBlock == 0 || number <= filter.ToBlock)
+}
+
+// ExportTransactionsByAddress calls fn for every transaction of the address
+// (or the block, for ADDRESS_BLOCKHASH) in the filter's time and block range
+func (m *MemoryStore) ExportTransactionsByAddress(address string, addrtype models.AddressType, filter models.ExportFilter, fn func(models.TransactionFull) error) error {
+	if addrtype == models.LATEST {
+		return errors.New("unsupported address type")
+	}
+
+	order := "asc"
+	if filter.Descending {
+		order = "desc"
+	}
+
+	// everything is in memory already, the copies are only taken so that
+	// fn runs without holding the lock
+	txs, err := m.GetTransactionsByAddress(address, addrtype, nil, ^uint64(0), order)
+	if err != nil {
+		return err
+	}
+
+	rows := uint64(0)
+	for _, tf := range txs {
+		if !inExportRange(filter, uint64(tf.Tx.Timestamp)) || !inExportBlockRange(filter, uint64(tf.Tx.BlockNumber)) {
+			continue
+		}
+		if filter.Limit > 0 && rows == filter.Limit {
+			break
+		}
+		rows++
+		if err := fn(tf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportBlocks calls fn for every block numbered from fromNumber to toNumber
+// (inclusive) in the filter's time range
+func (m *MemoryStore) ExportBlocks(fromNumber, toNumber uint64, filter models.ExportFilter, fn func(models.Block) error) error {
+	m.mu.RLock()
+	blocks := make([]models.Block, 0)
+	for number, bl := range m.blocks {
+		if number >= fromNumber && number <= toNumber && inExportRange(filter, uint64(bl.TimeStamp)) {
+			blocks = append(blocks, m.blockCopyLocked(bl))
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(blocks, func(i, j int) bool { return (blocks[i].Number < blocks[j].Number) != filter.Descending })
+
+	for _, block := range blocks {
+		if err := fn(block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportBalances calls fn for every entry of the rich list, richest first
+func (m *MemoryStore) ExportBalances(fn func(models.Balance) error) error {
+	balances, err := m.GetTopBalances(nil, ^uint64(0))
+	if err != nil {
+		return err
+	}
+
+	for _, balance := range balances {
+		if err := fn(balance); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetBlocksByNumbers returns the blocks with the numbers, in no particular
+// order. Missing blocks are left out.
+func (m *MemoryStore) GetBlocksByNumbers(numbers []uint64) ([]models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.Block, 0, len(numbers))
+	seen := make(map[uint64]bool)
+	for _, number := range numbers {
+		bl, ok := m.blocks[number]
+		if !ok || seen[number] {
+			continue
+		}
+		seen[number] = true
+		result = append(result, m.blockCopyLocked(bl))
+	}
+
+	return result, nil
+}
+
+// GetTransactionsByBlockNumbers returns the transactions of the blocks,
+// ordered by (block_number, tx_index)
+func (m *MemoryStore) GetTransactionsByBlockNumbers(numbers []uint64) ([]models.TransactionFull, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wanted := make(map[uint64]bool)
+	for _, number := range numbers {
+		wanted[number] = true
+	}
+
+	result := make([]models.TransactionFull, 0)
+	for _, tf := range m.transactions {
+		if wanted[uint64(tf.Tx.BlockNumber)] {
+			result = append(result, m.transactionCopyLocked(tf))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tx.BlockNumber != result[j].Tx.BlockNumber {
+			return result[i].Tx.BlockNumber < result[j].Tx.BlockNumber
+		}
+		return result[i].Tx.TransactionIndex < result[j].Tx.TransactionIndex
+	})
+
+	return result, nil
+}
+
+// GetEnsNames returns the ENS name of each address that has one
+func (m *MemoryStore) GetEnsNames(addresses []common.Address) (map[common.Address]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[common.Address]string)
+	for _, address := range addresses {
+		if name := m.ensNameLocked(address); name != "" {
+			result[address] = name
+		}
+	}
+
+	return result, nil
+}
+
+// GetProducers returns the producers among the addresses
+func (m *MemoryStore) GetProducers(addresses []common.Address) ([]models.Producer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.Producer, 0, len(addresses))
+	seen := make(map[common.Address]bool)
+	for _, address := range addresses {
+		prod, ok := m.producers[address]
+		if !ok || seen[address] {
+			continue
+		}
+		seen[address] = true
+
+		producer := *prod
+		producer.BlockRewards = new(big.Int).Set(prod.BlockRewards)
+		result = append(result, producer)
+	}
+
+	return result, nil
+}
+
+// GetIsContractAddresses returns which of the addresses are contracts,
+// leaving out the ones that are not
+func (m *MemoryStore) GetIsContractAddresses(addresses []common.Address) (map[common.Address]bool, error) {
+	result := make(map[common.Address]bool)
+	for _, address := range addresses {
+		isContract, err := m.GetIsContractAddress(address.Hex())
+		if err != nil {
+			return nil, err
+		}
+		if isContract {
+			result[address] = true
+		}
+	}
+
+	return result, nil
+}
+
+// GetTransactionCounts returns the transactions count of each address
+func (m *MemoryStore) GetTransactionCounts(addresses []common.Address) (map[common.Address]uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[common.Address]uint64, len(addresses))
+	for _, address := range addresses {
+		result[address] = 0
+	}
+
+	for _, txf := range m.transactions {
+		if _, ok := result[txf.Tx.From]; ok {
+			result[txf.Tx.From]++
+		}
+		if txf.Tx.To != nil && *txf.Tx.To != txf.Tx.From {
+			if _, ok := result[*txf.Tx.To]; ok {
+				result[*txf.Tx.To]++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// GetTransactionsByAddresses returns a page of the transactions of each
+// address, like GetTransactionsByAddress with ADDRESS_ALL does. Pages only
+// continue after the cursor, cursor.Prev is not supported.
+func (m *MemoryStore) GetTransactionsByAddresses(addresses []common.Address, cursor *models.Cursor, limit uint64, order string) (map[common.Address][]models.TransactionFull, error) {
+	result := make(map[common.Address][]models.TransactionFull, len(addresses))
+	for _, address := range addresses {
+		txs, err := m.GetTransactionsByAddress(address.Hex(), models.ADDRESS_ALL, cursor, limit, order)
+		if err != nil {
+			return nil, err
+		}
+		if len(txs) > 0 {
+			result[address] = txs
+		}
+	}
+
+	return result, nil
+}
+
+// RefreshRollups recomputes every hourly and daily rollup that overlaps
+// the time range [from, to] from the stored blocks and transactions
+func (m *MemoryStore) RefreshRollups(from, to uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for interval, length := range models.RollupIntervals {
+		if m.rollups[interval] == nil {
+			m.rollups[interval] = make(map[uint64]models.Rollup)
+		}
+
+		for _, bucket := range models.RollupBuckets(interval, from, to) {
+			m.rollups[interval][bucket] = m.computeRollupLocked(interval, bucket, bucket+length)
+		}
+	}
+
+	return nil
+}
+
+// computeRollupLocked aggregates the blocks and transactions in [start, end)
+func (m *MemoryStore) computeRollupLocked(interval string, start, end uint64) models.Rollup {
+	r := models.Rollup{Interval: interval, Bucket: start}
+
+	var firstBlock uint64
+	for number, bl := range m.blocks {
+		if uint64(bl.TimeStamp) < start || uint64(bl.TimeStamp) >= end {
+			continue
+		}
+		if r.BlockCount == 0 || number < firstBlock {
+			firstBlock = number
+		}
+		r.BlockCount++
+		r.GasUsed += uint64(bl.GasUsed)
+		r.TotalBlockSize += uint64(bl.Size)
+	}
+
+	active := make(map[common.Address]bool)
+	seenBefore := make(map[common.Address]bool)
+
+	for _, txf := range m.transactions {
+		tx := txf.Tx
+		if uint64(tx.BlockNumber) < firstBlock {
+			seenBefore[tx.From] = true
+			if tx.To != nil {
+				seenBefore[*tx.To] = true
+			}
+		}
+
+		if uint64(tx.Timestamp) < start || uint64(tx.Timestamp) >= end {
+			continue
+		}
+
+		r.TxCount++
+		if txf.Txr.Status == 0 {
+			r.FailedTxs++
+		}
+		if txf.Txr.ContractAddress != nil && *txf.Txr.ContractAddress != (common.Address{}) {
+			r.ContractDeployments++
+		}
+
+		active[tx.From] = true
+		if tx.To != nil {
+			active[*tx.To] = true
+		}
+	}
+
+	r.ActiveAddresses = uint64(len(active))
+	for address := range active {
+		if !seenBefore[address] {
+			r.NewAddresses++
+		}
+	}
+
+	return r
+}
+
+// GetRollups returns the stored rollups of interval whose bucket overlaps
+// the time range [from, to], in chronological order
+func (m *MemoryStore) GetRollups(interval string, from, to uint64) ([]models.Rollup, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	length, ok := models.RollupIntervals[interval]
+	if !ok {
+		return nil, ErrInvalidRollupInterval
+	}
+
+	result := make([]models.Rollup, 0)
+	for bucket, r := range m.rollups[interval] {
+		if bucket >= from-from%length && bucket <= to {
+			result = append(result, r)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Bucket < result[j].Bucket })
+
+	return result, nil
+}
+
+// InsertContractABI stores the ABI of a contract, replacing the one
+// uploaded before
+func (m *MemoryStore) InsertContractABI(address common.Address, abi string, uploadedAt uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.contractABIs[address] = abi
+	return nil
+}
+
+// GetContractABI returns the uploaded ABI of a contract, or sql.ErrNoRows
+func (m *MemoryStore) GetContractABI(address common.Address) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	abi, ok := m.contractABIs[address]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return abi, nil
+}
+
+// InsertAPIKey stores a new API key and sets its ID
+func (m *MemoryStore) InsertAPIKey(key *models.APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.apiKeys {
+		if k.Hash == key.Hash {
+			return errors.New("duplicate API key")
+		}
+	}
+
+	m.lastAPIKeyID++
+	key.ID = m.lastAPIKeyID
+
+	k := *key
+	m.apiKeys[k.ID] = &k
+	return nil
+}
+
+// GetAPIKey returns the API key with the id, or sql.ErrNoRows
+func (m *MemoryStore) GetAPIKey(id uint64) (*models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.apiKeys[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	k := *key
+	return &k, nil
+}
+
+// GetAPIKeyByHash returns the API key with the hash, or sql.ErrNoRows
+func (m *MemoryStore) GetAPIKeyByHash(hash string) (*models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.apiKeys {
+		if key.Hash == hash {
+			k := *key
+			return &k, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// GetAPIKeys returns all the API keys, revoked ones included
+func (m *MemoryStore) GetAPIKeys() ([]models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.APIKey, 0, len(m.apiKeys))
+	for _, key := range m.apiKeys {
+		result = append(result, *key)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result, nil
+}
+
+// RevokeAPIKey marks the API key as revoked, or returns sql.ErrNoRows
+func (m *MemoryStore) RevokeAPIKey(id uint64, revokedAt uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.apiKeys[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if !key.Revoked() {
+		key.RevokedAt = revokedAt
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ebakus_db_query_duration_seconds",
+		Help:    "Latency of the database queries, by the store method running them",
+		Buckets: metrics.DefaultBuckets,
+	}, []string{"method"})
+	queryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ebakus_db_query_errors_total",
+		Help: "Database queries that failed, by the store method running them",
+	}, []string{"method"})
+)
+
+// timedDB times the queries run on the database, logging them at debug.
+// Queries are labelled with the store method running them. Statements run
+// in transactions aren't timed.
+type timedDB struct {
+	*sql.DB
+	log logger.Logger
+}
+
+func (t timedDB) Query(method, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := t.DB.Query(query, args...)
+	t.observeQuery(method, start, err)
+	return rows, err
+}
+
+// QueryRow is timed until the query returns, its errors surface on Scan
+func (t timedDB) QueryRow(method, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := t.DB.QueryRow(query, args...)
+	t.observeQuery(method, start, nil)
+	return row
+}
+
+func (t timedDB) Exec(method, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := t.DB.Exec(query, args...)
+	t.observeQuery(method, start, err)
+	return res, err
+}
+
+// observeQuery records a query of a store method
+func (t timedDB) observeQuery(method string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	queryDuration.WithLabelValues(method).Observe(elapsed.Seconds())
+	if err != nil && err != sql.ErrNoRows {
+		queryErrors.WithLabelValues(method).Inc()
+		t.log.Debug("Query failed", "method", method, "elapsed", elapsed, "err", err)
+		return
+	}
+	t.log.Debug("Query", "method", method, "elapsed", elapsed)
+}
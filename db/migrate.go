@@ -0,0 +1,203 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/schema"
+
+	"github.com/urfave/cli"
+)
+
+var (
+	// ErrSchemaOutdated is returned by Init when migrations are pending
+	ErrSchemaOutdated = errors.New("Database schema is out of date")
+)
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+const createMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name VARCHAR(64),
+		applied_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+	)
+`
+
+// OpenFromCli is the same as Open but receives it's parameters
+// from a Context struct of the cli package (aka from program arguments)
+func OpenFromCli(c *cli.Context) (*sql.DB, error) {
+	return Open(connParamsFromCli(c))
+}
+
+// migrationsTableExists tells whether the database is under version control
+func migrationsTableExists(tdb *sql.DB) (bool, error) {
+	var tableExists bool
+	query := "SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = 'public' AND table_name = 'schema_migrations')"
+	err := tdb.QueryRow(query).Scan(&tableExists)
+	return tableExists, err
+}
+
+// SchemaVersion returns the latest migration applied to the database,
+// or 0 when the database is not under version control yet
+func SchemaVersion(tdb *sql.DB) (int, error) {
+	tableExists, err := migrationsTableExists(tdb)
+	if err != nil {
+		return 0, err
+	}
+
+	if !tableExists {
+		return 0, nil
+	}
+
+	var version sql.NullInt64
+	if err := tdb.QueryRow("SELECT max(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, err
+	}
+
+	return int(version.Int64), nil
+}
+
+// appliedMigrations returns when each applied migration was applied. It
+// doesn't create the migrations table, so that reading the status of a
+// database never changes it.
+func appliedMigrations(tdb *sql.DB) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+
+	tableExists, err := migrationsTableExists(tdb)
+	if err != nil || !tableExists {
+		return applied, err
+	}
+
+	rows, err := tdb.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+func runMigration(tdb *sql.DB, m schema.Migration, up bool) (err error) {
+	txn, err := tdb.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			txn.Rollback()
+		} else {
+			err = txn.Commit()
+		}
+	}()
+
+	if up {
+		if _, err = txn.Exec(m.Up); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %v", m.Version, m.Name, err)
+		}
+		_, err = txn.Exec("INSERT INTO schema_migrations(version, name) VALUES ($1, $2)", m.Version, m.Name)
+		return err
+	}
+
+	if _, err = txn.Exec(m.Down); err != nil {
+		return fmt.Errorf("rollback of migration %d (%s) failed: %v", m.Version, m.Name, err)
+	}
+	_, err = txn.Exec("DELETE FROM schema_migrations WHERE version = $1", m.Version)
+	return err
+}
+
+// MigrateUp applies all pending migrations up to and including target.
+// A target of 0 migrates to the latest version.
+func MigrateUp(tdb *sql.DB, target int) (int, error) {
+	if _, err := tdb.Exec(createMigrationsTable); err != nil {
+		return 0, err
+	}
+
+	applied, err := appliedMigrations(tdb)
+	if err != nil {
+		return 0, err
+	}
+
+	if target == 0 {
+		target = schema.Latest()
+	}
+
+	count := 0
+	for _, m := range schema.Migrations {
+		if m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		logger.Info("Applying migration", "version", m.Version, "name", m.Name)
+		if err := runMigration(tdb, m, true); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrateDown rolls back the last `steps` applied migrations
+func MigrateDown(tdb *sql.DB, steps int) (int, error) {
+	applied, err := appliedMigrations(tdb)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(schema.Migrations) - 1; i >= 0 && count < steps; i-- {
+		m := schema.Migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		logger.Info("Rolling back migration", "version", m.Version, "name", m.Name)
+		if err := runMigration(tdb, m, false); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// GetMigrationStatus lists all known migrations and when they were applied
+func GetMigrationStatus(tdb *sql.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(tdb)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationStatus, 0, len(schema.Migrations))
+	for _, m := range schema.Migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		result = append(result, status)
+	}
+
+	return result, nil
+}
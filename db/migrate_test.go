@@ -0,0 +1,75 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/schema"
+)
+
+func TestMigrationStatus(t *testing.T) {
+	tdb := openTestDB(t)
+	defer tdb.Close()
+	resetTestDB(t, tdb)
+
+	status, err := GetMigrationStatus(tdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != len(schema.Migrations) || status[0].AppliedAt != nil {
+		t.Errorf("GetMigrationStatus() of an empty database = %+v", status)
+	}
+
+	// reading the status doesn't put the database under version control
+	if exists, err := migrationsTableExists(tdb); err != nil || exists {
+		t.Errorf("schema_migrations exists after reading the status: %v, %v", exists, err)
+	}
+
+	if _, err := MigrateUp(tdb, 0); err != nil {
+		t.Fatal(err)
+	}
+	status, err = GetMigrationStatus(tdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range status {
+		if m.AppliedAt == nil {
+			t.Errorf("migration %d is pending after migrating up", m.Version)
+		}
+	}
+}
+
+func TestMigrateProducersBackfill(t *testing.T) {
+	tdb := openTestDB(t)
+	defer tdb.Close()
+	resetTestDB(t, tdb)
+
+	if _, err := MigrateUp(tdb, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &DBClient{timedDB{tdb, logger.New()}}
+	blocks, _ := testChain()
+	if err := store.InsertBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MigrateUp(tdb, 2); err != nil {
+		t.Fatal(err)
+	}
+	if producer, err := store.GetProducer(testProducer.Hex()); err != nil || producer.ProducedBlocksCount != 4 {
+		t.Fatalf("GetProducer() after the backfill = %+v, %v, want 4 blocks", producer, err)
+	}
+
+	// the blocks produced since are kept by the rollback
+	if err := store.InsertProducer(models.Producer{Address: testProducer, ProducedBlocksCount: 1, BlockRewards: precisionFactor}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MigrateDown(tdb, 1); err != nil {
+		t.Fatal(err)
+	}
+	if producer, err := store.GetProducer(testProducer.Hex()); err != nil || producer.ProducedBlocksCount != 1 || producer.BlockRewards.Cmp(precisionFactor) != 0 {
+		t.Errorf("GetProducer() after the rollback = %+v, %v, want 1 block", producer, err)
+	}
+}
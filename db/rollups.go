@@ -0,0 +1,106 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+// ErrInvalidRollupInterval is returned for intervals other than hour and day
+var ErrInvalidRollupInterval = errors.New("invalid rollup interval")
+
+const rollupColumns = "period, bucket, block_count, tx_count, active_addresses, new_addresses, gas_used, total_block_size, contract_deployments, failed_txs"
+
+// refreshRollupQuery recomputes a single bucket from the blocks and the
+// transactions in [$2, $3). New addresses are the active ones without a
+// transaction in an earlier block.
+const refreshRollupQuery = `
+WITH bucket_blocks AS (
+  SELECT COUNT(*) AS block_count, COALESCE(SUM(gas_used), 0) AS gas_used, COALESCE(SUM(size), 0) AS total_block_size, MIN(number) AS first_block
+  FROM blocks WHERE timestamp >= $2 AND timestamp < $3
+), bucket_txs AS (
+  SELECT addr_from, addr_to, status, contract_address
+  FROM transactions WHERE timestamp >= $2 AND timestamp < $3
+), active AS (
+  SELECT addr_from AS address FROM bucket_txs
+  UNION
+  SELECT addr_to FROM bucket_txs WHERE addr_to IS NOT NULL
+)
+INSERT INTO rollups(` + rollupColumns + `)
+SELECT $1, $2, b.block_count,
+  (SELECT COUNT(*) FROM bucket_txs),
+  (SELECT COUNT(*) FROM active),
+  (SELECT COUNT(*) FROM active a
+    WHERE NOT EXISTS (SELECT 1 FROM transactions t WHERE t.addr_from = a.address AND t.block_number < b.first_block)
+    AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.addr_to = a.address AND t.block_number < b.first_block)),
+  b.gas_used, b.total_block_size,
+  (SELECT COUNT(*) FROM bucket_txs WHERE contract_address IS NOT NULL AND contract_address <> $4),
+  (SELECT COUNT(*) FROM bucket_txs WHERE status = 0)
+FROM bucket_blocks b
+ON CONFLICT (period, bucket) DO UPDATE SET
+  block_count = EXCLUDED.block_count,
+  tx_count = EXCLUDED.tx_count,
+  active_addresses = EXCLUDED.active_addresses,
+  new_addresses = EXCLUDED.new_addresses,
+  gas_used = EXCLUDED.gas_used,
+  total_block_size = EXCLUDED.total_block_size,
+  contract_deployments = EXCLUDED.contract_deployments,
+  failed_txs = EXCLUDED.failed_txs`
+
+// RefreshRollups recomputes every hourly and daily rollup that overlaps
+// the time range [from, to] from the stored blocks and transactions
+func (cli *DBClient) RefreshRollups(from, to uint64) error {
+	txn, err := cli.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := txn.Prepare(refreshRollupQuery)
+	if err != nil {
+		txn.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for interval, length := range models.RollupIntervals {
+		for _, bucket := range models.RollupBuckets(interval, from, to) {
+			if _, err := stmt.Exec(interval, bucket, bucket+length, common.Address{}.Bytes()); err != nil {
+				txn.Rollback()
+				return err
+			}
+		}
+	}
+
+	return txn.Commit()
+}
+
+// GetRollups returns the stored rollups of interval whose bucket overlaps
+// the time range [from, to], in chronological order
+func (cli *DBClient) GetRollups(interval string, from, to uint64) ([]models.Rollup, error) {
+	length, ok := models.RollupIntervals[interval]
+	if !ok {
+		return nil, ErrInvalidRollupInterval
+	}
+
+	query := "SELECT " + rollupColumns + " FROM rollups WHERE period = $1 AND bucket >= $2 AND bucket <= $3 ORDER BY bucket"
+	rows, err := cli.db.Query("GetRollups", query, interval, from-from%length, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.Rollup, 0)
+
+	for rows.Next() {
+		var r models.Rollup
+		if err := rows.Scan(&r.Interval, &r.Bucket, &r.BlockCount, &r.TxCount, &r.ActiveAddresses, &r.NewAddresses,
+			&r.GasUsed, &r.TotalBlockSize, &r.ContractDeployments, &r.FailedTxs); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+
+	return result, rows.Err()
+}
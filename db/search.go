@@ -0,0 +1,86 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+// addressPrefixRange returns the lowest and highest address starting
+// with the hex prefix (without 0x)
+func addressPrefixRange(prefix string) (common.Address, common.Address) {
+	if len(prefix) > 2*common.AddressLength {
+		prefix = prefix[:2*common.AddressLength]
+	}
+	padding := 2*common.AddressLength - len(prefix)
+	lo := common.HexToAddress(prefix + strings.Repeat("0", padding))
+	hi := common.HexToAddress(prefix + strings.Repeat("f", padding))
+	return lo, hi
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SearchEnsNames returns the ENS entries whose name starts with prefix
+func (cli *DBClient) SearchEnsNames(prefix string, limit uint64) ([]models.ENS, error) {
+	query := "SELECT hash, address, name FROM ens WHERE name LIKE $1 ORDER BY name LIMIT $2"
+	rows, err := cli.db.Query("SearchEnsNames", query, escapeLike(prefix)+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.ENS, 0)
+
+	for rows.Next() {
+		var ens models.ENS
+		var address, hash []byte
+
+		if err := rows.Scan(&hash, &address, &ens.Name); err != nil {
+			return nil, err
+		}
+
+		ens.Hash.SetBytes(hash)
+		ens.Address.SetBytes(address)
+
+		result = append(result, ens)
+	}
+
+	return result, rows.Err()
+}
+
+// SearchAddresses returns known addresses (senders, recipients or producers)
+// starting with the hex prefix (without 0x)
+func (cli *DBClient) SearchAddresses(prefix string, limit uint64) ([]common.Address, error) {
+	lo, hi := addressPrefixRange(prefix)
+
+	// every part is limited on its own, so that the btree indexes stop early
+	query := strings.Join([]string{
+		"SELECT address FROM (",
+		"   (SELECT DISTINCT addr_from AS address FROM transactions WHERE addr_from BETWEEN $1 AND $2 ORDER BY addr_from LIMIT $3)",
+		"   UNION (SELECT DISTINCT addr_to FROM transactions WHERE addr_to BETWEEN $1 AND $2 ORDER BY addr_to LIMIT $3)",
+		"   UNION (SELECT DISTINCT producer FROM blocks WHERE producer BETWEEN $1 AND $2 ORDER BY producer LIMIT $3)",
+		" ) AS a",
+		" ORDER BY address LIMIT $3"}, "")
+	rows, err := cli.db.Query("SearchAddresses", query, lo.Bytes(), hi.Bytes(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]common.Address, 0)
+
+	for rows.Next() {
+		var address []byte
+		if err := rows.Scan(&address); err != nil {
+			return nil, err
+		}
+		result = append(result, common.BytesToAddress(address))
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+// Store is the storage backend used by the explorer and the crawler.
+// DBClient is the PostgreSQL implementation used in production, while
+// MemoryStore keeps everything in process for tests and local demos.
+type Store interface {
+	// Ping checks that the store can be reached
+	Ping(ctx context.Context) error
+
+	// Blocks
+	GetLatestBlockNumber() (uint64, error)
+	GetBlockByID(number uint64) (*models.Block, error)
+	GetBlockByHash(hash string) (*models.Block, error)
+	GetBlockRange(fromNumber, rng uint32, ascending bool) ([]models.Block, error)
+	GetBlocksByTimestamp(timestamp hexutil.Uint64, timestampCondition models.TimestampCondition, producer string) ([]models.Block, error)
+	GetBlockNumberByTimestamp(timestamp uint64, timestampCondition models.TimestampCondition) (uint64, error)
+	InsertBlocks(blocks []*models.Block) error
+	DeleteBlockWithTransactionsByID(number uint64, producer common.Address) error
+
+	// Transactions
+	GetTransactionByHash(hash string) (*models.TransactionFull, error)
+	GetTransactionsByAddress(address string, addrtype models.AddressType, cursor *models.Cursor, limit uint64, order string) ([]models.TransactionFull, error)
+	InsertTransactions(transactions []models.TransactionFull) error
+	GetAddressTotals(address string) (blockRewards *big.Int, txCount uint64, err error)
+	GetIsContractAddress(address string) (bool, error)
+
+	// Balances
+	InsertBalance(address common.Address, balance uint64, blockNumber uint64) error
+	GetBalanceStats() (uint64, uint64, uint64, error)
+	GetTopBalances(cursor *models.Cursor, limit uint64) ([]models.Balance, error)
+	PurgeBalanceObject(minAmount uint64) error
+
+	// ENS
+	InsertEns(ens models.ENS) error
+	GetEnsName(address string) (string, error)
+	GetEnsCount() (uint64, error)
+	GetEnsEntriesRange(limit uint64, offset uint64) ([]models.ENS, error)
+
+	// Search
+	SearchEnsNames(prefix string, limit uint64) ([]models.ENS, error)
+	SearchAddresses(prefix string, limit uint64) ([]common.Address, error)
+
+	// Exports, streaming rows to fn instead of returning them
+	ExportTransactionsByAddress(address string, addrtype models.AddressType, filter models.ExportFilter, fn func(models.TransactionFull) error) error
+	ExportBlocks(fromNumber, toNumber uint64, filter models.ExportFilter, fn func(models.Block) error) error
+	ExportBalances(fn func(models.Balance) error) error
+
+	// Batched lookups, used to resolve nested fields without a query per item
+	GetBlocksByNumbers(numbers []uint64) ([]models.Block, error)
+	GetTransactionsByBlockNumbers(numbers []uint64) ([]models.TransactionFull, error)
+	GetEnsNames(addresses []common.Address) (map[common.Address]string, error)
+	GetProducers(addresses []common.Address) ([]models.Producer, error)
+	GetIsContractAddresses(addresses []common.Address) (map[common.Address]bool, error)
+	GetTransactionCounts(addresses []common.Address) (map[common.Address]uint64, error)
+	GetTransactionsByAddresses(addresses []common.Address, cursor *models.Cursor, limit uint64, order string) (map[common.Address][]models.TransactionFull, error)
+
+	// Contract ABIs uploaded by users, for contracts the node has none for
+	InsertContractABI(address common.Address, abi string, uploadedAt uint64) error
+	GetContractABI(address common.Address) (string, error)
+
+	// Analytics, hourly and daily rollups of the network activity
+	RefreshRollups(from, to uint64) error
+	GetRollups(interval string, from, to uint64) ([]models.Rollup, error)
+
+	// Webhooks
+	InsertWebhook(webhook *models.Webhook) error
+	GetWebhook(id uint64) (*models.Webhook, error)
+	GetWebhooks() ([]models.Webhook, error)
+	CountWebhooks(apiKeyID uint64) (uint64, error)
+	DeleteWebhook(id uint64) error
+	InsertWebhookDeliveries(deliveries []models.WebhookDelivery) error
+	GetPendingWebhookDeliveries(now uint64, limit uint64) ([]models.WebhookDelivery, error)
+	GetWebhookDeliveries(webhookID uint64, limit uint64) ([]models.WebhookDelivery, error)
+	UpdateWebhookDelivery(delivery models.WebhookDelivery) error
+
+	// API keys
+	InsertAPIKey(key *models.APIKey) error
+	GetAPIKey(id uint64) (*models.APIKey, error)
+	GetAPIKeyByHash(hash string) (*models.APIKey, error)
+	GetAPIKeys() ([]models.APIKey, error)
+	RevokeAPIKey(id uint64, revokedAt uint64) error
+
+	// Producers
+	InsertProducer(producer models.Producer) error
+	GetProducer(address string) (*models.Producer, error)
+
+	// Globals
+	GetGlobalInt(varName string) (uint64, error)
+	SetGlobalInt(varName string, valInt uint64) error
+}
+
+// Make sure both implementations satisfy the interface
+var (
+	_ Store = (*DBClient)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
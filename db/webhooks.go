@@ -0,0 +1,187 @@
+package db
+
+import (
+	"database/sql"
+	"math/big"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+)
+
+const webhookColumns = "id, url, secret, event_type, address, direction, min_value, created_at, COALESCE(api_key_id, 0)"
+
+const webhookDeliveryColumns = "id, webhook_id, event_type, payload, status, attempts, status_code, error, COALESCE(next_attempt_at, 0), created_at, updated_at"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	var webhook models.Webhook
+	var address []byte
+	var minValue string
+
+	if err := row.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &webhook.EventType, &address, &webhook.Direction, &minValue, &webhook.CreatedAt, &webhook.APIKeyID); err != nil {
+		return nil, err
+	}
+
+	webhook.Address.SetBytes(address)
+	webhook.MinValue, _ = new(big.Int).SetString(minValue, 10)
+	if webhook.MinValue == nil {
+		webhook.MinValue = new(big.Int)
+	}
+
+	return &webhook, nil
+}
+
+func scanWebhookDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload string
+
+	if err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventType, &payload, &delivery.Status, &delivery.Attempts,
+		&delivery.StatusCode, &delivery.Error, &delivery.NextAttemptAt, &delivery.CreatedAt, &delivery.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = []byte(payload)
+
+	return &delivery, nil
+}
+
+// InsertWebhook stores a new webhook and sets its ID
+func (cli *DBClient) InsertWebhook(webhook *models.Webhook) error {
+	minValue := "0"
+	if webhook.MinValue != nil {
+		minValue = webhook.MinValue.String()
+	}
+
+	var apiKeyID sql.NullInt64
+	if webhook.APIKeyID != 0 {
+		apiKeyID = sql.NullInt64{Int64: int64(webhook.APIKeyID), Valid: true}
+	}
+
+	query := "INSERT INTO webhooks(url, secret, event_type, address, direction, min_value, created_at, api_key_id) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id"
+	return cli.db.QueryRow("InsertWebhook", query, webhook.URL, webhook.Secret, webhook.EventType, webhook.Address.Bytes(),
+		webhook.Direction, minValue, webhook.CreatedAt, apiKeyID).Scan(&webhook.ID)
+}
+
+// CountWebhooks returns how many webhooks were created with an API key
+func (cli *DBClient) CountWebhooks(apiKeyID uint64) (uint64, error) {
+	var count uint64
+	err := cli.db.QueryRow("CountWebhooks", "SELECT count(*) FROM webhooks WHERE api_key_id = $1", apiKeyID).Scan(&count)
+	return count, err
+}
+
+// GetWebhook returns the webhook with the id, or sql.ErrNoRows
+func (cli *DBClient) GetWebhook(id uint64) (*models.Webhook, error) {
+	row := cli.db.QueryRow("GetWebhook", "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id)
+	return scanWebhook(row)
+}
+
+// GetWebhooks returns all the webhooks
+func (cli *DBClient) GetWebhooks() ([]models.Webhook, error) {
+	rows, err := cli.db.Query("GetWebhooks", "SELECT "+webhookColumns+" FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.Webhook, 0)
+
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *webhook)
+	}
+
+	return result, rows.Err()
+}
+
+// DeleteWebhook removes the webhook and its delivery log, or returns sql.ErrNoRows
+func (cli *DBClient) DeleteWebhook(id uint64) error {
+	res, err := cli.db.Exec("DeleteWebhook", "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if count, err := res.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// InsertWebhookDeliveries adds deliveries to the log
+func (cli *DBClient) InsertWebhookDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	tx, err := cli.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO webhook_deliveries(webhook_id, event_type, payload, status, attempts, next_attempt_at, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, d := range deliveries {
+		if _, err := stmt.Exec(d.WebhookID, d.EventType, string(d.Payload), d.Status, d.Attempts, d.NextAttemptAt, d.CreatedAt, d.UpdatedAt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetPendingWebhookDeliveries returns up to limit pending deliveries due at now
+func (cli *DBClient) GetPendingWebhookDeliveries(now uint64, limit uint64) ([]models.WebhookDelivery, error) {
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries WHERE status = $1 AND next_attempt_at <= $2 ORDER BY next_attempt_at, id LIMIT $3"
+	return cli.queryWebhookDeliveries("GetPendingWebhookDeliveries", query, models.WEBHOOK_DELIVERY_PENDING, now, limit)
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook, newest first
+func (cli *DBClient) GetWebhookDeliveries(webhookID uint64, limit uint64) ([]models.WebhookDelivery, error) {
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2"
+	return cli.queryWebhookDeliveries("GetWebhookDeliveries", query, webhookID, limit)
+}
+
+func (cli *DBClient) queryWebhookDeliveries(method, query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := cli.db.Query(method, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.WebhookDelivery, 0)
+
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *delivery)
+	}
+
+	return result, rows.Err()
+}
+
+// UpdateWebhookDelivery records the outcome of a delivery attempt
+func (cli *DBClient) UpdateWebhookDelivery(d models.WebhookDelivery) error {
+	var nextAttemptAt interface{}
+	if d.Status == models.WEBHOOK_DELIVERY_PENDING {
+		nextAttemptAt = d.NextAttemptAt
+	}
+
+	query := "UPDATE webhook_deliveries SET status = $1, attempts = $2, status_code = $3, error = $4, next_attempt_at = $5, updated_at = $6 WHERE id = $7"
+	_, err := cli.db.Exec("UpdateWebhookDelivery", query, d.Status, d.Attempts, d.StatusCode, d.Error, nextAttemptAt, d.UpdatedAt, d.ID)
+	return err
+}
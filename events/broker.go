@@ -0,0 +1,152 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+// subscriptionBufferSize is how many messages a client may lag behind
+// before it gets disconnected
+const subscriptionBufferSize = 64
+
+// Message is a published event as delivered to subscribers
+type Message struct {
+	Type string
+	Data []byte
+}
+
+// Filter selects the events a subscriber receives. Producer only applies
+// to blocks and Address, matched against sender and recipient, only to
+// transactions. An empty Types means all types.
+type Filter struct {
+	Types    map[string]bool
+	Producer *common.Address
+	Address  *common.Address
+}
+
+// header holds the fields of an event needed for filtering, so that the
+// whole block or transaction doesn't have to be decoded
+type header struct {
+	Type  string `json:"type"`
+	Block *struct {
+		Producer common.Address `json:"producer"`
+	} `json:"block"`
+	Transaction *struct {
+		From common.Address  `json:"from"`
+		To   *common.Address `json:"to"`
+	} `json:"transaction"`
+}
+
+func (f Filter) match(h header) bool {
+	if len(f.Types) > 0 && !f.Types[h.Type] {
+		return false
+	}
+
+	switch h.Type {
+	case TYPE_BLOCK:
+		if f.Producer != nil && (h.Block == nil || h.Block.Producer != *f.Producer) {
+			return false
+		}
+	case TYPE_TRANSACTION:
+		if f.Address != nil {
+			if h.Transaction == nil {
+				return false
+			}
+			toMatches := h.Transaction.To != nil && *h.Transaction.To == *f.Address
+			if h.Transaction.From != *f.Address && !toMatches {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Subscription receives the messages matching its filter on C. C is closed
+// when the subscription ends, either by Unsubscribe or because the client
+// couldn't keep up.
+type Subscription struct {
+	C <-chan Message
+
+	ch     chan Message
+	filter Filter
+}
+
+// Broker fans out the events received from Redis to the local subscribers
+type Broker struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// NewBroker creates a broker with no subscribers, feed it with Run
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[*Subscription]struct{})}
+}
+
+// Listen subscribes to the events channel on Redis and returns a running broker
+func Listen() (*Broker, error) {
+	src, err := redis.Subscribe(Channel)
+	if err != nil {
+		return nil, err
+	}
+
+	b := NewBroker()
+	go b.Run(src)
+
+	return b, nil
+}
+
+// Run delivers every message received on src until src is closed
+func (b *Broker) Run(src <-chan []byte) {
+	for data := range src {
+		var h header
+		if err := json.Unmarshal(data, &h); err != nil {
+			logger.Error("Failed to decode event", "err", err)
+			continue
+		}
+
+		b.mu.Lock()
+		for sub := range b.subs {
+			if !sub.filter.match(h) {
+				continue
+			}
+
+			select {
+			case sub.ch <- Message{Type: h.Type, Data: data}:
+			default:
+				// a slow client must not hold back the rest, drop it
+				delete(b.subs, sub)
+				close(sub.ch)
+			}
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Subscribe registers a new subscriber receiving the events matching filter
+func (b *Broker) Subscribe(filter Filter) *Subscription {
+	ch := make(chan Message, subscriptionBufferSize)
+	sub := &Subscription{C: ch, ch: ch, filter: filter}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe removes the subscriber and closes its channel
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,147 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+// ChangesChannel is the Redis channel the changes to the index are published
+// on, kept apart from the events as they are not meant for clients
+const ChangesChannel = "explorer:changes"
+
+const (
+	CHANGE_BLOCK_ADDED     = "block_added"
+	CHANGE_BLOCK_REMOVED   = "block_removed"
+	CHANGE_ADDRESS_TOUCHED = "address_touched"
+)
+
+// changesBufferSize is how many batches of changes may wait to be handled
+const changesBufferSize = 256
+
+// Change tells what the crawler changed in the index, so that caches can
+// purge what depends on it. Blocks have their Number and Hash, along with
+// their Transactions when removed, and touched addresses their Address.
+type Change struct {
+	Type         string          `json:"type"`
+	Number       uint64          `json:"number,omitempty"`
+	Hash         *common.Hash    `json:"hash,omitempty"`
+	Transactions []common.Hash   `json:"transactions,omitempty"`
+	Address      *common.Address `json:"address,omitempty"`
+}
+
+// PublishChanges publishes a batch of changes. Failures are only logged, as
+// the caches expire on their own anyway.
+func PublishChanges(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		logger.Error("Failed to encode changes", "err", err)
+		return
+	}
+
+	if err := redis.Publish(ChangesChannel, data); err != nil {
+		logger.Error("Failed to publish changes", "err", err)
+	}
+}
+
+// ListenChanges subscribes to the changes channel on Redis and returns the
+// batches of changes published on it
+func ListenChanges() (<-chan []Change, error) {
+	src, err := redis.Subscribe(ChangesChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []Change, changesBufferSize)
+	go func() {
+		for data := range src {
+			var changes []Change
+			if err := json.Unmarshal(data, &changes); err != nil {
+				logger.Error("Failed to decode changes", "err", err)
+				continue
+			}
+			ch <- changes
+		}
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// touched collects the addresses touched by a batch, once each
+type touched map[common.Address]struct{}
+
+func (t touched) add(address *common.Address) {
+	if address != nil && *address != (common.Address{}) {
+		t[*address] = struct{}{}
+	}
+}
+
+func (t touched) addTransaction(tf models.TransactionFull) {
+	if tf.Tx != nil {
+		t.add(&tf.Tx.From)
+		t.add(tf.Tx.To)
+	}
+	if tf.Txr != nil {
+		t.add(tf.Txr.ContractAddress)
+	}
+}
+
+func (t touched) changes(changes []Change) []Change {
+	for address := range t {
+		a := address
+		changes = append(changes, Change{Type: CHANGE_ADDRESS_TOUCHED, Address: &a})
+	}
+	return changes
+}
+
+// BlocksAdded returns the changes of inserting blocks, which touch their producers
+func BlocksAdded(blocks []*models.Block) []Change {
+	changes := make([]Change, 0, 2*len(blocks))
+	producers := make(touched)
+
+	for _, block := range blocks {
+		hash := block.Hash
+		changes = append(changes, Change{Type: CHANGE_BLOCK_ADDED, Number: uint64(block.Number), Hash: &hash})
+		producers.add(&block.Producer)
+	}
+
+	return producers.changes(changes)
+}
+
+// TransactionsAdded returns the changes of inserting transactions, which
+// touch their senders, recipients and created contracts
+func TransactionsAdded(txs []models.TransactionFull) []Change {
+	addresses := make(touched)
+	for _, tf := range txs {
+		addresses.addTransaction(tf)
+	}
+
+	return addresses.changes(nil)
+}
+
+// BlockRemoved returns the changes of deleting a block replaced by a reorg,
+// which touches its producer and the addresses of its transactions
+func BlockRemoved(block *models.Block, txs []models.TransactionFull) []Change {
+	hash := block.Hash
+	removed := Change{Type: CHANGE_BLOCK_REMOVED, Number: uint64(block.Number), Hash: &hash}
+	addresses := make(touched)
+	addresses.add(&block.Producer)
+
+	for _, tf := range txs {
+		if tf.Tx != nil {
+			removed.Transactions = append(removed.Transactions, tf.Tx.Hash)
+		}
+		addresses.addTransaction(tf)
+	}
+
+	return addresses.changes([]Change{removed})
+}
@@ -0,0 +1,76 @@
+// Package events carries what the crawler ingests to the explorer instances
+// over Redis pub/sub, so that clients can be pushed new data as it arrives.
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+// Channel is the Redis channel the events are published on
+const Channel = "explorer:events"
+
+const (
+	TYPE_BLOCK       = "block"
+	TYPE_TRANSACTION = "transaction"
+	TYPE_REORG       = "reorg"
+)
+
+// Reorg notifies that the block at Number was replaced
+type Reorg struct {
+	Number  uint64      `json:"number"`
+	OldHash common.Hash `json:"oldHash"`
+	NewHash common.Hash `json:"newHash"`
+}
+
+// Event is what gets published, only the field matching Type is set
+type Event struct {
+	Type        string                  `json:"type"`
+	Block       *models.Block           `json:"block,omitempty"`
+	Transaction *models.TransactionFull `json:"transaction,omitempty"`
+	Reorg       *Reorg                  `json:"reorg,omitempty"`
+}
+
+// Publish sends the event to the explorer instances
+func Publish(ev Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return redis.Publish(Channel, data)
+}
+
+// PublishBlocks publishes blocks the crawler fetched newest first, in reverse
+// so that they reach clients in ascending order. Failures are only logged,
+// as streaming is best effort and must never stop the crawler.
+func PublishBlocks(blocks []*models.Block) {
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if err := Publish(Event{Type: TYPE_BLOCK, Block: blocks[i]}); err != nil {
+			logger.Error("Failed to publish block event", "err", err)
+			return
+		}
+	}
+}
+
+// PublishTransactions publishes the transactions, logging failures
+func PublishTransactions(txs []models.TransactionFull) {
+	for i := range txs {
+		if err := Publish(Event{Type: TYPE_TRANSACTION, Transaction: &txs[i]}); err != nil {
+			logger.Error("Failed to publish transaction event", "err", err)
+			return
+		}
+	}
+}
+
+// PublishReorg publishes a reorg notice, logging failures
+func PublishReorg(number uint64, oldHash, newHash common.Hash) {
+	ev := Event{Type: TYPE_REORG, Reorg: &Reorg{Number: number, OldHash: oldHash, NewHash: newHash}}
+	if err := Publish(ev); err != nil {
+		logger.Error("Failed to publish reorg event", "err", err)
+	}
+}
@@ -12,11 +12,13 @@ require (
 	github.com/ebakus/go-ebakus v1.0.4
 	github.com/elastic/gosigar v0.10.5 // indirect
 	github.com/gorilla/mux v1.7.3
-	github.com/gorilla/websocket v1.4.1 // indirect
+	github.com/gorilla/websocket v1.4.1
+	github.com/graph-gophers/graphql-go v1.3.0
 	github.com/hashicorp/golang-lru v0.5.4 // indirect
 	github.com/lib/pq v1.3.0
 	github.com/mediocregopher/radix/v3 v3.4.2
 	github.com/nightlyone/lockfile v0.0.0-20200124072040-edb130adc195
+	github.com/prometheus/client_golang v1.7.1
 	github.com/rs/cors v1.7.0
 	github.com/steakknife/bloomfilter v0.0.0-20180922174646-6819c0d2a570 // indirect
 	github.com/steakknife/hamming v0.0.0-20180906055917-c99c65617cd3 // indirect
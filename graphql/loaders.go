@@ -0,0 +1,347 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+const (
+	// loaderWait is how long a loader waits for more keys before it queries
+	loaderWait = 2 * time.Millisecond
+
+	// loaderMaxBatch is the most keys a loader queries at once
+	loaderMaxBatch = 500
+)
+
+type loadersKey struct{}
+
+// fetchFunc loads the values of keys, leaving out the ones that don't exist
+type fetchFunc func(keys []interface{}) (map[interface{}]interface{}, error)
+
+type loadResult struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+type loadBatch struct {
+	keys       []interface{}
+	results    []*loadResult
+	dispatched bool
+}
+
+// loader collects the keys that are requested while sibling fields are
+// resolved concurrently and fetches them with one query. Results are
+// cached for the lifetime of the loader, which is a single request.
+type loader struct {
+	fetch fetchFunc
+
+	mu    sync.Mutex
+	cache map[interface{}]*loadResult
+	batch *loadBatch
+}
+
+func newLoader(fetch fetchFunc) *loader {
+	return &loader{
+		fetch: fetch,
+		cache: make(map[interface{}]*loadResult),
+	}
+}
+
+// load returns the value of key, or nil when it doesn't exist
+func (l *loader) load(key interface{}) (interface{}, error) {
+	l.mu.Lock()
+
+	if res, ok := l.cache[key]; ok {
+		l.mu.Unlock()
+		<-res.done
+		return res.value, res.err
+	}
+
+	res := &loadResult{done: make(chan struct{})}
+	l.cache[key] = res
+
+	if l.batch == nil {
+		b := &loadBatch{}
+		l.batch = b
+		time.AfterFunc(loaderWait, func() { l.dispatch(b) })
+	}
+
+	b := l.batch
+	b.keys = append(b.keys, key)
+	b.results = append(b.results, res)
+	full := len(b.keys) >= loaderMaxBatch
+
+	l.mu.Unlock()
+
+	if full {
+		l.dispatch(b)
+	}
+
+	<-res.done
+	return res.value, res.err
+}
+
+func (l *loader) dispatch(b *loadBatch) {
+	l.mu.Lock()
+	if b.dispatched {
+		l.mu.Unlock()
+		return
+	}
+	b.dispatched = true
+	if l.batch == b {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	values, err := l.fetch(b.keys)
+	for i, res := range b.results {
+		if err != nil {
+			res.err = err
+		} else {
+			res.value = values[b.keys[i]]
+		}
+		close(res.done)
+	}
+}
+
+// addressTransactionsKey is a page of the transactions of an address
+type addressTransactionsKey struct {
+	address common.Address
+	page    addressTransactionsPage
+}
+
+// addressTransactionsPage are the arguments of a page, shared by the
+// addresses whose pages are queried together
+type addressTransactionsPage struct {
+	after string
+	limit uint64
+	order string
+}
+
+// loaders are the loaders of a request
+type loaders struct {
+	store               db.Store
+	blocks              *loader
+	blockTransactions   *loader
+	ensNames            *loader
+	producers           *loader
+	contracts           *loader
+	transactionCounts   *loader
+	addressTransactions *loader
+}
+
+// addressKeys returns the addresses of the keys of an address loader
+func addressKeys(keys []interface{}) []common.Address {
+	addresses := make([]common.Address, len(keys))
+	for i, key := range keys {
+		addresses[i] = key.(common.Address)
+	}
+	return addresses
+}
+
+func withLoaders(ctx context.Context, store db.Store) context.Context {
+	l := &loaders{store: store}
+
+	l.blocks = newLoader(func(keys []interface{}) (map[interface{}]interface{}, error) {
+		numbers := make([]uint64, len(keys))
+		for i, key := range keys {
+			numbers[i] = key.(uint64)
+		}
+
+		blocks, err := store.GetBlocksByNumbers(numbers)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[interface{}]interface{}, len(blocks))
+		for i := range blocks {
+			block := blocks[i]
+			values[uint64(block.Number)] = &block
+		}
+		return values, nil
+	})
+
+	l.blockTransactions = newLoader(func(keys []interface{}) (map[interface{}]interface{}, error) {
+		numbers := make([]uint64, len(keys))
+		values := make(map[interface{}]interface{}, len(keys))
+		for i, key := range keys {
+			numbers[i] = key.(uint64)
+			values[key] = []models.TransactionFull{}
+		}
+
+		txs, err := store.GetTransactionsByBlockNumbers(numbers)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tf := range txs {
+			number := uint64(tf.Tx.BlockNumber)
+			values[number] = append(values[number].([]models.TransactionFull), tf)
+		}
+		return values, nil
+	})
+
+	l.ensNames = newLoader(func(keys []interface{}) (map[interface{}]interface{}, error) {
+		addresses := make([]common.Address, len(keys))
+		for i, key := range keys {
+			addresses[i] = key.(common.Address)
+		}
+
+		names, err := store.GetEnsNames(addresses)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[interface{}]interface{}, len(names))
+		for address, name := range names {
+			values[address] = name
+		}
+		return values, nil
+	})
+
+	l.producers = newLoader(func(keys []interface{}) (map[interface{}]interface{}, error) {
+		addresses := make([]common.Address, len(keys))
+		for i, key := range keys {
+			addresses[i] = key.(common.Address)
+		}
+
+		producers, err := store.GetProducers(addresses)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[interface{}]interface{}, len(producers))
+		for i := range producers {
+			producer := producers[i]
+			values[producer.Address] = &producer
+		}
+		return values, nil
+	})
+
+	l.contracts = newLoader(func(keys []interface{}) (map[interface{}]interface{}, error) {
+		contracts, err := store.GetIsContractAddresses(addressKeys(keys))
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[interface{}]interface{}, len(contracts))
+		for address, isContract := range contracts {
+			values[address] = isContract
+		}
+		return values, nil
+	})
+
+	l.transactionCounts = newLoader(func(keys []interface{}) (map[interface{}]interface{}, error) {
+		counts, err := store.GetTransactionCounts(addressKeys(keys))
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[interface{}]interface{}, len(counts))
+		for address, count := range counts {
+			values[address] = count
+		}
+		return values, nil
+	})
+
+	// the addresses are queried together for each page arguments, which are
+	// the same for sibling fields
+	l.addressTransactions = newLoader(func(keys []interface{}) (map[interface{}]interface{}, error) {
+		pages := make(map[addressTransactionsPage][]common.Address)
+		for _, key := range keys {
+			k := key.(addressTransactionsKey)
+			pages[k.page] = append(pages[k.page], k.address)
+		}
+
+		values := make(map[interface{}]interface{}, len(keys))
+		for page, addresses := range pages {
+			var cursor *models.Cursor
+			if page.after != "" {
+				var err error
+				if cursor, err = models.DecodeCursor(page.after); err != nil {
+					return nil, err
+				}
+				cursor.Prev = false
+			}
+
+			txs, err := store.GetTransactionsByAddresses(addresses, cursor, page.limit, page.order)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, address := range addresses {
+				values[addressTransactionsKey{address, page}] = txs[address]
+			}
+		}
+		return values, nil
+	})
+
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func getLoaders(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func (l *loaders) block(number uint64) (*models.Block, error) {
+	value, err := l.blocks.load(number)
+	if value == nil || err != nil {
+		return nil, err
+	}
+	return value.(*models.Block), nil
+}
+
+func (l *loaders) transactionsOfBlock(number uint64) ([]models.TransactionFull, error) {
+	value, err := l.blockTransactions.load(number)
+	if err != nil {
+		return nil, err
+	}
+	return value.([]models.TransactionFull), nil
+}
+
+func (l *loaders) ensName(address common.Address) (string, error) {
+	value, err := l.ensNames.load(address)
+	if value == nil || err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+func (l *loaders) producer(address common.Address) (*models.Producer, error) {
+	value, err := l.producers.load(address)
+	if value == nil || err != nil {
+		return nil, err
+	}
+	return value.(*models.Producer), nil
+}
+
+func (l *loaders) isContract(address common.Address) (bool, error) {
+	value, err := l.contracts.load(address)
+	if value == nil || err != nil {
+		return false, err
+	}
+	return value.(bool), nil
+}
+
+func (l *loaders) transactionCount(address common.Address) (uint64, error) {
+	value, err := l.transactionCounts.load(address)
+	if value == nil || err != nil {
+		return 0, err
+	}
+	return value.(uint64), nil
+}
+
+func (l *loaders) transactionsOfAddress(address common.Address, after string, limit uint64, order string) ([]models.TransactionFull, error) {
+	value, err := l.addressTransactions.load(addressTransactionsKey{address, addressTransactionsPage{after, limit, order}})
+	if value == nil || err != nil {
+		return nil, err
+	}
+	return value.([]models.TransactionFull), nil
+}
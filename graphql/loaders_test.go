@@ -0,0 +1,177 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+// countingStore counts the queries made for the fields of addresses
+type countingStore struct {
+	*db.MemoryStore
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *countingStore) count(method string) {
+	s.mu.Lock()
+	s.calls[method]++
+	s.mu.Unlock()
+}
+
+func (s *countingStore) GetIsContractAddress(address string) (bool, error) {
+	s.count("GetIsContractAddress")
+	return s.MemoryStore.GetIsContractAddress(address)
+}
+
+func (s *countingStore) GetAddressTotals(address string) (*big.Int, uint64, error) {
+	s.count("GetAddressTotals")
+	return s.MemoryStore.GetAddressTotals(address)
+}
+
+func (s *countingStore) GetTransactionsByAddress(address string, addrtype models.AddressType, cursor *models.Cursor, limit uint64, order string) ([]models.TransactionFull, error) {
+	s.count("GetTransactionsByAddress")
+	return s.MemoryStore.GetTransactionsByAddress(address, addrtype, cursor, limit, order)
+}
+
+func (s *countingStore) GetIsContractAddresses(addresses []common.Address) (map[common.Address]bool, error) {
+	s.count("GetIsContractAddresses")
+	return s.MemoryStore.GetIsContractAddresses(addresses)
+}
+
+func (s *countingStore) GetTransactionCounts(addresses []common.Address) (map[common.Address]uint64, error) {
+	s.count("GetTransactionCounts")
+	return s.MemoryStore.GetTransactionCounts(addresses)
+}
+
+func (s *countingStore) GetTransactionsByAddresses(addresses []common.Address, cursor *models.Cursor, limit uint64, order string) (map[common.Address][]models.TransactionFull, error) {
+	s.count("GetTransactionsByAddresses")
+	return s.MemoryStore.GetTransactionsByAddresses(addresses, cursor, limit, order)
+}
+
+// setupAddressChain stores a block of 4 transactions, each between two of
+// 5 addresses, the first one deploying a contract
+func setupAddressChain(t *testing.T) (*countingStore, []common.Address) {
+	t.Helper()
+
+	addresses := make([]common.Address, 5)
+	for i := range addresses {
+		// addresses from 0x0000 to 0x0102 are system contracts
+		addresses[i] = common.BigToAddress(new(big.Int).Lsh(big.NewInt(int64(0xa0+i)), 152))
+	}
+	contract := common.HexToAddress("0xcccccccccccccccccccccccccccccccccccccccc")
+
+	block := &models.Block{Number: 1, TimeStamp: 1583020800, Hash: common.HexToHash("0xb10c")}
+	var txs []models.TransactionFull
+	for i := 0; i < 4; i++ {
+		to := addresses[i+1]
+		txr := &models.TransactionReceipt{Status: 1}
+		if i == 0 {
+			txr.ContractAddress = &contract
+		}
+
+		tx := &models.Transaction{
+			Hash:             common.BigToHash(big.NewInt(int64(0x7a00 + i))),
+			BlockHash:        block.Hash,
+			BlockNumber:      block.Number,
+			TransactionIndex: hexutil.Uint64(i),
+			Timestamp:        block.TimeStamp,
+			From:             addresses[i],
+			To:               &to,
+			Value:            hexutil.Big(*big.NewInt(0)),
+		}
+		block.Transactions = append(block.Transactions, tx.Hash)
+		txs = append(txs, models.TransactionFull{Tx: tx, Txr: txr})
+	}
+	block.TransactionCount = hexutil.Uint64(len(txs))
+
+	store := &countingStore{MemoryStore: db.NewMemoryStore(), calls: make(map[string]int)}
+	if err := store.InsertBlocks([]*models.Block{block}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertTransactions(txs); err != nil {
+		t.Fatal(err)
+	}
+
+	return store, append(addresses, contract)
+}
+
+func TestAddressLoaders(t *testing.T) {
+	store, addresses := setupAddressChain(t)
+
+	query := `{ block(number: 1) { transactions {
+		from { address isContract transactionCount transactions(first: 1) { hash } }
+		to { address isContract transactionCount transactions(first: 1) { hash } }
+		contractAddress { address isContract }
+	} } }`
+
+	res := Execute(context.Background(), store, query, "", nil)
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors)
+	}
+
+	type address struct {
+		Address          string
+		IsContract       bool
+		TransactionCount Long
+		Transactions     []struct{ Hash string }
+	}
+	var data struct {
+		Block struct {
+			Transactions []struct {
+				From            address
+				To              address
+				ContractAddress address
+			}
+		}
+	}
+	if err := json.Unmarshal(res.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	txs := data.Block.Transactions
+	if len(txs) != 4 {
+		t.Fatalf("got %d transactions, want 4", len(txs))
+	}
+
+	// the first and last addresses are in one transaction, the others in two
+	for i, tx := range txs {
+		count := Long(2)
+		if i == 0 {
+			count = 1
+		}
+		if tx.From.Address != addresses[i].Hex() || tx.From.IsContract || tx.From.TransactionCount != count || len(tx.From.Transactions) != 1 {
+			t.Errorf("from of transaction %d = %+v", i, tx.From)
+		}
+	}
+	if last := txs[3].To; last.TransactionCount != 1 || len(last.Transactions) != 1 || last.Transactions[0].Hash != common.BigToHash(big.NewInt(0x7a03)).Hex() {
+		t.Errorf("to of the last transaction = %+v", last)
+	}
+	if contract := txs[0].ContractAddress; contract.Address != addresses[5].Hex() || !contract.IsContract {
+		t.Errorf("contract address = %+v, want a contract", contract)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, method := range []string{"GetIsContractAddress", "GetAddressTotals", "GetTransactionsByAddress"} {
+		if calls := store.calls[method]; calls != 0 {
+			t.Errorf("%s was called %d times, want the batched lookups only", method, calls)
+		}
+	}
+	// the fields of the 9 addresses are batched with their siblings
+	for _, method := range []string{"GetIsContractAddresses", "GetTransactionCounts", "GetTransactionsByAddresses"} {
+		if calls := store.calls[method]; calls == 0 || calls > 2 {
+			t.Errorf("%s was called %d times, want once or twice", method, calls)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+var (
+	// ErrInvalidHash is returned for arguments that are not 32 byte hex hashes
+	ErrInvalidHash = errors.New("Invalid hash")
+
+	// ErrInvalidAddress is returned for arguments that are not hex addresses
+	ErrInvalidAddress = errors.New("Invalid address")
+
+	// ErrInvalidOrder is returned when the order is neither asc nor desc
+	ErrInvalidOrder = errors.New("Invalid order")
+
+	// ErrMissingIPC is returned when there is no node to read delegates from
+	ErrMissingIPC = errors.New("Failed to find IPC connection")
+)
+
+func parseHash(s string) (common.Hash, error) {
+	if !strings.HasPrefix(s, "0x") || len(s) != 2+2*common.HashLength {
+		return common.Hash{}, ErrInvalidHash
+	}
+	if _, err := hexutil.Decode(s); err != nil {
+		return common.Hash{}, ErrInvalidHash
+	}
+	return common.HexToHash(s), nil
+}
+
+// listSize clamps the size of a list requested by a client
+func listSize(first int32) uint64 {
+	if first <= 0 {
+		return 0
+	}
+	if first > maxListSize {
+		return maxListSize
+	}
+	return uint64(first)
+}
+
+type resolver struct{}
+
+type blockArgs struct {
+	Number *Long
+	Hash   *string
+}
+
+func (r *resolver) Block(ctx context.Context, args blockArgs) (*blockResolver, error) {
+	l := getLoaders(ctx)
+
+	if args.Hash != nil {
+		hash, err := parseHash(*args.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		block, err := l.store.GetBlockByHash(hash.Hex())
+		if err == sql.ErrNoRows || (err == nil && block.Hash != hash) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return &blockResolver{block: block}, nil
+	}
+
+	var number uint64
+	if args.Number != nil {
+		number = uint64(*args.Number)
+	} else {
+		latest, err := l.store.GetLatestBlockNumber()
+		if err != nil {
+			return nil, err
+		}
+		number = latest
+	}
+
+	block, err := l.block(number)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return &blockResolver{block: block}, nil
+}
+
+type blocksArgs struct {
+	First  int32
+	Before *Long
+}
+
+func (r *resolver) Blocks(ctx context.Context, args blocksArgs) ([]*blockResolver, error) {
+	l := getLoaders(ctx)
+	size := listSize(args.First)
+
+	var from uint64
+	if args.Before != nil {
+		if *args.Before == 0 {
+			return []*blockResolver{}, nil
+		}
+		from = uint64(*args.Before) - 1
+	} else {
+		latest, err := l.store.GetLatestBlockNumber()
+		if err != nil {
+			return nil, err
+		}
+		from = latest
+	}
+
+	result := make([]*blockResolver, 0, size)
+	if size == 0 {
+		return result, nil
+	}
+
+	blocks, err := l.store.GetBlockRange(uint32(from), uint32(size), false)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range blocks {
+		block := blocks[i]
+		result = append(result, &blockResolver{block: &block})
+	}
+	return result, nil
+}
+
+type transactionArgs struct {
+	Hash string
+}
+
+func (r *resolver) Transaction(ctx context.Context, args transactionArgs) (*transactionResolver, error) {
+	hash, err := parseHash(args.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	tf, err := getLoaders(ctx).store.GetTransactionByHash(hash.Hex())
+	if err == sql.ErrNoRows || (err == nil && (tf == nil || tf.Tx == nil || tf.Tx.Hash != hash)) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &transactionResolver{tx: tf.Tx, txr: tf.Txr}, nil
+}
+
+type addressArgs struct {
+	Address string
+}
+
+func (r *resolver) Address(ctx context.Context, args addressArgs) (*addressResolver, error) {
+	if !common.IsHexAddress(args.Address) {
+		return nil, ErrInvalidAddress
+	}
+	return &addressResolver{address: common.HexToAddress(args.Address)}, nil
+}
+
+type delegatesArgs struct {
+	Number *Long
+}
+
+func (r *resolver) Delegates(ctx context.Context, args delegatesArgs) ([]*delegateResolver, error) {
+	node := ipc.GetIPC()
+	if node == nil {
+		return nil, ErrMissingIPC
+	}
+
+	var number uint64
+	if args.Number != nil {
+		number = uint64(*args.Number)
+	} else {
+		latest, err := getLoaders(ctx).store.GetLatestBlockNumber()
+		if err != nil {
+			return nil, err
+		}
+		number = latest
+	}
+
+	delegates, err := node.GetDelegates(number)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*delegateResolver, len(delegates))
+	for i := range delegates {
+		result[i] = &delegateResolver{delegate: delegates[i]}
+	}
+	return result, nil
+}
+
+type blockResolver struct {
+	block *models.Block
+}
+
+func (b *blockResolver) Number() Long             { return Long(b.block.Number) }
+func (b *blockResolver) Hash() string             { return b.block.Hash.Hex() }
+func (b *blockResolver) ParentHash() string       { return b.block.ParentHash.Hex() }
+func (b *blockResolver) Timestamp() Long          { return Long(b.block.TimeStamp) }
+func (b *blockResolver) Size() Long               { return Long(b.block.Size) }
+func (b *blockResolver) GasUsed() Long            { return Long(b.block.GasUsed) }
+func (b *blockResolver) GasLimit() Long           { return Long(b.block.GasLimit) }
+func (b *blockResolver) TransactionsRoot() string { return b.block.TransactionsRoot.Hex() }
+func (b *blockResolver) ReceiptsRoot() string     { return b.block.ReceiptsRoot.Hex() }
+func (b *blockResolver) Signature() string        { return hexutil.Encode(b.block.Signature) }
+func (b *blockResolver) TransactionCount() Long   { return Long(b.block.TransactionCount) }
+
+func (b *blockResolver) Parent(ctx context.Context) (*blockResolver, error) {
+	if b.block.Number == 0 {
+		return nil, nil
+	}
+
+	parent, err := getLoaders(ctx).block(uint64(b.block.Number) - 1)
+	if parent == nil || err != nil {
+		return nil, err
+	}
+	return &blockResolver{block: parent}, nil
+}
+
+func (b *blockResolver) Transactions(ctx context.Context) ([]*transactionResolver, error) {
+	txs, err := getLoaders(ctx).transactionsOfBlock(uint64(b.block.Number))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*transactionResolver, len(txs))
+	for i := range txs {
+		result[i] = &transactionResolver{tx: txs[i].Tx, txr: txs[i].Txr}
+	}
+	return result, nil
+}
+
+func (b *blockResolver) Producer() *addressResolver {
+	return &addressResolver{address: b.block.Producer, ens: b.block.ProducerEns}
+}
+
+func (b *blockResolver) Delegates() []*addressResolver {
+	result := make([]*addressResolver, len(b.block.Delegates))
+	for i, delegate := range b.block.Delegates {
+		result[i] = &addressResolver{address: delegate}
+	}
+	return result
+}
+
+type transactionResolver struct {
+	tx  *models.Transaction
+	txr *models.TransactionReceipt
+}
+
+func (t *transactionResolver) Hash() string { return t.tx.Hash.Hex() }
+
+func (t *transactionResolver) Cursor() string {
+	return models.Cursor{Number: uint64(t.tx.BlockNumber), Index: uint64(t.tx.TransactionIndex)}.Encode()
+}
+
+func (t *transactionResolver) Nonce() Long             { return Long(t.tx.Nonce) }
+func (t *transactionResolver) BlockNumber() Long       { return Long(t.tx.BlockNumber) }
+func (t *transactionResolver) BlockHash() string       { return t.tx.BlockHash.Hex() }
+func (t *transactionResolver) Index() Long             { return Long(t.tx.TransactionIndex) }
+func (t *transactionResolver) Timestamp() Long         { return Long(t.tx.Timestamp) }
+func (t *transactionResolver) Value() string           { return t.tx.Value.ToInt().String() }
+func (t *transactionResolver) Gas() Long               { return Long(t.tx.GasLimit) }
+func (t *transactionResolver) GasPrice() Long          { return Long(t.tx.GasPrice) }
+func (t *transactionResolver) GasUsed() Long           { return Long(t.txr.GasUsed) }
+func (t *transactionResolver) CumulativeGasUsed() Long { return Long(t.txr.CumulativeGasUsed) }
+func (t *transactionResolver) Status() Long            { return Long(t.txr.Status) }
+func (t *transactionResolver) WorkNonce() Long         { return Long(t.tx.WorkNonce) }
+func (t *transactionResolver) Input() string           { return hexutil.Encode(t.tx.Input) }
+
+func (t *transactionResolver) Block(ctx context.Context) (*blockResolver, error) {
+	block, err := getLoaders(ctx).block(uint64(t.tx.BlockNumber))
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return &blockResolver{block: block}, nil
+}
+
+func (t *transactionResolver) From() *addressResolver {
+	return &addressResolver{address: t.tx.From, ens: t.tx.FromEns}
+}
+
+// To is null for contract creations, which the database stores as the zero address
+func (t *transactionResolver) To() *addressResolver {
+	if t.tx.To == nil || *t.tx.To == (common.Address{}) {
+		return nil
+	}
+	return &addressResolver{address: *t.tx.To, ens: t.tx.ToEns}
+}
+
+func (t *transactionResolver) ContractAddress() *addressResolver {
+	if t.txr.ContractAddress == nil || *t.txr.ContractAddress == (common.Address{}) {
+		return nil
+	}
+	return &addressResolver{address: *t.txr.ContractAddress, ens: t.txr.ContractAddressEns}
+}
+
+// addressResolver resolves an address. ens is set when the name was already
+// fetched along with the parent object, with an empty name meaning none.
+type addressResolver struct {
+	address common.Address
+	ens     *string
+}
+
+func (a *addressResolver) Address() string { return a.address.Hex() }
+
+func (a *addressResolver) Ens(ctx context.Context) (*ensResolver, error) {
+	name := ""
+	if a.ens != nil {
+		name = *a.ens
+	} else {
+		var err error
+		if name, err = getLoaders(ctx).ensName(a.address); err != nil {
+			return nil, err
+		}
+	}
+
+	if name == "" {
+		return nil, nil
+	}
+	return &ensResolver{name: name, address: a.address}, nil
+}
+
+func (a *addressResolver) IsContract(ctx context.Context) (bool, error) {
+	return getLoaders(ctx).isContract(a.address)
+}
+
+func (a *addressResolver) TransactionCount(ctx context.Context) (Long, error) {
+	txCount, err := getLoaders(ctx).transactionCount(a.address)
+	if err != nil {
+		return 0, err
+	}
+	return Long(txCount), nil
+}
+
+type addressTransactionsArgs struct {
+	First int32
+	After *string
+	Order string
+}
+
+func (a *addressResolver) Transactions(ctx context.Context, args addressTransactionsArgs) ([]*transactionResolver, error) {
+	if args.Order != "asc" && args.Order != "desc" {
+		return nil, ErrInvalidOrder
+	}
+
+	var after string
+	if args.After != nil {
+		// invalid cursors fail this field only, not the siblings they would
+		// be queried with
+		if _, err := models.DecodeCursor(*args.After); err != nil {
+			return nil, err
+		}
+		after = *args.After
+	}
+
+	size := listSize(args.First)
+	if size == 0 {
+		return []*transactionResolver{}, nil
+	}
+
+	txs, err := getLoaders(ctx).transactionsOfAddress(a.address, after, size, args.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*transactionResolver, len(txs))
+	for i := range txs {
+		result[i] = &transactionResolver{tx: txs[i].Tx, txr: txs[i].Txr}
+	}
+	return result, nil
+}
+
+func (a *addressResolver) Producer(ctx context.Context) (*producerResolver, error) {
+	producer, err := getLoaders(ctx).producer(a.address)
+	if producer == nil || err != nil {
+		return nil, err
+	}
+	return &producerResolver{producer: producer}, nil
+}
+
+type ensResolver struct {
+	name    string
+	address common.Address
+}
+
+func (e *ensResolver) Name() string { return e.name }
+
+func (e *ensResolver) Address() *addressResolver {
+	return &addressResolver{address: e.address, ens: &e.name}
+}
+
+type producerResolver struct {
+	producer *models.Producer
+}
+
+func (p *producerResolver) Address() *addressResolver {
+	return &addressResolver{address: p.producer.Address}
+}
+
+func (p *producerResolver) ProducedBlocksCount() Long {
+	return Long(p.producer.ProducedBlocksCount)
+}
+
+func (p *producerResolver) BlockRewards() string {
+	if p.producer.BlockRewards == nil {
+		return new(big.Int).String()
+	}
+	return p.producer.BlockRewards.String()
+}
+
+type delegateResolver struct {
+	delegate models.DelegateVoteInfo
+}
+
+func (d *delegateResolver) Address() *addressResolver {
+	return &addressResolver{address: d.delegate.Address}
+}
+
+func (d *delegateResolver) Stake() Long   { return Long(d.delegate.Stake) }
+func (d *delegateResolver) Elected() bool { return d.delegate.Elected }
@@ -0,0 +1,172 @@
+// Package graphql serves the explorer data over GraphQL. Nested fields are
+// resolved through per request loaders, which batch the lookups of sibling
+// fields into a single query.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+const (
+	// maxDepth is the maximum nesting of a query
+	maxDepth = 10
+
+	// maxListSize is the maximum number of items returned by list fields
+	maxListSize = 100
+)
+
+const schemaString = `
+schema {
+	query: Query
+}
+
+# Long is an unsigned 64-bit integer
+scalar Long
+
+type Query {
+	# block by number or hash, the latest block if neither is given
+	block(number: Long, hash: String): Block
+	# blocks in descending order, before a block number if given
+	blocks(first: Int = 20, before: Long): [Block!]!
+	transaction(hash: String!): Transaction
+	address(address: String!): Address
+	# delegates at a block number, at the latest block if not given
+	delegates(number: Long): [Delegate!]!
+}
+
+type Block {
+	number: Long!
+	hash: String!
+	parentHash: String!
+	parent: Block
+	timestamp: Long!
+	size: Long!
+	gasUsed: Long!
+	gasLimit: Long!
+	transactionsRoot: String!
+	receiptsRoot: String!
+	signature: String!
+	transactionCount: Long!
+	transactions: [Transaction!]!
+	producer: Address!
+	delegates: [Address!]!
+}
+
+type Transaction {
+	hash: String!
+	# opaque position of the transaction, used with Address.transactions(after)
+	cursor: String!
+	nonce: Long!
+	blockNumber: Long!
+	blockHash: String!
+	block: Block
+	index: Long!
+	timestamp: Long!
+	from: Address!
+	# null for contract creations
+	to: Address
+	contractAddress: Address
+	# value in wei
+	value: String!
+	gas: Long!
+	gasPrice: Long!
+	gasUsed: Long!
+	cumulativeGasUsed: Long!
+	status: Long!
+	workNonce: Long!
+	input: String!
+}
+
+type Address {
+	address: String!
+	ens: ENSName
+	isContract: Boolean!
+	transactionCount: Long!
+	transactions(first: Int = 20, after: String, order: String = "desc"): [Transaction!]!
+	# set when the address has produced blocks
+	producer: Producer
+}
+
+type ENSName {
+	name: String!
+	address: Address!
+}
+
+type Producer {
+	address: Address!
+	producedBlocksCount: Long!
+	# block rewards in wei
+	blockRewards: String!
+}
+
+type Delegate {
+	address: Address!
+	stake: Long!
+	elected: Boolean!
+}
+`
+
+var (
+	// ErrInvalidLong is returned when a Long argument can't be parsed
+	ErrInvalidLong = errors.New("Invalid Long value")
+)
+
+var schema = graphqlgo.MustParseSchema(schemaString, &resolver{},
+	graphqlgo.MaxDepth(maxDepth))
+
+// Execute runs a GraphQL query against the store
+func Execute(ctx context.Context, store db.Store, query, operationName string, variables map[string]interface{}) *graphqlgo.Response {
+	return schema.Exec(withLoaders(ctx, store), query, operationName, variables)
+}
+
+// Long is the GraphQL scalar for block numbers, timestamps and gas amounts,
+// which do not fit in the 32-bit Int type
+type Long uint64
+
+// ImplementsGraphQLType maps Long to the Long scalar
+func (Long) ImplementsGraphQLType(name string) bool {
+	return name == "Long"
+}
+
+// UnmarshalGraphQL parses a Long from a query literal or variable
+func (l *Long) UnmarshalGraphQL(input interface{}) error {
+	switch v := input.(type) {
+	case int32:
+		if v < 0 {
+			return ErrInvalidLong
+		}
+		*l = Long(v)
+	case int64:
+		if v < 0 {
+			return ErrInvalidLong
+		}
+		*l = Long(v)
+	case float64:
+		if v < 0 || v > math.MaxUint64 || v != math.Trunc(v) {
+			return ErrInvalidLong
+		}
+		*l = Long(v)
+	case string:
+		n, err := strconv.ParseUint(v, 0, 64)
+		if err != nil {
+			return ErrInvalidLong
+		}
+		*l = Long(n)
+	default:
+		return fmt.Errorf("%s: %T", ErrInvalidLong, input)
+	}
+	return nil
+}
+
+// MarshalJSON writes a Long as a JSON number
+func (l Long) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(l), 10)), nil
+}
@@ -0,0 +1,35 @@
+package ipc
+
+import (
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/params"
+)
+
+// mainnetChainID is the chain id of the ebakus mainnet, every other chain
+// is expected to run with the testnet DPOS parameters
+const mainnetChainID = 10
+
+// DPOSConfig returns the DPOS parameters of the chain
+func DPOSConfig(chainID uint64) *params.DPOSConfig {
+	if chainID == mainnetChainID {
+		return params.MainnetDPOSConfig
+	}
+	return params.TestnetDPOSConfig
+}
+
+// SignerAtSlot returns the delegate that had to produce the block at slot,
+// where delegates are the ones elected at the parent block
+func SignerAtSlot(dposConfig *params.DPOSConfig, delegates []common.Address, slot float64) common.Address {
+	if dposConfig.DelegateCount == 0 || dposConfig.TurnBlockCount == 0 {
+		return common.Address{}
+	}
+
+	slot = slot / float64(dposConfig.TurnBlockCount)
+	s := int(slot) % int(dposConfig.DelegateCount)
+
+	if s < len(delegates) {
+		return delegates[s]
+	}
+
+	return common.Address{}
+}
@@ -0,0 +1,677 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+// FakeTransaction is a transaction together with its receipt, as found in fixtures
+type FakeTransaction struct {
+	Transaction *models.Transaction        `json:"transaction"`
+	Receipt     *models.TransactionReceipt `json:"receipt"`
+	Logs        []models.Log               `json:"logs"`
+}
+
+// FakeChain is a list of blocks with their transactions
+type FakeChain struct {
+	Blocks       []*models.Block   `json:"blocks"`
+	Transactions []FakeTransaction `json:"transactions"`
+}
+
+// FakeFixture is the JSON format FakeNode replays. Blocks and transactions
+// use the same encoding the node returns over RPC. Reorgs are alternative
+// chains that replace the canonical one from their first block onwards.
+// Calls maps contracts to the outputs of eth_call by their hex encoded input.
+// Storage maps contracts to the values of their storage slots.
+type FakeFixture struct {
+	FakeChain
+
+	ChainID   uint64                                         `json:"chainId"`
+	Delegates map[string][]models.DelegateVoteInfo           `json:"delegates"`
+	Balances  map[common.Address]*hexutil.Big                `json:"balances"`
+	Staked    map[common.Address]uint64                      `json:"staked"`
+	ABIs      map[common.Address]json.RawMessage             `json:"abis"`
+	ENS       map[common.Hash]common.Address                 `json:"ens"`
+	Calls     map[common.Address]map[string]hexutil.Bytes    `json:"calls"`
+	Storage   map[common.Address]map[common.Hash]common.Hash `json:"storage"`
+	Reorgs    []FakeChain                                    `json:"reorgs"`
+}
+
+var (
+	// ErrFakeMethodNotSupported is returned by FakeNode.Call for methods it can't answer
+	ErrFakeMethodNotSupported = errors.New("Method not supported by the fake node")
+
+	// ErrFakeInvalidParams is returned by FakeNode.Call when the arguments can't be decoded
+	ErrFakeInvalidParams = errors.New("Invalid params")
+)
+
+// FakeTimeoutError is returned by FakeNode calls that are set to time out
+type FakeTimeoutError struct {
+	Method string
+}
+
+func (e *FakeTimeoutError) Error() string {
+	return fmt.Sprintf("%s: i/o timeout", e.Method)
+}
+
+// Timeout reports the error as a timeout, same as net.Error
+func (e *FakeTimeoutError) Timeout() bool { return true }
+
+type fakeFailure struct {
+	count int
+	err   error
+	delay time.Duration
+}
+
+// FakeNode is a Node that serves a chain loaded from fixtures.
+// It is safe for concurrent use and can simulate reorgs, failing
+// or slow calls and transactions without receipts.
+type FakeNode struct {
+	mu sync.Mutex
+
+	chainID      uint64
+	blocks       []*models.Block
+	transactions map[common.Hash]*models.Transaction
+	receipts     map[common.Hash]*models.TransactionReceipt
+	logs         map[common.Hash][]models.Log
+	delegates    map[uint64][]models.DelegateVoteInfo
+	balances     map[common.Address]*big.Int
+	staked       map[common.Address]uint64
+	abis         map[common.Address]string
+	ens          map[common.Hash]common.Address
+	callOutputs  map[common.Address]map[string]hexutil.Bytes
+	storage      map[common.Address]map[common.Hash]common.Hash
+	reorgs       []FakeChain
+
+	failures map[string]*fakeFailure
+	calls    map[string]int
+}
+
+// NewFakeNode creates a FakeNode from a fixture
+func NewFakeNode(fixture *FakeFixture) (*FakeNode, error) {
+	node := &FakeNode{
+		chainID:      fixture.ChainID,
+		transactions: make(map[common.Hash]*models.Transaction),
+		receipts:     make(map[common.Hash]*models.TransactionReceipt),
+		logs:         make(map[common.Hash][]models.Log),
+		delegates:    make(map[uint64][]models.DelegateVoteInfo),
+		balances:     make(map[common.Address]*big.Int),
+		staked:       make(map[common.Address]uint64),
+		abis:         make(map[common.Address]string),
+		ens:          make(map[common.Hash]common.Address),
+		callOutputs:  make(map[common.Address]map[string]hexutil.Bytes),
+		storage:      make(map[common.Address]map[common.Hash]common.Hash),
+		reorgs:       fixture.Reorgs,
+		failures:     make(map[string]*fakeFailure),
+		calls:        make(map[string]int),
+	}
+
+	if node.chainID == 0 {
+		node.chainID = 10
+	}
+
+	for number, delegates := range fixture.Delegates {
+		n, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delegates block number %q: %v", number, err)
+		}
+		node.delegates[n] = delegates
+	}
+	for address, balance := range fixture.Balances {
+		node.balances[address] = balance.ToInt()
+	}
+	for address, staked := range fixture.Staked {
+		node.staked[address] = staked
+	}
+	for address, abi := range fixture.ABIs {
+		// ABIs may be given either as JSON or as a JSON encoded string
+		var str string
+		if err := json.Unmarshal(abi, &str); err == nil {
+			node.abis[address] = str
+		} else {
+			node.abis[address] = string(abi)
+		}
+	}
+	for hash, address := range fixture.ENS {
+		node.ens[hash] = address
+	}
+	for address, calls := range fixture.Calls {
+		node.callOutputs[address] = make(map[string]hexutil.Bytes)
+		for input, output := range calls {
+			node.callOutputs[address][strings.ToLower(input)] = output
+		}
+	}
+	for address, slots := range fixture.Storage {
+		node.storage[address] = make(map[common.Hash]common.Hash)
+		for slot, value := range slots {
+			node.storage[address][slot] = value
+		}
+	}
+
+	if err := node.setChain(fixture.FakeChain, 0); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// NewFakeNodeFromFile creates a FakeNode from a JSON fixture file
+func NewFakeNodeFromFile(path string) (*FakeNode, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture FakeFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %v", path, err)
+	}
+
+	return NewFakeNode(&fixture)
+}
+
+// setChain replaces the chain from block number `from` onwards
+func (f *FakeNode) setChain(chain FakeChain, from uint64) error {
+	blocks := make([]*models.Block, len(chain.Blocks))
+	copy(blocks, chain.Blocks)
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Number < blocks[j].Number })
+
+	for i, bl := range blocks {
+		if uint64(bl.Number) != from+uint64(i) {
+			return fmt.Errorf("fixture blocks are not contiguous, expected %d got %d", from+uint64(i), bl.Number)
+		}
+	}
+
+	if from > uint64(len(f.blocks)) {
+		return fmt.Errorf("fixture chain starts at %d, beyond head %d", from, len(f.blocks))
+	}
+
+	f.blocks = append(f.blocks[:from], blocks...)
+
+	for _, t := range chain.Transactions {
+		if t.Transaction == nil {
+			continue
+		}
+		f.transactions[t.Transaction.Hash] = t.Transaction
+		if t.Receipt != nil {
+			f.receipts[t.Transaction.Hash] = t.Receipt
+		}
+		f.logs[t.Transaction.Hash] = t.Logs
+	}
+
+	return nil
+}
+
+// Reorg replaces the chain from the first block of chain onwards,
+// dropping any blocks after the new head
+func (f *FakeNode) Reorg(chain FakeChain) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(chain.Blocks) == 0 {
+		return nil
+	}
+
+	from := uint64(chain.Blocks[0].Number)
+	for _, bl := range chain.Blocks {
+		if uint64(bl.Number) < from {
+			from = uint64(bl.Number)
+		}
+	}
+
+	return f.setChain(chain, from)
+}
+
+// ApplyReorg applies the i-th reorg of the fixture
+func (f *FakeNode) ApplyReorg(i int) error {
+	if i < 0 || i >= len(f.reorgs) {
+		return fmt.Errorf("no reorg %d in fixture", i)
+	}
+	return f.Reorg(f.reorgs[i])
+}
+
+// AddBlocks appends blocks (and their transactions) to the head of the chain
+func (f *FakeNode) AddBlocks(chain FakeChain) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.setChain(chain, uint64(len(f.blocks)))
+}
+
+// DropReceipt makes the receipt of a transaction unavailable
+func (f *FakeNode) DropReceipt(hash common.Hash) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.receipts, hash)
+}
+
+// SetTimeout makes the next count calls of method (e.g. "GetBlock") wait
+// for delay and then fail with a FakeTimeoutError. A negative count makes
+// all calls fail.
+func (f *FakeNode) SetTimeout(method string, count int, delay time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures[method] = &fakeFailure{count: count, err: &FakeTimeoutError{Method: method}, delay: delay}
+}
+
+// SetFailure makes the next count calls of method fail with err.
+// A negative count makes all calls fail.
+func (f *FakeNode) SetFailure(method string, count int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures[method] = &fakeFailure{count: count, err: err}
+}
+
+// ClearFailures makes all calls succeed again
+func (f *FakeNode) ClearFailures() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures = make(map[string]*fakeFailure)
+}
+
+// Calls returns how many times method has been called
+func (f *FakeNode) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls[method]
+}
+
+// call records a call to method and returns the error it should fail with, if any.
+// It must be called without holding the lock.
+func (f *FakeNode) call(method string) error {
+	f.mu.Lock()
+	f.calls[method]++
+
+	failure, ok := f.failures[method]
+	if !ok || failure.count == 0 {
+		f.mu.Unlock()
+		return nil
+	}
+	if failure.count > 0 {
+		failure.count--
+	}
+	err, delay := failure.err, failure.delay
+	f.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return err
+}
+
+func (f *FakeNode) GetBlockNumber() (uint64, error) {
+	if err := f.call("GetBlockNumber"); err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.blocks) == 0 {
+		return 0, nil
+	}
+	return uint64(len(f.blocks) - 1), nil
+}
+
+func (f *FakeNode) GetBlock(number uint64) (*models.Block, error) {
+	if err := f.call("GetBlock"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if number >= uint64(len(f.blocks)) {
+		return nil, ErrBlockNotFound
+	}
+
+	block := *f.blocks[number]
+	return &block, nil
+}
+
+func (f *FakeNode) GetTransactionByHash(hash *common.Hash) (*models.Transaction, *models.TransactionReceipt, error) {
+	if err := f.call("GetTransactionByHash"); err != nil {
+		return nil, nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tx, ok := f.transactions[*hash]
+	if !ok {
+		return nil, nil, ErrTransactionNotFound
+	}
+
+	txr, ok := f.receipts[*hash]
+	if !ok {
+		return nil, nil, ErrMissingReceipt
+	}
+
+	txCopy, txrCopy := *tx, *txr
+	return &txCopy, &txrCopy, nil
+}
+
+// delegatesAt returns the delegates of the closest block at or before number
+func (f *FakeNode) delegatesAt(number uint64) []models.DelegateVoteInfo {
+	var found []models.DelegateVoteInfo
+	closest := int64(-1)
+	for n, delegates := range f.delegates {
+		if n <= number && int64(n) > closest {
+			closest = int64(n)
+			found = delegates
+		}
+	}
+	return found
+}
+
+func (f *FakeNode) GetDelegates(number uint64) ([]models.DelegateVoteInfo, error) {
+	if err := f.call("GetDelegates"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delegates := f.delegatesAt(number)
+	return append(make([]models.DelegateVoteInfo, 0, len(delegates)), delegates...), nil
+}
+
+func (f *FakeNode) GetDelegate(address common.Address, number int64) (*models.DelegateVoteInfo, error) {
+	if err := f.call("GetDelegate"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	blockNumber := uint64(number)
+	if number == -1 {
+		blockNumber = ^uint64(0)
+	}
+
+	for _, d := range f.delegatesAt(blockNumber) {
+		if d.Address == address {
+			delegate := d
+			return &delegate, nil
+		}
+	}
+
+	return &models.DelegateVoteInfo{Address: address}, nil
+}
+
+func (f *FakeNode) GetAddressBalance(address common.Address) (*big.Int, error) {
+	if err := f.call("GetAddressBalance"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if balance, ok := f.balances[address]; ok {
+		return new(big.Int).Set(balance), nil
+	}
+	return new(big.Int), nil
+}
+
+func (f *FakeNode) GetAddressStaked(address common.Address) (uint64, error) {
+	if err := f.call("GetAddressStaked"); err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.staked[address], nil
+}
+
+func (f *FakeNode) GetABIForContract(address common.Address) (string, error) {
+	if err := f.call("GetABIForContract"); err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	abi, ok := f.abis[address]
+	if !ok {
+		return "", fmt.Errorf("no abi found for %s", address.Hex())
+	}
+	return abi, nil
+}
+
+func (f *FakeNode) GetTransactionLogs(hash common.Hash) ([]models.Log, error) {
+	if err := f.call("GetTransactionLogs"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.receipts[hash]; !ok {
+		return nil, ErrMissingReceipt
+	}
+
+	logs := make([]models.Log, len(f.logs[hash]))
+	copy(logs, f.logs[hash])
+	return logs, nil
+}
+
+func (f *FakeNode) GetENSAddress(contractAddress common.Address, hash common.Hash) (common.Address, error) {
+	if err := f.call("GetENSAddress"); err != nil {
+		return common.Address{}, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if address, ok := f.ens[hash]; ok {
+		return address, nil
+	}
+	return common.BytesToAddress(f.storage[contractAddress][ENSAddressSlot(hash)].Bytes()), nil
+}
+
+// GetStorageAt returns the value the fixture has for the slot, or zero like
+// the node does for unset slots. The block number is ignored.
+func (f *FakeNode) GetStorageAt(address common.Address, slot common.Hash, blockNumber *uint64) (common.Hash, error) {
+	if err := f.call("GetStorageAt"); err != nil {
+		return common.Hash{}, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.storage[address][slot], nil
+}
+
+func (f *FakeNode) GetChainId() (uint64, error) {
+	if err := f.call("GetChainId"); err != nil {
+		return 0, err
+	}
+
+	return f.chainID, nil
+}
+
+// CallContract returns the output the fixture has for the call, or no output
+// like the node does for accounts without code. The block number is ignored.
+func (f *FakeNode) CallContract(ctx context.Context, to common.Address, data []byte, gas uint64, blockNumber *uint64) ([]byte, error) {
+	if err := f.call("CallContract"); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	output := f.callOutputs[to][hexutil.Encode(data)]
+	result := make([]byte, len(output))
+	copy(result, output)
+	return result, nil
+}
+
+// CallContext answers the few raw calls a fixture can serve: eth_blockNumber,
+// eth_chainId, net_version, eth_getBalance, eth_getTransactionByHash and
+// eth_getBlockByHash. Unknown transactions and blocks are null.
+func (f *FakeNode) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if err := f.call(method); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var value interface{}
+
+	switch method {
+	case "eth_blockNumber":
+		number, err := f.GetBlockNumber()
+		if err != nil {
+			return err
+		}
+		value = hexutil.Uint64(number)
+	case "eth_chainId":
+		value = hexutil.Uint64(f.chainID)
+	case "net_version":
+		value = strconv.FormatUint(f.chainID, 10)
+	case "eth_getBalance":
+		if len(args) == 0 {
+			return ErrFakeInvalidParams
+		}
+		var address common.Address
+		if err := remarshal(args[0], &address); err != nil {
+			return ErrFakeInvalidParams
+		}
+		balance, err := f.GetAddressBalance(address)
+		if err != nil {
+			return err
+		}
+		value = (*hexutil.Big)(balance)
+	case "eth_getTransactionByHash":
+		var hash common.Hash
+		if len(args) == 0 || remarshal(args[0], &hash) != nil {
+			return ErrFakeInvalidParams
+		}
+		value = f.rpcTransaction(hash)
+	case "eth_getBlockByHash":
+		var hash common.Hash
+		var full bool
+		if len(args) < 2 || remarshal(args[0], &hash) != nil || remarshal(args[1], &full) != nil {
+			return ErrFakeInvalidParams
+		}
+		value = f.rpcBlock(hash, full)
+	default:
+		return ErrFakeMethodNotSupported
+	}
+
+	return remarshal(value, result)
+}
+
+// fakeRPCTransaction is a transaction in the encoding of the node, which
+// differs from the one of models.Transaction
+type fakeRPCTransaction struct {
+	Hash             common.Hash     `json:"hash"`
+	Nonce            hexutil.Uint64  `json:"nonce"`
+	BlockHash        common.Hash     `json:"blockHash"`
+	BlockNumber      hexutil.Uint64  `json:"blockNumber"`
+	TransactionIndex hexutil.Uint64  `json:"transactionIndex"`
+	From             common.Address  `json:"from"`
+	To               *common.Address `json:"to"`
+	Value            *hexutil.Big    `json:"value"`
+	Gas              hexutil.Uint64  `json:"gas"`
+	GasPrice         hexutil.Uint64  `json:"gasPrice"`
+	WorkNonce        hexutil.Uint64  `json:"workNonce"`
+	Input            hexutil.Bytes   `json:"input"`
+}
+
+func newFakeRPCTransaction(tx *models.Transaction) *fakeRPCTransaction {
+	value := tx.Value
+	return &fakeRPCTransaction{
+		Hash:             tx.Hash,
+		Nonce:            tx.Nonce,
+		BlockHash:        tx.BlockHash,
+		BlockNumber:      tx.BlockNumber,
+		TransactionIndex: tx.TransactionIndex,
+		From:             tx.From,
+		To:               tx.To,
+		Value:            &value,
+		Gas:              tx.GasLimit,
+		GasPrice:         tx.GasPrice,
+		WorkNonce:        tx.WorkNonce,
+		Input:            hexutil.Bytes(tx.Input),
+	}
+}
+
+// rpcTransaction returns the transaction in the encoding of the node, or
+// nil when it is unknown
+func (f *FakeNode) rpcTransaction(hash common.Hash) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tx, ok := f.transactions[hash]
+	if !ok {
+		return nil
+	}
+	return newFakeRPCTransaction(tx)
+}
+
+// rpcBlock returns the block with the hashes of its transactions, or the
+// transactions when full is set, or nil when it is unknown
+func (f *FakeNode) rpcBlock(hash common.Hash, full bool) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, block := range f.blocks {
+		if block.Hash != hash {
+			continue
+		}
+
+		var transactions interface{} = block.Transactions
+		if full {
+			txs := make([]interface{}, len(block.Transactions))
+			for i, hash := range block.Transactions {
+				txs[i] = newFakeRPCTransaction(f.transactions[hash])
+			}
+			transactions = txs
+		}
+
+		return map[string]interface{}{
+			"number":       block.Number,
+			"hash":         block.Hash,
+			"parentHash":   block.ParentHash,
+			"timestamp":    block.TimeStamp,
+			"producer":     block.Producer,
+			"transactions": transactions,
+		}
+	}
+	return nil
+}
+
+// remarshal copies src into dst through their JSON encoding, the way
+// values travel over RPC
+func remarshal(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
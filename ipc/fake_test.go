@@ -0,0 +1,99 @@
+package ipc
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+const testFixture = "testdata/chain.json"
+
+func TestFakeNodeTimeout(t *testing.T) {
+	node, err := NewFakeNodeFromFile(testFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const delay = 20 * time.Millisecond
+	node.SetTimeout("GetBlock", 2, delay)
+
+	// the failures are shared by concurrent calls
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	start := time.Now()
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = node.GetBlock(1)
+		}(i)
+	}
+	wg.Wait()
+
+	timeouts := 0
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if e, ok := err.(*FakeTimeoutError); !ok || !e.Timeout() || e.Method != "GetBlock" {
+			t.Errorf("GetBlock() = %v, want a timeout", err)
+		}
+		timeouts++
+	}
+	if timeouts != 2 {
+		t.Errorf("got %d timeouts, want 2", timeouts)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("timeouts took %v, want at least %v", elapsed, delay)
+	}
+
+	if _, err := node.GetBlock(1); err != nil {
+		t.Errorf("GetBlock() after the timeouts = %v", err)
+	}
+	if calls := node.Calls("GetBlock"); calls != 4 {
+		t.Errorf("Calls(GetBlock) = %d, want 4", calls)
+	}
+}
+
+func TestFakeNodeReorg(t *testing.T) {
+	node, err := NewFakeNodeFromFile(testFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := node.GetBlock(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, err := node.GetBlock(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := node.ApplyReorg(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if head, err := node.GetBlockNumber(); err != nil || head != 6 {
+		t.Errorf("GetBlockNumber() after the reorg = %d, %v, want 6", head, err)
+	}
+
+	bl, err := node.GetBlock(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bl.Hash == old.Hash || bl.ParentHash != parent.Hash {
+		t.Errorf("block 4 after the reorg = %x with parent %x, want a new block on %x", bl.Hash, bl.ParentHash, parent.Hash)
+	}
+
+	for _, hash := range bl.Transactions {
+		hash := hash
+		if _, _, err := node.GetTransactionByHash(&hash); err != nil {
+			t.Errorf("transaction %x of the reorg: %v", hash, err)
+		}
+	}
+
+	if err := node.ApplyReorg(1); err == nil {
+		t.Errorf("ApplyReorg(1) of a fixture with one reorg succeeded")
+	}
+}
@@ -1,14 +1,15 @@
 package ipc
 
 import (
+	"context"
 	"errors"
-	"log"
 	"math/big"
+	"strings"
 	"sync"
 
 	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
 	"github.com/ebakus/ebakus-block-explorer-backend/models"
-	"golang.org/x/crypto/sha3"
 
 	"github.com/ebakus/go-ebakus/common"
 
@@ -19,8 +20,49 @@ import (
 var (
 	// ErrNoCode is returned when last is greater than first
 	ErrInvalideBlockRange = errors.New("Invalid block range")
+
+	// ErrBlockNotFound is returned when the node doesn't know the requested block
+	ErrBlockNotFound = errors.New("Block not found")
+
+	// ErrTransactionNotFound is returned when the node doesn't know the requested transaction
+	ErrTransactionNotFound = errors.New("Transaction not found")
+
+	// ErrMissingReceipt is returned when the node has no receipt for a transaction
+	ErrMissingReceipt = errors.New("Transaction receipt not found")
+)
+
+// Node is the set of calls the explorer and the crawler make to an ebakus node.
+// IPCInterface talks to a real node, while FakeNode replays fixtures for tests.
+type Node interface {
+	GetBlockNumber() (uint64, error)
+	GetBlock(number uint64) (*models.Block, error)
+	GetTransactionByHash(hash *common.Hash) (*models.Transaction, *models.TransactionReceipt, error)
+	GetDelegates(number uint64) ([]models.DelegateVoteInfo, error)
+	GetDelegate(address common.Address, number int64) (*models.DelegateVoteInfo, error)
+	GetAddressBalance(address common.Address) (*big.Int, error)
+	GetAddressStaked(address common.Address) (uint64, error)
+	GetABIForContract(address common.Address) (string, error)
+	GetTransactionLogs(hash common.Hash) ([]models.Log, error)
+	CallContract(ctx context.Context, to common.Address, data []byte, gas uint64, blockNumber *uint64) ([]byte, error)
+	GetStorageAt(address common.Address, slot common.Hash, blockNumber *uint64) (common.Hash, error)
+	GetENSAddress(contractAddress common.Address, hash common.Hash) (common.Address, error)
+	GetChainId() (uint64, error)
+
+	// CallContext makes a raw JSON-RPC call, used to proxy requests to the node
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// Make sure both implementations satisfy the interface
+var (
+	_ Node = (*IPCInterface)(nil)
+	_ Node = (*FakeNode)(nil)
 )
 
+// FixtureScheme is the endpoint prefix that makes Dial load a FakeNode
+// from a JSON fixture instead of connecting to a node,
+// e.g. fixture:./ipc/testdata/chain.json
+const FixtureScheme = "fixture:"
+
 type TransactionWithTimestamp struct {
 	Hash      common.Hash
 	Timestamp hexutil.Uint64
@@ -28,9 +70,10 @@ type TransactionWithTimestamp struct {
 
 type IPCInterface struct {
 	cli *rpc.Client
+	log logger.Logger
 }
 
-var ipci *IPCInterface
+var ipci Node
 
 func NewIPCInterface(endpoint string) (*IPCInterface, error) {
 	cli, err := rpc.Dial(endpoint)
@@ -38,26 +81,63 @@ func NewIPCInterface(endpoint string) (*IPCInterface, error) {
 		return nil, err
 	}
 
-	ipci = &IPCInterface{cli}
+	ipc := &IPCInterface{cli, logger.New()}
+	ipci = ipc
 
-	return ipci, nil
+	return ipc, nil
+}
+
+// Dial connects to the node at endpoint and makes it the current Node.
+// Endpoints starting with FixtureScheme load a FakeNode instead.
+func Dial(endpoint string) (Node, error) {
+	if strings.HasPrefix(endpoint, FixtureScheme) {
+		fake, err := NewFakeNodeFromFile(strings.TrimPrefix(endpoint, FixtureScheme))
+		if err != nil {
+			return nil, err
+		}
+		ipci = fake
+		return fake, nil
+	}
+
+	return NewIPCInterface(endpoint)
 }
 
 // GetIPC returns the current ipc instance.
 // Dev Commentary: I'm sorry for this but I needed a way to have
 // the IPC available throughout the project. If you know
 // a better way to do this I'd like to know it too.
-func GetIPC() *IPCInterface {
+func GetIPC() Node {
 	return ipci
 }
 
+// GetIPCContext returns the current ipc instance, logging its calls with
+// the logger carried by ctx, e.g. with the ID of the request
+func GetIPCContext(ctx context.Context) Node {
+	if ipc, ok := ipci.(*IPCInterface); ok {
+		return &IPCInterface{ipc.cli, logger.FromContext(ctx)}
+	}
+	return ipci
+}
+
+// Close closes the connection of the current ipc instance to the node, if any
+func Close() {
+	if ipc, ok := ipci.(*IPCInterface); ok {
+		ipc.cli.Close()
+	}
+}
+
+// SetIPC replaces the current Node instance, e.g. with a FakeNode
+func SetIPC(node Node) {
+	ipci = node
+}
+
 //
 // Get the top block number
 //
 func (ipc *IPCInterface) GetBlockNumber() (uint64, error) {
 	var v hexutil.Big
 
-	err := ipc.cli.Call(&v, "eth_blockNumber")
+	err := ipc.call(&v, "eth_blockNumber")
 	if err != nil {
 		return 0, err
 	}
@@ -66,14 +146,18 @@ func (ipc *IPCInterface) GetBlockNumber() (uint64, error) {
 }
 
 func (ipc *IPCInterface) GetBlock(number uint64) (*models.Block, error) {
-	var block models.Block
+	var block *models.Block
 
-	err := ipc.cli.Call(&block, "eth_getBlockByNumber", hexutil.EncodeUint64(number), false)
+	err := ipc.call(&block, "eth_getBlockByNumber", hexutil.EncodeUint64(number), false)
 	if err != nil {
 		return nil, err
 	}
 
-	return &block, nil
+	if block == nil {
+		return nil, ErrBlockNotFound
+	}
+
+	return block, nil
 }
 
 func (ipc *IPCInterface) GetLastBlocks(count uint64) ([]*models.Block, error) {
@@ -110,12 +194,13 @@ func (ipc *IPCInterface) GetBlocks(first, last uint64) ([]*models.Block, error)
 	return blocks, nil
 }
 
-func (ipc *IPCInterface) StreamTransactions(wg *sync.WaitGroup, db *db.DBClient, tCh chan<- models.TransactionFull, hashCh <-chan TransactionWithTimestamp) {
+// StreamTransactions fetches the transactions received on hashCh from the node
+func StreamTransactions(ipc Node, wg *sync.WaitGroup, db db.Store, tCh chan<- models.TransactionFull, hashCh <-chan TransactionWithTimestamp) {
 	defer wg.Done()
 	for obj := range hashCh {
 		tx, txr, err := ipc.GetTransactionByHash(&obj.Hash)
 		if err != nil {
-			log.Println("Error getTransaction ipc:", err, "hash", obj.Hash.Hex())
+			logger.Error("Failed to get transaction", "hash", obj.Hash, "err", err)
 			continue
 		}
 
@@ -127,9 +212,14 @@ func (ipc *IPCInterface) StreamTransactions(wg *sync.WaitGroup, db *db.DBClient,
 	close(tCh)
 }
 
-func (ipc *IPCInterface) StreamBlocks(wg *sync.WaitGroup, db *db.DBClient, bCh chan<- *models.Block, tCh chan<- TransactionWithTimestamp, pCh chan<- common.Address, dCh chan<- *models.Block, lastBlockNumber uint64) error {
+// StreamBlocks walks the node's chain backwards from lastBlockNumber until it
+// reaches a block already stored, sending new blocks to bCh and replaced ones to dCh
+func StreamBlocks(ipc Node, wg *sync.WaitGroup, db db.Store, bCh chan<- *models.Block, tCh chan<- TransactionWithTimestamp, pCh chan<- common.Address, dCh chan<- *models.Block, lastBlockNumber uint64) error {
 	defer wg.Done()
 
+	// always close dCh, as the rest of the pipeline waits on it
+	defer close(dCh)
+
 	for i := lastBlockNumber; i >= 0; {
 		bl, err := ipc.GetBlock(i)
 		if err != nil {
@@ -165,32 +255,38 @@ func (ipc *IPCInterface) StreamBlocks(wg *sync.WaitGroup, db *db.DBClient, bCh c
 		i--
 	}
 
-	close(dCh)
-
 	return nil
 }
 
 func (ipc *IPCInterface) GetTransactionByHash(hash *common.Hash) (*models.Transaction, *models.TransactionReceipt, error) {
-	var tx models.Transaction
-	var txr models.TransactionReceipt
+	var tx *models.Transaction
+	var txr *models.TransactionReceipt
 
-	err := ipc.cli.Call(&tx, "eth_getTransactionByHash", hash.String())
+	err := ipc.call(&tx, "eth_getTransactionByHash", hash.String())
 	if err != nil {
 		return nil, nil, err
 	}
 
-	err = ipc.cli.Call(&txr, "eth_getTransactionReceipt", hash.String())
+	if tx == nil {
+		return nil, nil, ErrTransactionNotFound
+	}
+
+	err = ipc.call(&txr, "eth_getTransactionReceipt", hash.String())
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return &tx, &txr, nil
+	if txr == nil {
+		return nil, nil, ErrMissingReceipt
+	}
+
+	return tx, txr, nil
 }
 
 func (ipc *IPCInterface) GetDelegates(number uint64) ([]models.DelegateVoteInfo, error) {
 	var di []models.DelegateVoteInfo
 
-	err := ipc.cli.Call(&di, "dpos_getDelegates", hexutil.EncodeUint64(number))
+	err := ipc.call(&di, "dpos_getDelegates", hexutil.EncodeUint64(number))
 	if err != nil {
 		return nil, err
 	}
@@ -206,7 +302,7 @@ func (ipc *IPCInterface) GetDelegate(address common.Address, number int64) (*mod
 		blockNumber = "latest"
 	}
 
-	err := ipc.cli.Call(&di, "dpos_getDelegate", address.Hex(), blockNumber)
+	err := ipc.call(&di, "dpos_getDelegate", address.Hex(), blockNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -217,7 +313,7 @@ func (ipc *IPCInterface) GetDelegate(address common.Address, number int64) (*mod
 func (ipc *IPCInterface) GetAddressBalance(address common.Address) (*big.Int, error) {
 	var balance hexutil.Big
 
-	err := ipc.cli.Call(&balance, "eth_getBalance", address, "latest")
+	err := ipc.call(&balance, "eth_getBalance", address, "latest")
 	if err != nil {
 		return nil, err
 	}
@@ -228,7 +324,7 @@ func (ipc *IPCInterface) GetAddressBalance(address common.Address) (*big.Int, er
 func (ipc *IPCInterface) GetAddressStaked(address common.Address) (uint64, error) {
 	var staked uint64
 
-	err := ipc.cli.Call(&staked, "eth_getStaked", address, "latest")
+	err := ipc.call(&staked, "eth_getStaked", address, "latest")
 	if err != nil {
 		return 0, err
 	}
@@ -239,7 +335,7 @@ func (ipc *IPCInterface) GetAddressStaked(address common.Address) (uint64, error
 func (ipc *IPCInterface) GetABIForContract(address common.Address) (string, error) {
 	var abi string
 
-	err := ipc.cli.Call(&abi, "eth_getAbiForAddress", address)
+	err := ipc.call(&abi, "eth_getAbiForAddress", address)
 	if err != nil {
 		return "", err
 	}
@@ -247,19 +343,64 @@ func (ipc *IPCInterface) GetABIForContract(address common.Address) (string, erro
 	return abi, nil
 }
 
-func (ipc *IPCInterface) GetENSAddress(contractAddress common.Address, hash common.Hash) (common.Address, error) {
-	keyBytesPadded := common.LeftPadBytes(hash.Bytes(), 32)
+// GetTransactionLogs returns the logs of a transaction from its receipt
+func (ipc *IPCInterface) GetTransactionLogs(hash common.Hash) ([]models.Log, error) {
+	var receipt *struct {
+		Logs []models.Log `json:"logs"`
+	}
 
-	// pos of _lookupOwner mapping in contract
-	posIndex := common.LeftPadBytes([]byte{5}, 32)
+	err := ipc.call(&receipt, "eth_getTransactionReceipt", hash.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if receipt == nil {
+		return nil, ErrMissingReceipt
+	}
 
-	keyBytes := sha3.NewLegacyKeccak256()
-	keyBytes.Write(append(keyBytesPadded, posIndex...))
+	return receipt.Logs, nil
+}
 
-	key := common.ToHex(keyBytes.Sum(nil))
+// CallContract executes a message call against the state at blockNumber,
+// or the latest block when it is nil, and returns its output
+func (ipc *IPCInterface) CallContract(ctx context.Context, to common.Address, data []byte, gas uint64, blockNumber *uint64) ([]byte, error) {
+	msg := map[string]interface{}{
+		"to":   to,
+		"data": hexutil.Bytes(data),
+		"gas":  hexutil.Uint64(gas),
+	}
+
+	block := "latest"
+	if blockNumber != nil {
+		block = hexutil.EncodeUint64(*blockNumber)
+	}
+
+	var result hexutil.Bytes
+	if err := ipc.callContext(ctx, &result, "eth_call", msg, block); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetStorageAt returns the value of a storage slot at blockNumber, or at
+// the latest block when it is nil
+func (ipc *IPCInterface) GetStorageAt(address common.Address, slot common.Hash, blockNumber *uint64) (common.Hash, error) {
+	block := "latest"
+	if blockNumber != nil {
+		block = hexutil.EncodeUint64(*blockNumber)
+	}
 
 	var res common.Hash
-	err := ipc.cli.Call(&res, "eth_getStorageAt", contractAddress, key, "latest")
+	if err := ipc.call(&res, "eth_getStorageAt", address, slot, block); err != nil {
+		return common.Hash{}, err
+	}
+
+	return res, nil
+}
+
+func (ipc *IPCInterface) GetENSAddress(contractAddress common.Address, hash common.Hash) (common.Address, error) {
+	res, err := ipc.GetStorageAt(contractAddress, ENSAddressSlot(hash), nil)
 	if err != nil {
 		return common.Address{}, err
 	}
@@ -271,10 +412,14 @@ func (ipc *IPCInterface) GetENSAddress(contractAddress common.Address, hash comm
 func (ipc *IPCInterface) GetChainId() (uint64, error) {
 	var v hexutil.Big
 
-	err := ipc.cli.Call(&v, "eth_chainId")
+	err := ipc.call(&v, "eth_chainId")
 	if err != nil {
 		return 0, err
 	}
 
 	return v.ToInt().Uint64(), nil
 }
+
+func (ipc *IPCInterface) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return ipc.callContext(ctx, result, method, args...)
+}
@@ -0,0 +1,43 @@
+package ipc
+
+import (
+	"context"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	callDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ebakus_ipc_call_duration_seconds",
+		Help:    "Latency of the calls to the node, by RPC method",
+		Buckets: metrics.DefaultBuckets,
+	}, []string{"method"})
+	callErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ebakus_ipc_call_errors_total",
+		Help: "Calls to the node that failed, by RPC method",
+	}, []string{"method"})
+)
+
+// call calls the node, timing the call and logging it at debug
+func (ipc *IPCInterface) call(result interface{}, method string, args ...interface{}) error {
+	return ipc.callContext(context.Background(), result, method, args...)
+}
+
+func (ipc *IPCInterface) callContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	start := time.Now()
+	err := ipc.cli.CallContext(ctx, result, method, args...)
+
+	elapsed := time.Since(start)
+	callDuration.WithLabelValues(method).Observe(elapsed.Seconds())
+	if err != nil {
+		callErrors.WithLabelValues(method).Inc()
+		ipc.log.Debug("Node call failed", "method", method, "elapsed", elapsed, "err", err)
+		return err
+	}
+	ipc.log.Debug("Node call", "method", method, "elapsed", elapsed)
+	return nil
+}
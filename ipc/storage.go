@@ -0,0 +1,47 @@
+package ipc
+
+import (
+	"math/big"
+
+	"github.com/ebakus/go-ebakus/common"
+	"golang.org/x/crypto/sha3"
+)
+
+// Storage slots follow the layout of solidity: a mapping at slot p keeps
+// the value of key k at keccak(k . p), and a dynamic array at slot p keeps
+// its elements from keccak(p) onwards.
+
+// MappingSlot returns the slot of a mapping value. Keys of value types are
+// left padded to 32 bytes, while string and bytes keys are used as they are.
+func MappingSlot(slot common.Hash, key []byte) common.Hash {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(key)
+	h.Write(slot.Bytes())
+	return common.BytesToHash(h.Sum(nil))
+}
+
+// ArraySlot returns the slot of an element of a dynamic array, for elements
+// that take elementSlots slots each
+func ArraySlot(slot common.Hash, index uint64, elementSlots uint64) common.Hash {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(slot.Bytes())
+	start := common.BytesToHash(h.Sum(nil))
+
+	offset := new(big.Int).Mul(new(big.Int).SetUint64(index), new(big.Int).SetUint64(elementSlots))
+	return OffsetSlot(start, offset)
+}
+
+// OffsetSlot returns the slot offset slots after slot, e.g. for a member of
+// a struct, wrapping around like the EVM does
+func OffsetSlot(slot common.Hash, offset *big.Int) common.Hash {
+	position := new(big.Int).Add(slot.Big(), offset)
+	return common.BytesToHash(position.Bytes())
+}
+
+// ensLookupOwnerSlot is the position of the _lookupOwner mapping in the ENS contract
+const ensLookupOwnerSlot = 5
+
+// ENSAddressSlot returns the slot that keeps the address an ENS name hash resolves to
+func ENSAddressSlot(hash common.Hash) common.Hash {
+	return MappingSlot(common.BigToHash(big.NewInt(ensLookupOwnerSlot)), hash.Bytes())
+}
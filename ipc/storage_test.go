@@ -0,0 +1,52 @@
+package ipc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ebakus/go-ebakus/common"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestStorageSlots(t *testing.T) {
+	slot := func(n int64) common.Hash { return common.BigToHash(big.NewInt(n)) }
+	max := common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	alice := common.LeftPadBytes(common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Bytes(), 32)
+
+	for _, c := range []struct {
+		name      string
+		got, want common.Hash
+	}{
+		// keccak(p) for the arrays at the first slots, as laid out by solc
+		{"array at 0", ArraySlot(slot(0), 0, 1), common.HexToHash("0x290decd9548b62a8d60345a988386fc84ba6bc95484008f6362f93160ef3e563")},
+		{"array at 1", ArraySlot(slot(1), 0, 1), common.HexToHash("0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6")},
+		{"element 3 of 2 slots at 2", ArraySlot(slot(2), 3, 2), common.HexToHash("0x405787fa12a823e0f2b7631cc41b3ba8828b3321ca811111fa75cd3aa3bb5ad4")},
+
+		// keccak(k . p)
+		{"mapping at 0, key 0", MappingSlot(slot(0), make([]byte, 32)), common.HexToHash("0xad3228b676f7d3cd4284a5443f17f1962b36e491b30a40b2405849e597ba5fb5")},
+		{"mapping at 3, address key", MappingSlot(slot(3), alice), common.HexToHash("0xca0453669a7127ce38f304ce121e552d78c30286022ebefeef6884684816084d")},
+		{"mapping at 1, string key", MappingSlot(slot(1), []byte("abc")), common.HexToHash("0xac85c8cc1ac92e94a731b8df588044cbfd366c5ee08805d198cb1b094f3cacac")},
+
+		{"struct member", OffsetSlot(slot(7), big.NewInt(2)), slot(9)},
+		{"offset wrapping around", OffsetSlot(max, big.NewInt(2)), slot(1)},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %x, want %x", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestENSAddressSlot(t *testing.T) {
+	// namehash("eth")
+	hash := common.HexToHash("0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae")
+
+	// the slot as GetENSAddress used to compute it, before the slot helpers
+	h := sha3.NewLegacyKeccak256()
+	h.Write(append(common.LeftPadBytes(hash.Bytes(), 32), common.LeftPadBytes([]byte{5}, 32)...))
+	legacy := common.BytesToHash(h.Sum(nil))
+
+	want := common.HexToHash("0xd99130487705d6970718a0cee91984b61956f8a1db3482bba7e6bf0131adb01f")
+	if got := ENSAddressSlot(hash); got != want || got != legacy {
+		t.Errorf("ENSAddressSlot(%x) = %x, want %x (legacy %x)", hash, got, want, legacy)
+	}
+}
@@ -0,0 +1,462 @@
+// Package jsonrpc serves a read-only JSON-RPC interface in front of the node.
+// The lookups the index can answer are served from the store, while the
+// rest of an allowlist is proxied to the node and cached.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+	"github.com/ebakus/go-ebakus/rpc"
+)
+
+const (
+	// receiptCacheSeconds is how long the receipts proxied to the node are cached
+	receiptCacheSeconds = 60
+
+	// DEFAULT_CALL_TIMEOUT is how long the node gets to answer the methods
+	// that run code
+	DEFAULT_CALL_TIMEOUT = 5 * time.Second
+
+	// DEFAULT_CALL_GAS caps the gas of the methods that run code
+	DEFAULT_CALL_GAS = 10000000
+)
+
+var (
+	// ErrMissingStore is returned when there is no DB client to answer from
+	ErrMissingStore = errors.New("DBClient is not initialized")
+
+	// ErrMissingIPC is returned when there is no node to proxy to
+	ErrMissingIPC = errors.New("IPC connection is not initialized")
+)
+
+type localMethod func(ctx context.Context, dbc db.Store, params []json.RawMessage) (interface{}, error)
+
+// localMethods are answered from the index
+var localMethods = map[string]localMethod{
+	"eth_blockNumber":           blockNumber,
+	"eth_getBlockByNumber":      getBlockByNumber,
+	"eth_getBlockByHash":        getBlockByHash,
+	"eth_getTransactionByHash":  getTransactionByHash,
+	"eth_getTransactionReceipt": getTransactionReceipt,
+}
+
+// proxiedMethods are forwarded to the node, mapped to the seconds their
+// results are cached for
+var proxiedMethods = map[string]uint64{
+	"eth_chainId":             60 * 60,
+	"net_version":             60 * 60,
+	"web3_clientVersion":      60,
+	"eth_syncing":             1,
+	"eth_gasPrice":            5,
+	"eth_getBalance":          1,
+	"eth_getStaked":           1,
+	"eth_getTransactionCount": 1,
+	"eth_getCode":             1,
+	"eth_getStorageAt":        1,
+	"eth_call":                1,
+	"eth_estimateGas":         1,
+	"eth_getAbiForAddress":    60,
+	"dpos_getDelegates":       1,
+	"dpos_getDelegate":        1,
+}
+
+// callMethods run code on the node, so their gas is capped and they are
+// given a timeout
+var callMethods = map[string]bool{
+	"eth_call":        true,
+	"eth_estimateGas": true,
+}
+
+// writeMethods are forwarded to the node only when the gateway allows
+// writes, and never cached
+var writeMethods = map[string]bool{
+	"eth_sendRawTransaction": true,
+}
+
+// Gateway answers JSON-RPC requests
+type Gateway struct {
+	allowWrite  bool
+	callTimeout time.Duration
+	callGas     uint64
+}
+
+// NewGateway creates a Gateway, which forwards write methods to the node
+// only if allowWrite is set
+func NewGateway(allowWrite bool) *Gateway {
+	return &Gateway{allowWrite: allowWrite, callTimeout: DEFAULT_CALL_TIMEOUT, callGas: DEFAULT_CALL_GAS}
+}
+
+// SetCallLimits sets the timeout of eth_call and eth_estimateGas, and the
+// gas their call objects are capped to
+func (g *Gateway) SetCallLimits(timeout time.Duration, gas uint64) {
+	g.callTimeout = timeout
+	g.callGas = gas
+}
+
+// Handle answers a single request, logging with the logger of ctx
+func (g *Gateway) Handle(ctx context.Context, req Request) Response {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return ErrorResponse(req.ID, CODE_INVALID_REQUEST, "invalid request")
+	}
+
+	var params []json.RawMessage
+	if len(req.Params) > 0 && !bytes.Equal(req.Params, []byte("null")) {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return ErrorResponse(req.ID, CODE_INVALID_PARAMS, "invalid params, expected an array")
+		}
+	}
+
+	var result interface{}
+	var err error
+
+	if method, ok := localMethods[req.Method]; ok {
+		dbc := db.GetClientContext(ctx)
+		if dbc == nil {
+			err = ErrMissingStore
+		} else {
+			result, err = method(ctx, dbc, params)
+		}
+	} else if ttl, ok := proxiedMethods[req.Method]; ok && callMethods[req.Method] {
+		result, err = g.proxyCall(ctx, req.Method, params, ttl)
+	} else if ok {
+		result, err = proxy(ctx, req.Method, params, ttl)
+	} else if writeMethods[req.Method] {
+		if !g.allowWrite {
+			return ErrorResponse(req.ID, CODE_METHOD_NOT_FOUND, fmt.Sprintf("the method %s is disabled on this gateway", req.Method))
+		}
+		result, err = proxy(ctx, req.Method, params, 0)
+	} else {
+		return ErrorResponse(req.ID, CODE_METHOD_NOT_FOUND, fmt.Sprintf("the method %s does not exist/is not available", req.Method))
+	}
+
+	if err != nil {
+		if rpcErr, ok := err.(*Error); ok {
+			return Response{ID: req.ID, Error: rpcErr}
+		}
+		logger.FromContext(ctx).Error("JSON-RPC call failed", "method", req.Method, "err", err)
+		return ErrorResponse(req.ID, CODE_INTERNAL_ERROR, "internal error")
+	}
+
+	return Response{ID: req.ID, Result: result}
+}
+
+func invalidParams(format string, args ...interface{}) error {
+	return &Error{Code: CODE_INVALID_PARAMS, Message: "invalid params: " + fmt.Sprintf(format, args...)}
+}
+
+// proxyCacheKey is derived from the compacted params, so that requests that
+// only differ in whitespace share the cache
+func proxyCacheKey(method string, params []json.RawMessage) string {
+	h := sha256.New()
+	for _, param := range params {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, param); err != nil {
+			h.Write(param)
+		} else {
+			h.Write(buf.Bytes())
+		}
+		h.Write([]byte{0})
+	}
+	return "rpc:" + method + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// capCallGas returns the params of a method that runs code with the gas of
+// its call object capped to gas, which is also the gas of the calls that
+// don't set it
+func capCallGas(params []json.RawMessage, gas uint64) ([]json.RawMessage, error) {
+	if len(params) == 0 {
+		return nil, invalidParams("missing call object")
+	}
+
+	var call map[string]json.RawMessage
+	if err := json.Unmarshal(params[0], &call); err != nil || call == nil {
+		return nil, invalidParams("call object must be an object")
+	}
+
+	if raw, ok := call["gas"]; ok && !bytes.Equal(raw, []byte("null")) {
+		var requested hexutil.Uint64
+		if err := json.Unmarshal(raw, &requested); err != nil {
+			return nil, invalidParams("invalid gas %s", raw)
+		}
+		if uint64(requested) < gas {
+			gas = uint64(requested)
+		}
+	}
+
+	var err error
+	if call["gas"], err = json.Marshal(hexutil.Uint64(gas)); err != nil {
+		return nil, err
+	}
+
+	capped := make([]json.RawMessage, len(params))
+	copy(capped, params)
+	if capped[0], err = json.Marshal(call); err != nil {
+		return nil, err
+	}
+	return capped, nil
+}
+
+// proxyCall proxies a method that runs code on the node, with the gas of
+// its call object capped and within the call timeout of the gateway
+func (g *Gateway) proxyCall(ctx context.Context, method string, params []json.RawMessage, ttl uint64) (interface{}, error) {
+	params, err := capCallGas(params, g.callGas)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, g.callTimeout)
+	defer cancel()
+
+	result, err := proxy(ctx, method, params, ttl)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return nil, &Error{Code: CODE_SERVER_ERROR, Message: "execution timeout"}
+	}
+	return result, err
+}
+
+// proxy forwards a call to the node, caching the result for ttl seconds
+// when ttl is set. Errors returned by the node are passed on to the client.
+func proxy(ctx context.Context, method string, params []json.RawMessage, ttl uint64) (interface{}, error) {
+	redisKey := proxyCacheKey(method, params)
+
+	if ttl > 0 {
+		if res, ok := redis.GetCache().Get(redisKey); ok {
+			return json.RawMessage(res), nil
+		}
+	}
+
+	node := ipc.GetIPCContext(ctx)
+	if node == nil {
+		return nil, ErrMissingIPC
+	}
+
+	args := make([]interface{}, len(params))
+	for i, param := range params {
+		args[i] = param
+	}
+
+	var result json.RawMessage
+	if err := node.CallContext(ctx, &result, method, args...); err != nil {
+		if rpcErr, ok := err.(rpc.Error); ok {
+			return nil, &Error{Code: rpcErr.ErrorCode(), Message: rpcErr.Error()}
+		}
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		result = json.RawMessage("null")
+	}
+
+	if ttl > 0 {
+		redis.GetCache().Set(redisKey, result, time.Duration(ttl)*time.Second)
+	}
+
+	return result, nil
+}
+
+// parseBlockNumber parses a block number param, either a hex quantity or
+// one of the earliest, latest and pending tags
+func parseBlockNumber(dbc db.Store, param json.RawMessage) (uint64, error) {
+	var tag string
+	if err := json.Unmarshal(param, &tag); err != nil {
+		return 0, invalidParams("block number must be a string")
+	}
+
+	switch tag {
+	case "earliest":
+		return 0, nil
+	case "latest", "pending":
+		return dbc.GetLatestBlockNumber()
+	}
+
+	number, err := hexutil.DecodeUint64(tag)
+	if err != nil {
+		return 0, invalidParams("invalid block number %q", tag)
+	}
+	return number, nil
+}
+
+func parseHash(param json.RawMessage) (common.Hash, error) {
+	var s string
+	if err := json.Unmarshal(param, &s); err != nil {
+		return common.Hash{}, invalidParams("hash must be a string")
+	}
+
+	b, err := hexutil.Decode(s)
+	if err != nil || len(b) != common.HashLength {
+		return common.Hash{}, invalidParams("invalid hash %q", s)
+	}
+	return common.BytesToHash(b), nil
+}
+
+// parseFullTransactions reads the optional flag that selects full
+// transaction objects instead of hashes
+func parseFullTransactions(params []json.RawMessage) (bool, error) {
+	if len(params) < 2 {
+		return false, nil
+	}
+
+	var full bool
+	if err := json.Unmarshal(params[1], &full); err != nil {
+		return false, invalidParams("full transactions flag must be a boolean")
+	}
+	return full, nil
+}
+
+func blockNumber(ctx context.Context, dbc db.Store, params []json.RawMessage) (interface{}, error) {
+	number, err := dbc.GetLatestBlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Uint64(number), nil
+}
+
+// blockResult encodes a block with its transactions, or null when the
+// block is missing, which the store returns as a zero block
+func blockResult(dbc db.Store, block *models.Block, full bool) (interface{}, error) {
+	if block == nil || block.Hash == (common.Hash{}) {
+		return nil, nil
+	}
+
+	txs, err := dbc.GetTransactionsByBlockNumbers([]uint64{uint64(block.Number)})
+	if err != nil {
+		return nil, err
+	}
+
+	if full {
+		transactions := make([]*rpcTransaction, len(txs))
+		for i, tf := range txs {
+			transactions[i] = newRPCTransaction(tf.Tx)
+		}
+		return newRPCBlock(block, transactions), nil
+	}
+
+	hashes := make([]common.Hash, len(txs))
+	for i, tf := range txs {
+		hashes[i] = tf.Tx.Hash
+	}
+	return newRPCBlock(block, hashes), nil
+}
+
+func getBlockByNumber(ctx context.Context, dbc db.Store, params []json.RawMessage) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, invalidParams("missing block number")
+	}
+
+	number, err := parseBlockNumber(dbc, params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	full, err := parseFullTransactions(params)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := dbc.GetBlockByID(number)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockResult(dbc, block, full)
+}
+
+func getBlockByHash(ctx context.Context, dbc db.Store, params []json.RawMessage) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, invalidParams("missing block hash")
+	}
+
+	hash, err := parseHash(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	full, err := parseFullTransactions(params)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := dbc.GetBlockByHash(hash.Hex())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if block != nil && block.Hash != hash {
+		return nil, nil
+	}
+
+	return blockResult(dbc, block, full)
+}
+
+// lookupTransaction returns the transaction, or nil when the index doesn't have it
+func lookupTransaction(dbc db.Store, params []json.RawMessage) (*models.TransactionFull, error) {
+	if len(params) < 1 {
+		return nil, invalidParams("missing transaction hash")
+	}
+
+	hash, err := parseHash(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	tf, err := dbc.GetTransactionByHash(hash.Hex())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if tf == nil || tf.Tx == nil || tf.Tx.Hash != hash {
+		return nil, nil
+	}
+	return tf, nil
+}
+
+func getTransactionByHash(ctx context.Context, dbc db.Store, params []json.RawMessage) (interface{}, error) {
+	tf, err := lookupTransaction(dbc, params)
+	if tf == nil || err != nil {
+		return nil, err
+	}
+	return newRPCTransaction(tf.Tx), nil
+}
+
+// getTransactionReceipt serves receipts from the index, except for the
+// transactions that may have emitted logs, which are not indexed. Those
+// are contract creations and calls, and are proxied to the node.
+func getTransactionReceipt(ctx context.Context, dbc db.Store, params []json.RawMessage) (interface{}, error) {
+	tf, err := lookupTransaction(dbc, params)
+	if tf == nil || err != nil {
+		return nil, err
+	}
+
+	mayHaveLogs := len(tf.Tx.Input) > 0 || optionalAddress(tf.Txr.ContractAddress) != nil
+	if to := optionalAddress(tf.Tx.To); !mayHaveLogs && to != nil {
+		if mayHaveLogs, err = dbc.GetIsContractAddress(to.Hex()); err != nil {
+			return nil, err
+		}
+	}
+
+	if mayHaveLogs {
+		return proxy(ctx, "eth_getTransactionReceipt", params[:1], receiptCacheSeconds)
+	}
+
+	return newRPCReceipt(tf.Tx, tf.Txr), nil
+}
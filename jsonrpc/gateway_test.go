@@ -0,0 +1,196 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+	"github.com/ebakus/ebakus-block-explorer-backend/redis"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+// testValue is not a multiple of the 1e14 wei the index used to keep
+var testValue, _ = new(big.Int).SetString("1234567890123456789", 10)
+
+// setupGateway indexes a block with a transfer of testValue, and sets up a
+// fake node to check that the index answers without it
+func setupGateway(t *testing.T) (*ipc.FakeNode, *models.Transaction, *models.Block) {
+	t.Helper()
+
+	to := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	tx := &models.Transaction{
+		Hash:        common.HexToHash("0x7a"),
+		BlockHash:   common.HexToHash("0xb1"),
+		BlockNumber: 1,
+		Timestamp:   1583020801,
+		From:        common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		To:          &to,
+		Value:       hexutil.Big(*testValue),
+		GasLimit:    21000,
+	}
+	txr := &models.TransactionReceipt{Status: 1, GasUsed: 21000, CumulativeGasUsed: 21000}
+	blocks := []*models.Block{
+		{Number: 0, TimeStamp: 1583020800, Hash: common.HexToHash("0xb0")},
+		{Number: 1, TimeStamp: 1583020801, Hash: tx.BlockHash, ParentHash: common.HexToHash("0xb0"), Transactions: []common.Hash{tx.Hash}, TransactionCount: 1},
+	}
+
+	node, err := ipc.NewFakeNode(&ipc.FakeFixture{FakeChain: ipc.FakeChain{
+		Blocks:       blocks,
+		Transactions: []ipc.FakeTransaction{{Transaction: tx, Receipt: txr}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipc.SetIPC(node)
+	redis.SetCache(redis.NewMemoryCache(redis.DefaultCacheSize))
+
+	store := db.NewMemoryStore()
+	if err := store.InsertBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertTransactions([]models.TransactionFull{{Tx: tx, Txr: txr}}); err != nil {
+		t.Fatal(err)
+	}
+	db.SetClient(store)
+
+	return node, tx, blocks[1]
+}
+
+// call makes a request to the gateway and decodes its result
+func call(t *testing.T, method, params string, result interface{}) {
+	t.Helper()
+
+	res := NewGateway(false).Handle(context.Background(), Request{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: method, Params: json.RawMessage(params)})
+	if res.Error != nil {
+		t.Fatalf("%s: %v", method, res.Error)
+	}
+
+	data, err := json.Marshal(res.Result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		t.Fatalf("%s: %v: %s", method, err, data)
+	}
+}
+
+func TestGetTransactionByHashValue(t *testing.T) {
+	node, tx, _ := setupGateway(t)
+
+	var result struct {
+		Hash  common.Hash
+		Value *hexutil.Big
+	}
+	call(t, "eth_getTransactionByHash", `["`+tx.Hash.Hex()+`"]`, &result)
+	if result.Hash != tx.Hash || result.Value == nil || result.Value.ToInt().Cmp(testValue) != 0 {
+		t.Errorf("eth_getTransactionByHash = %x with value %v, want the exact value %s", result.Hash, result.Value, testValue)
+	}
+
+	// transactions the index doesn't have are not looked up on the node
+	var missing *struct{}
+	call(t, "eth_getTransactionByHash", `["`+common.HexToHash("0xdead").Hex()+`"]`, &missing)
+	if missing != nil {
+		t.Errorf("eth_getTransactionByHash of a missing transaction = %+v, want null", missing)
+	}
+	if calls := node.Calls("eth_getTransactionByHash"); calls != 0 {
+		t.Errorf("the node got %d calls, want the index to answer", calls)
+	}
+}
+
+func TestGetBlockFullTransactionsValue(t *testing.T) {
+	node, tx, block := setupGateway(t)
+
+	for _, c := range []struct{ method, param string }{
+		{"eth_getBlockByNumber", `"0x1"`},
+		{"eth_getBlockByHash", `"` + block.Hash.Hex() + `"`},
+	} {
+		var result struct {
+			Hash         common.Hash
+			Transactions []struct {
+				Hash  common.Hash
+				Value *hexutil.Big
+			}
+		}
+		call(t, c.method, `[`+c.param+`, true]`, &result)
+
+		if result.Hash != block.Hash || len(result.Transactions) != 1 {
+			t.Fatalf("%s = %+v, want block %x with a transaction", c.method, result, block.Hash)
+		}
+		if got := result.Transactions[0]; got.Hash != tx.Hash || got.Value == nil || got.Value.ToInt().Cmp(testValue) != 0 {
+			t.Errorf("%s transaction = %x with value %v, want the exact value %s", c.method, got.Hash, got.Value, testValue)
+		}
+	}
+
+	var result struct{ Transactions []common.Hash }
+	call(t, "eth_getBlockByNumber", `["0x1", false]`, &result)
+	if len(result.Transactions) != 1 || result.Transactions[0] != tx.Hash {
+		t.Errorf("eth_getBlockByNumber transactions = %x, want %x", result.Transactions, tx.Hash)
+	}
+
+	if calls := node.Calls("eth_getBlockByHash"); calls != 0 {
+		t.Errorf("the node got %d calls, want the index to answer", calls)
+	}
+}
+
+func TestCapCallGas(t *testing.T) {
+	const to = `"to":"0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"`
+
+	for _, c := range []struct {
+		name, call string
+		gas        uint64
+		invalid    bool
+	}{
+		{"missing gas", `{` + to + `}`, 1000, false},
+		{"null gas", `{` + to + `,"gas":null}`, 1000, false},
+		{"lower gas", `{` + to + `,"gas":"0x64"}`, 100, false},
+		{"higher gas", `{` + to + `,"gas":"0xffffffffffff"}`, 1000, false},
+		{"invalid gas", `{` + to + `,"gas":100}`, 0, true},
+		{"not an object", `"0x01"`, 0, true},
+	} {
+		params, err := capCallGas([]json.RawMessage{json.RawMessage(c.call), json.RawMessage(`"latest"`)}, 1000)
+		if c.invalid {
+			if rpcErr, ok := err.(*Error); !ok || rpcErr.Code != CODE_INVALID_PARAMS {
+				t.Errorf("%s: err = %v, want invalid params", c.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		var call struct {
+			To  common.Address
+			Gas hexutil.Uint64
+		}
+		if err := json.Unmarshal(params[0], &call); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if uint64(call.Gas) != c.gas || call.To == (common.Address{}) || len(params) != 2 || string(params[1]) != `"latest"` {
+			t.Errorf("%s: params = %s, want the gas %d", c.name, params, c.gas)
+		}
+	}
+
+	if _, err := capCallGas(nil, 1000); err == nil {
+		t.Error("capCallGas() without a call object succeeded")
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	node, _, _ := setupGateway(t)
+	node.SetTimeout("eth_call", 1, 50*time.Millisecond)
+
+	gateway := NewGateway(false)
+	gateway.SetCallLimits(10*time.Millisecond, 1000)
+
+	res := gateway.Handle(context.Background(), Request{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: "eth_call", Params: json.RawMessage(`[{"to":"0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"}, "latest"]`)})
+	if res.Error == nil || res.Error.Code != CODE_SERVER_ERROR {
+		t.Errorf("eth_call = %+v, want a timeout", res)
+	}
+}
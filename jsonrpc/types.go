@@ -0,0 +1,190 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+const (
+	// standard JSON-RPC 2.0 error codes
+	CODE_PARSE_ERROR      = -32700
+	CODE_INVALID_REQUEST  = -32600
+	CODE_METHOD_NOT_FOUND = -32601
+	CODE_INVALID_PARAMS   = -32602
+	CODE_INTERNAL_ERROR   = -32603
+
+	// CODE_SERVER_ERROR is used for node errors without a code of their own
+	CODE_SERVER_ERROR = -32000
+)
+
+// bloomLength is the size of a receipt's logs bloom, which is all zeros
+// for the receipts served from the index as they have no logs
+const bloomLength = 256
+
+// Request is a JSON-RPC 2.0 request
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// Error is a JSON-RPC 2.0 error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Response is a JSON-RPC 2.0 response, carrying either a result or an error
+type Response struct {
+	ID     json.RawMessage
+	Result interface{}
+	Error  *Error
+}
+
+// MarshalJSON leaves out the result of error responses, as the spec requires
+func (r Response) MarshalJSON() ([]byte, error) {
+	id := r.ID
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			ID      json.RawMessage `json:"id"`
+			Error   *Error          `json:"error"`
+		}{"2.0", id, r.Error})
+	}
+
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  interface{}     `json:"result"`
+	}{"2.0", id, r.Result})
+}
+
+// ErrorResponse builds a response for a request that failed
+func ErrorResponse(id json.RawMessage, code int, message string) Response {
+	return Response{ID: id, Error: &Error{Code: code, Message: message}}
+}
+
+// rpcBlock is a block in the encoding of the node
+type rpcBlock struct {
+	Number           hexutil.Uint64   `json:"number"`
+	Timestamp        hexutil.Uint64   `json:"timestamp"`
+	Hash             common.Hash      `json:"hash"`
+	ParentHash       common.Hash      `json:"parentHash"`
+	Signature        hexutil.Bytes    `json:"signature"`
+	TransactionsRoot common.Hash      `json:"transactionsRoot"`
+	ReceiptsRoot     common.Hash      `json:"receiptsRoot"`
+	Size             hexutil.Uint64   `json:"size"`
+	GasUsed          hexutil.Uint64   `json:"gasUsed"`
+	GasLimit         hexutil.Uint64   `json:"gasLimit"`
+	Transactions     interface{}      `json:"transactions"`
+	Delegates        []common.Address `json:"delegates"`
+	Producer         common.Address   `json:"producer"`
+}
+
+// rpcTransaction is a transaction in the encoding of the node
+type rpcTransaction struct {
+	Hash             common.Hash     `json:"hash"`
+	Nonce            hexutil.Uint64  `json:"nonce"`
+	BlockHash        common.Hash     `json:"blockHash"`
+	BlockNumber      hexutil.Uint64  `json:"blockNumber"`
+	TransactionIndex hexutil.Uint64  `json:"transactionIndex"`
+	From             common.Address  `json:"from"`
+	To               *common.Address `json:"to"`
+	Value            *hexutil.Big    `json:"value"`
+	Gas              hexutil.Uint64  `json:"gas"`
+	GasPrice         hexutil.Uint64  `json:"gasPrice"`
+	WorkNonce        hexutil.Uint64  `json:"workNonce"`
+	Input            hexutil.Bytes   `json:"input"`
+}
+
+// rpcReceipt is a transaction receipt in the encoding of the node
+type rpcReceipt struct {
+	TransactionHash   common.Hash     `json:"transactionHash"`
+	TransactionIndex  hexutil.Uint64  `json:"transactionIndex"`
+	BlockHash         common.Hash     `json:"blockHash"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	From              common.Address  `json:"from"`
+	To                *common.Address `json:"to"`
+	GasUsed           hexutil.Uint64  `json:"gasUsed"`
+	CumulativeGasUsed hexutil.Uint64  `json:"cumulativeGasUsed"`
+	ContractAddress   *common.Address `json:"contractAddress"`
+	Logs              []interface{}   `json:"logs"`
+	LogsBloom         hexutil.Bytes   `json:"logsBloom"`
+	Status            hexutil.Uint64  `json:"status"`
+}
+
+// optionalAddress returns nil for the zero address, which the index stores
+// instead of NULL
+func optionalAddress(address *common.Address) *common.Address {
+	if address == nil || *address == (common.Address{}) {
+		return nil
+	}
+	a := *address
+	return &a
+}
+
+func newRPCBlock(block *models.Block, transactions interface{}) *rpcBlock {
+	return &rpcBlock{
+		Number:           block.Number,
+		Timestamp:        block.TimeStamp,
+		Hash:             block.Hash,
+		ParentHash:       block.ParentHash,
+		Signature:        hexutil.Bytes(block.Signature),
+		TransactionsRoot: block.TransactionsRoot,
+		ReceiptsRoot:     block.ReceiptsRoot,
+		Size:             block.Size,
+		GasUsed:          block.GasUsed,
+		GasLimit:         block.GasLimit,
+		Transactions:     transactions,
+		Delegates:        block.Delegates,
+		Producer:         block.Producer,
+	}
+}
+
+func newRPCTransaction(tx *models.Transaction) *rpcTransaction {
+	value := tx.Value
+	return &rpcTransaction{
+		Hash:             tx.Hash,
+		Nonce:            tx.Nonce,
+		BlockHash:        tx.BlockHash,
+		BlockNumber:      tx.BlockNumber,
+		TransactionIndex: tx.TransactionIndex,
+		From:             tx.From,
+		To:               optionalAddress(tx.To),
+		Value:            &value,
+		Gas:              tx.GasLimit,
+		GasPrice:         tx.GasPrice,
+		WorkNonce:        tx.WorkNonce,
+		Input:            hexutil.Bytes(tx.Input),
+	}
+}
+
+func newRPCReceipt(tx *models.Transaction, txr *models.TransactionReceipt) *rpcReceipt {
+	return &rpcReceipt{
+		TransactionHash:   tx.Hash,
+		TransactionIndex:  tx.TransactionIndex,
+		BlockHash:         tx.BlockHash,
+		BlockNumber:       tx.BlockNumber,
+		From:              tx.From,
+		To:                optionalAddress(tx.To),
+		GasUsed:           txr.GasUsed,
+		CumulativeGasUsed: txr.CumulativeGasUsed,
+		ContractAddress:   optionalAddress(txr.ContractAddress),
+		Logs:              []interface{}{},
+		LogsBloom:         make(hexutil.Bytes, bloomLength),
+		Status:            txr.Status,
+	}
+}
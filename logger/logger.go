@@ -0,0 +1,211 @@
+// Package logger is the structured logger shared by the explorer and the
+// crawler. Lines have a level, a message and key value pairs, and are
+// written as logfmt or JSON. Loggers carried in a context add the ID of the
+// request they log for.
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	stdlog "log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebakus/go-ebakus/log"
+	"github.com/urfave/cli"
+)
+
+const (
+	LOG_FORMAT_LOGFMT = "logfmt"
+	LOG_FORMAT_JSON   = "json"
+)
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Logger logs messages with the key value pairs it was created with
+type Logger = log.Logger
+
+func init() {
+	setHandler(log.LvlInfo, logfmtFormat())
+}
+
+// Init sets the level below which lines are dropped, one of trace, debug,
+// info, warn, error and crit, and the format of the lines
+func Init(level, format string) error {
+	lvl, err := log.LvlFromString(level)
+	if err != nil {
+		return fmt.Errorf("unknown log level %q, expected trace, debug, info, warn, error or crit", level)
+	}
+
+	switch format {
+	case LOG_FORMAT_LOGFMT, "":
+		setHandler(lvl, logfmtFormat())
+	case LOG_FORMAT_JSON:
+		setHandler(lvl, jsonFormat())
+	default:
+		return fmt.Errorf("unknown log format %q, expected logfmt or json", format)
+	}
+	return nil
+}
+
+// InitFromCli sets the level and the format from the loglevel and
+// logformat flags
+func InitFromCli(c *cli.Context) error {
+	return Init(c.String("loglevel"), c.String("logformat"))
+}
+
+func setHandler(lvl log.Lvl, format log.Format) {
+	log.Root().SetHandler(log.LvlFilterHandler(lvl, log.StreamHandler(os.Stderr, format)))
+
+	// libraries logging on the standard logger are logged at info
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(stdWriter{})
+}
+
+type stdWriter struct{}
+
+func (stdWriter) Write(p []byte) (int, error) {
+	log.Info(strings.TrimSpace(string(p)))
+	return len(p), nil
+}
+
+// New returns a logger that adds the key value pairs to its lines
+func New(ctx ...interface{}) Logger {
+	return log.New(ctx...)
+}
+
+func Trace(msg string, ctx ...interface{}) { log.Root().Trace(msg, ctx...) }
+func Debug(msg string, ctx ...interface{}) { log.Root().Debug(msg, ctx...) }
+func Info(msg string, ctx ...interface{})  { log.Root().Info(msg, ctx...) }
+func Warn(msg string, ctx ...interface{})  { log.Root().Warn(msg, ctx...) }
+func Error(msg string, ctx ...interface{}) { log.Root().Error(msg, ctx...) }
+
+// Crit logs and exits
+func Crit(msg string, ctx ...interface{}) { log.Root().Crit(msg, ctx...) }
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx carrying l
+func WithContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the root logger
+func FromContext(ctx context.Context) Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(Logger); ok {
+			return l
+		}
+	}
+	return log.Root()
+}
+
+// levelName spells out the levels, which log abbreviates to 4 letters
+func levelName(lvl log.Lvl) string {
+	switch lvl {
+	case log.LvlTrace:
+		return "trace"
+	case log.LvlDebug:
+		return "debug"
+	case log.LvlInfo:
+		return "info"
+	case log.LvlWarn:
+		return "warn"
+	case log.LvlError:
+		return "error"
+	case log.LvlCrit:
+		return "crit"
+	}
+	return "unknown"
+}
+
+// formatValue turns values into what is written for them, strings or
+// numbers and booleans
+func formatValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.Format(timeFormat)
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	case string, bool,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return v
+	}
+	return fmt.Sprintf("%+v", value)
+}
+
+// pairs calls fn with the key value pairs of a record, starting with its
+// time, level and message
+func pairs(r *log.Record, fn func(key string, value interface{})) {
+	fn("t", r.Time.Format(timeFormat))
+	fn("lvl", levelName(r.Lvl))
+	fn("msg", r.Msg)
+
+	for i := 0; i < len(r.Ctx); i += 2 {
+		key, ok := r.Ctx[i].(string)
+		if !ok {
+			key = fmt.Sprint(r.Ctx[i])
+		}
+
+		var value interface{} = "MISSING"
+		if i+1 < len(r.Ctx) {
+			value = formatValue(r.Ctx[i+1])
+		}
+		fn(key, value)
+	}
+}
+
+func logfmtFormat() log.Format {
+	return log.FormatFunc(func(r *log.Record) []byte {
+		var buf bytes.Buffer
+		pairs(r, func(key string, value interface{}) {
+			if buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(key)
+			buf.WriteByte('=')
+
+			s := fmt.Sprint(value)
+			if value == nil {
+				s = "nil"
+			} else if s == "" || strings.ContainsAny(s, " =\"\\") || strconv.Quote(s) != `"`+s+`"` {
+				s = strconv.Quote(s)
+			}
+			buf.WriteString(s)
+		})
+		buf.WriteByte('\n')
+		return buf.Bytes()
+	})
+}
+
+func jsonFormat() log.Format {
+	return log.FormatFunc(func(r *log.Record) []byte {
+		props := make(map[string]interface{}, 3+len(r.Ctx)/2)
+		pairs(r, func(key string, value interface{}) {
+			props[key] = value
+		})
+
+		out, err := json.Marshal(props)
+		if err != nil {
+			out, _ = json.Marshal(map[string]string{
+				"t":   r.Time.Format(timeFormat),
+				"lvl": levelName(r.Lvl),
+				"msg": r.Msg,
+				"err": "failed to encode the log line: " + err.Error(),
+			})
+		}
+		return append(out, '\n')
+	})
+}
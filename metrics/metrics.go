@@ -0,0 +1,29 @@
+// Package metrics exposes the metrics registered with the default
+// Prometheus registry, over HTTP or in a file, and holds the buckets the
+// histograms of the explorer and the crawler share.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultBuckets are the histogram buckets for latencies, in seconds
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// JobBuckets are the histogram buckets for the durations of jobs, in seconds
+var JobBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+// WriteFile writes the metrics to a file, replacing it at once so that it
+// is never read half written, e.g. by the textfile collector of the node
+// exporter
+func WriteFile(path string) error {
+	return prometheus.WriteToTextfile(path, prometheus.DefaultGatherer)
+}
+
+// Handler serves the metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
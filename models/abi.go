@@ -0,0 +1,67 @@
+package models
+
+import (
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+const (
+	// where the ABI used to decode a call or a log came from
+	ABI_SOURCE_NODE       = "node"
+	ABI_SOURCE_REGISTRY   = "registry"
+	ABI_SOURCE_SIGNATURES = "signatures"
+)
+
+// Log is an event emitted by a transaction, as returned in the receipts of the node
+type Log struct {
+	Address  common.Address `json:"address"`
+	Topics   []common.Hash  `json:"topics"`
+	Data     hexutil.Bytes  `json:"data"`
+	LogIndex hexutil.Uint64 `json:"logIndex"`
+}
+
+// DecodedParam is an argument of a decoded call or log. Values are JSON
+// friendly: numbers are decimal strings and bytes are hex encoded.
+type DecodedParam struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Indexed bool        `json:"indexed,omitempty"`
+	Value   interface{} `json:"value"`
+}
+
+// DecodedInput is the method call encoded in the input of a transaction
+type DecodedInput struct {
+	Method    string         `json:"method"`
+	Signature string         `json:"signature"`
+	Selector  string         `json:"selector"`
+	Source    string         `json:"source"`
+	Params    []DecodedParam `json:"params"`
+}
+
+// DecodedLog is an event emitted by a transaction
+type DecodedLog struct {
+	LogIndex  uint64         `json:"logIndex"`
+	Address   common.Address `json:"address"`
+	Event     string         `json:"event"`
+	Signature string         `json:"signature"`
+	Source    string         `json:"source"`
+	Params    []DecodedParam `json:"params"`
+}
+
+// CallResult is the output of a contract method run with eth_call
+type CallResult struct {
+	Method    string         `json:"method"`
+	Signature string         `json:"signature"`
+	Source    string         `json:"source"`
+	Output    string         `json:"output"`
+	Outputs   []DecodedParam `json:"outputs"`
+}
+
+// StorageValue is a storage slot of a contract, with its raw value and the
+// value decoded as the requested type
+type StorageValue struct {
+	Slot  string      `json:"slot"`
+	Raw   string      `json:"raw"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// APIKey identifies a client of the API. Only the hash of the key is
+// stored, the key itself is shown once when it is issued.
+type APIKey struct {
+	ID     uint64 `json:"id"`
+	Name   string `json:"name"`
+	Hash   string `json:"-"`
+	Prefix string `json:"prefix"`
+
+	// RateLimit is the requests per minute, 0 for the default of keys
+	RateLimit uint64 `json:"rateLimit"`
+
+	// DailyQuota is the requests per day, 0 for unlimited
+	DailyQuota uint64 `json:"dailyQuota"`
+
+	CreatedAt uint64 `json:"createdAt"`
+	RevokedAt uint64 `json:"revokedAt,omitempty"`
+}
+
+// Revoked reports whether the key may no longer be used
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != 0
+}
+
+// APIKeyUsage is the number of requests made with a key on a day
+type APIKeyUsage struct {
+	Day      string `json:"day"`
+	Requests uint64 `json:"requests"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// ExportFilter narrows down an export. FromTime is inclusive and ToTime
+// exclusive, both are unix timestamps and zero means unbounded.
+// FromBlock and ToBlock are inclusive block numbers that only apply to
+// transaction exports, again with zero meaning unbounded. Limit caps the
+// rows of transaction exports, zero for no cap.
+type ExportFilter struct {
+	FromTime   uint64
+	ToTime     uint64
+	FromBlock  uint64
+	ToBlock    uint64
+	Limit      uint64
+	Descending bool
+}
@@ -104,6 +104,10 @@ type TransactionReceipt struct {
 type TransactionFull struct {
 	Tx  *Transaction
 	Txr *TransactionReceipt
+
+	// set only when decoding is requested
+	DecodedInput *DecodedInput
+	DecodedLogs  []DecodedLog
 }
 
 type AddressType int
@@ -206,6 +210,8 @@ func (tf TransactionFull) MarshalJSON() ([]byte, error) {
 		ContractAddress    *common.Address `json:"contractAddress"`
 		ContractAddressEns *string         `json:"contractAddressEns"`
 		Input              string          `json:"input"`
+		DecodedInput       *DecodedInput   `json:"decodedInput,omitempty"`
+		DecodedLogs        []DecodedLog    `json:"decodedLogs,omitempty"`
 	}
 
 	t := tf.Tx
@@ -231,6 +237,8 @@ func (tf TransactionFull) MarshalJSON() ([]byte, error) {
 	enc.ContractAddress = r.ContractAddress
 	enc.ContractAddressEns = r.ContractAddressEns
 	enc.Input = "0x" + hex.EncodeToString(t.Input)
+	enc.DecodedInput = tf.DecodedInput
+	enc.DecodedLogs = tf.DecodedLogs
 
 	return json.Marshal(&enc)
 }
@@ -286,3 +294,40 @@ type Producer struct {
 	ProducedBlocksCount uint64         `json:"produced_blocks_count"`
 	BlockRewards        *big.Int       `json:"block_rewards"`
 }
+
+const (
+	SEARCH_QUERY_BLOCK_NUMBER   = "block_number"
+	SEARCH_QUERY_HASH           = "hash"
+	SEARCH_QUERY_ADDRESS        = "address"
+	SEARCH_QUERY_ADDRESS_PREFIX = "address_prefix"
+	SEARCH_QUERY_NAME           = "name"
+)
+
+const (
+	SEARCH_RESULT_BLOCK       = "block"
+	SEARCH_RESULT_TRANSACTION = "transaction"
+	SEARCH_RESULT_ADDRESS     = "address"
+	SEARCH_RESULT_ENS         = "ens"
+)
+
+const (
+	SEARCH_MATCH_EXACT  = "exact"
+	SEARCH_MATCH_PREFIX = "prefix"
+)
+
+type SearchResult struct {
+	Type  string      `json:"type"`
+	Match string      `json:"match"`
+	Data  interface{} `json:"data"`
+}
+
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Kind    string         `json:"kind"`
+	Results []SearchResult `json:"results"`
+}
+
+type AddressSearchResult struct {
+	Address    common.Address `json:"address"`
+	AddressEns *string        `json:"addressEns"`
+}
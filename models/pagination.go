@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+var (
+	// ErrInvalidCursor is returned when a cursor can't be decoded
+	ErrInvalidCursor = errors.New("Invalid cursor")
+)
+
+// Cursor is a position in a list used for keyset pagination. Clients only
+// see it encoded, so its fields can change without breaking them.
+//
+// Transaction lists are keyed on (Number: block number, Index: transaction index),
+// block lists on Number and the rich list on (Number: amount, Address).
+type Cursor struct {
+	// Prev is set when the page before the position is requested
+	Prev    bool            `json:"p,omitempty"`
+	Number  uint64          `json:"n"`
+	Index   uint64          `json:"i,omitempty"`
+	Address *common.Address `json:"a,omitempty"`
+}
+
+// Encode returns the opaque representation of the cursor
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// Page is the response envelope of the list endpoints. Next and Prev
+// are the cursors for the adjacent pages, or nil when there are none.
+type Page struct {
+	Data interface{} `json:"data"`
+	Next *string     `json:"next"`
+	Prev *string     `json:"prev"`
+}
+
+// NewPage builds the envelope for a page of `count` items that was fetched
+// with one extra item (count+1) to find out if there are more.
+// first and last are the cursors of the first and last item shown.
+func NewPage(data interface{}, requested *Cursor, hasMore bool, first, last *Cursor) Page {
+	page := Page{Data: data}
+
+	if first == nil || last == nil {
+		return page
+	}
+
+	first.Prev = true
+	last.Prev = false
+	prev, next := first.Encode(), last.Encode()
+
+	switch {
+	case requested == nil:
+		if hasMore {
+			page.Next = &next
+		}
+	case requested.Prev:
+		page.Next = &next
+		if hasMore {
+			page.Prev = &prev
+		}
+	default:
+		page.Prev = &prev
+		if hasMore {
+			page.Next = &next
+		}
+	}
+
+	return page
+}
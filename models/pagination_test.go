@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+func TestCursorEncoding(t *testing.T) {
+	address := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+
+	for _, c := range []Cursor{
+		{Number: 0},
+		{Number: 12, Index: 3},
+		{Prev: true, Number: 1 << 63, Index: 1},
+		{Number: 1000, Address: &address},
+	} {
+		decoded, err := DecodeCursor(c.Encode())
+		if err != nil {
+			t.Fatalf("DecodeCursor(%+v) failed: %v", c, err)
+		}
+		if decoded.Prev != c.Prev || decoded.Number != c.Number || decoded.Index != c.Index ||
+			(decoded.Address == nil) != (c.Address == nil) || c.Address != nil && *decoded.Address != *c.Address {
+			t.Errorf("DecodeCursor(%s) = %+v, want %+v", c.Encode(), decoded, c)
+		}
+	}
+}
+
+func TestDecodeTamperedCursor(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	for _, s := range []string{
+		"!",
+		Cursor{Number: 1}.Encode() + "=",
+		encode(`{"n":1`),
+		encode(`{"n":"1"}`),
+		encode(`{"n":-1}`),
+		encode(`{"n":1,"a":"0xzz"}`),
+		encode(`[1,2]`),
+	} {
+		if c, err := DecodeCursor(s); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) = %+v, %v, want ErrInvalidCursor", s, c, err)
+		}
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	prev := Cursor{Prev: true, Number: 5}.Encode()
+	next := Cursor{Number: 3}.Encode()
+
+	for _, c := range []struct {
+		name       string
+		requested  *Cursor
+		hasMore    bool
+		empty      bool
+		prev, next *string
+	}{
+		{"empty first page", nil, false, true, nil, nil},
+		{"empty page after a cursor", &Cursor{Number: 100}, false, true, nil, nil},
+		{"single page", nil, false, false, nil, nil},
+		{"first of many", nil, true, false, nil, &next},
+		{"middle going forward", &Cursor{Number: 6}, true, false, &prev, &next},
+		{"last going forward", &Cursor{Number: 6}, false, false, &prev, nil},
+		{"middle going back", &Cursor{Prev: true, Number: 2}, true, false, &prev, &next},
+		{"first going back", &Cursor{Prev: true, Number: 2}, false, false, nil, &next},
+	} {
+		var first, last *Cursor
+		if !c.empty {
+			first, last = &Cursor{Number: 5}, &Cursor{Prev: true, Number: 3}
+		}
+
+		page := NewPage([]int{}, c.requested, c.hasMore, first, last)
+		if !equalCursor(page.Prev, c.prev) || !equalCursor(page.Next, c.next) {
+			t.Errorf("%s: prev %v, next %v, want %v, %v", c.name, cursorString(page.Prev), cursorString(page.Next), cursorString(c.prev), cursorString(c.next))
+		}
+	}
+}
+
+func equalCursor(a, b *string) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func cursorString(s *string) string {
+	if s == nil {
+		return "nil"
+	}
+	return *s
+}
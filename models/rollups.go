@@ -0,0 +1,51 @@
+package models
+
+const (
+	ROLLUP_INTERVAL_HOUR = "hour"
+	ROLLUP_INTERVAL_DAY  = "day"
+)
+
+// RollupIntervals maps the rollup intervals to their length in seconds
+var RollupIntervals = map[string]uint64{
+	ROLLUP_INTERVAL_HOUR: 60 * 60,
+	ROLLUP_INTERVAL_DAY:  24 * 60 * 60,
+}
+
+// Rollup holds the network activity of an hour or a day, starting at Bucket.
+// ActiveAddresses are the distinct senders and recipients of the period and
+// NewAddresses the ones that had no transaction before it.
+type Rollup struct {
+	Interval            string `json:"interval"`
+	Bucket              uint64 `json:"bucket"`
+	BlockCount          uint64 `json:"blockCount"`
+	TxCount             uint64 `json:"txCount"`
+	ActiveAddresses     uint64 `json:"activeAddresses"`
+	NewAddresses        uint64 `json:"newAddresses"`
+	GasUsed             uint64 `json:"gasUsed"`
+	TotalBlockSize      uint64 `json:"totalBlockSize"`
+	ContractDeployments uint64 `json:"contractDeployments"`
+	FailedTxs           uint64 `json:"failedTxs"`
+}
+
+// AvgBlockSize returns the average size of the blocks of the period in bytes
+func (r Rollup) AvgBlockSize() uint64 {
+	if r.BlockCount == 0 {
+		return 0
+	}
+	return r.TotalBlockSize / r.BlockCount
+}
+
+// RollupBuckets returns the start of every bucket of interval that
+// overlaps the time range [from, to]
+func RollupBuckets(interval string, from, to uint64) []uint64 {
+	length, ok := RollupIntervals[interval]
+	if !ok || from > to {
+		return nil
+	}
+
+	buckets := make([]uint64, 0)
+	for bucket := from - from%length; bucket <= to; bucket += length {
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
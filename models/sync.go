@@ -0,0 +1,23 @@
+package models
+
+// Globals the crawler jobs keep their progress in
+const (
+	// GLOBAL_RICH_LIST_LAST_BLOCK is the block the rich list was computed up to
+	GLOBAL_RICH_LIST_LAST_BLOCK = "rich_list_last_block"
+	// GLOBAL_ENS_LAST_SYNC is the unix time the ENS names were last synced at
+	GLOBAL_ENS_LAST_SYNC = "ens_last_sync"
+)
+
+// SyncStatus tells how far the index is behind the node. The node's head is
+// missing when the node can't be reached. Problems lists why the index is
+// considered out of sync, if it is.
+type SyncStatus struct {
+	NodeHead      *uint64  `json:"nodeHead"`
+	IndexedHead   uint64   `json:"indexedHead"`
+	LagBlocks     uint64   `json:"lagBlocks"`
+	LagSeconds    uint64   `json:"lagSeconds"`
+	RichListBlock uint64   `json:"richListBlock"`
+	EnsLastSync   uint64   `json:"ensLastSync"`
+	Synced        bool     `json:"synced"`
+	Problems      []string `json:"problems,omitempty"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+const (
+	// WEBHOOK_EVENT_TRANSFER fires when the address sends or receives EBK
+	WEBHOOK_EVENT_TRANSFER = "transfer"
+	// WEBHOOK_EVENT_MISSED_SLOT fires when the address, as a delegate, misses its slot
+	WEBHOOK_EVENT_MISSED_SLOT = "missed_slot"
+)
+
+const (
+	WEBHOOK_DIRECTION_IN  = "in"
+	WEBHOOK_DIRECTION_OUT = "out"
+	WEBHOOK_DIRECTION_ANY = "any"
+)
+
+const (
+	WEBHOOK_DELIVERY_PENDING   = "pending"
+	WEBHOOK_DELIVERY_DELIVERED = "delivered"
+	WEBHOOK_DELIVERY_FAILED    = "failed"
+)
+
+// Webhook is a subscription to events of an address. Direction and MinValue
+// only apply to transfers.
+type Webhook struct {
+	ID        uint64         `json:"id"`
+	URL       string         `json:"url"`
+	Secret    string         `json:"-"`
+	EventType string         `json:"eventType"`
+	Address   common.Address `json:"address"`
+	Direction string         `json:"direction"`
+	MinValue  *big.Int       `json:"minValue"`
+	CreatedAt uint64         `json:"createdAt"`
+
+	// APIKeyID is the key the webhook was created with, 0 when unknown
+	APIKeyID uint64 `json:"-"`
+}
+
+// MarshalJSON encodes MinValue as a decimal string, as wei amounts don't
+// fit the numbers of most JSON parsers
+func (w Webhook) MarshalJSON() ([]byte, error) {
+	type JSONWebhook Webhook
+	minValue := "0"
+	if w.MinValue != nil {
+		minValue = w.MinValue.String()
+	}
+	return json.Marshal(struct {
+		JSONWebhook
+		MinValue string `json:"minValue"`
+	}{JSONWebhook(w), minValue})
+}
+
+// WebhookDelivery is an entry of the delivery log, NextAttemptAt is only
+// meaningful while the delivery is pending
+type WebhookDelivery struct {
+	ID            uint64          `json:"id"`
+	WebhookID     uint64          `json:"webhookId"`
+	EventType     string          `json:"eventType"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      uint64          `json:"attempts"`
+	StatusCode    uint64          `json:"statusCode"`
+	Error         string          `json:"error"`
+	NextAttemptAt uint64          `json:"nextAttemptAt"`
+	CreatedAt     uint64          `json:"createdAt"`
+	UpdatedAt     uint64          `json:"updatedAt"`
+}
+
+// MissedSlot is the payload of WEBHOOK_EVENT_MISSED_SLOT. Producer is
+// the delegate that produced the block instead, if any.
+type MissedSlot struct {
+	Delegate    common.Address  `json:"delegate"`
+	Timestamp   uint64          `json:"timestamp"`
+	BlockNumber uint64          `json:"blockNumber"`
+	Producer    *common.Address `json:"producer"`
+}
@@ -0,0 +1,207 @@
+package openapi
+
+// DocsPage renders the document served at /openapi.json, grouped by tag,
+// without loading anything from other hosts
+const DocsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Ebakus block explorer API</title>
+<style>
+body { font: 14px/1.5 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; margin: 0; }
+header { background: #1b2330; color: #fff; padding: 16px 32px; }
+header h1 { margin: 0; font-size: 20px; }
+header a { color: #9cc3ff; }
+main { max-width: 1100px; margin: 0 auto; padding: 16px 32px 64px; }
+h2 { border-bottom: 1px solid #ddd; padding-bottom: 4px; margin-top: 32px; }
+details.op { border: 1px solid #ddd; border-radius: 4px; margin: 8px 0; }
+details.op > summary { cursor: pointer; padding: 8px 12px; list-style: none; }
+details.op > summary::-webkit-details-marker { display: none; }
+details.op[open] > summary { border-bottom: 1px solid #ddd; }
+.op-body { padding: 8px 12px; }
+.method { display: inline-block; min-width: 60px; text-align: center; font-weight: bold; color: #fff; border-radius: 3px; padding: 0 6px; margin-right: 8px; font-size: 12px; }
+.get { background: #2f80ed; } .post { background: #27ae60; } .delete { background: #eb5757; }
+.path { font-family: Menlo, Consolas, monospace; font-weight: bold; }
+.summary { color: #555; margin-left: 12px; }
+table { border-collapse: collapse; width: 100%; margin: 4px 0 12px; }
+th, td { text-align: left; border-bottom: 1px solid #eee; padding: 4px 8px; vertical-align: top; }
+code, pre { font-family: Menlo, Consolas, monospace; font-size: 12px; }
+pre { background: #f6f8fa; padding: 8px; overflow-x: auto; margin: 4px 0 12px; }
+.status { font-weight: bold; }
+.muted { color: #888; }
+#error { color: #eb5757; }
+</style>
+</head>
+<body>
+<header>
+<h1 id="title">Ebakus block explorer API</h1>
+<div id="description"></div>
+<div><a href="openapi.json">openapi.json</a></div>
+</header>
+<main id="main"><p class="muted">Loading...</p></main>
+<script>
+(function () {
+  var spec;
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (key) { node.setAttribute(key, attrs[key]); });
+    (children || []).forEach(function (child) {
+      node.appendChild(typeof child === "string" ? document.createTextNode(child) : child);
+    });
+    return node;
+  }
+
+  function resolve(object) {
+    while (object && object.$ref) {
+      object = object.$ref.replace(/^#\//, "").split("/").reduce(function (target, part) { return target[part]; }, spec);
+    }
+    return object;
+  }
+
+  function refName(object) {
+    return object && object.$ref ? object.$ref.split("/").pop() : null;
+  }
+
+  // describe renders a schema as a JSON like outline, with the schemas
+  // referenced more than once by name
+  function describe(schema, indent, seen) {
+    var pad = new Array(indent + 1).join("  ");
+    var name = refName(schema);
+    if (name && seen.indexOf(name) >= 0) {
+      return name;
+    }
+    if (name) {
+      seen = seen.concat([name]);
+    }
+
+    var s = resolve(schema) || {};
+    var suffix = s.nullable ? " | null" : "";
+
+    if (s.allOf) {
+      return s.allOf.map(function (sub) { return describe(sub, indent, seen); }).join(" & ") + suffix;
+    }
+    if (s.oneOf) {
+      return "one of (" + s.oneOf.map(function (sub) { return describe(sub, indent, seen); }).join(" | ") + ")" + suffix;
+    }
+    if (s.type === "array") {
+      return "[" + describe(s.items, indent, seen) + "]" + suffix;
+    }
+    if (s.type === "object" && s.properties) {
+      var required = s.required || [];
+      var lines = Object.keys(s.properties).map(function (key) {
+        var optional = required.indexOf(key) < 0 ? "?" : "";
+        return pad + "  " + key + optional + ": " + describe(s.properties[key], indent + 1, seen);
+      });
+      return "{\n" + lines.join(",\n") + "\n" + pad + "}" + suffix;
+    }
+    if (s.type === "object") {
+      return "object" + suffix;
+    }
+    if (s.enum) {
+      return s.enum.map(function (value) { return JSON.stringify(value); }).join(" | ") + suffix;
+    }
+    if (s.type) {
+      return (name || s.type) + suffix;
+    }
+    return "any";
+  }
+
+  function content(object) {
+    var nodes = [];
+    Object.keys(object.content || {}).forEach(function (mediaType) {
+      var schema = object.content[mediaType].schema;
+      nodes.push(el("div", {}, [el("code", {}, [mediaType])]));
+      if (schema && !(resolve(schema).type === "string" && mediaType.indexOf("json") < 0)) {
+        nodes.push(el("pre", {}, [describe(schema, 0, [])]));
+      }
+    });
+    return nodes;
+  }
+
+  function operation(method, path, op) {
+    var body = el("div", { "class": "op-body" });
+    if (op.description) {
+      body.appendChild(el("p", {}, [op.description]));
+    }
+
+    var params = (op.parameters || []).map(resolve);
+    if (params.length) {
+      var rows = params.map(function (p) {
+        return el("tr", {}, [
+          el("td", {}, [el("code", {}, [p.name]), p.required ? "" : el("span", { "class": "muted" }, [" optional"])]),
+          el("td", {}, [p["in"]]),
+          el("td", {}, [el("code", {}, [describe(p.schema, 0, [])])]),
+          el("td", {}, [p.description || ""])
+        ]);
+      });
+      body.appendChild(el("h4", {}, ["Parameters"]));
+      body.appendChild(el("table", {}, rows));
+    }
+
+    if (op.requestBody) {
+      body.appendChild(el("h4", {}, ["Request body"]));
+      content(resolve(op.requestBody)).forEach(function (node) { body.appendChild(node); });
+    }
+
+    body.appendChild(el("h4", {}, ["Responses"]));
+    Object.keys(op.responses).forEach(function (status) {
+      var response = resolve(op.responses[status]);
+      body.appendChild(el("div", {}, [el("span", { "class": "status" }, [status]), " " + response.description]));
+      content(response).forEach(function (node) { body.appendChild(node); });
+    });
+
+    return el("details", { "class": "op", id: method + "-" + path }, [
+      el("summary", {}, [
+        el("span", { "class": "method " + method }, [method.toUpperCase()]),
+        el("span", { "class": "path" }, [path]),
+        el("span", { "class": "summary" }, [op.summary || ""])
+      ]),
+      body
+    ]);
+  }
+
+  function render() {
+    document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+    document.getElementById("description").textContent = spec.info.description || "";
+
+    var tags = {};
+    var order = [];
+    Object.keys(spec.paths).forEach(function (path) {
+      Object.keys(spec.paths[path]).forEach(function (method) {
+        var op = spec.paths[path][method];
+        var tag = (op.tags || ["Other"])[0];
+        if (!tags[tag]) {
+          tags[tag] = [];
+          order.push(tag);
+        }
+        tags[tag].push(operation(method, path, op));
+      });
+    });
+
+    var main = document.getElementById("main");
+    main.innerHTML = "";
+    order.forEach(function (tag) {
+      main.appendChild(el("h2", {}, [tag]));
+      tags[tag].forEach(function (node) { main.appendChild(node); });
+    });
+  }
+
+  fetch("openapi.json").then(function (res) {
+    if (!res.ok) {
+      throw new Error("openapi.json: " + res.status);
+    }
+    return res.json();
+  }).then(function (json) {
+    spec = json;
+    render();
+  }).catch(function (err) {
+    document.getElementById("main").innerHTML = "";
+    document.getElementById("main").appendChild(el("p", { id: "error" }, [String(err)]));
+  });
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,3924 @@
+// Package openapi holds the OpenAPI document of the explorer API, with a
+// validator that checks responses against it and a page that renders it.
+//
+// The document is written by hand, so that it describes the responses as
+// the clients see them rather than as the models are declared. Update it
+// along with the handlers, TestOpenAPI of the api package fails when they
+// drift.
+package openapi
+
+// Spec is the OpenAPI 3 document of the explorer, served at /openapi.json
+const Spec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "Ebakus block explorer API",
+    "version": "1.0.0",
+    "description": "The REST API of the Ebakus block explorer. Requests are rate limited per IP, or per API key when one is given in the X-API-Key header, and X-RateLimit-Limit and X-RateLimit-Remaining tell how many requests are left. Read routes are cached, with X-Cache set to HIT, STALE or MISS. Every response carries an X-Request-ID. Errors are returned as plain text. The apikey query parameter is deprecated and only kept for Etherscan clients: it is still accepted, but the header takes precedence over it."
+  },
+  "servers": [
+    {
+      "url": "/"
+    }
+  ],
+  "security": [
+    {},
+    {
+      "apiKeyHeader": []
+    },
+    {
+      "apiKeyQuery": []
+    }
+  ],
+  "paths": {
+    "/health": {
+      "get": {
+        "tags": [
+          "Health"
+        ],
+        "summary": "Liveness",
+        "operationId": "getHealth",
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "The explorer runs",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Health"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/ready": {
+      "get": {
+        "tags": [
+          "Health"
+        ],
+        "summary": "Readiness",
+        "operationId": "getReady",
+        "description": "Checks that the database, the node and Redis can be reached and that the index is in sync.",
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "Ready",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Readiness"
+                }
+              }
+            }
+          },
+          "503": {
+            "description": "Not ready, with the reason of each failed check",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Readiness"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/sync-status": {
+      "get": {
+        "tags": [
+          "Health"
+        ],
+        "summary": "Sync status of the index",
+        "operationId": "getSyncStatus",
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "How far the index is behind the node",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/SyncStatus"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metrics": {
+      "get": {
+        "tags": [
+          "Health"
+        ],
+        "summary": "Prometheus metrics",
+        "operationId": "getMetrics",
+        "description": "Protected with Authorization: Bearer <token> when metricstoken is set.",
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "Metrics in the Prometheus text format",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          }
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "tags": [
+          "Docs"
+        ],
+        "summary": "This document",
+        "operationId": "getOpenAPI",
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "The OpenAPI document",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "additionalProperties": true
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/docs": {
+      "get": {
+        "tags": [
+          "Docs"
+        ],
+        "summary": "API documentation page",
+        "operationId": "getDocs",
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "A page rendering this document",
+            "content": {
+              "text/html": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/block/{param}": {
+      "get": {
+        "tags": [
+          "Blocks"
+        ],
+        "summary": "Block by number or hash, or a range of blocks",
+        "operationId": "getBlock",
+        "parameters": [
+          {
+            "name": "param",
+            "in": "path",
+            "required": true,
+            "description": "A block number or hash. With range, the newest block of the range, -1 for the latest",
+            "schema": {
+              "type": "string"
+            },
+            "example": "1"
+          },
+          {
+            "name": "range",
+            "in": "query",
+            "description": "Return a page of up to range blocks (100 at most) going down from the block",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          },
+          {
+            "$ref": "#/components/parameters/cursor"
+          },
+          {
+            "$ref": "#/components/parameters/format"
+          },
+          {
+            "$ref": "#/components/parameters/from"
+          },
+          {
+            "$ref": "#/components/parameters/to"
+          },
+          {
+            "$ref": "#/components/parameters/order"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The block, or with range a page of blocks or an export",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "oneOf": [
+                    {
+                      "$ref": "#/components/schemas/Block"
+                    },
+                    {
+                      "$ref": "#/components/schemas/BlockPage"
+                    }
+                  ]
+                }
+              },
+              "text/csv": {
+                "schema": {
+                  "type": "string"
+                }
+              },
+              "application/x-ndjson": {
+                "schema": {
+                  "$ref": "#/components/schemas/BlockExportRow"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/transaction/latest": {
+      "get": {
+        "tags": [
+          "Transactions"
+        ],
+        "summary": "Latest transactions",
+        "operationId": "getLatestTransactions",
+        "parameters": [
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Page size, 20 by default",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          },
+          {
+            "$ref": "#/components/parameters/cursor"
+          },
+          {
+            "$ref": "#/components/parameters/order"
+          },
+          {
+            "$ref": "#/components/parameters/decode"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "A page of transactions",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TransactionPage"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/transaction/{hash}": {
+      "get": {
+        "tags": [
+          "Transactions"
+        ],
+        "summary": "Transaction by hash",
+        "operationId": "getTransaction",
+        "parameters": [
+          {
+            "name": "hash",
+            "in": "path",
+            "required": true,
+            "description": "Transaction hash",
+            "schema": {
+              "$ref": "#/components/schemas/Hash"
+            }
+          },
+          {
+            "$ref": "#/components/parameters/decode"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The transaction with its receipt",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Transaction"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/transaction/{ref}/{address}": {
+      "get": {
+        "tags": [
+          "Transactions"
+        ],
+        "summary": "Transactions of an address or block",
+        "operationId": "getTransactionsByAddress",
+        "parameters": [
+          {
+            "name": "ref",
+            "in": "path",
+            "required": true,
+            "description": "Transactions from or to the address, all of them, or of the block with the hash given as address",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "from",
+                "to",
+                "all",
+                "block"
+              ]
+            }
+          },
+          {
+            "name": "address",
+            "in": "path",
+            "required": true,
+            "description": "An address, or a block hash",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Page size, 20 by default",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          },
+          {
+            "$ref": "#/components/parameters/cursor"
+          },
+          {
+            "$ref": "#/components/parameters/order"
+          },
+          {
+            "$ref": "#/components/parameters/decode"
+          },
+          {
+            "$ref": "#/components/parameters/format"
+          },
+          {
+            "$ref": "#/components/parameters/from"
+          },
+          {
+            "$ref": "#/components/parameters/to"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "A page of transactions, or an export",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TransactionPage"
+                }
+              },
+              "text/csv": {
+                "schema": {
+                  "type": "string"
+                }
+              },
+              "application/x-ndjson": {
+                "schema": {
+                  "$ref": "#/components/schemas/TransactionExportRow"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/address/{address}": {
+      "get": {
+        "tags": [
+          "Addresses"
+        ],
+        "summary": "Address summary",
+        "operationId": "getAddress",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/address"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The balance, stake and totals of the address",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/AddressInfo"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/stats": {
+      "get": {
+        "tags": [
+          "Delegates"
+        ],
+        "summary": "Block density of the delegates",
+        "operationId": "getStats",
+        "responses": {
+          "200": {
+            "description": "Missed blocks and density of the delegates over the last hour",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/DelegateStats"
+                }
+              }
+            }
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/stats/{address}": {
+      "get": {
+        "tags": [
+          "Delegates"
+        ],
+        "summary": "Block density of a delegate",
+        "operationId": "getDelegateStats",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/address"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Missed blocks and density of the delegate over the last hour",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/DelegateStats"
+                }
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/rich-list": {
+      "get": {
+        "tags": [
+          "Addresses"
+        ],
+        "summary": "Top balances",
+        "operationId": "getRichList",
+        "parameters": [
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Page size, 100 by default",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          },
+          {
+            "$ref": "#/components/parameters/cursor"
+          },
+          {
+            "$ref": "#/components/parameters/format"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "A page of balances, or an export",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/BalancePage"
+                }
+              },
+              "text/csv": {
+                "schema": {
+                  "type": "string"
+                }
+              },
+              "application/x-ndjson": {
+                "schema": {
+                  "$ref": "#/components/schemas/BalanceExportRow"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/ens": {
+      "post": {
+        "tags": [
+          "ENS"
+        ],
+        "summary": "Store a reverse ENS record",
+        "operationId": "addENS",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ENS"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The record stored",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ENS"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/ens/{address}": {
+      "get": {
+        "tags": [
+          "ENS"
+        ],
+        "summary": "ENS name of an address",
+        "operationId": "getENS",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/address"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The name",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ENSName"
+                }
+              }
+            }
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/delegates": {
+      "get": {
+        "tags": [
+          "Delegates"
+        ],
+        "summary": "Delegates of the latest block",
+        "operationId": "getDelegates",
+        "responses": {
+          "200": {
+            "description": "The delegates with their stake",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Delegate"
+                  }
+                }
+              }
+            }
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/delegates/{number}": {
+      "get": {
+        "tags": [
+          "Delegates"
+        ],
+        "summary": "Delegates of a block",
+        "operationId": "getBlockDelegates",
+        "parameters": [
+          {
+            "name": "number",
+            "in": "path",
+            "required": true,
+            "description": "Block number, the latest block when it isn't a number",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The delegates with their stake",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Delegate"
+                  }
+                }
+              }
+            }
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/abi/{address}": {
+      "get": {
+        "tags": [
+          "Contracts"
+        ],
+        "summary": "ABI of a contract",
+        "operationId": "getABI",
+        "description": "The ABI the node has, or else the one uploaded, or else a partial ABI derived from the signatures of the methods called.",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/address"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The ABI",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ABI"
+                }
+              }
+            },
+            "headers": {
+              "X-ABI-Source": {
+                "description": "Where the ABI came from",
+                "schema": {
+                  "$ref": "#/components/schemas/AbiSource"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      },
+      "post": {
+        "tags": [
+          "Contracts"
+        ],
+        "summary": "Upload the ABI of a contract",
+        "operationId": "uploadABI",
+        "description": "Authorized with Authorization: Bearer <token>, the abiuploadtoken of the explorer.",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/address"
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ABI"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "The ABI stored",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ABI"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "description": "Uploads are disabled",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/contract/{address}/call": {
+      "post": {
+        "tags": [
+          "Contracts"
+        ],
+        "summary": "Call a view function",
+        "operationId": "callContract",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/address"
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ContractCallRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The decoded outputs",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CallResult"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "422": {
+            "description": "The call failed on the node",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "504": {
+            "description": "The call timed out",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/contract/{address}/storage": {
+      "get": {
+        "tags": [
+          "Contracts"
+        ],
+        "summary": "Read a storage slot",
+        "operationId": "getStorage",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/address"
+          },
+          {
+            "name": "slot",
+            "in": "query",
+            "description": "The slot, a decimal or hex number",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          },
+          {
+            "name": "type",
+            "in": "query",
+            "description": "Decode the value as address, uint, bool or bytes",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "address",
+                "uint",
+                "bool",
+                "bytes"
+              ]
+            }
+          },
+          {
+            "name": "block",
+            "in": "query",
+            "description": "A block number, the latest by default",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The raw and decoded value",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/StorageValue"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      },
+      "post": {
+        "tags": [
+          "Contracts"
+        ],
+        "summary": "Read a variable inside mappings and arrays",
+        "operationId": "readStorage",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/address"
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/StorageRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The computed slot with the raw and decoded value",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/StorageValue"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/chain-info": {
+      "get": {
+        "tags": [
+          "Chain"
+        ],
+        "summary": "Chain summary",
+        "operationId": "getChainInfo",
+        "responses": {
+          "200": {
+            "description": "The latest block and the supply",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ChainInfo"
+                }
+              }
+            }
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/conversion-rate": {
+      "get": {
+        "tags": [
+          "Chain"
+        ],
+        "summary": "USD rate of EBK",
+        "operationId": "getConversionRate",
+        "responses": {
+          "200": {
+            "description": "The rate, from CoinMarketCap",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ConversionRate"
+                }
+              }
+            }
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/search": {
+      "get": {
+        "tags": [
+          "Search"
+        ],
+        "summary": "Search blocks, transactions, addresses and ENS names",
+        "operationId": "search",
+        "parameters": [
+          {
+            "name": "q",
+            "in": "query",
+            "description": "A block number, a block or transaction hash, an address or an address prefix, or an ENS name",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Maximum results, 10 by default and 50 at most",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The results, exact matches first",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/SearchResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/charts/{metric}": {
+      "get": {
+        "tags": [
+          "Chain"
+        ],
+        "summary": "Time series of a metric",
+        "operationId": "getChart",
+        "parameters": [
+          {
+            "name": "metric",
+            "in": "path",
+            "required": true,
+            "description": "The metric",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "tx_count",
+                "active_addresses",
+                "new_addresses",
+                "gas_used",
+                "avg_block_size",
+                "contract_deployments",
+                "failed_txs"
+              ]
+            }
+          },
+          {
+            "name": "interval",
+            "in": "query",
+            "description": "Length of the periods, day by default",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "hour",
+                "day"
+              ]
+            }
+          },
+          {
+            "name": "from",
+            "in": "query",
+            "description": "Start of the series, the last 30 days or 48 hours by default",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "to",
+            "in": "query",
+            "description": "End of the series, now by default",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "One point per hour or day, zero for periods without activity",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Chart"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/api": {
+      "get": {
+        "tags": [
+          "Etherscan"
+        ],
+        "summary": "Etherscan compatible API",
+        "operationId": "etherscanGet",
+        "description": "Parameters may also be sent as a form with POST.",
+        "parameters": [
+          {
+            "name": "module",
+            "in": "query",
+            "description": "account, block, transaction, contract or stats",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          },
+          {
+            "name": "action",
+            "in": "query",
+            "description": "balance, txlist, tokentx, getblocknobytime, getstatus, getabi or ethsupply",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          },
+          {
+            "name": "address",
+            "in": "query",
+            "description": "For account and contract actions",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "For account.balance, only latest",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "startblock",
+            "in": "query",
+            "description": "For account.txlist",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "endblock",
+            "in": "query",
+            "description": "For account.txlist",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "page",
+            "in": "query",
+            "description": "For account.txlist",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "offset",
+            "in": "query",
+            "description": "For account.txlist",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "sort",
+            "in": "query",
+            "description": "For account.txlist, asc or desc",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "timestamp",
+            "in": "query",
+            "description": "For block.getblocknobytime",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "closest",
+            "in": "query",
+            "description": "For block.getblocknobytime, before or after",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "txhash",
+            "in": "query",
+            "description": "For transaction.getstatus",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The result, or the error with status 0",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/EtherscanResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      },
+      "post": {
+        "tags": [
+          "Etherscan"
+        ],
+        "summary": "Etherscan compatible API",
+        "operationId": "etherscanPost",
+        "description": "Parameters may also be sent as a form with POST.",
+        "parameters": [
+          {
+            "name": "module",
+            "in": "query",
+            "description": "account, block, transaction, contract or stats",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          },
+          {
+            "name": "action",
+            "in": "query",
+            "description": "balance, txlist, tokentx, getblocknobytime, getstatus, getabi or ethsupply",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          },
+          {
+            "name": "address",
+            "in": "query",
+            "description": "For account and contract actions",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "For account.balance, only latest",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "startblock",
+            "in": "query",
+            "description": "For account.txlist",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "endblock",
+            "in": "query",
+            "description": "For account.txlist",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "page",
+            "in": "query",
+            "description": "For account.txlist",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "offset",
+            "in": "query",
+            "description": "For account.txlist",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "sort",
+            "in": "query",
+            "description": "For account.txlist, asc or desc",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "timestamp",
+            "in": "query",
+            "description": "For block.getblocknobytime",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "closest",
+            "in": "query",
+            "description": "For block.getblocknobytime, before or after",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "txhash",
+            "in": "query",
+            "description": "For transaction.getstatus",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "requestBody": {
+          "required": false,
+          "content": {
+            "application/x-www-form-urlencoded": {
+              "schema": {
+                "type": "object",
+                "description": "The same parameters, as a form",
+                "additionalProperties": {
+                  "type": "string"
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The result, or the error with status 0",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/EtherscanResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/rpc": {
+      "post": {
+        "tags": [
+          "JSON-RPC"
+        ],
+        "summary": "Read-only JSON-RPC gateway",
+        "operationId": "rpc",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "oneOf": [
+                  {
+                    "$ref": "#/components/schemas/JSONRPCRequest"
+                  },
+                  {
+                    "type": "array",
+                    "items": {
+                      "$ref": "#/components/schemas/JSONRPCRequest"
+                    }
+                  }
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The response, or a batch of them",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "oneOf": [
+                    {
+                      "$ref": "#/components/schemas/JSONRPCResponse"
+                    },
+                    {
+                      "type": "array",
+                      "items": {
+                        "$ref": "#/components/schemas/JSONRPCResponse"
+                      }
+                    }
+                  ]
+                }
+              }
+            }
+          },
+          "413": {
+            "description": "The request is too large",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/graphql": {
+      "get": {
+        "tags": [
+          "GraphQL"
+        ],
+        "summary": "Run a GraphQL query",
+        "operationId": "graphqlGet",
+        "parameters": [
+          {
+            "name": "query",
+            "in": "query",
+            "description": "The query",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          },
+          {
+            "name": "operationName",
+            "in": "query",
+            "description": "The operation to run",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "variables",
+            "in": "query",
+            "description": "The variables, as a JSON object",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The result",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/GraphQLResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      },
+      "post": {
+        "tags": [
+          "GraphQL"
+        ],
+        "summary": "Run a GraphQL query",
+        "operationId": "graphqlPost",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/GraphQLRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The result",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/GraphQLResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/stream/ws": {
+      "get": {
+        "tags": [
+          "Streaming"
+        ],
+        "summary": "Stream events over WebSocket",
+        "operationId": "streamWS",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/streamTypes"
+          },
+          {
+            "$ref": "#/components/parameters/streamProducer"
+          },
+          {
+            "$ref": "#/components/parameters/streamAddress"
+          }
+        ],
+        "responses": {
+          "101": {
+            "description": "Switching to the WebSocket protocol. Every message is an Event."
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "503": {
+            "description": "Streaming is not available",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/stream/sse": {
+      "get": {
+        "tags": [
+          "Streaming"
+        ],
+        "summary": "Stream events as Server-Sent Events",
+        "operationId": "streamSSE",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/streamTypes"
+          },
+          {
+            "$ref": "#/components/parameters/streamProducer"
+          },
+          {
+            "$ref": "#/components/parameters/streamAddress"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "An event stream, the data of every event is an Event",
+            "content": {
+              "text/event-stream": {
+                "schema": {
+                  "$ref": "#/components/schemas/Event"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "503": {
+            "description": "Streaming is not available",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/webhooks": {
+      "post": {
+        "tags": [
+          "Webhooks"
+        ],
+        "summary": "Create a webhook",
+        "operationId": "addWebhook",
+        "description": "Webhooks are created with an API key, which can create a limited number of them. The url has to point to a public address, deliveries to private, loopback and link-local addresses are refused and redirects are not followed.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/WebhookRequest"
+              }
+            }
+          }
+        },
+        "security": [
+          {
+            "apiKeyHeader": []
+          },
+          {
+            "apiKeyQuery": []
+          }
+        ],
+        "responses": {
+          "201": {
+            "description": "The webhook and its secret",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/WebhookCreated"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "description": "The API key is missing, unknown or revoked",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The API key has reached its maximum webhooks",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/webhooks/{id}": {
+      "get": {
+        "tags": [
+          "Webhooks"
+        ],
+        "summary": "Get a webhook",
+        "operationId": "getWebhook",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/webhookId"
+          },
+          {
+            "$ref": "#/components/parameters/webhookSecret"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The webhook",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Webhook"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      },
+      "delete": {
+        "tags": [
+          "Webhooks"
+        ],
+        "summary": "Delete a webhook",
+        "operationId": "deleteWebhook",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/webhookId"
+          },
+          {
+            "$ref": "#/components/parameters/webhookSecret"
+          }
+        ],
+        "responses": {
+          "204": {
+            "description": "Deleted"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    },
+    "/webhooks/{id}/deliveries": {
+      "get": {
+        "tags": [
+          "Webhooks"
+        ],
+        "summary": "Delivery log of a webhook",
+        "operationId": "getWebhookDeliveries",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/webhookId"
+          },
+          {
+            "$ref": "#/components/parameters/webhookSecret"
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Maximum deliveries",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The deliveries, newest first",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/WebhookDelivery"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/RateLimited"
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Address": {
+        "type": "string",
+        "pattern": "^0x[0-9a-fA-F]{40}$",
+        "example": "0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+      },
+      "Hash": {
+        "type": "string",
+        "pattern": "^0x[0-9a-fA-F]{64}$"
+      },
+      "HexData": {
+        "type": "string",
+        "pattern": "^0x[0-9a-fA-F]*$"
+      },
+      "BigInt": {
+        "type": "integer",
+        "description": "An integer in wei, which may not fit in a double and should be parsed as a big number"
+      },
+      "AnyValue": {
+        "description": "Any JSON value"
+      },
+      "Block": {
+        "type": "object",
+        "required": [
+          "number",
+          "timestamp",
+          "hash",
+          "parentHash",
+          "signature",
+          "transactionsRoot",
+          "receiptsRoot",
+          "size",
+          "transactionCount",
+          "gasUsed",
+          "gasLimit",
+          "delegates",
+          "producer",
+          "producerEns"
+        ],
+        "properties": {
+          "number": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "timestamp": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "Unix time"
+          },
+          "hash": {
+            "$ref": "#/components/schemas/Hash"
+          },
+          "parentHash": {
+            "$ref": "#/components/schemas/Hash"
+          },
+          "signature": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/HexData"
+              }
+            ],
+            "nullable": true
+          },
+          "transactionsRoot": {
+            "$ref": "#/components/schemas/Hash"
+          },
+          "receiptsRoot": {
+            "$ref": "#/components/schemas/Hash"
+          },
+          "size": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "transactionCount": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "gasUsed": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "gasLimit": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "delegates": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Address"
+            },
+            "nullable": true
+          },
+          "producer": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "producerEns": {
+            "type": "string",
+            "nullable": true,
+            "description": "ENS name of the producer, empty or null when it has none"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Transaction": {
+        "type": "object",
+        "required": [
+          "hash",
+          "timestamp",
+          "status",
+          "nonce",
+          "blockHash",
+          "blockNumber",
+          "transactionIndex",
+          "from",
+          "fromEns",
+          "to",
+          "toEns",
+          "value",
+          "gasUsed",
+          "cumulativeGasUsed",
+          "gasLimit",
+          "gasPrice",
+          "workNonce",
+          "contractAddress",
+          "contractAddressEns",
+          "input"
+        ],
+        "properties": {
+          "hash": {
+            "$ref": "#/components/schemas/Hash"
+          },
+          "timestamp": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "Unix time of the block"
+          },
+          "status": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "1 for success, 0 for failure"
+          },
+          "nonce": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "blockHash": {
+            "$ref": "#/components/schemas/Hash"
+          },
+          "blockNumber": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "transactionIndex": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "from": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "fromEns": {
+            "type": "string",
+            "nullable": true
+          },
+          "to": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/Address"
+              }
+            ],
+            "nullable": true,
+            "description": "null for contract creations"
+          },
+          "toEns": {
+            "type": "string",
+            "nullable": true
+          },
+          "value": {
+            "$ref": "#/components/schemas/BigInt"
+          },
+          "gasUsed": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "cumulativeGasUsed": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "gasLimit": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "The gas limit, named gas by the node"
+          },
+          "gasPrice": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "workNonce": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "contractAddress": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/Address"
+              }
+            ],
+            "nullable": true,
+            "description": "The contract created, the zero address or null otherwise"
+          },
+          "contractAddressEns": {
+            "type": "string",
+            "nullable": true
+          },
+          "input": {
+            "$ref": "#/components/schemas/HexData"
+          },
+          "decodedInput": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/DecodedInput"
+              }
+            ],
+            "description": "Only with decode=true, for contract calls"
+          },
+          "decodedLogs": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/DecodedLog"
+            },
+            "description": "Only with decode=true, for single transactions with logs"
+          }
+        },
+        "additionalProperties": false
+      },
+      "DecodedParam": {
+        "type": "object",
+        "required": [
+          "name",
+          "type",
+          "value"
+        ],
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "type": {
+            "type": "string",
+            "description": "ABI type"
+          },
+          "indexed": {
+            "type": "boolean"
+          },
+          "value": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/AnyValue"
+              }
+            ],
+            "description": "Numbers are decimal strings and bytes hex strings"
+          }
+        },
+        "additionalProperties": false
+      },
+      "AbiSource": {
+        "type": "string",
+        "enum": [
+          "node",
+          "registry",
+          "signatures"
+        ]
+      },
+      "DecodedInput": {
+        "type": "object",
+        "required": [
+          "method",
+          "signature",
+          "selector",
+          "source",
+          "params"
+        ],
+        "properties": {
+          "method": {
+            "type": "string"
+          },
+          "signature": {
+            "type": "string"
+          },
+          "selector": {
+            "$ref": "#/components/schemas/HexData"
+          },
+          "source": {
+            "$ref": "#/components/schemas/AbiSource"
+          },
+          "params": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/DecodedParam"
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "DecodedLog": {
+        "type": "object",
+        "required": [
+          "logIndex",
+          "address",
+          "event",
+          "signature",
+          "source",
+          "params"
+        ],
+        "properties": {
+          "logIndex": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "address": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "event": {
+            "type": "string"
+          },
+          "signature": {
+            "type": "string"
+          },
+          "source": {
+            "$ref": "#/components/schemas/AbiSource"
+          },
+          "params": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/DecodedParam"
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "BlockPage": {
+        "type": "object",
+        "required": [
+          "data",
+          "next",
+          "prev"
+        ],
+        "properties": {
+          "data": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Block"
+            }
+          },
+          "next": {
+            "type": "string",
+            "nullable": true,
+            "description": "Cursor of the next page, null on the last one"
+          },
+          "prev": {
+            "type": "string",
+            "nullable": true,
+            "description": "Cursor of the previous page, null on the first one"
+          }
+        },
+        "additionalProperties": false
+      },
+      "TransactionPage": {
+        "type": "object",
+        "required": [
+          "data",
+          "next",
+          "prev"
+        ],
+        "properties": {
+          "data": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Transaction"
+            }
+          },
+          "next": {
+            "type": "string",
+            "nullable": true,
+            "description": "Cursor of the next page, null on the last one"
+          },
+          "prev": {
+            "type": "string",
+            "nullable": true,
+            "description": "Cursor of the previous page, null on the first one"
+          }
+        },
+        "additionalProperties": false
+      },
+      "BalancePage": {
+        "type": "object",
+        "required": [
+          "data",
+          "next",
+          "prev"
+        ],
+        "properties": {
+          "data": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Balance"
+            }
+          },
+          "next": {
+            "type": "string",
+            "nullable": true,
+            "description": "Cursor of the next page, null on the last one"
+          },
+          "prev": {
+            "type": "string",
+            "nullable": true,
+            "description": "Cursor of the previous page, null on the first one"
+          }
+        },
+        "additionalProperties": false
+      },
+      "AddressInfo": {
+        "type": "object",
+        "required": [
+          "address",
+          "addressEns",
+          "isContract",
+          "balance",
+          "stake",
+          "tx_count",
+          "block_rewards"
+        ],
+        "properties": {
+          "address": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "addressEns": {
+            "type": "string",
+            "nullable": true
+          },
+          "isContract": {
+            "type": "boolean"
+          },
+          "balance": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/BigInt"
+              }
+            ],
+            "nullable": true,
+            "description": "Balance in wei, null when the node can't be reached"
+          },
+          "stake": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "tx_count": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "block_rewards": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/BigInt"
+              }
+            ],
+            "nullable": true
+          }
+        },
+        "additionalProperties": false
+      },
+      "DelegateStats": {
+        "type": "object",
+        "required": [
+          "total_seconds_examined",
+          "total_missed_blocks",
+          "delegates"
+        ],
+        "properties": {
+          "address": {
+            "type": "string",
+            "description": "The address looked up, only for /stats/{address}"
+          },
+          "total_seconds_examined": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "total_missed_blocks": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "delegates": {
+            "type": "array",
+            "items": {
+              "type": "array",
+              "items": {
+                "$ref": "#/components/schemas/DelegateDensity"
+              }
+            },
+            "description": "Per delegate, its density over each look back period (5 minutes, 1 hour)"
+          }
+        },
+        "additionalProperties": false
+      },
+      "DelegateDensity": {
+        "type": "object",
+        "required": [
+          "address",
+          "seconds_examined",
+          "missed_blocks",
+          "total_blocks",
+          "density"
+        ],
+        "properties": {
+          "address": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "seconds_examined": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "missed_blocks": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "total_blocks": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "density": {
+            "type": "number"
+          },
+          "stake": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "Only for /stats/{address}, the stake at the latest block"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Delegate": {
+        "type": "object",
+        "required": [
+          "address",
+          "stake"
+        ],
+        "properties": {
+          "address": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "stake": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "elected": {
+            "type": "boolean"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Balance": {
+        "type": "object",
+        "required": [
+          "address",
+          "addressEns",
+          "amount"
+        ],
+        "properties": {
+          "address": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "addressEns": {
+            "type": "string"
+          },
+          "amount": {
+            "type": "number",
+            "description": "Balance in EBK, with 4 decimals"
+          }
+        },
+        "additionalProperties": false
+      },
+      "ENS": {
+        "type": "object",
+        "required": [
+          "address",
+          "hash",
+          "name"
+        ],
+        "properties": {
+          "address": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "hash": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/Hash"
+              }
+            ],
+            "description": "Namehash of the reverse record"
+          },
+          "name": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "ENSName": {
+        "type": "object",
+        "required": [
+          "name"
+        ],
+        "properties": {
+          "name": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "ChainInfo": {
+        "type": "object",
+        "required": [
+          "block_number",
+          "block_timestamp",
+          "block_hash",
+          "total_supply_wei",
+          "circulating_supply_wei"
+        ],
+        "properties": {
+          "block_number": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "block_timestamp": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "block_hash": {
+            "$ref": "#/components/schemas/Hash"
+          },
+          "total_supply_wei": {
+            "$ref": "#/components/schemas/BigInt"
+          },
+          "circulating_supply_wei": {
+            "$ref": "#/components/schemas/BigInt"
+          }
+        },
+        "additionalProperties": false
+      },
+      "ConversionRate": {
+        "type": "object",
+        "required": [
+          "currency",
+          "usd_rate"
+        ],
+        "properties": {
+          "currency": {
+            "type": "string"
+          },
+          "usd_rate": {
+            "type": "number"
+          }
+        },
+        "additionalProperties": false
+      },
+      "AddressSearchResult": {
+        "type": "object",
+        "required": [
+          "address",
+          "addressEns"
+        ],
+        "properties": {
+          "address": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "addressEns": {
+            "type": "string",
+            "nullable": true
+          }
+        },
+        "additionalProperties": false
+      },
+      "SearchResult": {
+        "type": "object",
+        "required": [
+          "type",
+          "match",
+          "data"
+        ],
+        "properties": {
+          "type": {
+            "type": "string",
+            "enum": [
+              "block",
+              "transaction",
+              "address",
+              "ens"
+            ]
+          },
+          "match": {
+            "type": "string",
+            "enum": [
+              "exact",
+              "prefix"
+            ]
+          },
+          "data": {
+            "oneOf": [
+              {
+                "$ref": "#/components/schemas/Block"
+              },
+              {
+                "$ref": "#/components/schemas/Transaction"
+              },
+              {
+                "$ref": "#/components/schemas/AddressSearchResult"
+              },
+              {
+                "$ref": "#/components/schemas/ENS"
+              }
+            ],
+            "description": "The block, transaction, address or ENS entry, by type"
+          }
+        },
+        "additionalProperties": false
+      },
+      "SearchResponse": {
+        "type": "object",
+        "required": [
+          "query",
+          "kind",
+          "results"
+        ],
+        "properties": {
+          "query": {
+            "type": "string",
+            "description": "The query, trimmed and lowercased"
+          },
+          "kind": {
+            "type": "string",
+            "enum": [
+              "block_number",
+              "hash",
+              "address",
+              "address_prefix",
+              "name"
+            ]
+          },
+          "results": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/SearchResult"
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "Chart": {
+        "type": "object",
+        "required": [
+          "metric",
+          "interval",
+          "data"
+        ],
+        "properties": {
+          "metric": {
+            "type": "string"
+          },
+          "interval": {
+            "type": "string",
+            "enum": [
+              "hour",
+              "day"
+            ]
+          },
+          "data": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": [
+                "timestamp",
+                "value"
+              ],
+              "properties": {
+                "timestamp": {
+                  "type": "integer",
+                  "minimum": 0,
+                  "description": "Start of the period, unix time"
+                },
+                "value": {
+                  "type": "integer",
+                  "minimum": 0
+                }
+              },
+              "additionalProperties": false
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "Health": {
+        "type": "object",
+        "required": [
+          "status",
+          "cache"
+        ],
+        "properties": {
+          "status": {
+            "type": "string",
+            "enum": [
+              "ok"
+            ]
+          },
+          "cache": {
+            "type": "string",
+            "enum": [
+              "redis",
+              "memory"
+            ]
+          }
+        },
+        "additionalProperties": false
+      },
+      "SyncStatus": {
+        "type": "object",
+        "required": [
+          "nodeHead",
+          "indexedHead",
+          "lagBlocks",
+          "lagSeconds",
+          "richListBlock",
+          "ensLastSync",
+          "synced"
+        ],
+        "properties": {
+          "nodeHead": {
+            "type": "integer",
+            "minimum": 0,
+            "nullable": true,
+            "description": "null when the node can't be reached"
+          },
+          "indexedHead": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "lagBlocks": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "lagSeconds": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "richListBlock": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "ensLastSync": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "Unix time, 0 when never synced"
+          },
+          "synced": {
+            "type": "boolean"
+          },
+          "problems": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "The thresholds crossed"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Readiness": {
+        "type": "object",
+        "required": [
+          "ready",
+          "checks",
+          "sync"
+        ],
+        "properties": {
+          "ready": {
+            "type": "boolean"
+          },
+          "checks": {
+            "type": "object",
+            "description": "ok or the error, by check (db, node, redis, sync)",
+            "additionalProperties": {
+              "type": "string"
+            }
+          },
+          "sync": {
+            "$ref": "#/components/schemas/SyncStatus"
+          }
+        },
+        "additionalProperties": false
+      },
+      "EtherscanResponse": {
+        "type": "object",
+        "required": [
+          "status",
+          "message",
+          "result"
+        ],
+        "properties": {
+          "status": {
+            "type": "string",
+            "enum": [
+              "0",
+              "1"
+            ]
+          },
+          "message": {
+            "type": "string",
+            "enum": [
+              "OK",
+              "NOTOK",
+              "No transactions found"
+            ]
+          },
+          "result": {
+            "oneOf": [
+              {
+                "type": "string"
+              },
+              {
+                "type": "array",
+                "items": {
+                  "$ref": "#/components/schemas/EtherscanTransaction"
+                }
+              },
+              {
+                "$ref": "#/components/schemas/EtherscanTxStatus"
+              }
+            ],
+            "description": "The result of the action, or the error description"
+          }
+        },
+        "additionalProperties": false
+      },
+      "EtherscanTransaction": {
+        "type": "object",
+        "required": [
+          "blockNumber",
+          "timeStamp",
+          "hash",
+          "nonce",
+          "blockHash",
+          "transactionIndex",
+          "from",
+          "to",
+          "value",
+          "gas",
+          "gasPrice",
+          "isError",
+          "txreceipt_status",
+          "input",
+          "contractAddress",
+          "cumulativeGasUsed",
+          "gasUsed",
+          "confirmations"
+        ],
+        "properties": {
+          "blockNumber": {
+            "type": "string"
+          },
+          "timeStamp": {
+            "type": "string"
+          },
+          "hash": {
+            "type": "string"
+          },
+          "nonce": {
+            "type": "string"
+          },
+          "blockHash": {
+            "type": "string"
+          },
+          "transactionIndex": {
+            "type": "string"
+          },
+          "from": {
+            "type": "string"
+          },
+          "to": {
+            "type": "string"
+          },
+          "value": {
+            "type": "string"
+          },
+          "gas": {
+            "type": "string"
+          },
+          "gasPrice": {
+            "type": "string"
+          },
+          "isError": {
+            "type": "string"
+          },
+          "txreceipt_status": {
+            "type": "string"
+          },
+          "input": {
+            "type": "string"
+          },
+          "contractAddress": {
+            "type": "string"
+          },
+          "cumulativeGasUsed": {
+            "type": "string"
+          },
+          "gasUsed": {
+            "type": "string"
+          },
+          "confirmations": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "EtherscanTxStatus": {
+        "type": "object",
+        "required": [
+          "isError",
+          "errDescription"
+        ],
+        "properties": {
+          "isError": {
+            "type": "string"
+          },
+          "errDescription": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "ContractCallRequest": {
+        "type": "object",
+        "required": [
+          "function"
+        ],
+        "properties": {
+          "function": {
+            "type": "string",
+            "description": "A name, or a signature for overloaded functions"
+          },
+          "args": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/AnyValue"
+            }
+          },
+          "block": {
+            "oneOf": [
+              {
+                "type": "integer",
+                "minimum": 0
+              },
+              {
+                "type": "string"
+              }
+            ],
+            "description": "A block number, or latest"
+          }
+        },
+        "additionalProperties": false
+      },
+      "CallResult": {
+        "type": "object",
+        "required": [
+          "method",
+          "signature",
+          "source",
+          "output",
+          "outputs"
+        ],
+        "properties": {
+          "method": {
+            "type": "string"
+          },
+          "signature": {
+            "type": "string"
+          },
+          "source": {
+            "$ref": "#/components/schemas/AbiSource"
+          },
+          "output": {
+            "$ref": "#/components/schemas/HexData"
+          },
+          "outputs": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/DecodedParam"
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "StorageStep": {
+        "type": "object",
+        "required": [],
+        "properties": {
+          "key": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/AnyValue"
+              }
+            ],
+            "description": "Mapping key"
+          },
+          "keyType": {
+            "type": "string",
+            "description": "ABI type of the key"
+          },
+          "index": {
+            "type": "integer",
+            "minimum": 0,
+            "nullable": true,
+            "description": "Dynamic array index"
+          },
+          "elementSlots": {
+            "type": "integer",
+            "minimum": 0
+          }
+        },
+        "additionalProperties": false
+      },
+      "StorageRequest": {
+        "type": "object",
+        "required": [
+          "slot"
+        ],
+        "properties": {
+          "slot": {
+            "oneOf": [
+              {
+                "type": "integer",
+                "minimum": 0
+              },
+              {
+                "type": "string"
+              }
+            ],
+            "description": "Slot of the state variable, a number or a decimal or hex string"
+          },
+          "path": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/StorageStep"
+            }
+          },
+          "offset": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "type": {
+            "type": "string",
+            "enum": [
+              "address",
+              "uint",
+              "bool",
+              "bytes"
+            ]
+          },
+          "block": {
+            "oneOf": [
+              {
+                "type": "integer",
+                "minimum": 0
+              },
+              {
+                "type": "string"
+              }
+            ]
+          }
+        },
+        "additionalProperties": false
+      },
+      "StorageValue": {
+        "type": "object",
+        "required": [
+          "slot",
+          "raw",
+          "type",
+          "value"
+        ],
+        "properties": {
+          "slot": {
+            "$ref": "#/components/schemas/Hash"
+          },
+          "raw": {
+            "$ref": "#/components/schemas/Hash"
+          },
+          "type": {
+            "type": "string"
+          },
+          "value": {
+            "$ref": "#/components/schemas/AnyValue"
+          }
+        },
+        "additionalProperties": false
+      },
+      "WebhookRequest": {
+        "type": "object",
+        "required": [
+          "url",
+          "eventType",
+          "address"
+        ],
+        "properties": {
+          "url": {
+            "type": "string"
+          },
+          "eventType": {
+            "type": "string",
+            "enum": [
+              "transfer",
+              "missed_slot"
+            ]
+          },
+          "address": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "direction": {
+            "type": "string",
+            "enum": [
+              "in",
+              "out",
+              "any"
+            ]
+          },
+          "minValue": {
+            "type": "string",
+            "description": "Minimum value in wei, as a decimal string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Webhook": {
+        "type": "object",
+        "required": [
+          "id",
+          "url",
+          "eventType",
+          "address",
+          "direction",
+          "minValue",
+          "createdAt"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "url": {
+            "type": "string"
+          },
+          "eventType": {
+            "type": "string",
+            "enum": [
+              "transfer",
+              "missed_slot"
+            ]
+          },
+          "address": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "direction": {
+            "type": "string",
+            "enum": [
+              "in",
+              "out",
+              "any"
+            ]
+          },
+          "minValue": {
+            "type": "string"
+          },
+          "createdAt": {
+            "type": "integer",
+            "minimum": 0
+          }
+        },
+        "additionalProperties": false
+      },
+      "WebhookCreated": {
+        "type": "object",
+        "required": [
+          "webhook",
+          "secret"
+        ],
+        "properties": {
+          "webhook": {
+            "$ref": "#/components/schemas/Webhook"
+          },
+          "secret": {
+            "type": "string",
+            "description": "Only returned on creation"
+          }
+        },
+        "additionalProperties": false
+      },
+      "WebhookDelivery": {
+        "type": "object",
+        "required": [
+          "id",
+          "webhookId",
+          "eventType",
+          "payload",
+          "status",
+          "attempts",
+          "statusCode",
+          "error",
+          "nextAttemptAt",
+          "createdAt",
+          "updatedAt"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "webhookId": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "eventType": {
+            "type": "string"
+          },
+          "payload": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/AnyValue"
+              }
+            ],
+            "description": "The body POSTed"
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "pending",
+              "delivered",
+              "failed"
+            ]
+          },
+          "attempts": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "statusCode": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "error": {
+            "type": "string"
+          },
+          "nextAttemptAt": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "createdAt": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "updatedAt": {
+            "type": "integer",
+            "minimum": 0
+          }
+        },
+        "additionalProperties": false
+      },
+      "ABI": {
+        "type": "array",
+        "items": {
+          "type": "object",
+          "additionalProperties": true
+        },
+        "description": "A contract ABI"
+      },
+      "JSONRPCRequest": {
+        "type": "object",
+        "required": [
+          "jsonrpc",
+          "method"
+        ],
+        "properties": {
+          "jsonrpc": {
+            "type": "string",
+            "enum": [
+              "2.0"
+            ]
+          },
+          "id": {
+            "$ref": "#/components/schemas/AnyValue"
+          },
+          "method": {
+            "type": "string"
+          },
+          "params": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/AnyValue"
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "JSONRPCResponse": {
+        "oneOf": [
+          {
+            "type": "object",
+            "required": [
+              "jsonrpc",
+              "id",
+              "result"
+            ],
+            "properties": {
+              "jsonrpc": {
+                "type": "string"
+              },
+              "id": {
+                "$ref": "#/components/schemas/AnyValue"
+              },
+              "result": {
+                "$ref": "#/components/schemas/AnyValue"
+              }
+            },
+            "additionalProperties": false
+          },
+          {
+            "type": "object",
+            "required": [
+              "jsonrpc",
+              "id",
+              "error"
+            ],
+            "properties": {
+              "jsonrpc": {
+                "type": "string"
+              },
+              "id": {
+                "$ref": "#/components/schemas/AnyValue"
+              },
+              "error": {
+                "type": "object",
+                "required": [
+                  "code",
+                  "message"
+                ],
+                "properties": {
+                  "code": {
+                    "type": "integer"
+                  },
+                  "message": {
+                    "type": "string"
+                  }
+                },
+                "additionalProperties": false
+              }
+            },
+            "additionalProperties": false
+          }
+        ]
+      },
+      "GraphQLRequest": {
+        "type": "object",
+        "required": [
+          "query"
+        ],
+        "properties": {
+          "query": {
+            "type": "string"
+          },
+          "operationName": {
+            "type": "string"
+          },
+          "variables": {
+            "type": "object",
+            "additionalProperties": true
+          }
+        },
+        "additionalProperties": false
+      },
+      "GraphQLResponse": {
+        "type": "object",
+        "required": [],
+        "properties": {
+          "data": {
+            "$ref": "#/components/schemas/AnyValue"
+          },
+          "errors": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "additionalProperties": true
+            }
+          },
+          "extensions": {
+            "type": "object",
+            "additionalProperties": true
+          }
+        },
+        "additionalProperties": false
+      },
+      "Event": {
+        "type": "object",
+        "required": [
+          "type"
+        ],
+        "properties": {
+          "type": {
+            "type": "string",
+            "enum": [
+              "block",
+              "transaction",
+              "reorg"
+            ]
+          },
+          "block": {
+            "$ref": "#/components/schemas/Block"
+          },
+          "transaction": {
+            "$ref": "#/components/schemas/Transaction"
+          },
+          "reorg": {
+            "type": "object",
+            "required": [
+              "number",
+              "oldHash",
+              "newHash"
+            ],
+            "properties": {
+              "number": {
+                "type": "integer",
+                "minimum": 0
+              },
+              "oldHash": {
+                "$ref": "#/components/schemas/Hash"
+              },
+              "newHash": {
+                "$ref": "#/components/schemas/Hash"
+              }
+            },
+            "additionalProperties": false
+          }
+        },
+        "additionalProperties": false
+      },
+      "TransactionExportRow": {
+        "type": "object",
+        "required": [
+          "hash",
+          "blockNumber",
+          "transactionIndex",
+          "timestamp",
+          "date",
+          "from",
+          "fromEns",
+          "to",
+          "toEns",
+          "valueWei",
+          "valueEbk",
+          "gasUsed",
+          "gasPrice",
+          "status",
+          "contractAddress"
+        ],
+        "properties": {
+          "hash": {
+            "$ref": "#/components/schemas/Hash"
+          },
+          "blockNumber": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "transactionIndex": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "timestamp": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "date": {
+            "type": "string",
+            "description": "RFC3339, UTC"
+          },
+          "from": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "fromEns": {
+            "type": "string"
+          },
+          "to": {
+            "type": "string"
+          },
+          "toEns": {
+            "type": "string"
+          },
+          "valueWei": {
+            "type": "string"
+          },
+          "valueEbk": {
+            "type": "string"
+          },
+          "gasUsed": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "gasPrice": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "status": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "contractAddress": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "BlockExportRow": {
+        "type": "object",
+        "required": [
+          "number",
+          "timestamp",
+          "date",
+          "hash",
+          "producer",
+          "producerEns",
+          "transactionCount",
+          "gasUsed",
+          "gasLimit",
+          "size"
+        ],
+        "properties": {
+          "number": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "timestamp": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "date": {
+            "type": "string"
+          },
+          "hash": {
+            "$ref": "#/components/schemas/Hash"
+          },
+          "producer": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "producerEns": {
+            "type": "string"
+          },
+          "transactionCount": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "gasUsed": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "gasLimit": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "size": {
+            "type": "integer",
+            "minimum": 0
+          }
+        },
+        "additionalProperties": false
+      },
+      "BalanceExportRow": {
+        "type": "object",
+        "required": [
+          "rank",
+          "address",
+          "addressEns",
+          "balanceWei",
+          "balanceEbk",
+          "blockNumber"
+        ],
+        "properties": {
+          "rank": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "address": {
+            "$ref": "#/components/schemas/Address"
+          },
+          "addressEns": {
+            "type": "string"
+          },
+          "balanceWei": {
+            "type": "string"
+          },
+          "balanceEbk": {
+            "type": "string"
+          },
+          "blockNumber": {
+            "type": "integer",
+            "minimum": 0
+          }
+        },
+        "additionalProperties": false
+      }
+    },
+    "parameters": {
+      "cursor": {
+        "name": "cursor",
+        "in": "query",
+        "description": "A next or prev cursor of a previous page",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "decode": {
+        "name": "decode",
+        "in": "query",
+        "description": "Decode the input and logs of contract calls",
+        "schema": {
+          "type": "boolean"
+        }
+      },
+      "format": {
+        "name": "format",
+        "in": "query",
+        "description": "Download the list as an export instead of a page. Transaction exports are capped at 100000 rows, and CSV cells starting with =, +, - or @ are prefixed with a quote.",
+        "schema": {
+          "type": "string",
+          "enum": [
+            "json",
+            "csv",
+            "ndjson"
+          ]
+        }
+      },
+      "from": {
+        "name": "from",
+        "in": "query",
+        "description": "Start of the range, inclusive, as YYYY-MM-DD, RFC3339 or unix time",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "to": {
+        "name": "to",
+        "in": "query",
+        "description": "End of the range, inclusive, as YYYY-MM-DD, RFC3339 or unix time",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "order": {
+        "name": "order",
+        "in": "query",
+        "description": "Direction of the list",
+        "schema": {
+          "type": "string",
+          "enum": [
+            "asc",
+            "desc"
+          ]
+        }
+      },
+      "address": {
+        "name": "address",
+        "in": "path",
+        "required": true,
+        "description": "An address",
+        "schema": {
+          "$ref": "#/components/schemas/Address"
+        }
+      },
+      "webhookId": {
+        "name": "id",
+        "in": "path",
+        "required": true,
+        "description": "ID of the webhook",
+        "schema": {
+          "type": "integer",
+          "minimum": 0
+        }
+      },
+      "webhookSecret": {
+        "name": "X-Webhook-Secret",
+        "in": "header",
+        "required": true,
+        "description": "Secret returned when the webhook was created",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "streamTypes": {
+        "name": "types",
+        "in": "query",
+        "description": "Comma separated event types to send, all by default",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "streamProducer": {
+        "name": "producer",
+        "in": "query",
+        "description": "Only send blocks produced by the address",
+        "schema": {
+          "$ref": "#/components/schemas/Address"
+        }
+      },
+      "streamAddress": {
+        "name": "address",
+        "in": "query",
+        "description": "Only send transactions from or to the address",
+        "schema": {
+          "$ref": "#/components/schemas/Address"
+        }
+      }
+    },
+    "responses": {
+      "BadRequest": {
+        "description": "The request is invalid",
+        "content": {
+          "text/plain": {
+            "schema": {
+              "type": "string"
+            }
+          }
+        }
+      },
+      "NotFound": {
+        "description": "Not found",
+        "content": {
+          "text/plain": {
+            "schema": {
+              "type": "string"
+            }
+          }
+        }
+      },
+      "InternalError": {
+        "description": "The database or the node failed",
+        "content": {
+          "text/plain": {
+            "schema": {
+              "type": "string"
+            }
+          }
+        }
+      },
+      "Unauthorized": {
+        "description": "The API key is unknown or revoked, or the token is missing",
+        "content": {
+          "text/plain": {
+            "schema": {
+              "type": "string"
+            }
+          }
+        }
+      },
+      "RateLimited": {
+        "description": "The rate limit or the daily quota of the client is exceeded",
+        "headers": {
+          "Retry-After": {
+            "description": "Seconds to wait",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          }
+        },
+        "content": {
+          "text/plain": {
+            "schema": {
+              "type": "string"
+            }
+          }
+        }
+      }
+    },
+    "securitySchemes": {
+      "apiKeyHeader": {
+        "type": "apiKey",
+        "in": "header",
+        "name": "X-API-Key"
+      },
+      "apiKeyQuery": {
+        "type": "apiKey",
+        "in": "query",
+        "name": "apikey",
+        "description": "Deprecated, use the X-API-Key header, which takes precedence. The parameter is removed from the request before it is cached or logged."
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,250 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	MEDIA_TYPE_JSON   = "application/json"
+	MEDIA_TYPE_NDJSON = "application/x-ndjson"
+)
+
+// Document is the parsed OpenAPI document. Schemas are checked with the
+// keywords the document uses: type, nullable, enum, pattern, minimum,
+// properties, required, additionalProperties, items, allOf, oneOf and $ref.
+type Document struct {
+	root map[string]interface{}
+}
+
+// Operation is a method of a path of the document
+type Operation struct {
+	Method string
+	Path   string
+}
+
+// Load parses the document of the explorer
+func Load() (*Document, error) {
+	var root map[string]interface{}
+	if err := decodeJSON([]byte(Spec), &root); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %v", err)
+	}
+	return &Document{root: root}, nil
+}
+
+// decodeJSON keeps numbers as json.Number, so that integers can be told
+// from floats
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// Operations lists the operations of the document
+func (d *Document) Operations() []Operation {
+	var ops []Operation
+	for path, item := range asMap(d.root["paths"]) {
+		for method := range asMap(item) {
+			ops = append(ops, Operation{Method: strings.ToUpper(method), Path: path})
+		}
+	}
+	return ops
+}
+
+// ValidateRequest checks the body of a request to an operation
+func (d *Document) ValidateRequest(method, path, contentType string, body []byte) error {
+	op := asMap(asMap(asMap(d.root["paths"])[path])[strings.ToLower(method)])
+	if op == nil {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+	return d.validateContent(method+" "+path+" request", asMap(d.resolve(op["requestBody"])), contentType, body)
+}
+
+// ValidateResponse checks that the status of a response to an operation is
+// documented, and that its body matches the schema of its content type
+func (d *Document) ValidateResponse(method, path string, status int, contentType string, body []byte) error {
+	op := asMap(asMap(asMap(d.root["paths"])[path])[strings.ToLower(method)])
+	if op == nil {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+
+	response := asMap(op["responses"])[strconv.Itoa(status)]
+	if response == nil {
+		return fmt.Errorf("%s %s: status %d is not documented", method, path, status)
+	}
+	return d.validateContent(fmt.Sprintf("%s %s %d", method, path, status), asMap(d.resolve(response)), contentType, body)
+}
+
+// validateContent checks a body against the content of a request body or
+// a response. Bodies of other media types than JSON and NDJSON, like CSV
+// exports, only have to be documented.
+func (d *Document) validateContent(name string, object map[string]interface{}, contentType string, body []byte) error {
+	content := asMap(object["content"])
+	if content == nil {
+		if len(body) > 0 {
+			return fmt.Errorf("%s: no content is documented", name)
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media, ok := content[mediaType]
+	if !ok {
+		return fmt.Errorf("%s: content type %q is not documented", name, contentType)
+	}
+
+	var values [][]byte
+	switch mediaType {
+	case MEDIA_TYPE_JSON:
+		values = [][]byte{body}
+	case MEDIA_TYPE_NDJSON:
+		values = bytes.Split(bytes.TrimSpace(body), []byte("\n"))
+	}
+
+	var errs []string
+	for i, data := range values {
+		var value interface{}
+		if err := decodeJSON(data, &value); err != nil {
+			return fmt.Errorf("%s: invalid JSON on line %d: %v", name, i+1, err)
+		}
+		errs = d.validate(asMap(media)["schema"], value, "$", errs)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s:\n  %s", name, strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// resolve follows a local $ref, e.g. #/components/schemas/Block
+func (d *Document) resolve(object interface{}) interface{} {
+	for {
+		ref, ok := asMap(object)["$ref"].(string)
+		if !ok {
+			return object
+		}
+
+		object = d.root
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			object = asMap(object)[part]
+		}
+		if object == nil {
+			panic("openapi: unresolved reference " + ref)
+		}
+	}
+}
+
+// validate checks value against schema, appending the problems found to errs
+func (d *Document) validate(schema, value interface{}, at string, errs []string) []string {
+	s := asMap(d.resolve(schema))
+
+	if nullable, _ := s["nullable"].(bool); nullable && value == nil {
+		return errs
+	}
+
+	for _, sub := range asSlice(s["allOf"]) {
+		errs = d.validate(sub, value, at, errs)
+	}
+
+	if oneOf := asSlice(s["oneOf"]); len(oneOf) > 0 {
+		matches := 0
+		for _, sub := range oneOf {
+			if len(d.validate(sub, value, at, nil)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			errs = append(errs, fmt.Sprintf("%s: matches %d of the oneOf schemas instead of one", at, matches))
+		}
+	}
+
+	if enum := asSlice(s["enum"]); len(enum) > 0 {
+		found := false
+		for _, allowed := range enum {
+			found = found || fmt.Sprint(allowed) == fmt.Sprint(value)
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: %v is not one of %v", at, value, enum))
+		}
+	}
+
+	switch typ, _ := s["type"].(string); typ {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: %v is not a string", at, value))
+		}
+		if pattern, ok := s["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(str) {
+			errs = append(errs, fmt.Sprintf("%s: %q does not match %s", at, str, pattern))
+		}
+
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok || typ == "integer" && strings.ContainsAny(number.String(), ".eE") {
+			return append(errs, fmt.Sprintf("%s: %v is not %s", at, value, typ))
+		}
+		if minimum, ok := s["minimum"].(json.Number); ok {
+			x, _ := number.Float64()
+			min, _ := minimum.Float64()
+			if x < min {
+				errs = append(errs, fmt.Sprintf("%s: %s is less than %s", at, number, minimum))
+			}
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return append(errs, fmt.Sprintf("%s: %v is not a boolean", at, value))
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: %v is not an array", at, value))
+		}
+		for i, item := range items {
+			errs = d.validate(s["items"], item, fmt.Sprintf("%s[%d]", at, i), errs)
+		}
+
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: %v is not an object", at, value))
+		}
+		for _, name := range asSlice(s["required"]) {
+			if _, ok := object[name.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing property %s", at, name))
+			}
+		}
+
+		// properties that aren't declared are errors unless
+		// additionalProperties allows them
+		for name, property := range object {
+			schema, ok := asMap(s["properties"])[name]
+			if !ok {
+				schema = s["additionalProperties"]
+			}
+			if allowed, ok := schema.(bool); ok && !allowed {
+				errs = append(errs, fmt.Sprintf("%s: undocumented property %s", at, name))
+			} else if schema != nil {
+				errs = d.validate(schema, property, at+"."+name, errs)
+			}
+		}
+	}
+
+	return errs
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
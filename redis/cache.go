@@ -0,0 +1,347 @@
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+
+	"github.com/mediocregopher/radix/v3"
+	"github.com/urfave/cli"
+)
+
+const (
+	CACHE_BACKEND_AUTO   = "auto"
+	CACHE_BACKEND_REDIS  = "redis"
+	CACHE_BACKEND_MEMORY = "memory"
+)
+
+// DefaultCacheSize is the bytes the in-memory cache holds when not configured
+const DefaultCacheSize = 64 << 20
+
+// cacheProbeInterval is how often Redis is probed while the cache has fallen
+// back to memory
+const cacheProbeInterval = 10 * time.Second
+
+// Cache stores values that expire after a TTL, or never for a zero TTL.
+// Get reports a miss on errors too, so that a failing cache only costs
+// misses. Values returned by Get must not be modified.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+
+	// Incr increments the counter at key, which expires ttl after it is
+	// created, and returns its new value
+	Incr(key string, ttl time.Duration) (uint64, error)
+
+	// TakeToken takes a token from the bucket at key, which holds up to size
+	// tokens and refills by size every period. It returns the tokens left,
+	// or how long until one is available when the bucket is empty.
+	TakeToken(key string, size uint64, period time.Duration) (left uint64, wait time.Duration, err error)
+
+	// Backend tells where the values are currently stored, redis or memory
+	Backend() string
+}
+
+var cache Cache = NewMemoryCache(DefaultCacheSize)
+
+// GetCache returns the cache used by the explorer, which is in memory
+// until another one is set
+func GetCache() Cache {
+	return cache
+}
+
+// SetCache sets the cache returned by GetCache
+func SetCache(c Cache) {
+	cache = c
+}
+
+// InitCacheFromCli connects to Redis as configured by the cachebackend flag
+// and sets the cache. The auto backend uses Redis when it can connect to it
+// and memory otherwise, and falls back to memory while Redis is down.
+func InitCacheFromCli(c *cli.Context) error {
+	size := c.Int("cachesize")
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+
+	switch backend := c.String("cachebackend"); backend {
+	case CACHE_BACKEND_MEMORY:
+		logger.Warn("Using in-memory cache, it is not shared between instances")
+		SetCache(NewMemoryCache(int64(size)))
+
+	case CACHE_BACKEND_REDIS:
+		if err := InitFromCli(c); err != nil {
+			return err
+		}
+		SetCache(NewRedisCache())
+
+	case CACHE_BACKEND_AUTO, "":
+		if err := InitFromCli(c); err != nil {
+			logger.Warn("Failed to connect to redis, using in-memory cache", "err", err)
+			Pool = nil
+			SetCache(NewMemoryCache(int64(size)))
+			return nil
+		}
+		SetCache(NewFallbackCache(NewRedisCache(), NewMemoryCache(int64(size))))
+
+	default:
+		return fmt.Errorf("unknown cache backend %q, expected auto, redis or memory", backend)
+	}
+
+	return nil
+}
+
+// RedisCache stores the values in Redis
+type RedisCache struct{}
+
+// NewRedisCache creates a cache on the Redis Pool
+func NewRedisCache() *RedisCache {
+	return &RedisCache{}
+}
+
+func (rc *RedisCache) get(key string) ([]byte, bool, error) {
+	var data []byte
+	mn := radix.MaybeNil{Rcv: &data}
+	if err := Pool.Do(radix.Cmd(&mn, "GET", key)); err != nil {
+		return nil, false, fmt.Errorf("error getting key %s: %v", key, err)
+	}
+	return data, !mn.Nil, nil
+}
+
+func (rc *RedisCache) Get(key string) ([]byte, bool) {
+	data, ok, _ := rc.get(key)
+	return data, ok
+}
+
+func (rc *RedisCache) Set(key string, value []byte, ttl time.Duration) error {
+	var err error
+	if ttl > 0 {
+		// redis expires in seconds, round up so that nothing expires early
+		seconds := uint64((ttl + time.Second - 1) / time.Second)
+		err = Pool.Do(radix.FlatCmd(nil, "SET", key, value, "EX", seconds))
+	} else {
+		err = Pool.Do(radix.FlatCmd(nil, "SET", key, value))
+	}
+
+	if err != nil {
+		v := string(value)
+		if len(v) > 15 {
+			v = v[0:12] + "..."
+		}
+		return fmt.Errorf("error setting key %s to %s: %v", key, v, err)
+	}
+	return nil
+}
+
+func (rc *RedisCache) Delete(key string) error {
+	if err := Pool.Do(radix.Cmd(nil, "DEL", key)); err != nil {
+		return fmt.Errorf("error deleting key %s: %v", key, err)
+	}
+	return nil
+}
+
+func (rc *RedisCache) Incr(key string, ttl time.Duration) (uint64, error) {
+	var count uint64
+	if err := Pool.Do(radix.Cmd(&count, "INCR", key)); err != nil {
+		return 0, fmt.Errorf("error incrementing key %s: %v", key, err)
+	}
+
+	if count == 1 && ttl > 0 {
+		if err := Pool.Do(radix.FlatCmd(nil, "PEXPIRE", key, int64(ttl/time.Millisecond))); err != nil {
+			return 0, fmt.Errorf("error setting expire for key %s: %v", key, err)
+		}
+	}
+	return count, nil
+}
+
+// tokenBucketScript refills and takes from a bucket atomically. The bucket
+// is a hash of its tokens and the time they were counted at, in ms.
+var tokenBucketScript = radix.NewEvalScript(1, `
+local size = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "at")
+local tokens = tonumber(bucket[1]) or size
+local at = tonumber(bucket[2]) or now
+
+tokens = math.min(size, tokens + math.max(0, now - at) * size / period)
+
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) * period / size)
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "at", tostring(now))
+redis.call("PEXPIRE", KEYS[1], period)
+
+return {math.floor(tokens), wait}
+`)
+
+func (rc *RedisCache) TakeToken(key string, size uint64, period time.Duration) (uint64, time.Duration, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	periodMs := int64(period / time.Millisecond)
+	if size == 0 || periodMs <= 0 {
+		return 0, 0, fmt.Errorf("invalid token bucket of %d tokens per %s", size, period)
+	}
+
+	var res []int64
+	err := Pool.Do(tokenBucketScript.Cmd(&res, key, strconv.FormatUint(size, 10),
+		strconv.FormatInt(periodMs, 10), strconv.FormatInt(now, 10)))
+	if err != nil {
+		return 0, 0, fmt.Errorf("error taking token from %s: %v", key, err)
+	}
+	if len(res) != 2 {
+		return 0, 0, fmt.Errorf("unexpected reply taking token from %s", key)
+	}
+
+	return uint64(res[0]), time.Duration(res[1]) * time.Millisecond, nil
+}
+
+func (rc *RedisCache) Backend() string {
+	return CACHE_BACKEND_REDIS
+}
+
+func (rc *RedisCache) ping() error {
+	return Pool.Do(radix.Cmd(nil, "PING"))
+}
+
+// primaryCache is the cache a FallbackCache prefers, whose commands report
+// the failures of the connection
+type primaryCache interface {
+	get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+	Incr(key string, ttl time.Duration) (uint64, error)
+	TakeToken(key string, size uint64, period time.Duration) (uint64, time.Duration, error)
+	ping() error
+}
+
+// FallbackCache uses Redis, and switches to memory when a Redis command
+// fails until Redis answers again. The memory is emptied on every switch, so
+// that nothing that was invalidated meanwhile is served.
+type FallbackCache struct {
+	redis         primaryCache
+	memory        *MemoryCache
+	probeInterval time.Duration
+
+	mu   sync.RWMutex
+	down bool
+}
+
+// NewFallbackCache creates a cache on Redis that falls back to memory
+func NewFallbackCache(redis *RedisCache, memory *MemoryCache) *FallbackCache {
+	return &FallbackCache{redis: redis, memory: memory, probeInterval: cacheProbeInterval}
+}
+
+func (fc *FallbackCache) isDown() bool {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	return fc.down
+}
+
+// fail switches to memory and probes Redis until it answers
+func (fc *FallbackCache) fail(err error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.down {
+		return
+	}
+
+	logger.Warn("Redis is unavailable, switching to in-memory cache", "err", err)
+	fc.down = true
+	fc.memory.Purge()
+	go fc.probe()
+}
+
+func (fc *FallbackCache) probe() {
+	for {
+		time.Sleep(fc.probeInterval)
+		if err := fc.redis.ping(); err != nil {
+			continue
+		}
+
+		fc.mu.Lock()
+		logger.Info("Redis is available again, switching back from in-memory cache")
+		fc.down = false
+		fc.memory.Purge()
+		fc.mu.Unlock()
+		return
+	}
+}
+
+func (fc *FallbackCache) Get(key string) ([]byte, bool) {
+	if fc.isDown() {
+		return fc.memory.Get(key)
+	}
+
+	data, ok, err := fc.redis.get(key)
+	if err != nil {
+		fc.fail(err)
+		return nil, false
+	}
+	return data, ok
+}
+
+func (fc *FallbackCache) Set(key string, value []byte, ttl time.Duration) error {
+	if fc.isDown() {
+		return fc.memory.Set(key, value, ttl)
+	}
+
+	if err := fc.redis.Set(key, value, ttl); err != nil {
+		fc.fail(err)
+		return fc.memory.Set(key, value, ttl)
+	}
+	return nil
+}
+
+func (fc *FallbackCache) Delete(key string) error {
+	fc.memory.Delete(key)
+	if fc.isDown() {
+		return nil
+	}
+
+	if err := fc.redis.Delete(key); err != nil {
+		fc.fail(err)
+	}
+	return nil
+}
+
+func (fc *FallbackCache) Incr(key string, ttl time.Duration) (uint64, error) {
+	if fc.isDown() {
+		return fc.memory.Incr(key, ttl)
+	}
+
+	count, err := fc.redis.Incr(key, ttl)
+	if err != nil {
+		fc.fail(err)
+		return fc.memory.Incr(key, ttl)
+	}
+	return count, nil
+}
+
+func (fc *FallbackCache) TakeToken(key string, size uint64, period time.Duration) (uint64, time.Duration, error) {
+	if fc.isDown() {
+		return fc.memory.TakeToken(key, size, period)
+	}
+
+	left, wait, err := fc.redis.TakeToken(key, size, period)
+	if err != nil {
+		fc.fail(err)
+		return fc.memory.TakeToken(key, size, period)
+	}
+	return left, wait, nil
+}
+
+func (fc *FallbackCache) Backend() string {
+	if fc.isDown() {
+		return CACHE_BACKEND_MEMORY
+	}
+	return CACHE_BACKEND_REDIS
+}
@@ -0,0 +1,167 @@
+package redis
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errConnRefused = errors.New("connection refused")
+
+// flakyCache stands in for Redis, keeping the values in memory and failing
+// every command while it is down
+type flakyCache struct {
+	values *MemoryCache
+
+	mu    sync.Mutex
+	down  bool
+	pings int
+}
+
+func (c *flakyCache) setDown(down bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down = down
+}
+
+func (c *flakyCache) err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return errConnRefused
+	}
+	return nil
+}
+
+func (c *flakyCache) get(key string) ([]byte, bool, error) {
+	if err := c.err(); err != nil {
+		return nil, false, err
+	}
+	data, ok := c.values.Get(key)
+	return data, ok, nil
+}
+
+func (c *flakyCache) Set(key string, value []byte, ttl time.Duration) error {
+	if err := c.err(); err != nil {
+		return err
+	}
+	return c.values.Set(key, value, ttl)
+}
+
+func (c *flakyCache) Delete(key string) error {
+	if err := c.err(); err != nil {
+		return err
+	}
+	return c.values.Delete(key)
+}
+
+func (c *flakyCache) Incr(key string, ttl time.Duration) (uint64, error) {
+	if err := c.err(); err != nil {
+		return 0, err
+	}
+	return c.values.Incr(key, ttl)
+}
+
+func (c *flakyCache) TakeToken(key string, size uint64, period time.Duration) (uint64, time.Duration, error) {
+	if err := c.err(); err != nil {
+		return 0, 0, err
+	}
+	return c.values.TakeToken(key, size, period)
+}
+
+func (c *flakyCache) ping() error {
+	c.mu.Lock()
+	c.pings++
+	c.mu.Unlock()
+	return c.err()
+}
+
+func (c *flakyCache) pingCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pings
+}
+
+func TestFallbackCache(t *testing.T) {
+	const probeInterval = 50 * time.Millisecond
+
+	for _, failover := range []string{"read", "write"} {
+		primary := &flakyCache{values: NewMemoryCache(DefaultCacheSize)}
+		fc := &FallbackCache{redis: primary, memory: NewMemoryCache(DefaultCacheSize), probeInterval: probeInterval}
+
+		if err := fc.Set("primary", []byte("1"), 0); err != nil || fc.Backend() != CACHE_BACKEND_REDIS {
+			t.Fatalf("%s: Set() = %v on %s, want the primary", failover, err, fc.Backend())
+		}
+		if _, ok := fc.memory.Get("primary"); ok {
+			t.Errorf("%s: the value was written to memory while the primary was up", failover)
+		}
+
+		primary.setDown(true)
+		failed := time.Now()
+
+		switch failover {
+		case "read":
+			if _, ok := fc.Get("primary"); ok {
+				t.Errorf("read: Get() of a failing primary found a value")
+			}
+			if err := fc.Set("fallback", []byte("2"), 0); err != nil {
+				t.Fatal(err)
+			}
+		case "write":
+			if err := fc.Set("fallback", []byte("2"), 0); err != nil {
+				t.Fatalf("write: Set() of a failing primary = %v, want the memory to take it", err)
+			}
+		}
+
+		if fc.Backend() != CACHE_BACKEND_MEMORY {
+			t.Fatalf("%s: backend = %s after a failure, want memory", failover, fc.Backend())
+		}
+		if data, ok := fc.Get("fallback"); !ok || string(data) != "2" {
+			t.Errorf("%s: Get() = %q, %v, want the value written to memory", failover, data, ok)
+		}
+		if count, err := fc.Incr("counter", time.Minute); err != nil || count != 1 {
+			t.Errorf("%s: Incr() = %d, %v, want 1 from memory", failover, count, err)
+		}
+		if _, _, err := fc.TakeToken("bucket", 10, time.Minute); err != nil {
+			t.Errorf("%s: TakeToken() = %v, want a token from memory", failover, err)
+		}
+
+		// the primary is probed every interval, and stays unused while it fails
+		for primary.pingCount() < 2 {
+			time.Sleep(probeInterval / 10)
+		}
+		if elapsed := time.Since(failed); elapsed < 2*probeInterval {
+			t.Errorf("%s: 2 probes after %s, want one every %s", failover, elapsed, probeInterval)
+		}
+		if fc.Backend() != CACHE_BACKEND_MEMORY {
+			t.Errorf("%s: backend = %s while the primary fails, want memory", failover, fc.Backend())
+		}
+
+		primary.setDown(false)
+		deadline := time.Now().Add(10 * probeInterval)
+		for fc.Backend() != CACHE_BACKEND_REDIS && time.Now().Before(deadline) {
+			time.Sleep(probeInterval / 10)
+		}
+		if fc.Backend() != CACHE_BACKEND_REDIS {
+			t.Fatalf("%s: backend = %s after the primary recovered, want redis", failover, fc.Backend())
+		}
+
+		// nothing written to memory meanwhile is served after the recovery
+		if _, ok := fc.memory.Get("fallback"); ok {
+			t.Errorf("%s: the memory wasn't purged on recovery", failover)
+		}
+		if _, ok := fc.Get("fallback"); ok {
+			t.Errorf("%s: Get() served a value written while the primary was down", failover)
+		}
+		if data, ok := fc.Get("primary"); !ok || string(data) != "1" {
+			t.Errorf("%s: Get() = %q, %v, want the value of the primary", failover, data, ok)
+		}
+	}
+}
+
+func TestNewFallbackCache(t *testing.T) {
+	if fc := NewFallbackCache(NewRedisCache(), NewMemoryCache(DefaultCacheSize)); fc.probeInterval != 10*time.Second {
+		t.Errorf("probe interval = %s, want 10s", fc.probeInterval)
+	}
+}
@@ -0,0 +1,185 @@
+package redis
+
+import (
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryCache keeps the values in memory, evicting the least recently used
+// ones once they take more than its size in bytes
+type MemoryCache struct {
+	size int64
+
+	mu      sync.Mutex
+	used    int64
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time // zero for entries that never expire
+}
+
+// NewMemoryCache creates a cache that holds up to size bytes
+func NewMemoryCache(size int64) *MemoryCache {
+	return &MemoryCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (mc *MemoryCache) Get(key string) ([]byte, bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if entry := mc.lookup(key); entry != nil {
+		return entry.value, true
+	}
+	return nil, false
+}
+
+func (mc *MemoryCache) Set(key string, value []byte, ttl time.Duration) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if el, ok := mc.entries[key]; ok {
+		mc.remove(el)
+	}
+
+	// values that would evict everything else are not kept
+	if int64(len(value)) > mc.size/2 {
+		return nil
+	}
+
+	mc.update(mc.insert(key, ttl), append([]byte(nil), value...))
+	return nil
+}
+
+func (mc *MemoryCache) Delete(key string) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if el, ok := mc.entries[key]; ok {
+		mc.remove(el)
+	}
+	return nil
+}
+
+func (mc *MemoryCache) Incr(key string, ttl time.Duration) (uint64, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	entry := mc.lookup(key)
+	if entry == nil {
+		entry = mc.insert(key, ttl)
+	}
+
+	count, _ := strconv.ParseUint(string(entry.value), 10, 64)
+	count++
+	mc.update(entry, []byte(strconv.FormatUint(count, 10)))
+	return count, nil
+}
+
+func (mc *MemoryCache) TakeToken(key string, size uint64, period time.Duration) (uint64, time.Duration, error) {
+	if size == 0 || period <= 0 {
+		return 0, 0, fmt.Errorf("invalid token bucket of %d tokens per %s", size, period)
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	now := time.Now()
+	tokens := float64(size)
+
+	// buckets are kept as their tokens and the time they were counted at
+	entry := mc.lookup(key)
+	if entry != nil && len(entry.value) == 16 {
+		tokens = math.Float64frombits(binary.BigEndian.Uint64(entry.value))
+		at := time.Unix(0, int64(binary.BigEndian.Uint64(entry.value[8:])))
+		if elapsed := now.Sub(at); elapsed > 0 {
+			tokens = math.Min(float64(size), tokens+float64(size)*float64(elapsed)/float64(period))
+		}
+	} else {
+		entry = mc.insert(key, 0)
+	}
+	entry.expires = now.Add(period)
+
+	var wait time.Duration
+	if tokens >= 1 {
+		tokens--
+	} else {
+		wait = time.Duration(math.Ceil((1 - tokens) * float64(period) / float64(size)))
+	}
+
+	value := make([]byte, 16)
+	binary.BigEndian.PutUint64(value, math.Float64bits(tokens))
+	binary.BigEndian.PutUint64(value[8:], uint64(now.UnixNano()))
+	mc.update(entry, value)
+
+	return uint64(tokens), wait, nil
+}
+
+func (mc *MemoryCache) Backend() string {
+	return CACHE_BACKEND_MEMORY
+}
+
+// Purge removes all the entries
+func (mc *MemoryCache) Purge() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.entries = make(map[string]*list.Element)
+	mc.order.Init()
+	mc.used = 0
+}
+
+// lookup returns the entry of a key unless it expired, marking it as used
+func (mc *MemoryCache) lookup(key string) *memoryEntry {
+	el, ok := mc.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := el.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		mc.remove(el)
+		return nil
+	}
+
+	mc.order.MoveToFront(el)
+	return entry
+}
+
+// insert adds an empty entry, which update fills
+func (mc *MemoryCache) insert(key string, ttl time.Duration) *memoryEntry {
+	entry := &memoryEntry{key: key}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	mc.entries[key] = mc.order.PushFront(entry)
+	return entry
+}
+
+// update replaces the value of an entry and evicts what no longer fits
+func (mc *MemoryCache) update(entry *memoryEntry, value []byte) {
+	mc.used += int64(len(value) - len(entry.value))
+	entry.value = value
+
+	for mc.used > mc.size && mc.order.Len() > 1 {
+		mc.remove(mc.order.Back())
+	}
+}
+
+func (mc *MemoryCache) remove(el *list.Element) {
+	entry := mc.order.Remove(el).(*memoryEntry)
+	delete(mc.entries, entry.key)
+	mc.used -= int64(len(entry.value))
+}
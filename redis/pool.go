@@ -1,10 +1,8 @@
 package redis
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/mediocregopher/radix/v3"
 	"github.com/urfave/cli"
@@ -12,6 +10,10 @@ import (
 
 var (
 	Pool *radix.Pool
+
+	// kept for the dedicated pub/sub connections, which can't come from the pool
+	poolAddr     string
+	poolConnFunc radix.ConnFunc
 )
 
 // InitFromCli is the same as Init but receives it's parameters
@@ -36,16 +38,23 @@ func Init(host string, port, poolSize int, dbSelect int) (err error) {
 	}
 
 	addr := fmt.Sprintf("%s:%d", host, port)
+	poolAddr, poolConnFunc = addr, connFunc
 	Pool, err = radix.NewPool("tcp", addr, poolSize, radix.PoolConnFunc(connFunc))
 	return err
 }
 
-func CleanupHook() {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGKILL)
-	go func() {
-		<-c
-		Pool.Close()
-		os.Exit(0)
-	}()
+// Ping checks that Redis answers
+func Ping() error {
+	if Pool == nil {
+		return errors.New("redis is not connected")
+	}
+	return Pool.Do(radix.Cmd(nil, "PING"))
+}
+
+// Close closes the Redis Pool, if connected
+func Close() error {
+	if Pool == nil {
+		return nil
+	}
+	return Pool.Close()
 }
@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"fmt"
+
+	"github.com/mediocregopher/radix/v3"
+)
+
+// Publish sends data to all the subscribers of channel
+func Publish(channel string, data []byte) error {
+	if Pool == nil {
+		return fmt.Errorf("error publishing to %s: redis is not connected", channel)
+	}
+	if err := Pool.Do(radix.FlatCmd(nil, "PUBLISH", channel, data)); err != nil {
+		return fmt.Errorf("error publishing to %s: %v", channel, err)
+	}
+	return nil
+}
+
+// Subscribe returns the messages published to channel. The subscription uses
+// its own connection which reconnects on failures, so it lasts for the whole
+// life of the process.
+func Subscribe(channel string) (<-chan []byte, error) {
+	ps := radix.PersistentPubSub("tcp", poolAddr, poolConnFunc)
+
+	msgCh := make(chan radix.PubSubMessage, 256)
+	if err := ps.Subscribe(msgCh, channel); err != nil {
+		ps.Close()
+		return nil, fmt.Errorf("error subscribing to %s: %v", channel, err)
+	}
+
+	dataCh := make(chan []byte, 256)
+	go func() {
+		for msg := range msgCh {
+			dataCh <- msg.Message
+		}
+	}()
+
+	return dataCh, nil
+}
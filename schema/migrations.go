@@ -0,0 +1,293 @@
+// Package schema holds the ordered list of database migrations.
+//
+// Migrations are applied with the `migrate` command of the crawler and
+// their versions are recorded in the schema_migrations table. Never edit
+// a migration that has been released, append a new one instead.
+package schema
+
+// Migration is a single, versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrations is the list of all migrations, ordered by version
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial",
+		// Statements are idempotent so that databases created by hand
+		// from the old schema files can be brought under version control.
+		Up: `
+CREATE TABLE IF NOT EXISTS blocks (
+  number BIGINT PRIMARY KEY,
+  timestamp BIGINT,
+  hash bytea,
+  parent_hash bytea,
+  transactions_root bytea,
+  receipts_root bytea,
+  size INT,
+  transaction_count INT,
+  gas_used BIGINT,
+  gas_limit BIGINT,
+  delegates bytea,
+  producer bytea,
+  signature bytea
+);
+
+CREATE INDEX IF NOT EXISTS blockhash_idx ON blocks USING btree (hash);
+CREATE INDEX IF NOT EXISTS producer_idx ON blocks USING btree (producer);
+CREATE INDEX IF NOT EXISTS timestamp_idx ON blocks USING btree (timestamp);
+
+CREATE TABLE IF NOT EXISTS transactions (
+  hash bytea PRIMARY KEY,
+  nonce BIGINT,
+  block_hash bytea,
+  block_number BIGINT,
+  tx_index BIGINT,
+  addr_from bytea,
+  addr_to bytea,
+  value BIGINT,
+  gas_limit BIGINT,
+  gas_used BIGINT,
+  cumulative_gas_used BIGINT,
+  gas_price BIGINT,
+  contract_address bytea,
+  input bytea,
+  status BIGINT,
+  work_nonce BIGINT,
+  timestamp BIGINT
+);
+
+DROP INDEX IF EXISTS txblock_hash_idx;
+CREATE INDEX IF NOT EXISTS txfrom_idx ON transactions USING btree (addr_from);
+CREATE INDEX IF NOT EXISTS txto_idx ON transactions USING btree (addr_to);
+CREATE INDEX IF NOT EXISTS txcontract_address_idx ON transactions USING btree (contract_address);
+CREATE INDEX IF NOT EXISTS txblock_number_idx ON transactions USING btree (block_number);
+CREATE INDEX IF NOT EXISTS txtimestamp_idx ON transactions USING btree (timestamp);
+
+CREATE TABLE IF NOT EXISTS balances (
+  address bytea PRIMARY KEY,
+  amount BIGINT,
+  block_number BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS bl_amount_idx ON balances USING btree (amount);
+
+CREATE TABLE IF NOT EXISTS ens (
+  hash bytea PRIMARY KEY,
+  address bytea,
+  name VARCHAR(64)
+);
+
+CREATE INDEX IF NOT EXISTS ens_address_idx ON ens USING btree (address);
+
+CREATE TABLE IF NOT EXISTS producers (
+  address bytea PRIMARY KEY,
+  produced_blocks_count INT,
+  block_rewards BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS globals (
+  var_name CHAR(64) PRIMARY KEY,
+  value_int BIGINT,
+  value_str VARCHAR(64)
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS globals;
+DROP TABLE IF EXISTS producers;
+DROP TABLE IF EXISTS ens;
+DROP TABLE IF EXISTS balances;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS blocks;
+`,
+	},
+	{
+		Version: 2,
+		Name:    "producers_backfill",
+		// Calculate block rewards for blocks already in the DB. It is skipped
+		// when producers are already tracked, so it is safe on old databases.
+		// The backfilled totals are kept in producers_backfill, so that the
+		// rollback only takes them away from the totals tracked since.
+		Up: `
+CREATE TABLE producers_backfill (
+  address bytea PRIMARY KEY,
+  produced_blocks_count INT,
+  block_rewards BIGINT
+);
+
+INSERT INTO producers_backfill(address, produced_blocks_count, block_rewards)
+SELECT producer, count(*) as produced_blocks_count, count(*) * 3171 as block_rewards
+FROM blocks
+WHERE producer IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM producers)
+GROUP BY producer;
+
+INSERT INTO producers(address, produced_blocks_count, block_rewards)
+SELECT address, produced_blocks_count, block_rewards
+FROM producers_backfill;
+`,
+		Down: `
+UPDATE producers p
+SET produced_blocks_count = p.produced_blocks_count - b.produced_blocks_count,
+    block_rewards = p.block_rewards - b.block_rewards
+FROM producers_backfill b
+WHERE p.address = b.address;
+
+DELETE FROM producers p
+USING producers_backfill b
+WHERE p.address = b.address AND p.produced_blocks_count <= 0;
+
+DROP TABLE producers_backfill;
+`,
+	},
+	{
+		Version: 3,
+		Name:    "keyset_pagination",
+		// Indexes backing the (block_number, tx_index) and (amount, address)
+		// keyset pagination of the list endpoints
+		Up: `
+CREATE INDEX IF NOT EXISTS txfrom_position_idx ON transactions USING btree (addr_from, block_number, tx_index);
+CREATE INDEX IF NOT EXISTS txto_position_idx ON transactions USING btree (addr_to, block_number, tx_index);
+CREATE INDEX IF NOT EXISTS txposition_idx ON transactions USING btree (block_number, tx_index);
+CREATE INDEX IF NOT EXISTS bl_amount_address_idx ON balances USING btree (amount, address);
+`,
+		Down: `
+DROP INDEX IF EXISTS bl_amount_address_idx;
+DROP INDEX IF EXISTS txposition_idx;
+DROP INDEX IF EXISTS txto_position_idx;
+DROP INDEX IF EXISTS txfrom_position_idx;
+`,
+	},
+	{
+		Version: 4,
+		Name:    "webhooks",
+		Up: `
+CREATE TABLE webhooks (
+  id BIGSERIAL PRIMARY KEY,
+  url TEXT NOT NULL,
+  secret VARCHAR(64) NOT NULL,
+  event_type VARCHAR(32) NOT NULL,
+  address bytea NOT NULL,
+  direction VARCHAR(8) NOT NULL,
+  min_value NUMERIC(78) NOT NULL DEFAULT 0,
+  created_at BIGINT
+);
+
+CREATE TABLE webhook_deliveries (
+  id BIGSERIAL PRIMARY KEY,
+  webhook_id BIGINT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+  event_type VARCHAR(32) NOT NULL,
+  payload TEXT NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  status_code INT NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  next_attempt_at BIGINT,
+  created_at BIGINT,
+  updated_at BIGINT
+);
+
+CREATE INDEX webhook_deliveries_webhook_idx ON webhook_deliveries USING btree (webhook_id, id);
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries USING btree (next_attempt_at) WHERE status = 'pending';
+`,
+		Down: `
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+`,
+	},
+	{
+		Version: 5,
+		Name:    "rollups",
+		// Rollups are recomputed from blocks and transactions, existing
+		// databases are backfilled with the rollups command of the crawler.
+		Up: `
+CREATE TABLE rollups (
+  period VARCHAR(8) NOT NULL,
+  bucket BIGINT NOT NULL,
+  block_count BIGINT NOT NULL DEFAULT 0,
+  tx_count BIGINT NOT NULL DEFAULT 0,
+  active_addresses BIGINT NOT NULL DEFAULT 0,
+  new_addresses BIGINT NOT NULL DEFAULT 0,
+  gas_used BIGINT NOT NULL DEFAULT 0,
+  total_block_size BIGINT NOT NULL DEFAULT 0,
+  contract_deployments BIGINT NOT NULL DEFAULT 0,
+  failed_txs BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (period, bucket)
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS rollups;
+`,
+	},
+	{
+		Version: 6,
+		Name:    "contract_abis",
+		Up: `
+CREATE TABLE contract_abis (
+  address BYTEA PRIMARY KEY,
+  abi TEXT NOT NULL,
+  uploaded_at BIGINT NOT NULL
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS contract_abis;
+`,
+	},
+	{
+		Version: 7,
+		Name:    "api_keys",
+		Up: `
+CREATE TABLE api_keys (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  key_hash VARCHAR(64) NOT NULL UNIQUE,
+  prefix VARCHAR(16) NOT NULL,
+  rate_limit BIGINT NOT NULL DEFAULT 0,
+  daily_quota BIGINT NOT NULL DEFAULT 0,
+  created_at BIGINT NOT NULL,
+  revoked_at BIGINT NOT NULL DEFAULT 0
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS api_keys;
+`,
+	},
+	{
+		Version: 8,
+		Name:    "webhooks_api_key",
+		// The key a webhook was created with, which caps the webhooks of a
+		// client. Older webhooks have none.
+		Up: `
+ALTER TABLE webhooks ADD COLUMN api_key_id BIGINT REFERENCES api_keys(id);
+CREATE INDEX webhooks_api_key_idx ON webhooks USING btree (api_key_id);
+`,
+		Down: `
+DROP INDEX IF EXISTS webhooks_api_key_idx;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS api_key_id;
+`,
+	},
+	{
+		Version: 9,
+		Name:    "transactions_value_wei",
+		// Values were stored in 1/10000 EBK, which lost the rest of the
+		// wei. Stored values are converted, the lost digits stay zero.
+		Up: `
+ALTER TABLE transactions ALTER COLUMN value TYPE NUMERIC(78) USING value * 100000000000000;
+`,
+		Down: `
+ALTER TABLE transactions ALTER COLUMN value TYPE BIGINT USING div(value, 100000000000000);
+`,
+	},
+}
+
+// Latest returns the version of the most recent migration
+func Latest() int {
+	if len(Migrations) == 0 {
+		return 0
+	}
+	return Migrations[len(Migrations)-1].Version
+}
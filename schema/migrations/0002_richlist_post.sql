@@ -1,2 +0,0 @@
-
-DROP INDEX txblock_hash_idx;
\ No newline at end of file
@@ -1,6 +0,0 @@
-
-CREATE TABLE producers (
-  address bytea PRIMARY KEY,
-  produced_blocks_count INT,
-  block_rewards BIGINT
-);
@@ -6,8 +6,8 @@ psql postgres -c "drop database $DB_NAME"
 echo "\n* Create postgres db"
 createdb $DB_NAME
 
-echo "\n* Create db structure from schema"
-psql -d $DB_NAME -a -f $GOPATH/src/github.com/ebakus/ebakus-block-explorer-backend/schema/create.sql
+echo "\n* Create db structure from schema migrations"
+$GOPATH/bin/ebakus_crawler migrate up --config $GOPATH/src/github.com/ebakus/ebakus-block-explorer-backend/configs/default.config.yaml --dbname $DB_NAME
 
 echo "\n* Clean redis"
 redis-cli FLUSHDB
@@ -0,0 +1,270 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+)
+
+const (
+	// MaxAttempts is the number of attempts after which a delivery is failed
+	MaxAttempts = 8
+
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 6 * time.Hour
+
+	deliveryBatchSize = 100
+	maxErrorLength    = 512
+)
+
+const (
+	HeaderEvent     = "X-Ebakus-Event"
+	HeaderDelivery  = "X-Ebakus-Delivery"
+	HeaderTimestamp = "X-Ebakus-Timestamp"
+	HeaderSignature = "X-Ebakus-Signature"
+)
+
+var (
+	// ErrNonPublicAddress is returned for deliveries to private, loopback and
+	// link-local addresses, which would let webhooks reach internal services
+	ErrNonPublicAddress = errors.New("webhook address is not public")
+
+	// ErrRedirect is returned for deliveries answered with a redirect, as it
+	// could point anywhere
+	ErrRedirect = errors.New("webhook redirects are not followed")
+)
+
+// nonPublicNetworks are the networks, besides loopback, link-local,
+// multicast and unspecified addresses, that deliveries are refused to
+var nonPublicNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"fc00::/7",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// PublicIP tells whether deliveries may be made to ip
+func PublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// newClient returns the client deliveries are made with. The addresses
+// are checked once resolved, right before connecting, so that names
+// resolving to internal addresses are refused as well.
+func newClient(timeout time.Duration, allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allowed(ip) {
+				return ErrNonPublicAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// no proxy, the checked address is the one connected to
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     time.Minute,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return ErrRedirect
+		},
+	}
+}
+
+// Sign returns the HMAC-SHA256 of "timestamp.body" with the webhook's secret,
+// as sent in the X-Ebakus-Signature header prefixed with "sha256=".
+// Receivers should also reject old timestamps to prevent replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryDelay is the exponential backoff after the attempt-th failed attempt
+func retryDelay(attempt uint64) time.Duration {
+	delay := retryBaseDelay
+	for i := uint64(1); i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// Deliverer sends the pending deliveries of the log
+type Deliverer struct {
+	store  db.Store
+	client *http.Client
+}
+
+// NewDeliverer creates a Deliverer whose requests time out after timeout.
+// Deliveries are only made to public addresses and redirects are not
+// followed.
+func NewDeliverer(store db.Store, timeout time.Duration) *Deliverer {
+	return &Deliverer{
+		store:  store,
+		client: newClient(timeout, PublicIP),
+	}
+}
+
+// Run delivers the pending deliveries every interval, forever
+func (d *Deliverer) Run(interval time.Duration) {
+	for {
+		for {
+			count, err := d.DeliverPending()
+			if err != nil {
+				logger.Error("Failed to deliver webhooks", "err", err)
+			}
+			if err != nil || count < deliveryBatchSize {
+				break
+			}
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// DeliverPending attempts a batch of the deliveries that are due and
+// returns how many were attempted
+func (d *Deliverer) DeliverPending() (int, error) {
+	deliveries, err := d.store.GetPendingWebhookDeliveries(uint64(time.Now().Unix()), deliveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	webhooks := make(map[uint64]*models.Webhook)
+
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook, err = d.store.GetWebhook(delivery.WebhookID)
+			if err == sql.ErrNoRows {
+				// deleted in the meantime, along with its deliveries
+				continue
+			} else if err != nil {
+				return 0, err
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		d.attempt(webhook, &delivery)
+
+		if err := d.store.UpdateWebhookDelivery(delivery); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// attempt POSTs the delivery and updates it with the outcome
+func (d *Deliverer) attempt(webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.StatusCode = 0
+	delivery.Error = ""
+
+	statusCode, err := d.post(webhook, delivery)
+	delivery.StatusCode = uint64(statusCode)
+
+	now := time.Now()
+	delivery.UpdatedAt = uint64(now.Unix())
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WEBHOOK_DELIVERY_DELIVERED
+		return
+	case delivery.Attempts >= MaxAttempts:
+		delivery.Status = models.WEBHOOK_DELIVERY_FAILED
+	default:
+		delivery.NextAttemptAt = uint64(now.Add(retryDelay(delivery.Attempts)).Unix())
+	}
+
+	delivery.Error = err.Error()
+	if len(delivery.Error) > maxErrorLength {
+		delivery.Error = delivery.Error[:maxErrorLength]
+	}
+}
+
+func (d *Deliverer) post(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// drain a bit of the body so that the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
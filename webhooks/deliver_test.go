@@ -0,0 +1,163 @@
+package webhooks
+
+import (
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+)
+
+func TestPublicIP(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:2800::1":    true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"100.64.0.1":      false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"224.0.0.1":       false,
+	}
+
+	for address, public := range cases {
+		if got := PublicIP(net.ParseIP(address)); got != public {
+			t.Errorf("PublicIP(%s) = %v, want %v", address, got, public)
+		}
+	}
+}
+
+// setupDelivery stores a webhook to url with a pending delivery
+func setupDelivery(t *testing.T, url string) (*db.MemoryStore, *models.Webhook) {
+	t.Helper()
+
+	store := db.NewMemoryStore()
+	webhook := &models.Webhook{
+		URL:       url,
+		Secret:    "secret",
+		EventType: models.WEBHOOK_EVENT_TRANSFER,
+		Address:   common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		Direction: models.WEBHOOK_DIRECTION_ANY,
+		MinValue:  big.NewInt(0),
+	}
+	if err := store.InsertWebhook(webhook); err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventType: models.WEBHOOK_EVENT_TRANSFER,
+		Payload:   []byte(`{"hash":"0x01"}`),
+		Status:    models.WEBHOOK_DELIVERY_PENDING,
+	}
+	if err := store.InsertWebhookDeliveries([]models.WebhookDelivery{delivery}); err != nil {
+		t.Fatal(err)
+	}
+
+	return store, webhook
+}
+
+// deliver attempts the pending delivery and returns it
+func deliver(t *testing.T, d *Deliverer, store *db.MemoryStore, webhook *models.Webhook) models.WebhookDelivery {
+	t.Helper()
+
+	if count, err := d.DeliverPending(); err != nil || count != 1 {
+		t.Fatalf("DeliverPending() = %d, %v, want 1 delivery", count, err)
+	}
+
+	deliveries, err := store.GetWebhookDeliveries(webhook.ID, 1)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("GetWebhookDeliveries() = %+v, %v", deliveries, err)
+	}
+	return deliveries[0]
+}
+
+func TestDeliverNonPublicAddress(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	store, webhook := setupDelivery(t, server.URL)
+	delivery := deliver(t, NewDeliverer(store, time.Second), store, webhook)
+
+	if requests != 0 {
+		t.Errorf("the loopback server got %d requests", requests)
+	}
+	if delivery.Status != models.WEBHOOK_DELIVERY_PENDING || !strings.Contains(delivery.Error, ErrNonPublicAddress.Error()) {
+		t.Errorf("delivery = %+v, want it refused", delivery)
+	}
+}
+
+func TestDeliverRedirect(t *testing.T) {
+	redirected := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			redirected = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	}))
+	defer server.Close()
+
+	store, webhook := setupDelivery(t, server.URL+"/hook")
+	d := &Deliverer{
+		store:  store,
+		client: newClient(time.Second, func(net.IP) bool { return true }),
+	}
+	delivery := deliver(t, d, store, webhook)
+
+	if redirected {
+		t.Errorf("the redirect was followed")
+	}
+	if delivery.Status != models.WEBHOOK_DELIVERY_PENDING || !strings.Contains(delivery.Error, ErrRedirect.Error()) {
+		t.Errorf("delivery = %+v, want the redirect refused", delivery)
+	}
+}
+
+func TestDeliverSignature(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	store, webhook := setupDelivery(t, server.URL)
+	d := &Deliverer{
+		store:  store,
+		client: newClient(time.Second, func(net.IP) bool { return true }),
+	}
+	delivery := deliver(t, d, store, webhook)
+
+	if delivery.Status != models.WEBHOOK_DELIVERY_DELIVERED || delivery.StatusCode != http.StatusOK {
+		t.Fatalf("delivery = %+v, want it delivered", delivery)
+	}
+
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature := header.Get(HeaderSignature); signature != "sha256="+Sign(webhook.Secret, timestamp, body) {
+		t.Errorf("%s = %s, want the signature of the body", HeaderSignature, signature)
+	}
+	if event := header.Get(HeaderEvent); event != models.WEBHOOK_EVENT_TRANSFER {
+		t.Errorf("%s = %s", HeaderEvent, event)
+	}
+}
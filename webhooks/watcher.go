@@ -0,0 +1,278 @@
+// Package webhooks matches the ingested data against the webhook
+// subscriptions and delivers the resulting events.
+//
+// The crawler only queues deliveries in the log while ingesting blocks,
+// they are sent by the long running Deliverer, which retries failures.
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/ipc"
+	"github.com/ebakus/ebakus-block-explorer-backend/logger"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/params"
+)
+
+// Payload is the body POSTed to the webhook URL
+type Payload struct {
+	Event       string                  `json:"event"`
+	WebhookID   uint64                  `json:"webhookId"`
+	Direction   string                  `json:"direction,omitempty"`
+	Transaction *models.TransactionFull `json:"transaction,omitempty"`
+	MissedSlot  *models.MissedSlot      `json:"missedSlot,omitempty"`
+}
+
+// Watcher evaluates the webhooks on the blocks and transactions ingested
+// by the crawler and queues the deliveries. It works on the webhooks
+// existing when it is created. Transactions and Blocks may be called
+// concurrently, but not each of them with itself.
+type Watcher struct {
+	store      db.Store
+	dposConfig *params.DPOSConfig
+
+	transfers   []models.Webhook
+	missedSlots map[common.Address][]models.Webhook
+
+	// blocks whose parent was not stored yet when they were received
+	pending []*models.Block
+}
+
+// NewWatcher loads the webhooks from the store
+func NewWatcher(store db.Store, chainID uint64) (*Watcher, error) {
+	webhooks, err := store.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		store:       store,
+		dposConfig:  ipc.DPOSConfig(chainID),
+		missedSlots: make(map[common.Address][]models.Webhook),
+	}
+
+	for _, webhook := range webhooks {
+		switch webhook.EventType {
+		case models.WEBHOOK_EVENT_TRANSFER:
+			w.transfers = append(w.transfers, webhook)
+		case models.WEBHOOK_EVENT_MISSED_SLOT:
+			w.missedSlots[webhook.Address] = append(w.missedSlots[webhook.Address], webhook)
+		}
+	}
+
+	return w, nil
+}
+
+// transferDirection returns the direction of the transfer from the point
+// of view of the webhook's address, or "" if it doesn't match
+func transferDirection(webhook models.Webhook, tx *models.Transaction) string {
+	value := tx.Value.ToInt()
+	if value.Sign() <= 0 || value.Cmp(webhook.MinValue) < 0 {
+		return ""
+	}
+
+	in := webhook.Direction != models.WEBHOOK_DIRECTION_OUT && tx.To != nil && *tx.To == webhook.Address
+	out := webhook.Direction != models.WEBHOOK_DIRECTION_IN && tx.From == webhook.Address
+
+	switch {
+	case in:
+		return models.WEBHOOK_DIRECTION_IN
+	case out:
+		return models.WEBHOOK_DIRECTION_OUT
+	}
+
+	return ""
+}
+
+// Transactions queues the transfer events of the transactions
+func (w *Watcher) Transactions(txs []models.TransactionFull) {
+	if len(w.transfers) == 0 {
+		return
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0)
+
+	for i := range txs {
+		tf := &txs[i]
+
+		// failed transactions don't move any value
+		if tf.Tx == nil || tf.Txr == nil || tf.Txr.Status != 1 {
+			continue
+		}
+
+		for _, webhook := range w.transfers {
+			direction := transferDirection(webhook, tf.Tx)
+			if direction == "" {
+				continue
+			}
+
+			payload := Payload{Event: models.WEBHOOK_EVENT_TRANSFER, WebhookID: webhook.ID, Direction: direction, Transaction: tf}
+			if d, err := newDelivery(webhook, payload); err == nil {
+				deliveries = append(deliveries, d)
+			}
+		}
+	}
+
+	w.queue(deliveries)
+}
+
+// Blocks queues the missed slot events of the blocks. Blocks are checked
+// against their parent, from the same batch or else from the store, so the
+// ones whose parent isn't stored yet are kept until a later call or Flush.
+// The crawler inserts blocks newest first, which leaves the oldest block of
+// each batch waiting for the next one.
+func (w *Watcher) Blocks(blocks []*models.Block) {
+	if len(w.missedSlots) == 0 {
+		return
+	}
+
+	w.pending = append(w.pending, blocks...)
+	w.checkPending(false)
+}
+
+// Flush checks the blocks still waiting for their parent, dropping the ones
+// whose parent is missing for good (e.g. the genesis block)
+func (w *Watcher) Flush() {
+	if len(w.missedSlots) == 0 {
+		return
+	}
+
+	w.checkPending(true)
+}
+
+// findParents returns the parents of the pending blocks that are pending
+// themselves or stored, looking up the rest with a single query
+func (w *Watcher) findParents() (map[uint64]*models.Block, error) {
+	parents := make(map[uint64]*models.Block, len(w.pending))
+	for _, block := range w.pending {
+		parents[uint64(block.Number)] = block
+	}
+
+	missing := make([]uint64, 0)
+	for _, block := range w.pending {
+		if number := uint64(block.Number); number > 0 && parents[number-1] == nil {
+			missing = append(missing, number-1)
+		}
+	}
+	if len(missing) == 0 {
+		return parents, nil
+	}
+
+	stored, err := w.store.GetBlocksByNumbers(missing)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stored {
+		parents[uint64(stored[i].Number)] = &stored[i]
+	}
+
+	return parents, nil
+}
+
+func (w *Watcher) checkPending(final bool) {
+	parents, err := w.findParents()
+	if err != nil {
+		// the blocks stay pending and are checked again with the next
+		// batch, unless this is the last chance
+		if final {
+			logger.Error("Failed to check missed slots, dropping blocks", "blocks", len(w.pending), "err", err)
+			w.pending = nil
+		} else {
+			logger.Warn("Failed to check missed slots, retrying with the next blocks", "blocks", len(w.pending), "err", err)
+		}
+		return
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	pending := make([]*models.Block, 0)
+
+	for _, block := range w.pending {
+		if block.Number == 0 {
+			continue
+		}
+
+		parent := parents[uint64(block.Number)-1]
+		if parent == nil || parent.Hash == (common.Hash{}) {
+			if final {
+				logger.Debug("Skipping missed slots of block without parent", "number", block.Number)
+			} else {
+				pending = append(pending, block)
+			}
+			continue
+		}
+
+		for _, missed := range w.findMissedSlots(parent, block) {
+			missed := missed
+			for _, webhook := range w.missedSlots[missed.Delegate] {
+				payload := Payload{Event: models.WEBHOOK_EVENT_MISSED_SLOT, WebhookID: webhook.ID, MissedSlot: &missed}
+				if d, err := newDelivery(webhook, payload); err == nil {
+					deliveries = append(deliveries, d)
+				}
+			}
+		}
+	}
+
+	w.pending = pending
+	w.queue(deliveries)
+}
+
+// findMissedSlots walks the slots after the parent up to the block and returns
+// the ones where the scheduled delegate didn't produce a block
+func (w *Watcher) findMissedSlots(parent *models.Block, block *models.Block) []models.MissedSlot {
+	missed := make([]models.MissedSlot, 0)
+
+	period := w.dposConfig.Period
+	if period == 0 {
+		return missed
+	}
+
+	for t := uint64(parent.TimeStamp) + period; t <= uint64(block.TimeStamp); t += period {
+		delegate := ipc.SignerAtSlot(w.dposConfig, parent.Delegates, float64(t)/float64(period))
+		if delegate == (common.Address{}) {
+			continue
+		}
+
+		if t < uint64(block.TimeStamp) {
+			missed = append(missed, models.MissedSlot{Delegate: delegate, Timestamp: t, BlockNumber: uint64(block.Number)})
+		} else if block.Producer != delegate {
+			producer := block.Producer
+			missed = append(missed, models.MissedSlot{Delegate: delegate, Timestamp: t, BlockNumber: uint64(block.Number), Producer: &producer})
+		}
+	}
+
+	return missed
+}
+
+func (w *Watcher) queue(deliveries []models.WebhookDelivery) {
+	if len(deliveries) == 0 {
+		return
+	}
+
+	if err := w.store.InsertWebhookDeliveries(deliveries); err != nil {
+		logger.Error("Failed to queue webhook deliveries", "err", err)
+	}
+}
+
+func newDelivery(webhook models.Webhook, payload Payload) (models.WebhookDelivery, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("Failed to encode webhook payload", "err", err)
+		return models.WebhookDelivery{}, err
+	}
+
+	now := uint64(time.Now().Unix())
+
+	return models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventType:     payload.Event,
+		Payload:       data,
+		Status:        models.WEBHOOK_DELIVERY_PENDING,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ebakus/ebakus-block-explorer-backend/db"
+	"github.com/ebakus/ebakus-block-explorer-backend/models"
+
+	"github.com/ebakus/go-ebakus/common"
+	"github.com/ebakus/go-ebakus/common/hexutil"
+)
+
+// blockStore counts the lookups of parent blocks, failing the first ones
+// when told to
+type blockStore struct {
+	*db.MemoryStore
+	lookups  int
+	failures int
+}
+
+func (s *blockStore) GetBlocksByNumbers(numbers []uint64) ([]models.Block, error) {
+	s.lookups++
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("connection refused")
+	}
+	return s.MemoryStore.GetBlocksByNumbers(numbers)
+}
+
+func TestWatcherMissedSlots(t *testing.T) {
+	delegate := common.HexToAddress("0xdddddddddddddddddddddddddddddddddddddddd")
+	delegates := []common.Address{delegate, delegate, delegate}
+
+	// the slot after block 2 is missed, on a testnet with 1 second slots
+	blocks := make([]*models.Block, 6)
+	for i := range blocks {
+		timestamp := uint64(1583020800 + i)
+		if i >= 3 {
+			timestamp++
+		}
+		blocks[i] = &models.Block{
+			Number:    hexutil.Uint64(i),
+			TimeStamp: hexutil.Uint64(timestamp),
+			Hash:      common.BigToHash(big.NewInt(int64(i + 1))),
+			Delegates: delegates,
+			Producer:  delegate,
+		}
+	}
+
+	for _, c := range []struct {
+		name     string
+		failures int
+	}{
+		{"stored parents", 0},
+		{"failed lookup", 1},
+	} {
+		store := &blockStore{MemoryStore: db.NewMemoryStore(), failures: c.failures}
+		webhook := &models.Webhook{URL: "https://example.com/hook", EventType: models.WEBHOOK_EVENT_MISSED_SLOT, Address: delegate}
+		if err := store.InsertWebhook(webhook); err != nil {
+			t.Fatal(err)
+		}
+
+		watcher, err := NewWatcher(store, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the crawler inserts the blocks newest first, in batches
+		for _, batch := range [][]*models.Block{
+			{blocks[5], blocks[4], blocks[3]},
+			{blocks[2], blocks[1], blocks[0]},
+		} {
+			if err := store.InsertBlocks(batch); err != nil {
+				t.Fatal(err)
+			}
+			watcher.Blocks(batch)
+		}
+		watcher.Flush()
+
+		deliveries, err := store.GetWebhookDeliveries(webhook.ID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) != 1 {
+			t.Fatalf("%s: %d deliveries, want the missed slot of block 3", c.name, len(deliveries))
+		}
+		if payload := string(deliveries[0].Payload); !strings.Contains(payload, `"blockNumber":3`) {
+			t.Errorf("%s: payload = %s, want the missed slot of block 3", c.name, payload)
+		}
+
+		// only the parent of the oldest block of the first batch is looked up
+		if store.lookups != 1 {
+			t.Errorf("%s: %d lookups of parents, want 1", c.name, store.lookups)
+		}
+	}
+}